@@ -0,0 +1,48 @@
+// Command export writes the forum's data in the database at DB_URL to stdout as a JSON Lines archive
+//
+//	go run ./cmd/export [-passwords] > forum.jsonl
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/joho/godotenv"
+	"gorm.io/driver/postgres"
+
+	"cvwo-backend/internal/archive"
+	"cvwo-backend/internal/data"
+)
+
+func main() {
+	passwords := flag.Bool("passwords", false, "include the users' password hashes, so that they can log in after an import")
+	flag.Parse()
+
+	// Load .env file in development but not in production
+	if os.Getenv("ENV") == "development" {
+		if err := godotenv.Load(".env.development"); err != nil {
+			log.Fatal("Error loading .env file")
+		}
+	}
+
+	// Open the database without seeding it
+	db, err := data.Open(postgres.Open(os.Getenv("DB_URL")))
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	writer := bufio.NewWriter(os.Stdout)
+	if err := archive.Export(ctx, db, writer, archive.Options{Passwords: *passwords}); err != nil {
+		log.Fatalf("Failed to export: %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		log.Fatalf("Failed to export: %v", err)
+	}
+}
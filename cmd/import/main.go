@@ -0,0 +1,43 @@
+// Command import restores a JSON Lines archive written by the export command from stdin into the empty database at DB_URL
+//
+//	go run ./cmd/import < forum.jsonl
+//
+// The database is migrated first. Nothing is imported if it already has any users, topics, posts or comments.
+package main
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/joho/godotenv"
+	"gorm.io/driver/postgres"
+
+	"cvwo-backend/internal/archive"
+	"cvwo-backend/internal/data"
+)
+
+func main() {
+	// Load .env file in development but not in production
+	if os.Getenv("ENV") == "development" {
+		if err := godotenv.Load(".env.development"); err != nil {
+			log.Fatal("Error loading .env file")
+		}
+	}
+
+	// Open the database without seeding it, since it must be empty
+	db, err := data.Open(postgres.Open(os.Getenv("DB_URL")))
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := archive.Import(ctx, db, bufio.NewReader(os.Stdin)); err != nil {
+		log.Fatalf("Failed to import: %v", err)
+	}
+	log.Println("Imported archive")
+}
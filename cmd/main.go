@@ -1,20 +1,23 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 
-	"cvwo-backend/internal/controllers"
+	"cvwo-backend/internal/app"
 	"cvwo-backend/internal/data"
-	"cvwo-backend/internal/middleware"
-	"cvwo-backend/internal/repos"
-	"cvwo-backend/internal/routes"
-	"cvwo-backend/internal/services"
+	"cvwo-backend/internal/events"
+	"cvwo-backend/internal/jobs"
+	"cvwo-backend/internal/tracing"
 )
 
 func main() {
@@ -23,62 +26,72 @@ func main() {
 		if err := godotenv.Load(".env.development"); err != nil {
 			log.Fatal("Error loading .env file")
 		}
+	} else {
+		// Send compact JSON and skip debug logging in production
+		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// Initialize tracing with the exporter selected by OTEL_TRACES_EXPORTER (stdout, otlp or none)
+	shutdownTracing, err := tracing.Init(context.Background(), os.Getenv("OTEL_TRACES_EXPORTER"))
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Initialize database
 	db := data.InitDB(os.Getenv("DB_URL"))
 
-	// Initialize application layers
-	// Repositories (data access)
-	userRepo := repos.NewUserRepo(db)
-	postRepo := repos.NewPostRepo(db)
-	commentRepo := repos.NewCommentRepo(db)
-	topicRepo := repos.NewTopicRepo(db)
-	postVoteRepo := repos.NewPostVoteRepo(db)
-	commentVoteRepo := repos.NewCommentVoteRepo(db)
-
-	// Services (business logic)
-	userService := services.NewUserService(*userRepo)
-	postService := services.NewPostService(*postRepo, *userRepo, *topicRepo)
-	commentService := services.NewCommentService(*commentRepo, *postRepo, *userRepo)
-	topicService := services.NewTopicService(*topicRepo)
-	taggingService := services.NewTaggingService(*postRepo, *topicRepo)
-	votingService := services.NewVotingService(*postVoteRepo, *commentVoteRepo)
-	authService := services.NewAuthService(*userRepo)
-
-	// Controllers (route handlers)
-	userController := controllers.NewUserController(*userService)
-	postController := controllers.NewPostController(*postService, *taggingService, *votingService)
-	commentController := controllers.NewCommentController(*commentService, *votingService)
-	topicController := controllers.NewTopicController(*topicService)
-	authController := controllers.NewAuthController(authService)
+	// Grant moderator to the comma-separated usernames in MODERATORS
+	if err := data.GrantModerators(db, app.ModeratorsFromEnv()); err != nil {
+		log.Fatalf("Failed to grant moderators: %v", err)
+	}
 
-	// Initialize router
-	router := gin.Default()
+	config, err := app.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
 
-	// Logger middleware to log request body of all requests
-	router.Use(middleware.ResponseLogger)
+	// Stop gracefully on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// CORS middleware
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{os.Getenv("FRONTEND_URL")},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
+	// Run background jobs such as publishing scheduled posts and deleting orphaned attachment files
+	runner, err := app.NewJobRunner(db, config, jobs.DefaultOptions)
+	if err != nil {
+		log.Fatalf("Failed to create job runner: %v", err)
+	}
+	// Dispatch domain events from the outbox to their subscribers, which run as jobs
+	dispatcher := events.NewDispatcher(db, runner, time.Second)
+	app.SubscribeToEvents(dispatcher, config)
+	go dispatcher.Run(ctx)
+	runnerDone := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(runnerDone)
+	}()
 
-	// Authentication middleware to validate jwt from requests
-	authMiddleware := middleware.NewAuthMiddleware(authService)
-	router.Use(authMiddleware.Authenticate())
+	// Initialize router with all application layers
+	router := app.NewRouter(db, config)
 
-	// Register route handlers
-	routes.RegisterUserRoutes(router, userController)
-	routes.RegisterPostRoutes(router, postController)
-	routes.RegisterCommentRoutes(router, commentController)
-	routes.RegisterTopicRoutes(router, topicController)
-	routes.RegisterAuthRoutes(router, authController)
+	// Listen on PORT, like gin's default
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	server := &http.Server{Addr: ":" + port, Handler: router}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
 
-	router.Run()
+	// Wait for a signal, then finish in-flight requests and jobs
+	<-ctx.Done()
+	log.Println("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to shut down server: %v", err)
+	}
+	<-runnerDone
 }
@@ -0,0 +1,113 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"cvwo-backend/internal/archive"
+	"cvwo-backend/internal/data"
+	"cvwo-backend/internal/models"
+)
+
+// Moderators export the forum, which is restored into an empty database where its users can log in
+func TestExportAndImport(t *testing.T) {
+	server := newTestServer(t)
+	topics := server.seedTopics("Philosophy", "Literature")
+	alice, aliceToken := server.registerAndLogin("alice", "password")
+	_, modToken := server.registerAndLogin("mod", "password")
+	if err := data.GrantModerators(server.db, []string{"mod"}); err != nil {
+		t.Fatalf("failed to grant moderators: %v", err)
+	}
+
+	post := server.createPost(aliceToken, "First", []uint{topics[1].ID})
+	server.request(http.MethodPost, "/comments", modToken, gin.H{"content": "A comment", "post_id": post.ID}).expectStatus(http.StatusCreated)
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/votes/%d", post.ID, alice.ID), aliceToken, gin.H{"value": 1}).expectStatus(http.StatusNoContent)
+
+	// A poll that alice voted in, pinned to its topic
+	var pollPost models.Post
+	server.request(http.MethodPost, "/posts", aliceToken, gin.H{
+		"title":     "Lunch",
+		"content":   "Where should we go?",
+		"topic_ids": []uint{topics[0].ID},
+		"poll":      gin.H{"options": []string{"Pizza", "Sushi"}},
+	}).expectStatus(http.StatusCreated).decode(&pollPost)
+	sushi := pollPost.Poll.Options[1].ID
+	server.request(http.MethodPost, fmt.Sprintf("/posts/%d/poll/votes", pollPost.ID), aliceToken, gin.H{"option_ids": []uint{sushi}}).expectStatus(http.StatusCreated)
+	server.createPost(aliceToken, "Newer", []uint{topics[0].ID})
+	server.request(http.MethodPut, fmt.Sprintf("/topics/%d/pins/%d", topics[0].ID, pollPost.ID), modToken, gin.H{"position": 0}).expectStatus(http.StatusNoContent)
+
+	// Only moderators can export
+	server.request(http.MethodGet, "/admin/export", "", nil).expectStatus(http.StatusUnauthorized)
+	res := server.request(http.MethodGet, "/admin/export", aliceToken, nil).expectStatus(http.StatusForbidden)
+	if res.header.Get("Content-Disposition") != "" {
+		t.Errorf("expected errors not to be sent as a download, got %q", res.header.Get("Content-Disposition"))
+	}
+
+	// Password hashes are never sent over HTTP, even if requested
+	res = server.request(http.MethodGet, "/admin/export?passwords=true", modToken, nil).expectStatus(http.StatusOK)
+	if got := res.header.Get("Content-Type"); got != "application/x-ndjson" {
+		t.Errorf("expected a JSON Lines content type, got %q", got)
+	}
+	if !strings.HasPrefix(res.header.Get("Content-Disposition"), `attachment; filename="forum-`) {
+		t.Errorf("expected the archive to be downloaded as a file, got %q", res.header.Get("Content-Disposition"))
+	}
+	if bytes.Contains(res.body, []byte(`"password"`)) || !bytes.Contains(res.body, []byte(`"passwords":false`)) {
+		t.Errorf("expected no password hashes, got %s", res.body)
+	}
+
+	// Only the export command includes them, so that users can log in after an import
+	var exported bytes.Buffer
+	if err := archive.Export(context.Background(), server.db, &exported, archive.Options{Passwords: true}); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+
+	t.Run("import", func(t *testing.T) {
+		restored := newTestServer(t)
+		if err := archive.Import(context.Background(), restored.db, &exported); err != nil {
+			t.Fatalf("failed to import: %v", err)
+		}
+
+		// Users log in with their passwords, and see everything under the same IDs
+		_, token := restored.registerAndLogin("bob", "password")
+		var login struct {
+			Token string `json:"token"`
+		}
+		restored.request(http.MethodPost, "/login", "", gin.H{"username": "alice", "password": "password"}).expectStatus(http.StatusOK).decode(&login)
+		var got struct {
+			ID       uint   `json:"id"`
+			Title    string `json:"title"`
+			Votes    int    `json:"votes"`
+			AuthorID uint   `json:"author_id"`
+			Topics   []struct {
+				Name string `json:"name"`
+			} `json:"topics"`
+		}
+		restored.request(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), token, nil).expectStatus(http.StatusOK).decode(&got)
+		if got.Title != "First" || got.Votes != 1 || got.AuthorID != alice.ID || len(got.Topics) != 1 || got.Topics[0].Name != "Literature" {
+			t.Errorf("expected the post with its votes, author and topics, got %+v", got)
+		}
+		var comments commentList
+		restored.request(http.MethodGet, fmt.Sprintf("/posts/%d/comments", post.ID), "", nil).expectStatus(http.StatusOK).decode(&comments)
+		if comments.TotalCount != 1 || comments.Data[0].Content != "A comment" {
+			t.Errorf("expected the comment, got %+v", comments)
+		}
+
+		// The poll keeps its options and votes, and stays pinned above newer posts of its topic
+		var fetched models.Post
+		restored.request(http.MethodGet, fmt.Sprintf("/posts/%d", pollPost.ID), login.Token, nil).expectStatus(http.StatusOK).decode(&fetched)
+		if fetched.Poll == nil || len(fetched.Poll.Options) != 2 || !fetched.Poll.Voted || !fetched.Poll.Options[1].Selected || *fetched.Poll.Options[1].Votes != 1 {
+			t.Errorf("expected the poll with alice's vote, got %+v", fetched.Poll)
+		}
+		var list postList
+		restored.request(http.MethodGet, fmt.Sprintf("/posts?tag=%d", topics[0].ID), "", nil).expectStatus(http.StatusOK).decode(&list)
+		if len(list.Data) != 2 || list.Data[0].ID != pollPost.ID || !list.Data[0].Pinned {
+			t.Errorf("expected the poll to be pinned to its topic, got %+v", list.Data)
+		}
+	})
+}
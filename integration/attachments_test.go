@@ -0,0 +1,143 @@
+package integration
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"strings"
+	"testing"
+
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Upload attachments with multipart forms, download them and delete them with their post
+func TestAttachments(t *testing.T) {
+	server := newTestServer(t)
+	_, aliceToken := server.registerAndLogin("alice", "password")
+	_, bobToken := server.registerAndLogin("bob", "password")
+	post := server.createPost(aliceToken, "With attachments", nil)
+
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for x := 0; x < 400; x++ {
+		img.Set(x, x/2, color.Black)
+	}
+	var photo bytes.Buffer
+	if err := png.Encode(&photo, img); err != nil {
+		t.Fatalf("failed to encode image: %v", err)
+	}
+
+	var attachment models.Attachment
+	server.upload(fmt.Sprintf("/posts/%d/attachments", post.ID), aliceToken, `../../photo "1".png`, photo.Bytes()).
+		expectStatus(http.StatusCreated).decode(&attachment)
+	if attachment.ContentType != "image/png" || attachment.Width != 400 || attachment.Height != 200 || !attachment.HasThumbnail || attachment.Filename != `photo "1".png` {
+		t.Fatalf("unexpected attachment: %+v", attachment)
+	}
+
+	// Attachments are returned with the post
+	var fetched models.Post
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), "", nil).expectStatus(http.StatusOK).decode(&fetched)
+	if len(fetched.Attachments) != 1 || fetched.Attachments[0].ID != attachment.ID {
+		t.Fatalf("expected the attachment on the post, got %+v", fetched.Attachments)
+	}
+
+	// The file is served as uploaded, inline since it is an image
+	res := server.request(http.MethodGet, fmt.Sprintf("/attachments/%d", attachment.ID), "", nil).expectStatus(http.StatusOK)
+	if !bytes.Equal(res.body, photo.Bytes()) || res.header.Get("Content-Type") != "image/png" || res.header.Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatalf("unexpected download: %v", res.header)
+	}
+	if disposition := res.header.Get("Content-Disposition"); disposition != `inline; filename="photo \"1\".png"` {
+		t.Errorf("unexpected Content-Disposition %q", disposition)
+	}
+	res = server.request(http.MethodGet, fmt.Sprintf("/attachments/%d/thumbnail", attachment.ID), "", nil).expectStatus(http.StatusOK)
+	if config, format, err := image.DecodeConfig(bytes.NewReader(res.body)); err != nil || format != "jpeg" || config.Width != 320 || config.Height != 160 {
+		t.Fatalf("unexpected thumbnail: %+v %s %v", config, format, err)
+	}
+
+	// Files other than images are downloaded rather than shown
+	var text models.Attachment
+	server.upload(fmt.Sprintf("/posts/%d/attachments", post.ID), aliceToken, "page.html", []byte("<html><script>alert(1)</script></html>")).
+		expectStatus(http.StatusUnsupportedMediaType)
+	server.upload(fmt.Sprintf("/posts/%d/attachments", post.ID), aliceToken, "notes.txt", []byte("some notes")).
+		expectStatus(http.StatusCreated).decode(&text)
+	res = server.request(http.MethodGet, fmt.Sprintf("/attachments/%d", text.ID), "", nil).expectStatus(http.StatusOK)
+	if !strings.HasPrefix(res.header.Get("Content-Disposition"), "attachment;") {
+		t.Errorf("expected text to be downloaded, got %q", res.header.Get("Content-Disposition"))
+	}
+
+	// Uploads are limited in size, and to the post's author
+	var problem errs.Problem
+	server.upload(fmt.Sprintf("/posts/%d/attachments", post.ID), aliceToken, "big.txt", bytes.Repeat([]byte("a"), int(services.DefaultAttachmentLimits.MaxSize)+1)).
+		expectStatus(http.StatusRequestEntityTooLarge).decode(&problem)
+	if problem.Code != "too_large" {
+		t.Errorf("expected too_large, got %+v", problem)
+	}
+	server.upload(fmt.Sprintf("/posts/%d/attachments", post.ID), bobToken, "notes.txt", []byte("some notes")).expectStatus(http.StatusForbidden)
+	server.upload(fmt.Sprintf("/posts/%d/attachments", post.ID), "", "notes.txt", []byte("some notes")).expectStatus(http.StatusUnauthorized)
+	server.request(http.MethodPost, fmt.Sprintf("/posts/%d/attachments", post.ID), aliceToken, gin.H{"file": "notes"}).expectStatus(http.StatusBadRequest)
+
+	// Deleting an attachment is limited to its uploader
+	server.request(http.MethodDelete, fmt.Sprintf("/attachments/%d", text.ID), bobToken, nil).expectStatus(http.StatusForbidden)
+	server.request(http.MethodDelete, fmt.Sprintf("/attachments/%d", text.ID), aliceToken, nil).expectStatus(http.StatusNoContent)
+	server.request(http.MethodGet, fmt.Sprintf("/attachments/%d", text.ID), "", nil).expectStatus(http.StatusNotFound)
+
+	// Deleting the post deletes its attachments
+	server.request(http.MethodDelete, fmt.Sprintf("/posts/%d", post.ID), aliceToken, nil).expectStatus(http.StatusNoContent)
+	server.request(http.MethodGet, fmt.Sprintf("/attachments/%d", attachment.ID), "", nil).expectStatus(http.StatusNotFound)
+	server.request(http.MethodGet, fmt.Sprintf("/attachments/%d/thumbnail", attachment.ID), "", nil).expectStatus(http.StatusNotFound)
+}
+
+// Attach a file to a comment and see it in the post's comments
+func TestCommentAttachments(t *testing.T) {
+	server := newTestServer(t)
+	_, token := server.registerAndLogin("alice", "password")
+	post := server.createPost(token, "Discussion", nil)
+
+	var comment models.Comment
+	server.request(http.MethodPost, "/comments", token, gin.H{"content": "See the attached notes", "post_id": post.ID}).expectStatus(http.StatusCreated).decode(&comment)
+	var attachment models.Attachment
+	server.upload(fmt.Sprintf("/comments/%d/attachments", comment.ID), token, "notes.txt", []byte("some notes")).
+		expectStatus(http.StatusCreated).decode(&attachment)
+
+	var comments commentList
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d/comments", post.ID), "", nil).expectStatus(http.StatusOK).decode(&comments)
+	if len(comments.Data) != 1 || len(comments.Data[0].Attachments) != 1 || comments.Data[0].Attachments[0].ID != attachment.ID {
+		t.Fatalf("expected the attachment on the comment, got %+v", comments.Data)
+	}
+
+	server.request(http.MethodDelete, fmt.Sprintf("/comments/%d", comment.ID), token, nil).expectStatus(http.StatusNoContent)
+	server.request(http.MethodGet, fmt.Sprintf("/attachments/%d", attachment.ID), "", nil).expectStatus(http.StatusNotFound)
+}
+
+// Attachments of drafts are only served to their author, until the draft is published
+func TestDraftAttachments(t *testing.T) {
+	server := newTestServer(t)
+	_, aliceToken := server.registerAndLogin("alice", "password")
+	_, bobToken := server.registerAndLogin("bob", "password")
+
+	var draft models.Post
+	server.request(http.MethodPost, "/posts", aliceToken, gin.H{"title": "Draft", "content": "Not published yet", "status": "draft"}).expectStatus(http.StatusCreated).decode(&draft)
+	var attachment models.Attachment
+	server.upload(fmt.Sprintf("/posts/%d/attachments", draft.ID), aliceToken, "notes.txt", []byte("some notes")).
+		expectStatus(http.StatusCreated).decode(&attachment)
+
+	path := fmt.Sprintf("/attachments/%d", attachment.ID)
+	server.request(http.MethodGet, path, "", nil).expectStatus(http.StatusNotFound)
+	server.request(http.MethodGet, path, bobToken, nil).expectStatus(http.StatusNotFound)
+	res := server.request(http.MethodGet, path, aliceToken, nil).expectStatus(http.StatusOK)
+	if got := res.header.Get("Cache-Control"); got != "private, no-cache" {
+		t.Errorf("expected the draft's attachment not to be stored by shared caches, got %q", got)
+	}
+
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/status", draft.ID), aliceToken, gin.H{"status": "published"}).expectStatus(http.StatusOK)
+	res = server.request(http.MethodGet, path, "", nil).expectStatus(http.StatusOK)
+	if got := res.header.Get("Cache-Control"); got != "public, max-age=31536000, immutable" {
+		t.Errorf("expected the published attachment to be publicly cacheable, got %q", got)
+	}
+}
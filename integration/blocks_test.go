@@ -0,0 +1,91 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"cvwo-backend/internal/models"
+)
+
+type blockList struct {
+	Data       []models.Block `json:"data"`
+	TotalCount int64          `json:"total_count"`
+}
+
+// Block and mute users, and check that their content is hidden and that blocked users cannot reply or mention
+func TestBlocksAndMutes(t *testing.T) {
+	server := newTestServer(t)
+	topics := server.seedTopics("Philosophy")
+	_, aliceToken := server.registerAndLogin("alice", "password")
+	bob, bobToken := server.registerAndLogin("bob", "password")
+	carol, carolToken := server.registerAndLogin("carol", "password")
+
+	alicesPost := server.createPost(aliceToken, "Alice's post", []uint{topics[0].ID})
+	bobsPost := server.createPost(bobToken, "Bob's post", []uint{topics[0].ID})
+	carolsPost := server.createPost(carolToken, "Carol's post", []uint{topics[0].ID})
+	server.request(http.MethodPost, "/comments", bobToken, gin.H{"content": "Bob's comment", "post_id": carolsPost.ID}).expectStatus(http.StatusCreated)
+	server.request(http.MethodPost, "/comments", carolToken, gin.H{"content": "Carol's comment", "post_id": carolsPost.ID}).expectStatus(http.StatusCreated)
+
+	server.request(http.MethodPut, fmt.Sprintf("/users/me/blocks/%d", bob.ID), aliceToken, gin.H{"type": "ignore"}).expectStatus(http.StatusBadRequest)
+	server.request(http.MethodPut, "/users/me/blocks/999", aliceToken, gin.H{"type": "block"}).expectStatus(http.StatusNotFound)
+	server.request(http.MethodPut, fmt.Sprintf("/users/me/blocks/%d", bob.ID), "", gin.H{"type": "block"}).expectStatus(http.StatusUnauthorized)
+	server.request(http.MethodPut, fmt.Sprintf("/users/me/blocks/%d", bob.ID), aliceToken, gin.H{"type": "block"}).expectStatus(http.StatusNoContent)
+	server.request(http.MethodPut, fmt.Sprintf("/users/me/blocks/%d", carol.ID), aliceToken, gin.H{"type": "mute"}).expectStatus(http.StatusNoContent)
+
+	var blocks blockList
+	server.request(http.MethodGet, "/users/me/blocks?type=block", aliceToken, nil).expectStatus(http.StatusOK).decode(&blocks)
+	if blocks.TotalCount != 1 || blocks.Data[0].BlockedUserID != bob.ID || blocks.Data[0].BlockedUser == nil || blocks.Data[0].BlockedUser.Username != "bob" {
+		t.Fatalf("unexpected blocks: %+v", blocks)
+	}
+	server.request(http.MethodGet, "/users/me/blocks", aliceToken, nil).expectStatus(http.StatusOK).decode(&blocks)
+	if blocks.TotalCount != 2 {
+		t.Fatalf("expected 2 blocks and mutes, got %d", blocks.TotalCount)
+	}
+
+	// Blocked and muted users' posts are hidden from every listing of the blocking user
+	var posts postList
+	for _, path := range []string{"/posts", fmt.Sprintf("/posts?tag=%d", topics[0].ID)} {
+		server.request(http.MethodGet, path, aliceToken, nil).expectStatus(http.StatusOK).decode(&posts)
+		if want := []uint{alicesPost.ID}; !slices.Equal(postIDs(posts.Data), want) || posts.TotalCount != 1 {
+			t.Fatalf("%s: expected %v, got %v (total %d)", path, want, postIDs(posts.Data), posts.TotalCount)
+		}
+	}
+	server.request(http.MethodPut, fmt.Sprintf("/topics/%d/subscription", topics[0].ID), aliceToken, nil).expectStatus(http.StatusNoContent)
+	server.request(http.MethodGet, "/feed", aliceToken, nil).expectStatus(http.StatusOK).decode(&posts)
+	if want := []uint{alicesPost.ID}; !slices.Equal(postIDs(posts.Data), want) || posts.TotalCount != 1 {
+		t.Fatalf("feed: expected %v, got %v (total %d)", want, postIDs(posts.Data), posts.TotalCount)
+	}
+	// Other users still see everything
+	server.request(http.MethodGet, "/posts", bobToken, nil).expectStatus(http.StatusOK).decode(&posts)
+	if posts.TotalCount != 3 {
+		t.Fatalf("expected bob to see 3 posts, got %d", posts.TotalCount)
+	}
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d", bobsPost.ID), aliceToken, nil).expectStatus(http.StatusOK)
+
+	var comments commentList
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d/comments", carolsPost.ID), aliceToken, nil).expectStatus(http.StatusOK).decode(&comments)
+	if comments.TotalCount != 0 || len(comments.Data) != 0 {
+		t.Fatalf("expected comments by blocked and muted users to be hidden, got %+v", comments)
+	}
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d/comments", carolsPost.ID), "", nil).expectStatus(http.StatusOK).decode(&comments)
+	if comments.TotalCount != 2 {
+		t.Fatalf("expected anonymous users to see 2 comments, got %d", comments.TotalCount)
+	}
+
+	// Blocked users cannot reply or mention, but muted users can
+	server.request(http.MethodPost, "/comments", bobToken, gin.H{"content": "A reply", "post_id": alicesPost.ID}).expectStatus(http.StatusForbidden)
+	server.request(http.MethodPost, "/comments", bobToken, gin.H{"content": "Hey @alice, look", "post_id": carolsPost.ID}).expectStatus(http.StatusForbidden)
+	server.request(http.MethodPost, "/comments", carolToken, gin.H{"content": "A reply from @carol to @alice", "post_id": alicesPost.ID}).expectStatus(http.StatusCreated)
+
+	// Unblocking restores everything
+	server.request(http.MethodDelete, fmt.Sprintf("/users/me/blocks/%d", bob.ID), aliceToken, nil).expectStatus(http.StatusNoContent)
+	server.request(http.MethodPost, "/comments", bobToken, gin.H{"content": "A reply", "post_id": alicesPost.ID}).expectStatus(http.StatusCreated)
+	server.request(http.MethodGet, "/posts", aliceToken, nil).expectStatus(http.StatusOK).decode(&posts)
+	if posts.TotalCount != 2 {
+		t.Fatalf("expected alice to see 2 posts after unblocking bob, got %d", posts.TotalCount)
+	}
+}
@@ -0,0 +1,202 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"cvwo-backend/internal/app"
+	"cvwo-backend/internal/cache"
+	"cvwo-backend/internal/events"
+	"cvwo-backend/internal/jobs"
+	"cvwo-backend/internal/models"
+)
+
+// Listings and posts can be revalidated with their ETag, and are only resent when they have changed
+func TestConditionalGets(t *testing.T) {
+	server := newTestServer(t)
+	server.seedTopics("Philosophy")
+	alice, aliceToken := server.registerAndLogin("alice", "password")
+	post := server.createPost(aliceToken, "Hello", nil)
+	server.request(http.MethodPost, "/comments", aliceToken, gin.H{"content": "Hi", "post_id": post.ID}).expectStatus(http.StatusCreated)
+
+	paths := []string{"/posts", fmt.Sprintf("/posts/%d", post.ID), fmt.Sprintf("/posts/%d/comments", post.ID), "/topics"}
+	etags := map[string]string{}
+	for _, path := range paths {
+		res := server.request(http.MethodGet, path, "", nil).expectStatus(http.StatusOK)
+		etags[path] = res.header.Get("ETag")
+		if etags[path] == "" {
+			t.Fatalf("expected %s to have an ETag", path)
+		}
+		res = server.requestWithHeader(http.MethodGet, path, "", nil, http.Header{"If-None-Match": {etags[path]}}).expectStatus(http.StatusNotModified)
+		if len(res.body) != 0 || res.header.Get("ETag") != etags[path] {
+			t.Errorf("expected an empty 304 response for %s with the same ETag, got %q with %s", path, res.body, res.header.Get("ETag"))
+		}
+		server.requestWithHeader(http.MethodGet, path, "", nil, http.Header{"If-None-Match": {`"other", W/` + etags[path]}}).expectStatus(http.StatusNotModified)
+		server.requestWithHeader(http.MethodGet, path, "", nil, http.Header{"If-None-Match": {`"other"`}}).expectStatus(http.StatusOK)
+	}
+
+	// Votes change the ETags of the listing and the post, even though they are not edits
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/votes/%d", post.ID, alice.ID), aliceToken, gin.H{"value": 1}).expectStatus(http.StatusNoContent)
+	for _, path := range paths[:2] {
+		res := server.requestWithHeader(http.MethodGet, path, "", nil, http.Header{"If-None-Match": {etags[path]}}).expectStatus(http.StatusOK)
+		if res.header.Get("ETag") == etags[path] {
+			t.Errorf("expected the ETag of %s to change after a vote", path)
+		}
+	}
+
+	// There is no Last-Modified, since votes and deletions change responses without changing the time of any edit,
+	// so If-Modified-Since alone never gives a 304
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	for _, path := range paths {
+		res := server.requestWithHeader(http.MethodGet, path, "", nil, http.Header{"If-Modified-Since": {future}}).expectStatus(http.StatusOK)
+		if got := res.header.Get("Last-Modified"); got != "" {
+			t.Errorf("expected no Last-Modified for %s, got %q", path, got)
+		}
+	}
+}
+
+// Responses including the current user's votes must not be stored by shared caches
+func TestCacheControl(t *testing.T) {
+	server := newTestServer(t)
+	_, token := server.registerAndLogin("alice", "password")
+	server.createPost(token, "Hello", nil)
+
+	tests := []struct {
+		name             string
+		token            string
+		wantCacheControl string
+	}{
+		{"anonymous", "", "public, max-age=60"},
+		{"authenticated", token, "private, no-cache"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := server.request(http.MethodGet, "/posts", tt.token, nil).expectStatus(http.StatusOK)
+			if got := res.header.Get("Cache-Control"); got != tt.wantCacheControl {
+				t.Errorf("expected Cache-Control %q, got %q", tt.wantCacheControl, got)
+			}
+			if got := res.header.Get("Vary"); got != "Authorization" {
+				t.Errorf("expected Vary Authorization, got %q", got)
+			}
+		})
+	}
+}
+
+// Responses are compact outside of debug mode
+func TestCompactJSON(t *testing.T) {
+	server := newTestServer(t)
+	_, token := server.registerAndLogin("alice", "password")
+	server.createPost(token, "Hello", nil)
+
+	res := server.request(http.MethodGet, "/posts", "", nil).expectStatus(http.StatusOK)
+	if bytes.Contains(res.body, []byte("\n")) {
+		t.Errorf("expected a compact body, got %s", res.body)
+	}
+	var list postList
+	res.decode(&list)
+	if len(list.Data) != 1 || list.Data[0].Status != models.PostStatusPublished {
+		t.Errorf("expected the published post, got %+v", list.Data)
+	}
+}
+
+// Anonymous listings are served from the cache until a post is created, edited, deleted, voted on or tagged
+func TestListingCache(t *testing.T) {
+	withCache := func(config *app.Config) {
+		config.Cache = cache.NewLRU(100)
+		config.CacheTTL = time.Minute
+	}
+	server := newTestServer(t, withCache)
+	topics := server.seedTopics("Philosophy")
+	alice, aliceToken := server.registerAndLogin("alice", "password")
+	first := server.createPost(aliceToken, "First", nil)
+
+	listing := func(query string) []models.Post {
+		var list postList
+		server.request(http.MethodGet, "/posts"+query, "", nil).expectStatus(http.StatusOK).decode(&list)
+		return list.Data
+	}
+	if got := listing(""); len(got) != 1 {
+		t.Fatalf("expected 1 post, got %+v", got)
+	}
+
+	// Changes made behind the services' back are not seen until the cached listing is invalidated
+	if err := server.db.Model(&models.Post{}).Where("id = ?", first.ID).UpdateColumn("title", "Renamed").Error; err != nil {
+		t.Fatalf("failed to rename post: %v", err)
+	}
+	if got := listing(""); got[0].Title != "First" {
+		t.Errorf("expected the cached listing, got %+v", got)
+	}
+
+	// New posts, votes and tags are seen straight away
+	second := server.createPost(aliceToken, "Second", nil)
+	if got := listing(""); len(got) != 2 || got[1].Title != "Renamed" {
+		t.Errorf("expected the new post to invalidate the listing, got %+v", got)
+	}
+	if got := listing("?sort=votes"); len(got) != 2 || got[0].NetVotes != 0 {
+		t.Fatalf("expected 2 posts without votes, got %+v", got)
+	}
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/votes/%d", second.ID, alice.ID), aliceToken, gin.H{"value": 1}).expectStatus(http.StatusNoContent)
+	if got := listing("?sort=votes"); got[0].ID != second.ID || got[0].NetVotes != 1 {
+		t.Errorf("expected the voted post first with its vote, got %+v", got)
+	}
+	query := fmt.Sprintf("?tag=%d", topics[0].ID)
+	if got := listing(query); len(got) != 0 {
+		t.Fatalf("expected no tagged posts, got %+v", got)
+	}
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/topics", first.ID), aliceToken, gin.H{"topic_ids": []uint{topics[0].ID}}).expectStatus(http.StatusNoContent)
+	if got := listing(query); len(got) != 1 || got[0].ID != first.ID {
+		t.Errorf("expected the tagged post, got %+v", got)
+	}
+}
+
+// With a shared cache, post events invalidate the listings again in case the services failed to, while events cannot reach the memory of every instance
+func TestListingCacheEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		shared bool
+		want   int64
+	}{
+		{"shared", true, 2},
+		{"memory", false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			alice, token := server.registerAndLogin("alice", "password")
+			post := server.createPost(token, "Hello", nil)
+			server.request(http.MethodPut, fmt.Sprintf("/posts/%d/votes/%d", post.ID, alice.ID), token, gin.H{"value": 1}).expectStatus(http.StatusNoContent)
+
+			config := app.Config{Cache: cache.NewLRU(100), CacheTTL: time.Minute, SharedCache: tt.shared}
+			runner, err := app.NewJobRunner(server.db, config, jobs.DefaultOptions)
+			if err != nil {
+				t.Fatalf("failed to create job runner: %v", err)
+			}
+			dispatcher := events.NewDispatcher(server.db, runner, time.Second)
+			app.SubscribeToEvents(dispatcher, config)
+			if _, err := dispatcher.Dispatch(context.Background()); err != nil {
+				t.Fatalf("failed to dispatch events: %v", err)
+			}
+			assertInvalidations(t, server, tt.want)
+		})
+	}
+}
+
+// Check the number of jobs enqueued to invalidate the cached listings
+func assertInvalidations(t *testing.T, server *testServer, want int64) {
+	t.Helper()
+	var count int64
+	if err := server.db.Model(&models.Job{}).Where("type LIKE ?", "event:%:listing-cache").Count(&count).Error; err != nil {
+		t.Fatalf("failed to count jobs: %v", err)
+	}
+	if count != want {
+		t.Errorf("expected %d invalidations, got %d", want, count)
+	}
+}
@@ -0,0 +1,166 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+)
+
+// Number of concurrent requests sent by each test
+const concurrency = 20
+
+// Call fn n times at once, returning once every call has returned
+func hammer(n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			fn(i)
+		}()
+	}
+	close(start)
+	wg.Wait()
+}
+
+// Send n requests at once, returning how many responses had each status
+func hammerRequests(n int, send func(i int) *response) map[int]int {
+	var mu sync.Mutex
+	statuses := map[int]int{}
+	hammer(n, func(i int) {
+		res := send(i)
+		mu.Lock()
+		defer mu.Unlock()
+		statuses[res.status]++
+	})
+	return statuses
+}
+
+// Repeated votes by the same user, e.g. from double clicks, are all accepted and counted once
+func TestConcurrentVotes(t *testing.T) {
+	server := newTestServer(t)
+	alice, aliceToken := server.registerAndLogin("alice", "password")
+	post := server.createPost(aliceToken, "Hello", nil)
+	var comment models.Comment
+	server.request(http.MethodPost, "/comments", aliceToken, gin.H{"content": "Hi", "post_id": post.ID}).expectStatus(http.StatusCreated).decode(&comment)
+
+	postVote := fmt.Sprintf("/posts/%d/votes/%d", post.ID, alice.ID)
+	commentVote := fmt.Sprintf("/comments/%d/votes/%d", comment.ID, alice.ID)
+	statuses := hammerRequests(concurrency, func(i int) *response {
+		if i%2 == 0 {
+			return server.request(http.MethodPut, postVote, aliceToken, gin.H{"value": 1})
+		}
+		return server.request(http.MethodPut, commentVote, aliceToken, gin.H{"value": -1})
+	})
+	if statuses[http.StatusNoContent] != concurrency {
+		t.Fatalf("expected every vote to succeed, got statuses %v", statuses)
+	}
+
+	var fetched models.Post
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), aliceToken, nil).expectStatus(http.StatusOK).decode(&fetched)
+	if fetched.NetVotes != 1 || fetched.UserVote != 1 {
+		t.Errorf("expected votes 1 and user_vote 1, got %d and %d", fetched.NetVotes, fetched.UserVote)
+	}
+	var comments commentList
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d/comments", post.ID), aliceToken, nil).expectStatus(http.StatusOK).decode(&comments)
+	if comments.Data[0].NetVotes != -1 || comments.Data[0].UserVote != -1 {
+		t.Errorf("expected votes -1 and user_vote -1, got %d and %d", comments.Data[0].NetVotes, comments.Data[0].UserVote)
+	}
+}
+
+// Upserts do not race with each other outside of a transaction either
+func TestConcurrentVoteUpserts(t *testing.T) {
+	server := newTestServer(t)
+	alice, aliceToken := server.registerAndLogin("alice", "password")
+	post := server.createPost(aliceToken, "Hello", nil)
+	var comment models.Comment
+	server.request(http.MethodPost, "/comments", aliceToken, gin.H{"content": "Hi", "post_id": post.ID}).expectStatus(http.StatusCreated).decode(&comment)
+
+	postVotes := repos.NewPostVoteRepo(server.db)
+	commentVotes := repos.NewCommentVoteRepo(server.db)
+	var (
+		mu     sync.Mutex
+		failed []error
+	)
+	hammer(concurrency, func(i int) {
+		var err error
+		if i%2 == 0 {
+			err = postVotes.Upsert(context.Background(), &models.PostVote{PostID: post.ID, UserID: alice.ID, Value: 1})
+		} else {
+			err = commentVotes.Upsert(context.Background(), &models.CommentVote{CommentID: comment.ID, UserID: alice.ID, Value: 1})
+		}
+		if err != nil {
+			mu.Lock()
+			failed = append(failed, err)
+			mu.Unlock()
+		}
+	})
+	if len(failed) != 0 {
+		t.Errorf("expected every upsert to succeed, got errors %v", failed)
+	}
+}
+
+// Of several edits based on the same version, only one succeeds and the others are rejected instead of overwriting it
+func TestConcurrentEdits(t *testing.T) {
+	server := newTestServer(t)
+	_, aliceToken := server.registerAndLogin("alice", "password")
+
+	res := server.request(http.MethodPost, "/posts", aliceToken, gin.H{"title": "Hello", "content": "Original content"}).expectStatus(http.StatusCreated)
+	var post models.Post
+	res.decode(&post)
+	etag := res.header.Get("ETag")
+	if !strings.HasPrefix(etag, `"1-`) {
+		t.Fatalf("expected the ETag of the new post to be for version 1, got %s", etag)
+	}
+	var comment models.Comment
+	server.request(http.MethodPost, "/comments", aliceToken, gin.H{"content": "Original comment", "post_id": post.ID}).expectStatus(http.StatusCreated).decode(&comment)
+
+	tests := []struct {
+		name string
+		path string
+		body func(i int) gin.H
+	}{
+		{"post", fmt.Sprintf("/posts/%d", post.ID), func(i int) gin.H { return gin.H{"title": "Edited", "content": fmt.Sprintf("Edit number %d", i)} }},
+		{"comment", fmt.Sprintf("/comments/%d", comment.ID), func(i int) gin.H { return gin.H{"content": fmt.Sprintf("Edit number %d", i)} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statuses := hammerRequests(concurrency, func(i int) *response {
+				return server.requestWithHeader(http.MethodPatch, tt.path, aliceToken, tt.body(i), http.Header{"If-Match": {etag}})
+			})
+			if statuses[http.StatusOK] != 1 || statuses[http.StatusPreconditionFailed] != concurrency-1 {
+				t.Errorf("expected 1 edit to succeed and the others to fail with 412, got statuses %v", statuses)
+			}
+		})
+	}
+
+	// Edits based on the latest version succeed, and edits without If-Match overwrite any version
+	res = server.request(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), "", nil).expectStatus(http.StatusOK)
+	etag = res.header.Get("ETag")
+	if !strings.HasPrefix(etag, `"2-`) {
+		t.Fatalf("expected the ETag to be for version 2 after the edit, got %s", etag)
+	}
+	res = server.requestWithHeader(http.MethodPatch, fmt.Sprintf("/posts/%d", post.ID), aliceToken, gin.H{"title": "Edited", "content": "Latest content"}, http.Header{"If-Match": {etag}}).
+		expectStatus(http.StatusOK)
+	if etag = res.header.Get("ETag"); !strings.HasPrefix(etag, `"3-`) {
+		t.Errorf("expected the ETag to be for version 3 after the edit, got %s", etag)
+	}
+	server.request(http.MethodPatch, fmt.Sprintf("/posts/%d", post.ID), aliceToken, gin.H{"title": "Edited", "content": "Unconditional content"}).
+		expectStatus(http.StatusOK)
+	server.requestWithHeader(http.MethodPatch, fmt.Sprintf("/posts/%d", post.ID), aliceToken, gin.H{"title": "Edited", "content": "Weak content"}, http.Header{"If-Match": {"W/" + etag}}).
+		expectStatus(http.StatusPreconditionFailed)
+	// A list of tags is rejected even if its first tag is for the current version
+	server.requestWithHeader(http.MethodPatch, fmt.Sprintf("/posts/%d", post.ID), aliceToken, gin.H{"title": "Edited", "content": "Listed content"}, http.Header{"If-Match": {`"4-abc", "5-def"`}}).
+		expectStatus(http.StatusPreconditionFailed)
+}
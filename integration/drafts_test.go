@@ -0,0 +1,136 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"cvwo-backend/internal/app"
+	"cvwo-backend/internal/jobs"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/services"
+	"cvwo-backend/internal/storage"
+)
+
+// Write a draft, publish it, and see it appear in listings only once published
+func TestDrafts(t *testing.T) {
+	server := newTestServer(t)
+	_, aliceToken := server.registerAndLogin("alice", "password")
+	_, bobToken := server.registerAndLogin("bob", "password")
+
+	var draft models.Post
+	server.request(http.MethodPost, "/posts", aliceToken, gin.H{"title": "Work in progress", "content": "Not quite ready yet", "status": "draft"}).
+		expectStatus(http.StatusCreated).decode(&draft)
+	if draft.Status != models.PostStatusDraft {
+		t.Fatalf("expected a draft, got %+v", draft)
+	}
+	server.request(http.MethodPost, "/posts", aliceToken, gin.H{"title": "Never", "content": "Not quite ready yet", "status": "hidden"}).
+		expectStatus(http.StatusBadRequest)
+	server.request(http.MethodPost, "/posts", aliceToken, gin.H{"title": "Never", "content": "Not quite ready yet", "status": "scheduled"}).
+		expectStatus(http.StatusBadRequest)
+
+	// The draft is only visible to its author
+	var drafts postList
+	server.request(http.MethodGet, "/users/me/drafts", aliceToken, nil).expectStatus(http.StatusOK).decode(&drafts)
+	if drafts.TotalCount != 1 || postIDs(drafts.Data)[0] != draft.ID {
+		t.Fatalf("expected the draft, got %+v", drafts)
+	}
+	server.request(http.MethodGet, "/users/me/drafts", "", nil).expectStatus(http.StatusUnauthorized)
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d", draft.ID), aliceToken, nil).expectStatus(http.StatusOK)
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d", draft.ID), bobToken, nil).expectStatus(http.StatusNotFound)
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d", draft.ID), "", nil).expectStatus(http.StatusNotFound)
+	server.request(http.MethodPost, "/comments", bobToken, gin.H{"content": "Sneak peek", "post_id": draft.ID}).expectStatus(http.StatusNotFound)
+	var posts postList
+	server.request(http.MethodGet, "/posts", aliceToken, nil).expectStatus(http.StatusOK).decode(&posts)
+	if posts.TotalCount != 0 {
+		t.Fatalf("expected no listed posts, got %+v", posts)
+	}
+
+	// Publishing the draft lists it
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/status", draft.ID), bobToken, gin.H{"status": "published"}).expectStatus(http.StatusForbidden)
+	var published models.Post
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/status", draft.ID), aliceToken, gin.H{"status": "published"}).
+		expectStatus(http.StatusOK).decode(&published)
+	if published.Status != models.PostStatusPublished || !published.CreatedAt.After(draft.CreatedAt) {
+		t.Fatalf("expected the post to be published now, got %+v", published)
+	}
+	server.request(http.MethodGet, "/posts", bobToken, nil).expectStatus(http.StatusOK).decode(&posts)
+	if posts.TotalCount != 1 || posts.Data[0].ID != draft.ID {
+		t.Fatalf("expected the published post to be listed, got %+v", posts)
+	}
+	server.request(http.MethodGet, "/users/me/drafts", aliceToken, nil).expectStatus(http.StatusOK).decode(&drafts)
+	if drafts.TotalCount != 0 {
+		t.Errorf("expected no drafts, got %+v", drafts)
+	}
+
+	// Published posts cannot go back to being drafts
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/status", draft.ID), aliceToken, gin.H{"status": "draft"}).expectStatus(http.StatusConflict)
+}
+
+// Schedule a post and let the background job publish it
+func TestScheduledPosts(t *testing.T) {
+	server := newTestServer(t)
+	_, aliceToken := server.registerAndLogin("alice", "password")
+
+	var scheduled models.Post
+	server.request(http.MethodPost, "/posts", aliceToken, gin.H{"title": "Announcement", "content": "Coming right up", "status": "scheduled", "publish_at": time.Now().Add(time.Hour)}).
+		expectStatus(http.StatusCreated).decode(&scheduled)
+	if scheduled.Status != models.PostStatusScheduled || scheduled.PublishAt == nil {
+		t.Fatalf("expected a scheduled post, got %+v", scheduled)
+	}
+	server.request(http.MethodPost, "/posts", aliceToken, gin.H{"title": "Announcement", "content": "Coming right up", "status": "scheduled", "publish_at": time.Now().Add(-time.Hour)}).
+		expectStatus(http.StatusBadRequest)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create blob store: %v", err)
+	}
+	runner, err := app.NewJobRunner(server.db, app.Config{ContentLimits: services.DefaultContentLimits, BlobStore: blobs}, jobs.Options{Workers: 1, PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("failed to create job runner: %v", err)
+	}
+	runnerDone := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(runnerDone)
+	}()
+	defer func() {
+		cancel()
+		<-runnerDone
+	}()
+
+	// Nothing is published before the post is due
+	publish := func() {
+		t.Helper()
+		if err := jobs.Enqueue(ctx, server.db, jobs.Job{Type: app.JobPublishScheduledPosts}); err != nil {
+			t.Fatalf("failed to enqueue job: %v", err)
+		}
+	}
+	publish()
+	time.Sleep(50 * time.Millisecond)
+	var posts postList
+	server.request(http.MethodGet, "/posts", "", nil).expectStatus(http.StatusOK).decode(&posts)
+	if posts.TotalCount != 0 {
+		t.Fatalf("expected the scheduled post to be unlisted, got %+v", posts)
+	}
+
+	// Make the post due
+	if err := server.db.Model(&models.Post{}).Where("id = ?", scheduled.ID).Update("publish_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatalf("failed to reschedule post: %v", err)
+	}
+	publish()
+	deadline := time.Now().Add(5 * time.Second)
+	for posts.TotalCount == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		server.request(http.MethodGet, "/posts", "", nil).expectStatus(http.StatusOK).decode(&posts)
+	}
+	if posts.TotalCount != 1 || posts.Data[0].ID != scheduled.ID || posts.Data[0].Status != models.PostStatusPublished {
+		t.Fatalf("expected the job to publish the post, got %+v", posts)
+	}
+}
@@ -0,0 +1,91 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"cvwo-backend/internal/app"
+	"cvwo-backend/internal/events"
+	"cvwo-backend/internal/jobs"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/services"
+	"cvwo-backend/internal/storage"
+)
+
+// Changes made through the API are recorded in the outbox and delivered to subscribers
+func TestDomainEvents(t *testing.T) {
+	server := newTestServer(t)
+	topics := server.seedTopics("General")
+	alice, aliceToken := server.registerAndLogin("alice", "password")
+	bob, bobToken := server.registerAndLogin("bob", "password")
+
+	post := server.createPost(aliceToken, "Hello", []uint{topics[0].ID})
+	var comment models.Comment
+	server.request(http.MethodPost, "/comments", bobToken, gin.H{"content": "Hi", "post_id": post.ID}).expectStatus(http.StatusCreated).decode(&comment)
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/votes/%d", post.ID, bob.ID), bobToken, gin.H{"value": 1}).expectStatus(http.StatusNoContent)
+	server.request(http.MethodPut, fmt.Sprintf("/comments/%d/votes/%d", comment.ID, alice.ID), aliceToken, gin.H{"value": -1}).expectStatus(http.StatusNoContent)
+	server.request(http.MethodDelete, fmt.Sprintf("/posts/%d", post.ID), aliceToken, nil).expectStatus(http.StatusNoContent)
+	// Rejected changes record nothing
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/votes/%d", 9999, bob.ID), bobToken, gin.H{"value": 1}).expectStatus(http.StatusNotFound)
+
+	var records []models.OutboxEvent
+	if err := server.db.Order("id").Find(&records).Error; err != nil {
+		t.Fatalf("failed to get events: %v", err)
+	}
+	types := []string{}
+	for _, record := range records {
+		types = append(types, record.Type)
+	}
+	want := []string{"post.created", "post.tagged", "comment.created", "post.voted", "comment.voted", "post.deleted"}
+	if !slices.Equal(types, want) {
+		t.Fatalf("expected events %v, got %v", want, types)
+	}
+
+	// Deliver the events to a subscriber
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create blob store: %v", err)
+	}
+	runner, err := app.NewJobRunner(server.db, app.Config{ContentLimits: services.DefaultContentLimits, BlobStore: blobs}, jobs.Options{Workers: 1, PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("failed to create job runner: %v", err)
+	}
+	dispatcher := events.NewDispatcher(server.db, runner, 10*time.Millisecond)
+	deleted := make(chan events.PostDeleted, 1)
+	events.Subscribe(dispatcher, "test", func(ctx context.Context, event events.PostDeleted) error {
+		deleted <- event
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		runner.Run(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		dispatcher.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	select {
+	case event := <-deleted:
+		if event.PostID != post.ID || event.AuthorID != alice.ID {
+			t.Errorf("expected post %d by %d to be deleted, got %+v", post.ID, alice.ID, event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the event")
+	}
+}
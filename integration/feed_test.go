@@ -0,0 +1,65 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"cvwo-backend/internal/models"
+)
+
+// Subscribe to topics and check that the home feed only contains their posts
+func TestSubscriptionsAndFeed(t *testing.T) {
+	server := newTestServer(t)
+	topics := server.seedTopics("Philosophy", "Literature", "History")
+	_, aliceToken := server.registerAndLogin("alice", "password")
+	_, bobToken := server.registerAndLogin("bob", "password")
+
+	plato := server.createPost(aliceToken, "Plato", []uint{topics[0].ID})
+	homer := server.createPost(aliceToken, "Homer", []uint{topics[1].ID})
+	herodotus := server.createPost(aliceToken, "Herodotus", []uint{topics[2].ID})
+	both := server.createPost(aliceToken, "Philosophy in literature", []uint{topics[0].ID, topics[1].ID})
+
+	// Without subscriptions, the feed is the global listing
+	var feed postList
+	server.request(http.MethodGet, "/feed", bobToken, nil).expectStatus(http.StatusOK).decode(&feed)
+	if want := []uint{both.ID, herodotus.ID, homer.ID, plato.ID}; !slices.Equal(postIDs(feed.Data), want) || feed.TotalCount != 4 {
+		t.Fatalf("expected global listing %v, got %v (total %d)", want, postIDs(feed.Data), feed.TotalCount)
+	}
+
+	server.request(http.MethodPut, fmt.Sprintf("/topics/%d/subscription", topics[0].ID), "", nil).expectStatus(http.StatusUnauthorized)
+	server.request(http.MethodPut, "/topics/999/subscription", bobToken, nil).expectStatus(http.StatusNotFound)
+	server.request(http.MethodPut, fmt.Sprintf("/topics/%d/subscription", topics[0].ID), bobToken, nil).expectStatus(http.StatusNoContent)
+	server.request(http.MethodPut, fmt.Sprintf("/topics/%d/subscription", topics[1].ID), bobToken, nil).expectStatus(http.StatusNoContent)
+	server.request(http.MethodPut, fmt.Sprintf("/topics/%d/subscription", topics[1].ID), bobToken, nil).expectStatus(http.StatusNoContent)
+
+	var subscribed []models.Topic
+	server.request(http.MethodGet, "/users/me/subscriptions", bobToken, nil).expectStatus(http.StatusOK).decode(&subscribed)
+	if len(subscribed) != 2 || subscribed[0].Name != "Literature" || subscribed[1].Name != "Philosophy" {
+		t.Fatalf("unexpected subscriptions: %+v", subscribed)
+	}
+
+	// Posts tagged with several subscribed topics appear once
+	server.request(http.MethodGet, "/feed?sort=old", bobToken, nil).expectStatus(http.StatusOK).decode(&feed)
+	if want := []uint{plato.ID, homer.ID, both.ID}; !slices.Equal(postIDs(feed.Data), want) || feed.TotalCount != 3 {
+		t.Fatalf("expected feed %v, got %v (total %d)", want, postIDs(feed.Data), feed.TotalCount)
+	}
+	server.request(http.MethodGet, "/feed?sort=old&limit=2&page=2", bobToken, nil).expectStatus(http.StatusOK).decode(&feed)
+	if want := []uint{both.ID}; !slices.Equal(postIDs(feed.Data), want) || feed.TotalCount != 3 {
+		t.Fatalf("expected second page %v, got %v (total %d)", want, postIDs(feed.Data), feed.TotalCount)
+	}
+	server.request(http.MethodGet, "/feed?sort=random", bobToken, nil).expectStatus(http.StatusBadRequest)
+
+	// Anonymous users get the global listing
+	server.request(http.MethodGet, "/feed", "", nil).expectStatus(http.StatusOK).decode(&feed)
+	if feed.TotalCount != 4 {
+		t.Fatalf("expected 4 posts for anonymous users, got %d", feed.TotalCount)
+	}
+
+	server.request(http.MethodDelete, fmt.Sprintf("/topics/%d/subscription", topics[0].ID), bobToken, nil).expectStatus(http.StatusNoContent)
+	server.request(http.MethodGet, "/feed", bobToken, nil).expectStatus(http.StatusOK).decode(&feed)
+	if want := []uint{both.ID, homer.ID}; !slices.Equal(postIDs(feed.Data), want) {
+		t.Fatalf("expected feed %v after unsubscribing, got %v", want, postIDs(feed.Data))
+	}
+}
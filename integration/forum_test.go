@@ -0,0 +1,227 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+)
+
+// Walks through the main flow of the forum: register, log in, post, tag, comment, vote, list and delete
+func TestForumFlow(t *testing.T) {
+	server := newTestServer(t)
+	topics := server.seedTopics("Philosophy", "Literature")
+	alice, aliceToken := server.registerAndLogin("alice", "password")
+	_, bobToken := server.registerAndLogin("bob", "password")
+
+	// Create a post tagged with a topic
+	post := server.createPost(aliceToken, "To be or not to be", []uint{topics[0].ID})
+	if post.AuthorID != alice.ID {
+		t.Fatalf("expected author %d, got %d", alice.ID, post.AuthorID)
+	}
+
+	// Retag the post with both topics
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/topics", post.ID), aliceToken, gin.H{"topic_ids": []uint{topics[0].ID, topics[1].ID}}).
+		expectStatus(http.StatusNoContent)
+
+	// Other users cannot retag the post
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/topics", post.ID), bobToken, gin.H{"topic_ids": []uint{}}).
+		expectStatus(http.StatusForbidden)
+
+	var fetched models.Post
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), "", nil).expectStatus(http.StatusOK).decode(&fetched)
+	if len(fetched.Topics) != 2 {
+		t.Fatalf("expected 2 topics, got %d", len(fetched.Topics))
+	}
+	if fetched.Author == nil || fetched.Author.Username != "alice" {
+		t.Fatalf("expected author to be included, got %+v", fetched.Author)
+	}
+
+	// Comment on the post
+	var comment models.Comment
+	server.request(http.MethodPost, "/comments", bobToken, gin.H{"content": "That is the question", "post_id": post.ID}).
+		expectStatus(http.StatusCreated).
+		decode(&comment)
+
+	// Edit the comment
+	var edited models.Comment
+	server.request(http.MethodPatch, fmt.Sprintf("/comments/%d", comment.ID), bobToken, gin.H{"content": "That is indeed the question"}).
+		expectStatus(http.StatusOK).
+		decode(&edited)
+	if edited.Content != "That is indeed the question" {
+		t.Fatalf("expected edited content, got %q", edited.Content)
+	}
+
+	// Vote on the post and the comment
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/votes/%d", post.ID, alice.ID), aliceToken, gin.H{"value": 1}).
+		expectStatus(http.StatusNoContent)
+	server.request(http.MethodPut, fmt.Sprintf("/comments/%d/votes/%d", comment.ID, alice.ID), aliceToken, gin.H{"value": -1}).
+		expectStatus(http.StatusNoContent)
+
+	// The voter sees their own vote; anonymous users only see the total
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), aliceToken, nil).expectStatus(http.StatusOK).decode(&fetched)
+	if fetched.NetVotes != 1 || fetched.UserVote != 1 {
+		t.Fatalf("expected votes 1 and user_vote 1, got %d and %d", fetched.NetVotes, fetched.UserVote)
+	}
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), "", nil).expectStatus(http.StatusOK).decode(&fetched)
+	if fetched.NetVotes != 1 || fetched.UserVote != 0 {
+		t.Fatalf("expected votes 1 and user_vote 0, got %d and %d", fetched.NetVotes, fetched.UserVote)
+	}
+
+	var comments commentList
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d/comments", post.ID), aliceToken, nil).expectStatus(http.StatusOK).decode(&comments)
+	if comments.TotalCount != 1 || comments.Data[0].NetVotes != -1 || comments.Data[0].UserVote != -1 {
+		t.Fatalf("unexpected comments: %+v", comments)
+	}
+
+	// Delete the comment, then the post
+	server.request(http.MethodDelete, fmt.Sprintf("/comments/%d", comment.ID), aliceToken, nil).expectStatus(http.StatusForbidden)
+	server.request(http.MethodDelete, fmt.Sprintf("/comments/%d", comment.ID), bobToken, nil).expectStatus(http.StatusNoContent)
+	server.request(http.MethodDelete, fmt.Sprintf("/posts/%d", post.ID), bobToken, nil).expectStatus(http.StatusForbidden)
+	server.request(http.MethodDelete, fmt.Sprintf("/posts/%d", post.ID), aliceToken, nil).expectStatus(http.StatusNoContent)
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), "", nil).expectStatus(http.StatusNotFound)
+}
+
+func TestListPostsSortAndPaginate(t *testing.T) {
+	server := newTestServer(t)
+	topics := server.seedTopics("Philosophy", "Literature")
+	alice, aliceToken := server.registerAndLogin("alice", "password")
+	bob, bobToken := server.registerAndLogin("bob", "password")
+
+	first := server.createPost(aliceToken, "first", []uint{topics[0].ID})
+	second := server.createPost(aliceToken, "second", []uint{topics[1].ID})
+	third := server.createPost(bobToken, "third", []uint{topics[0].ID, topics[1].ID})
+
+	// second: +2, third: +1, first: -1
+	votes := []struct {
+		postID uint
+		userID uint
+		token  string
+		value  int
+	}{
+		{second.ID, alice.ID, aliceToken, 1},
+		{second.ID, bob.ID, bobToken, 1},
+		{third.ID, alice.ID, aliceToken, 1},
+		{first.ID, bob.ID, bobToken, -1},
+	}
+	for _, vote := range votes {
+		server.request(http.MethodPut, fmt.Sprintf("/posts/%d/votes/%d", vote.postID, vote.userID), vote.token, gin.H{"value": vote.value}).
+			expectStatus(http.StatusNoContent)
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantIDs   []uint
+		wantTotal int64
+	}{
+		{"default sort is newest first", "", []uint{third.ID, second.ID, first.ID}, 3},
+		{"oldest first", "?sort=old", []uint{first.ID, second.ID, third.ID}, 3},
+		{"most votes first", "?sort=votes", []uint{second.ID, third.ID, first.ID}, 3},
+		{"first page", "?sort=old&limit=2&page=1", []uint{first.ID, second.ID}, 3},
+		{"second page", "?sort=old&limit=2&page=2", []uint{third.ID}, 3},
+		{"filter by topic", fmt.Sprintf("?sort=old&tag=%d", topics[0].ID), []uint{first.ID, third.ID}, 2},
+		{"filter by either topic", fmt.Sprintf("?sort=old&tag=%d&tag=%d", topics[0].ID, topics[1].ID), []uint{first.ID, second.ID, third.ID}, 3},
+		{"filter by topic and paginate", fmt.Sprintf("?sort=old&tag=%d&limit=1&page=2", topics[0].ID), []uint{third.ID}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var list postList
+			server.request(http.MethodGet, "/posts"+tt.query, "", nil).expectStatus(http.StatusOK).decode(&list)
+
+			if ids := postIDs(list.Data); !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("expected posts %v, got %v", tt.wantIDs, ids)
+			}
+			if list.TotalCount != tt.wantTotal {
+				t.Errorf("expected total count %d, got %d", tt.wantTotal, list.TotalCount)
+			}
+		})
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	server := newTestServer(t)
+	_, token := server.registerAndLogin("alice", "password")
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		body       any
+		wantStatus int
+		wantCode   string
+	}{
+		{"unauthenticated post", http.MethodPost, "/posts", "", gin.H{"title": "title", "content": "long enough content"}, http.StatusUnauthorized, "unauthorized"},
+		{"invalid post body", http.MethodPost, "/posts", token, gin.H{"title": "title", "content": "short"}, http.StatusBadRequest, "invalid"},
+		{"invalid sort", http.MethodGet, "/posts?sort=random", "", nil, http.StatusBadRequest, "invalid"},
+		{"invalid post ID", http.MethodGet, "/posts/abc", "", nil, http.StatusBadRequest, "invalid"},
+		{"missing post", http.MethodGet, "/posts/999", "", nil, http.StatusNotFound, "not_found"},
+		{"comment on missing post", http.MethodPost, "/comments", token, gin.H{"content": "hello", "post_id": 999}, http.StatusNotFound, "not_found"},
+		{"duplicate username", http.MethodPost, "/users", "", gin.H{"username": "alice", "password": "password"}, http.StatusConflict, "conflict"},
+		{"wrong password", http.MethodPost, "/login", "", gin.H{"username": "alice", "password": "wrong-password"}, http.StatusUnauthorized, "unauthorized"},
+		{"unknown route", http.MethodGet, "/nowhere", "", nil, http.StatusNotFound, "not_found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := server.request(tt.method, tt.path, tt.token, tt.body).expectStatus(tt.wantStatus)
+
+			if contentType := res.header.Get("Content-Type"); contentType != errs.ProblemContentType {
+				t.Errorf("expected content type %s, got %s", errs.ProblemContentType, contentType)
+			}
+			var problem errs.Problem
+			res.decode(&problem)
+			if problem.Code != tt.wantCode {
+				t.Errorf("expected error code %s, got %s", tt.wantCode, problem.Code)
+			}
+			if problem.RequestID == "" {
+				t.Error("expected request ID in problem details")
+			}
+		})
+	}
+}
+
+// Repeated failed logins lock the username out for the client that sent them only
+func TestLoginLockout(t *testing.T) {
+	server := newTestServer(t)
+	server.registerAndLogin("alice", "password")
+
+	for i := 0; i < 5; i++ {
+		server.request(http.MethodPost, "/login", "", gin.H{"username": "alice", "password": "wrong-password"}).expectStatus(http.StatusUnauthorized)
+	}
+	res := server.request(http.MethodPost, "/login", "", gin.H{"username": "alice", "password": "password"}).expectStatus(http.StatusTooManyRequests)
+	if got := res.header.Get("Retry-After"); got != "60" {
+		t.Errorf("expected to retry after the 1 minute lockout, got %q", got)
+	}
+
+	other := http.Header{"X-Forwarded-For": {"198.51.100.1"}}
+	server.requestWithHeader(http.MethodPost, "/login", "", gin.H{"username": "alice", "password": "password"}, other).expectStatus(http.StatusOK)
+}
+
+// A post with an unknown topic is rejected as a whole rather than created untagged
+func TestCreatePostWithUnknownTopic(t *testing.T) {
+	server := newTestServer(t)
+	topics := server.seedTopics("Philosophy")
+	_, token := server.registerAndLogin("alice", "password")
+
+	var problem errs.Problem
+	server.request(http.MethodPost, "/posts", token, gin.H{"title": "title", "content": "long enough content", "topic_ids": []uint{topics[0].ID, 999}}).
+		expectStatus(http.StatusBadRequest).
+		decode(&problem)
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "topic_ids" {
+		t.Errorf("expected an error on topic_ids, got %+v", problem.Errors)
+	}
+
+	var list postList
+	server.request(http.MethodGet, "/posts", "", nil).expectStatus(http.StatusOK).decode(&list)
+	if list.TotalCount != 0 {
+		t.Errorf("expected no post to be created, got %+v", list.Data)
+	}
+}
@@ -0,0 +1,241 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"cvwo-backend/internal/app"
+	"cvwo-backend/internal/data"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/ratelimit"
+	"cvwo-backend/internal/services"
+	"cvwo-backend/internal/storage"
+	"cvwo-backend/internal/syndication"
+)
+
+// Full application router backed by its own in-memory SQLite database
+type testServer struct {
+	t      *testing.T
+	db     *gorm.DB
+	router *gin.Engine
+}
+
+// Limit high enough that tests are never rate limited
+var unlimited = ratelimit.Limit{Requests: 10000, Period: time.Second}
+
+// options adjust the config before the router is built
+func newTestServer(t *testing.T, options ...func(config *app.Config)) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SECRET", "integration-test-secret")
+
+	// Name the database after the test so that tests do not share data
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_foreign_keys=on", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := data.Open(sqlite.Open(dsn))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get database handle: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create blob store: %v", err)
+	}
+
+	feeds, err := syndication.NewBuilder("https://forum.example.com")
+	if err != nil {
+		t.Fatalf("failed to create feed builder: %v", err)
+	}
+
+	config := app.Config{
+		FrontendURL:      "http://localhost:5173",
+		WritesRateLimit:  unlimited,
+		VotesRateLimit:   unlimited,
+		LoginRateLimit:   unlimited,
+		ContentLimits:    services.DefaultContentLimits,
+		BlobStore:        blobs,
+		AttachmentLimits: services.DefaultAttachmentLimits,
+		Feeds:            feeds,
+	}
+	for _, option := range options {
+		option(&config)
+	}
+	router := app.NewRouter(db, config)
+
+	return &testServer{t: t, db: db, router: router}
+}
+
+// Insert topics directly, since there is no route to create them
+func (server *testServer) seedTopics(names ...string) []models.Topic {
+	server.t.Helper()
+
+	topics := make([]models.Topic, 0, len(names))
+	for _, name := range names {
+		topics = append(topics, models.Topic{Name: name})
+	}
+	if err := server.db.Create(&topics).Error; err != nil {
+		server.t.Fatalf("failed to seed topics: %v", err)
+	}
+	return topics
+}
+
+// Response of a request made to the test server
+type response struct {
+	t      *testing.T
+	status int
+	header http.Header
+	body   []byte
+}
+
+// Send a request with an optional JSON body and bearer token
+func (server *testServer) request(method string, path string, token string, body any) *response {
+	server.t.Helper()
+	return server.requestWithHeader(method, path, token, body, nil)
+}
+
+// Send a request like request, with additional headers
+func (server *testServer) requestWithHeader(method string, path string, token string, body any, header http.Header) *response {
+	server.t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			server.t.Fatalf("failed to encode request body: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for name, values := range header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, req)
+
+	return &response{t: server.t, status: recorder.Code, header: recorder.Header(), body: recorder.Body.Bytes()}
+}
+
+// Upload a file in the "file" field of a multipart form
+func (server *testServer) upload(path string, token string, filename string, content []byte) *response {
+	server.t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		server.t.Fatalf("failed to create form file: %v", err)
+	}
+	part.Write(content)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, req)
+
+	return &response{t: server.t, status: recorder.Code, header: recorder.Header(), body: recorder.Body.Bytes()}
+}
+
+// Fail the test if the response does not have the expected status
+func (res *response) expectStatus(status int) *response {
+	res.t.Helper()
+	if res.status != status {
+		res.t.Fatalf("expected status %d, got %d: %s", status, res.status, res.body)
+	}
+	return res
+}
+
+// Decode the JSON response body into the given value
+func (res *response) decode(value any) {
+	res.t.Helper()
+	if err := json.Unmarshal(res.body, value); err != nil {
+		res.t.Fatalf("failed to decode response body %q: %v", res.body, err)
+	}
+}
+
+// Register a user and log in, returning the user and their token
+func (server *testServer) registerAndLogin(username string, password string) (models.User, string) {
+	server.t.Helper()
+
+	var user models.User
+	server.request(http.MethodPost, "/users", "", gin.H{"username": username, "password": password}).
+		expectStatus(http.StatusCreated).
+		decode(&user)
+
+	var login struct {
+		Token string      `json:"token"`
+		User  models.User `json:"user"`
+	}
+	server.request(http.MethodPost, "/login", "", gin.H{"username": username, "password": password}).
+		expectStatus(http.StatusOK).
+		decode(&login)
+
+	if login.Token == "" || login.User.ID != user.ID {
+		server.t.Fatalf("unexpected login response: %+v", login)
+	}
+	return user, login.Token
+}
+
+// Create a post and return it
+func (server *testServer) createPost(token string, title string, topicIDs []uint) models.Post {
+	server.t.Helper()
+
+	var post models.Post
+	server.request(http.MethodPost, "/posts", token, gin.H{
+		"title":     title,
+		"content":   "Content of the post titled " + title,
+		"topic_ids": topicIDs,
+	}).expectStatus(http.StatusCreated).decode(&post)
+	return post
+}
+
+// Paginated list response
+type postList struct {
+	Data       []models.Post `json:"data"`
+	TotalCount int64         `json:"total_count"`
+}
+
+type commentList struct {
+	Data       []models.Comment `json:"data"`
+	TotalCount int64            `json:"total_count"`
+}
+
+func postIDs(posts []models.Post) []uint {
+	ids := make([]uint, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	return ids
+}
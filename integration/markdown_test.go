@@ -0,0 +1,66 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"cvwo-backend/internal/data"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/services"
+)
+
+// Write posts and comments in Markdown and check the rendered HTML and length limits
+func TestMarkdownContent(t *testing.T) {
+	server := newTestServer(t)
+	alice, token := server.registerAndLogin("alice", "password")
+
+	var post models.Post
+	server.request(http.MethodPost, "/posts", token, gin.H{
+		"title":   "Markdown",
+		"content": "Some **bold** text\n\n<script>alert(1)</script>\n\n| a | b |\n|---|---|\n| 1 | 2 |",
+	}).expectStatus(http.StatusCreated).decode(&post)
+	if !strings.Contains(post.ContentHTML, "<strong>bold</strong>") || !strings.Contains(post.ContentHTML, "<table>") || strings.Contains(post.ContentHTML, "script") {
+		t.Fatalf("unexpected rendered post: %q", post.ContentHTML)
+	}
+
+	// The rendered content is stored and returned by reads, and replaced by edits
+	server.request(http.MethodPatch, fmt.Sprintf("/posts/%d", post.ID), token, gin.H{"title": "Markdown", "content": "Now with _emphasis_"}).expectStatus(http.StatusOK)
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), "", nil).expectStatus(http.StatusOK).decode(&post)
+	if strings.TrimSpace(post.ContentHTML) != "<p>Now with <em>emphasis</em></p>" {
+		t.Fatalf("unexpected rendered post after edit: %q", post.ContentHTML)
+	}
+
+	var comment models.Comment
+	server.request(http.MethodPost, "/comments", token, gin.H{"content": "It was ||the butler||", "post_id": post.ID}).expectStatus(http.StatusCreated).decode(&comment)
+	var comments commentList
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d/comments", post.ID), "", nil).expectStatus(http.StatusOK).decode(&comments)
+	if len(comments.Data) != 1 || !strings.Contains(comments.Data[0].ContentHTML, `<span class="spoiler">the butler</span>`) {
+		t.Fatalf("unexpected comments: %+v", comments.Data)
+	}
+
+	// Content over the configured limit is rejected with a field error
+	var problem errs.Problem
+	server.request(http.MethodPost, "/comments", token, gin.H{"content": strings.Repeat("a", services.DefaultContentLimits.Comment+1), "post_id": post.ID}).
+		expectStatus(http.StatusBadRequest).decode(&problem)
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "content" {
+		t.Fatalf("expected a content field error, got %+v", problem)
+	}
+
+	// Content written before Markdown support is rendered on startup
+	legacy := models.Post{Title: "Legacy", Content: "Plain *old* post", AuthorID: alice.ID}
+	if err := server.db.Create(&legacy).Error; err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+	if err := data.RenderMissingHTML(server.db); err != nil {
+		t.Fatalf("failed to render content: %v", err)
+	}
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d", legacy.ID), "", nil).expectStatus(http.StatusOK).decode(&post)
+	if strings.TrimSpace(post.ContentHTML) != "<p>Plain <em>old</em> post</p>" {
+		t.Fatalf("unexpected rendered legacy post: %q", post.ContentHTML)
+	}
+}
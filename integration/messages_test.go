@@ -0,0 +1,108 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"cvwo-backend/internal/models"
+)
+
+type conversationList struct {
+	Data       []models.Conversation `json:"data"`
+	NextCursor string                `json:"next_cursor"`
+}
+
+type messageList struct {
+	Data       []models.Message `json:"data"`
+	NextCursor string           `json:"next_cursor"`
+}
+
+// Start conversations, exchange messages, read them and page through them
+func TestDirectMessages(t *testing.T) {
+	server := newTestServer(t)
+	alice, aliceToken := server.registerAndLogin("alice", "password")
+	bob, bobToken := server.registerAndLogin("bob", "password")
+	carol, carolToken := server.registerAndLogin("carol", "password")
+
+	server.request(http.MethodPost, "/conversations", "", gin.H{"participant_ids": []uint{bob.ID}, "content": "Hi"}).expectStatus(http.StatusUnauthorized)
+	server.request(http.MethodPost, "/conversations", aliceToken, gin.H{"participant_ids": []uint{}, "content": "Hi"}).expectStatus(http.StatusBadRequest)
+	server.request(http.MethodPost, "/conversations", aliceToken, gin.H{"participant_ids": []uint{999}, "content": "Hi"}).expectStatus(http.StatusNotFound)
+
+	var direct models.Conversation
+	server.request(http.MethodPost, "/conversations", aliceToken, gin.H{"participant_ids": []uint{bob.ID}, "content": "Hi bob"}).expectStatus(http.StatusCreated).decode(&direct)
+	if len(direct.Participants) != 2 || direct.LastMessage == nil || direct.LastMessage.Content != "Hi bob" {
+		t.Fatalf("unexpected conversation: %+v", direct)
+	}
+	var group models.Conversation
+	server.request(http.MethodPost, "/conversations", aliceToken, gin.H{"participant_ids": []uint{bob.ID, carol.ID}, "content": "Hi all"}).expectStatus(http.StatusCreated).decode(&group)
+	if len(group.Participants) != 3 || group.ID == direct.ID {
+		t.Fatalf("unexpected group conversation: %+v", group)
+	}
+
+	// Bob replies in the direct conversation, which moves it to the top
+	var reply models.Message
+	server.request(http.MethodPost, fmt.Sprintf("/conversations/%d/messages", direct.ID), bobToken, gin.H{"content": "Hi alice"}).expectStatus(http.StatusCreated).decode(&reply)
+	if reply.SenderID != bob.ID || reply.ConversationID != direct.ID {
+		t.Fatalf("unexpected message: %+v", reply)
+	}
+
+	var conversations conversationList
+	server.request(http.MethodGet, "/conversations", bobToken, nil).expectStatus(http.StatusOK).decode(&conversations)
+	if len(conversations.Data) != 2 || conversations.Data[0].ID != direct.ID || conversations.NextCursor != "" {
+		t.Fatalf("unexpected conversations: %+v", conversations)
+	}
+	// Bob has read his own reply, but not the group message
+	if conversations.Data[0].UnreadCount != 0 || conversations.Data[1].UnreadCount != 1 {
+		t.Fatalf("unexpected unread counts: %d and %d", conversations.Data[0].UnreadCount, conversations.Data[1].UnreadCount)
+	}
+	if last := conversations.Data[0].LastMessage; last == nil || last.ID != reply.ID || last.Sender == nil || last.Sender.Username != "bob" {
+		t.Fatalf("unexpected last message: %+v", last)
+	}
+
+	// Page through alice's conversations 1 at a time
+	server.request(http.MethodGet, "/conversations?limit=1", aliceToken, nil).expectStatus(http.StatusOK).decode(&conversations)
+	if len(conversations.Data) != 1 || conversations.Data[0].ID != direct.ID || conversations.Data[0].UnreadCount != 1 || conversations.NextCursor == "" {
+		t.Fatalf("unexpected first page: %+v", conversations)
+	}
+	server.request(http.MethodGet, "/conversations?limit=1&cursor="+conversations.NextCursor, aliceToken, nil).expectStatus(http.StatusOK).decode(&conversations)
+	if len(conversations.Data) != 1 || conversations.Data[0].ID != group.ID || conversations.NextCursor != "" {
+		t.Fatalf("unexpected second page: %+v", conversations)
+	}
+
+	// Read receipts update once alice reads the conversation
+	var messages messageList
+	server.request(http.MethodGet, fmt.Sprintf("/conversations/%d/messages", direct.ID), bobToken, nil).expectStatus(http.StatusOK).decode(&messages)
+	if len(messages.Data) != 2 || messages.Data[0].ID != reply.ID || len(messages.Data[0].ReadBy) != 0 || len(messages.Data[1].ReadBy) != 1 {
+		t.Fatalf("unexpected messages before reading: %+v", messages)
+	}
+	server.request(http.MethodPut, fmt.Sprintf("/conversations/%d/read", direct.ID), aliceToken, nil).expectStatus(http.StatusNoContent)
+	server.request(http.MethodGet, fmt.Sprintf("/conversations/%d/messages?limit=1", direct.ID), bobToken, nil).expectStatus(http.StatusOK).decode(&messages)
+	if len(messages.Data) != 1 || len(messages.Data[0].ReadBy) != 1 || messages.Data[0].ReadBy[0] != alice.ID || messages.NextCursor == "" {
+		t.Fatalf("unexpected messages after reading: %+v", messages)
+	}
+	server.request(http.MethodGet, fmt.Sprintf("/conversations/%d/messages?limit=1&cursor=%s", direct.ID, messages.NextCursor), bobToken, nil).expectStatus(http.StatusOK).decode(&messages)
+	if len(messages.Data) != 1 || messages.Data[0].Content != "Hi bob" || messages.NextCursor != "" {
+		t.Fatalf("unexpected older messages: %+v", messages)
+	}
+
+	// Messaging bob again continues the direct conversation
+	var again models.Conversation
+	server.request(http.MethodPost, "/conversations", bobToken, gin.H{"participant_ids": []uint{alice.ID}, "content": "Still there?"}).expectStatus(http.StatusCreated).decode(&again)
+	if again.ID != direct.ID {
+		t.Fatalf("expected conversation %d to be reused, got %d", direct.ID, again.ID)
+	}
+
+	// Only participants may read or write
+	server.request(http.MethodGet, fmt.Sprintf("/conversations/%d/messages", direct.ID), carolToken, nil).expectStatus(http.StatusForbidden)
+	server.request(http.MethodPost, fmt.Sprintf("/conversations/%d/messages", direct.ID), carolToken, gin.H{"content": "Hello?"}).expectStatus(http.StatusForbidden)
+	server.request(http.MethodPut, fmt.Sprintf("/conversations/%d/read", direct.ID), carolToken, nil).expectStatus(http.StatusForbidden)
+	server.request(http.MethodGet, "/conversations/999/messages", carolToken, nil).expectStatus(http.StatusNotFound)
+
+	// Blocked users cannot be messaged, in new or existing conversations
+	server.request(http.MethodPut, fmt.Sprintf("/users/me/blocks/%d", alice.ID), carolToken, gin.H{"type": "block"}).expectStatus(http.StatusNoContent)
+	server.request(http.MethodPost, "/conversations", aliceToken, gin.H{"participant_ids": []uint{carol.ID}, "content": "Hi carol"}).expectStatus(http.StatusForbidden)
+	server.request(http.MethodPost, fmt.Sprintf("/conversations/%d/messages", group.ID), aliceToken, gin.H{"content": "Hi again"}).expectStatus(http.StatusForbidden)
+}
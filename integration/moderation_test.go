@@ -0,0 +1,131 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"cvwo-backend/internal/data"
+	"cvwo-backend/internal/models"
+)
+
+// Pin posts globally and to topics, and see them first in listings regardless of sort
+func TestPinnedPosts(t *testing.T) {
+	server := newTestServer(t)
+	topics := server.seedTopics("Announcements", "General")
+	alice, aliceToken := server.registerAndLogin("alice", "password")
+	_, modToken := server.registerAndLogin("mod", "password")
+	if err := data.GrantModerators(server.db, []string{"mod"}); err != nil {
+		t.Fatalf("failed to grant moderators: %v", err)
+	}
+
+	first := server.createPost(aliceToken, "first", []uint{topics[0].ID, topics[1].ID})
+	second := server.createPost(aliceToken, "second", []uint{topics[0].ID, topics[1].ID})
+	third := server.createPost(aliceToken, "third", []uint{topics[1].ID})
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/votes/%d", third.ID, alice.ID), aliceToken, gin.H{"value": 1}).expectStatus(http.StatusNoContent)
+
+	// Only moderators can pin posts
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/pin", first.ID), aliceToken, gin.H{"position": 0}).expectStatus(http.StatusForbidden)
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/pin", first.ID), "", gin.H{"position": 0}).expectStatus(http.StatusUnauthorized)
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/pin", first.ID), modToken, gin.H{"position": -1}).expectStatus(http.StatusBadRequest)
+
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/pin", first.ID), modToken, gin.H{"position": 0}).expectStatus(http.StatusNoContent)
+	// first and second are pinned to General, with second above first. Posts pinned to both topics are not counted twice.
+	server.request(http.MethodPut, fmt.Sprintf("/topics/%d/pins/%d", topics[1].ID, first.ID), modToken, gin.H{"position": 2}).expectStatus(http.StatusNoContent)
+	server.request(http.MethodPut, fmt.Sprintf("/topics/%d/pins/%d", topics[1].ID, second.ID), modToken, gin.H{"position": 1}).expectStatus(http.StatusNoContent)
+	server.request(http.MethodPut, fmt.Sprintf("/topics/%d/pins/%d", topics[0].ID, first.ID), modToken, gin.H{"position": 3}).expectStatus(http.StatusNoContent)
+	// Posts can only be pinned to their own topics
+	server.request(http.MethodPut, fmt.Sprintf("/topics/%d/pins/%d", topics[0].ID, third.ID), modToken, gin.H{"position": 0}).expectStatus(http.StatusBadRequest)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantIDs    []uint
+		wantPinned []bool
+		wantVotes  []int
+		wantTotal  int64
+	}{
+		{"newest first", "", []uint{first.ID, third.ID, second.ID}, []bool{true, false, false}, []int{0, 1, 0}, 3},
+		{"most votes first", "?sort=votes", []uint{first.ID, third.ID, second.ID}, []bool{true, false, false}, []int{0, 1, 0}, 3},
+		{"filter by topic", fmt.Sprintf("?sort=votes&tag=%d", topics[1].ID), []uint{second.ID, first.ID, third.ID}, []bool{true, true, false}, []int{0, 0, 1}, 3},
+		{"filter by either topic", fmt.Sprintf("?sort=old&tag=%d&tag=%d", topics[0].ID, topics[1].ID), []uint{second.ID, first.ID, third.ID}, []bool{true, true, false}, []int{0, 0, 1}, 3},
+		{"paginate", fmt.Sprintf("?tag=%d&limit=1&page=2", topics[1].ID), []uint{first.ID}, []bool{true}, []int{0}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var list postList
+			server.request(http.MethodGet, "/posts"+tt.query, "", nil).expectStatus(http.StatusOK).decode(&list)
+
+			if ids := postIDs(list.Data); !slices.Equal(ids, tt.wantIDs) {
+				t.Fatalf("expected posts %v, got %v", tt.wantIDs, ids)
+			}
+			for i, post := range list.Data {
+				if post.Pinned != tt.wantPinned[i] || post.NetVotes != tt.wantVotes[i] {
+					t.Errorf("expected post %d to have pinned %v and %d votes, got %v and %d", post.ID, tt.wantPinned[i], tt.wantVotes[i], post.Pinned, post.NetVotes)
+				}
+			}
+			if list.TotalCount != tt.wantTotal {
+				t.Errorf("expected total count %d, got %d", tt.wantTotal, list.TotalCount)
+			}
+		})
+	}
+
+	// The pin is shown on the post itself
+	var fetched models.Post
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d", first.ID), "", nil).expectStatus(http.StatusOK).decode(&fetched)
+	if !fetched.Pinned || fetched.PinPosition == nil || *fetched.PinPosition != 0 {
+		t.Errorf("expected the post to be pinned at 0, got %+v", fetched)
+	}
+
+	// Unpinning returns posts to their place in the sort order
+	server.request(http.MethodDelete, fmt.Sprintf("/posts/%d/pin", first.ID), modToken, nil).expectStatus(http.StatusNoContent)
+	server.request(http.MethodDelete, fmt.Sprintf("/topics/%d/pins/%d", topics[1].ID, second.ID), modToken, nil).expectStatus(http.StatusNoContent)
+	var list postList
+	server.request(http.MethodGet, fmt.Sprintf("/posts?sort=old&tag=%d", topics[1].ID), "", nil).expectStatus(http.StatusOK).decode(&list)
+	if ids := postIDs(list.Data); !slices.Equal(ids, []uint{first.ID, second.ID, third.ID}) {
+		t.Errorf("expected first to remain pinned to General, got %v", ids)
+	}
+
+	// Revoking moderator stops the user from pinning
+	if err := data.GrantModerators(server.db, nil); err != nil {
+		t.Fatalf("failed to revoke moderators: %v", err)
+	}
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/pin", first.ID), modToken, gin.H{"position": 0}).expectStatus(http.StatusForbidden)
+}
+
+// Lock a post to stop comments and votes on it
+func TestLockedPosts(t *testing.T) {
+	server := newTestServer(t)
+	alice, aliceToken := server.registerAndLogin("alice", "password")
+	bob, bobToken := server.registerAndLogin("bob", "password")
+	_, modToken := server.registerAndLogin("mod", "password")
+	if err := data.GrantModerators(server.db, []string{"mod"}); err != nil {
+		t.Fatalf("failed to grant moderators: %v", err)
+	}
+
+	post := server.createPost(aliceToken, "Flame war", nil)
+	var comment models.Comment
+	server.request(http.MethodPost, "/comments", bobToken, gin.H{"content": "You are wrong", "post_id": post.ID}).expectStatus(http.StatusCreated).decode(&comment)
+
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/lock", post.ID), aliceToken, nil).expectStatus(http.StatusForbidden)
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/lock", post.ID), modToken, nil).expectStatus(http.StatusNoContent)
+
+	var fetched models.Post
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), "", nil).expectStatus(http.StatusOK).decode(&fetched)
+	if !fetched.Locked {
+		t.Fatalf("expected the post to be locked, got %+v", fetched)
+	}
+
+	server.request(http.MethodPost, "/comments", aliceToken, gin.H{"content": "No, you are", "post_id": post.ID}).expectStatus(http.StatusForbidden)
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/votes/%d", post.ID, bob.ID), bobToken, gin.H{"value": -1}).expectStatus(http.StatusForbidden)
+	server.request(http.MethodPut, fmt.Sprintf("/comments/%d/votes/%d", comment.ID, alice.ID), aliceToken, gin.H{"value": -1}).expectStatus(http.StatusForbidden)
+
+	// Unlocking allows comments and votes again
+	server.request(http.MethodDelete, fmt.Sprintf("/posts/%d/lock", post.ID), modToken, nil).expectStatus(http.StatusNoContent)
+	server.request(http.MethodPost, "/comments", aliceToken, gin.H{"content": "Let us agree to disagree", "post_id": post.ID}).expectStatus(http.StatusCreated)
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/votes/%d", post.ID, bob.ID), bobToken, gin.H{"value": 1}).expectStatus(http.StatusNoContent)
+}
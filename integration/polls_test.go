@@ -0,0 +1,100 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+)
+
+// Create a poll post, vote in it and see the tallies in the post
+func TestPolls(t *testing.T) {
+	server := newTestServer(t)
+	_, aliceToken := server.registerAndLogin("alice", "password")
+	_, bobToken := server.registerAndLogin("bob", "password")
+	_, carolToken := server.registerAndLogin("carol", "password")
+
+	var post models.Post
+	server.request(http.MethodPost, "/posts", aliceToken, gin.H{
+		"title":   "Lunch",
+		"content": "Where should we go?",
+		"poll": gin.H{
+			"options":         []string{"Pizza", "Sushi", "Tacos"},
+			"multiple_choice": true,
+			"closes_at":       time.Now().Add(time.Hour),
+		},
+	}).expectStatus(http.StatusCreated).decode(&post)
+	if post.Type != models.PostTypePoll || post.Poll == nil || len(post.Poll.Options) != 3 {
+		t.Fatalf("unexpected poll post: %+v", post)
+	}
+	pizza, sushi, tacos := post.Poll.Options[0].ID, post.Poll.Options[1].ID, post.Poll.Options[2].ID
+
+	// Polls are validated when binding and in the service
+	var problem errs.Problem
+	server.request(http.MethodPost, "/posts", aliceToken, gin.H{"title": "Lunch", "content": "Where should we go?", "poll": gin.H{"options": []string{"Pizza"}}}).
+		expectStatus(http.StatusBadRequest).decode(&problem)
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "options" {
+		t.Errorf("expected an options field error, got %+v", problem)
+	}
+	server.request(http.MethodPost, "/posts", aliceToken, gin.H{"title": "Lunch", "content": "Where should we go?", "poll": gin.H{"options": []string{"Pizza", "pizza"}}}).
+		expectStatus(http.StatusBadRequest)
+
+	// Voters see the results straight away
+	var poll models.Poll
+	server.request(http.MethodPost, fmt.Sprintf("/posts/%d/poll/votes", post.ID), bobToken, gin.H{"option_ids": []uint{pizza, tacos}}).
+		expectStatus(http.StatusCreated).decode(&poll)
+	if !poll.Voted || poll.TotalVoters == nil || *poll.TotalVoters != 1 || !poll.Options[0].Selected || poll.Options[1].Selected {
+		t.Fatalf("unexpected poll after voting: %+v", poll)
+	}
+	server.request(http.MethodPost, fmt.Sprintf("/posts/%d/poll/votes", post.ID), carolToken, gin.H{"option_ids": []uint{pizza}}).expectStatus(http.StatusCreated)
+
+	// Each user votes once
+	server.request(http.MethodPost, fmt.Sprintf("/posts/%d/poll/votes", post.ID), bobToken, gin.H{"option_ids": []uint{sushi}}).expectStatus(http.StatusConflict)
+	server.request(http.MethodPost, fmt.Sprintf("/posts/%d/poll/votes", post.ID), "", gin.H{"option_ids": []uint{sushi}}).expectStatus(http.StatusUnauthorized)
+
+	// The tallies are included in the post for voters, and hidden from others until the poll closes
+	var fetched models.Post
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), bobToken, nil).expectStatus(http.StatusOK).decode(&fetched)
+	votes := []int64{}
+	for _, option := range fetched.Poll.Options {
+		votes = append(votes, *option.Votes)
+	}
+	if fmt.Sprint(votes) != "[2 0 1]" || *fetched.Poll.TotalVoters != 2 {
+		t.Errorf("expected tallies [2 0 1] from 2 voters, got %v from %v", votes, fetched.Poll.TotalVoters)
+	}
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), "", nil).expectStatus(http.StatusOK).decode(&fetched)
+	if fetched.Poll.TotalVoters != nil || fetched.Poll.Options[0].Votes != nil {
+		t.Errorf("expected hidden results for anonymous users, got %+v", fetched.Poll)
+	}
+
+	// Close the poll, after which everyone sees the results and no one can vote
+	if err := server.db.Model(&models.Poll{}).Where("post_id = ?", post.ID).Update("closes_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatalf("failed to close poll: %v", err)
+	}
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), "", nil).expectStatus(http.StatusOK).decode(&fetched)
+	if !fetched.Poll.Closed || fetched.Poll.TotalVoters == nil || *fetched.Poll.TotalVoters != 2 {
+		t.Errorf("expected visible results after closing, got %+v", fetched.Poll)
+	}
+	_, daveToken := server.registerAndLogin("dave", "password")
+	server.request(http.MethodPost, fmt.Sprintf("/posts/%d/poll/votes", post.ID), daveToken, gin.H{"option_ids": []uint{sushi}}).expectStatus(http.StatusConflict)
+
+	// Listings show the post type without the poll
+	var posts postList
+	server.request(http.MethodGet, "/posts", "", nil).expectStatus(http.StatusOK).decode(&posts)
+	if len(posts.Data) != 1 || posts.Data[0].Type != models.PostTypePoll || posts.Data[0].Poll != nil {
+		t.Errorf("unexpected listing: %+v", posts.Data)
+	}
+
+	// Deleting the post deletes the poll and its votes
+	server.request(http.MethodDelete, fmt.Sprintf("/posts/%d", post.ID), aliceToken, nil).expectStatus(http.StatusNoContent)
+	var remaining int64
+	server.db.Model(&models.PollChoice{}).Count(&remaining)
+	if remaining != 0 {
+		t.Errorf("expected poll choices to be deleted, %d remain", remaining)
+	}
+}
@@ -0,0 +1,91 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"cvwo-backend/internal/models"
+)
+
+type savedList struct {
+	Data       []models.SavedItem `json:"data"`
+	TotalCount int64              `json:"total_count"`
+}
+
+// Save and unsave posts and comments, then list them with the type filter and check the saved flag
+func TestSavedItems(t *testing.T) {
+	server := newTestServer(t)
+	_, aliceToken := server.registerAndLogin("alice", "password")
+	_, bobToken := server.registerAndLogin("bob", "password")
+
+	first := server.createPost(aliceToken, "First post", []uint{})
+	second := server.createPost(aliceToken, "Second post", []uint{})
+	var comment models.Comment
+	server.request(http.MethodPost, "/comments", aliceToken, gin.H{"content": "A comment worth keeping", "post_id": first.ID}).
+		expectStatus(http.StatusCreated).
+		decode(&comment)
+
+	// Saving requires authentication and an existing target
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/save", first.ID), "", nil).expectStatus(http.StatusUnauthorized)
+	server.request(http.MethodPut, "/posts/999/save", bobToken, nil).expectStatus(http.StatusNotFound)
+	server.request(http.MethodPut, "/comments/999/save", bobToken, nil).expectStatus(http.StatusNotFound)
+
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/save", first.ID), bobToken, nil).expectStatus(http.StatusNoContent)
+	server.request(http.MethodPut, fmt.Sprintf("/comments/%d/save", comment.ID), bobToken, nil).expectStatus(http.StatusNoContent)
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/save", second.ID), bobToken, nil).expectStatus(http.StatusNoContent)
+	// Saving twice is idempotent
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/save", second.ID), bobToken, nil).expectStatus(http.StatusNoContent)
+
+	var saved savedList
+	server.request(http.MethodGet, "/users/me/saved", bobToken, nil).expectStatus(http.StatusOK).decode(&saved)
+	if saved.TotalCount != 3 || len(saved.Data) != 3 {
+		t.Fatalf("expected 3 saved items, got %+v", saved)
+	}
+	if saved.Data[1].ItemType != models.SavedItemComment || saved.Data[1].Comment == nil || saved.Data[1].Comment.ID != comment.ID {
+		t.Fatalf("expected saved comment to be included, got %+v", saved.Data[1])
+	}
+
+	server.request(http.MethodGet, "/users/me/saved?type=post&limit=1&page=2", bobToken, nil).expectStatus(http.StatusOK).decode(&saved)
+	if saved.TotalCount != 2 || len(saved.Data) != 1 || saved.Data[0].Post == nil || saved.Data[0].Post.ID != first.ID {
+		t.Fatalf("expected the first post on the second page, got %+v", saved)
+	}
+	server.request(http.MethodGet, "/users/me/saved?type=user", bobToken, nil).expectStatus(http.StatusBadRequest)
+	server.request(http.MethodGet, "/users/me/saved", "", nil).expectStatus(http.StatusUnauthorized)
+
+	// The saved flag is computed for the current user only
+	var posts postList
+	server.request(http.MethodGet, "/posts?sort=new", bobToken, nil).expectStatus(http.StatusOK).decode(&posts)
+	for _, post := range posts.Data {
+		if !post.Saved {
+			t.Fatalf("expected post %d to be saved for bob", post.ID)
+		}
+	}
+	server.request(http.MethodGet, "/posts?sort=new", aliceToken, nil).expectStatus(http.StatusOK).decode(&posts)
+	for _, post := range posts.Data {
+		if post.Saved {
+			t.Fatalf("expected post %d not to be saved for alice", post.ID)
+		}
+	}
+	var comments commentList
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d/comments", first.ID), bobToken, nil).expectStatus(http.StatusOK).decode(&comments)
+	if comments.TotalCount != 1 || !comments.Data[0].Saved {
+		t.Fatalf("expected comment to be saved for bob, got %+v", comments)
+	}
+
+	// Unsave, and deleted posts drop out of the list
+	server.request(http.MethodDelete, fmt.Sprintf("/comments/%d/save", comment.ID), bobToken, nil).expectStatus(http.StatusNoContent)
+	server.request(http.MethodDelete, fmt.Sprintf("/posts/%d", second.ID), aliceToken, nil).expectStatus(http.StatusNoContent)
+
+	var fetched models.Post
+	server.request(http.MethodGet, fmt.Sprintf("/posts/%d", first.ID), bobToken, nil).expectStatus(http.StatusOK).decode(&fetched)
+	if !fetched.Saved {
+		t.Fatal("expected post to still be saved")
+	}
+	server.request(http.MethodGet, "/users/me/saved", bobToken, nil).expectStatus(http.StatusOK).decode(&saved)
+	if saved.TotalCount != 1 || saved.Data[0].ItemID != first.ID {
+		t.Fatalf("expected only the first post to remain saved, got %+v", saved)
+	}
+}
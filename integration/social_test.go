@@ -0,0 +1,136 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"cvwo-backend/internal/models"
+)
+
+type activityPage struct {
+	Data       []models.Activity `json:"data"`
+	NextCursor string            `json:"next_cursor"`
+}
+
+type userList struct {
+	Data       []models.User `json:"data"`
+	TotalCount int64         `json:"total_count"`
+}
+
+// Follow users, check profiles, follower lists and the feed, then page through an activity stream
+func TestFollowsAndActivity(t *testing.T) {
+	server := newTestServer(t)
+	alice, aliceToken := server.registerAndLogin("alice", "password")
+	bob, bobToken := server.registerAndLogin("bob", "password")
+	_, carolToken := server.registerAndLogin("carol", "password")
+
+	server.request(http.MethodPut, fmt.Sprintf("/users/%d/follow", alice.ID), "", nil).expectStatus(http.StatusUnauthorized)
+	server.request(http.MethodPut, fmt.Sprintf("/users/%d/follow", alice.ID), aliceToken, nil).expectStatus(http.StatusBadRequest)
+	server.request(http.MethodPut, "/users/999/follow", bobToken, nil).expectStatus(http.StatusNotFound)
+	server.request(http.MethodPut, fmt.Sprintf("/users/%d/follow", alice.ID), bobToken, nil).expectStatus(http.StatusNoContent)
+	server.request(http.MethodPut, fmt.Sprintf("/users/%d/follow", alice.ID), carolToken, nil).expectStatus(http.StatusNoContent)
+	server.request(http.MethodPut, fmt.Sprintf("/users/%d/follow", bob.ID), aliceToken, nil).expectStatus(http.StatusNoContent)
+
+	var profile models.UserProfile
+	server.request(http.MethodGet, fmt.Sprintf("/users/%d", alice.ID), bobToken, nil).expectStatus(http.StatusOK).decode(&profile)
+	if profile.Username != "alice" || profile.FollowerCount != 2 || profile.FollowingCount != 1 || !profile.Followed {
+		t.Fatalf("unexpected profile: %+v", profile)
+	}
+	server.request(http.MethodGet, fmt.Sprintf("/users/%d", alice.ID), "", nil).expectStatus(http.StatusOK).decode(&profile)
+	if profile.Followed {
+		t.Fatal("expected anonymous users not to follow anyone")
+	}
+
+	var followers userList
+	server.request(http.MethodGet, fmt.Sprintf("/users/%d/followers", alice.ID), "", nil).expectStatus(http.StatusOK).decode(&followers)
+	if followers.TotalCount != 2 || followers.Data[0].Username != "carol" || followers.Data[1].Username != "bob" {
+		t.Fatalf("unexpected followers: %+v", followers)
+	}
+	var following userList
+	server.request(http.MethodGet, fmt.Sprintf("/users/%d/following", alice.ID), "", nil).expectStatus(http.StatusOK).decode(&following)
+	if following.TotalCount != 1 || following.Data[0].ID != bob.ID {
+		t.Fatalf("unexpected following: %+v", following)
+	}
+
+	// Posts by followed users appear in the feed
+	first := server.createPost(aliceToken, "First post", []uint{})
+	bobsPost := server.createPost(bobToken, "Bob's post", []uint{})
+	server.createPost(carolToken, "Carol's post", []uint{})
+	var feed postList
+	server.request(http.MethodGet, "/feed", bobToken, nil).expectStatus(http.StatusOK).decode(&feed)
+	if want := []uint{first.ID}; !slices.Equal(postIDs(feed.Data), want) {
+		t.Fatalf("expected feed %v, got %v", want, postIDs(feed.Data))
+	}
+
+	// Alice comments, votes and posts again
+	var comment models.Comment
+	server.request(http.MethodPost, "/comments", aliceToken, gin.H{"content": "Nice post", "post_id": bobsPost.ID}).
+		expectStatus(http.StatusCreated).
+		decode(&comment)
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/votes/%d", bobsPost.ID, alice.ID), aliceToken, gin.H{"value": 1}).
+		expectStatus(http.StatusNoContent)
+	second := server.createPost(aliceToken, "Second post", []uint{})
+
+	// Page through the stream one entry at a time
+	collect := func(token string) []string {
+		t.Helper()
+		entries := []string{}
+		cursor := ""
+		for page := 0; page < 10; page++ {
+			var activity activityPage
+			server.request(http.MethodGet, fmt.Sprintf("/users/%d/activity?limit=1&cursor=%s", alice.ID, url.QueryEscape(cursor)), token, nil).
+				expectStatus(http.StatusOK).
+				decode(&activity)
+			for _, entry := range activity.Data {
+				entries = append(entries, fmt.Sprintf("%s:%d", entry.Type, entry.TargetID()))
+			}
+			if activity.NextCursor == "" {
+				return entries
+			}
+			cursor = activity.NextCursor
+		}
+		t.Fatal("expected the activity stream to end")
+		return nil
+	}
+
+	withVotes := []string{
+		fmt.Sprintf("post:%d", second.ID),
+		fmt.Sprintf("post_vote:%d", bobsPost.ID),
+		fmt.Sprintf("comment:%d", comment.ID),
+		fmt.Sprintf("post:%d", first.ID),
+	}
+	withoutVotes := slices.Delete(slices.Clone(withVotes), 1, 2)
+
+	if got := collect(aliceToken); !slices.Equal(got, withVotes) {
+		t.Fatalf("expected own activity %v, got %v", withVotes, got)
+	}
+	if got := collect(bobToken); !slices.Equal(got, withoutVotes) {
+		t.Fatalf("expected activity without private votes %v, got %v", withoutVotes, got)
+	}
+
+	// Make votes public
+	server.request(http.MethodPut, "/users/me/settings", aliceToken, gin.H{}).expectStatus(http.StatusBadRequest)
+	var updated models.User
+	server.request(http.MethodPut, "/users/me/settings", aliceToken, gin.H{"public_votes": true}).expectStatus(http.StatusOK).decode(&updated)
+	if !updated.PublicVotes {
+		t.Fatal("expected votes to be public")
+	}
+	if got := collect(""); !slices.Equal(got, withVotes) {
+		t.Fatalf("expected activity with public votes %v, got %v", withVotes, got)
+	}
+
+	server.request(http.MethodGet, fmt.Sprintf("/users/%d/activity?cursor=nonsense", alice.ID), "", nil).expectStatus(http.StatusBadRequest)
+	server.request(http.MethodGet, "/users/999/activity", "", nil).expectStatus(http.StatusNotFound)
+
+	// Unfollowing updates the counts
+	server.request(http.MethodDelete, fmt.Sprintf("/users/%d/follow", alice.ID), bobToken, nil).expectStatus(http.StatusNoContent)
+	server.request(http.MethodGet, fmt.Sprintf("/users/%d", alice.ID), bobToken, nil).expectStatus(http.StatusOK).decode(&profile)
+	if profile.FollowerCount != 1 || profile.Followed {
+		t.Fatalf("unexpected profile after unfollowing: %+v", profile)
+	}
+}
@@ -0,0 +1,147 @@
+package integration
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type rssFeed struct {
+	Items []struct {
+		Title      string   `xml:"title"`
+		Link       string   `xml:"link"`
+		Creator    string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+		Categories []string `xml:"category"`
+		GUID       struct {
+			IsPermaLink string `xml:"isPermaLink,attr"`
+			Value       string `xml:",chardata"`
+		} `xml:"guid"`
+		PubDate string `xml:"pubDate"`
+	} `xml:"channel>item"`
+}
+
+type atomFeed struct {
+	Title   string `xml:"title"`
+	Updated string `xml:"updated"`
+	Entries []struct {
+		ID     string `xml:"id"`
+		Title  string `xml:"title"`
+		Author string `xml:"author>name"`
+		Link   struct {
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+		Categories []struct {
+			Term string `xml:"term,attr"`
+		} `xml:"category"`
+		Updated string `xml:"updated"`
+		Content string `xml:"content"`
+	} `xml:"entry"`
+}
+
+func (res *response) decodeXML(v any) *response {
+	res.t.Helper()
+	if err := xml.Unmarshal(res.body, v); err != nil {
+		res.t.Fatalf("failed to decode XML response: %v\n%s", err, res.body)
+	}
+	return res
+}
+
+// Feeds list the newest published posts with links under the configured base URL, and the same ID for a post in every feed
+func TestFeeds(t *testing.T) {
+	server := newTestServer(t)
+	topics := server.seedTopics("Philosophy", "Literature")
+	_, aliceToken := server.registerAndLogin("alice", "password")
+	bob, bobToken := server.registerAndLogin("bob", "password")
+
+	first := server.createPost(aliceToken, "First", []uint{topics[0].ID})
+	second := server.createPost(bobToken, "Second", []uint{topics[1].ID})
+	// Drafts are not in feeds
+	server.request(http.MethodPost, "/posts", aliceToken, gin.H{"title": "Draft", "content": "Not published yet", "status": "draft"}).expectStatus(http.StatusCreated)
+
+	res := server.request(http.MethodGet, "/feeds/posts.rss", "", nil).expectStatus(http.StatusOK)
+	if got := res.header.Get("Content-Type"); got != "application/rss+xml; charset=utf-8" {
+		t.Errorf("expected an RSS content type, got %q", got)
+	}
+	var rss rssFeed
+	res.decodeXML(&rss)
+	if len(rss.Items) != 2 || rss.Items[0].Title != "Second" || rss.Items[1].Title != "First" {
+		t.Fatalf("expected the published posts, newest first, got %+v", rss.Items)
+	}
+	item := rss.Items[0]
+	if item.Link != fmt.Sprintf("https://forum.example.com/posts/%d", second.ID) || item.Creator != "bob" || !slices.Equal(item.Categories, []string{"Literature"}) {
+		t.Errorf("expected the link, author and topics of the post, got %+v", item)
+	}
+	if item.GUID.IsPermaLink != "false" || !strings.HasPrefix(item.GUID.Value, "tag:forum.example.com,") || item.GUID.Value == rss.Items[1].GUID.Value {
+		t.Errorf("expected a unique tag URI as the GUID, got %+v", item.GUID)
+	}
+	if _, err := time.Parse(time.RFC1123Z, item.PubDate); err != nil {
+		t.Errorf("expected an RFC 1123 publication date, got %q", item.PubDate)
+	}
+
+	var topicFeed atomFeed
+	res = server.request(http.MethodGet, fmt.Sprintf("/feeds/topics/%d.atom", topics[0].ID), "", nil).expectStatus(http.StatusOK).decodeXML(&topicFeed)
+	if got := res.header.Get("Content-Type"); got != "application/atom+xml; charset=utf-8" {
+		t.Errorf("expected an Atom content type, got %q", got)
+	}
+	if topicFeed.Title != "Posts in Philosophy" || len(topicFeed.Entries) != 1 {
+		t.Fatalf("expected the post tagged with the topic, got %+v", topicFeed)
+	}
+	entry := topicFeed.Entries[0]
+	if entry.Title != "First" || entry.Author != "alice" || len(entry.Categories) != 1 || entry.Categories[0].Term != "Philosophy" || !strings.Contains(entry.Content, "<p>") {
+		t.Errorf("expected the post with its author, topics and HTML content, got %+v", entry)
+	}
+	if entry.ID != rss.Items[1].GUID.Value {
+		t.Errorf("expected the same ID as in the RSS feed, got %q and %q", entry.ID, rss.Items[1].GUID.Value)
+	}
+
+	var userFeed atomFeed
+	server.request(http.MethodGet, fmt.Sprintf("/feeds/users/%d.atom", bob.ID), "", nil).expectStatus(http.StatusOK).decodeXML(&userFeed)
+	if userFeed.Title != "Posts by bob" || len(userFeed.Entries) != 1 || userFeed.Entries[0].Title != "Second" {
+		t.Errorf("expected the user's post, got %+v", userFeed)
+	}
+
+	// Edits update the entry and the feed, but not the entry's ID
+	time.Sleep(time.Second)
+	server.request(http.MethodPatch, fmt.Sprintf("/posts/%d", first.ID), aliceToken, gin.H{"title": "First, edited", "content": "New content of the post"}).expectStatus(http.StatusOK)
+	var edited atomFeed
+	server.request(http.MethodGet, fmt.Sprintf("/feeds/topics/%d.atom", topics[0].ID), "", nil).expectStatus(http.StatusOK).decodeXML(&edited)
+	if edited.Entries[0].ID != entry.ID || edited.Entries[0].Updated == entry.Updated || edited.Updated == topicFeed.Updated {
+		t.Errorf("expected the entry and feed to be updated under the same ID, got %+v", edited)
+	}
+
+	// Votes do not change feeds, so feed readers can revalidate them
+	res = server.request(http.MethodGet, "/feeds/posts.rss", "", nil).expectStatus(http.StatusOK)
+	server.request(http.MethodPut, fmt.Sprintf("/posts/%d/votes/%d", second.ID, bob.ID), bobToken, gin.H{"value": 1}).expectStatus(http.StatusNoContent)
+	server.requestWithHeader(http.MethodGet, "/feeds/posts.rss", "", nil, http.Header{"If-None-Match": {res.header.Get("ETag")}}).expectStatus(http.StatusNotModified)
+	if got := res.header.Get("Cache-Control"); got != "public, max-age=60" {
+		t.Errorf("expected feeds to be publicly cacheable, got %q", got)
+	}
+}
+
+func TestFeedErrors(t *testing.T) {
+	server := newTestServer(t)
+	server.seedTopics("Philosophy")
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{"unknown topic", "/feeds/topics/9999.atom", http.StatusNotFound},
+		{"unknown user", "/feeds/users/9999.atom", http.StatusNotFound},
+		{"invalid ID", "/feeds/users/alice.atom", http.StatusBadRequest},
+		{"other format", "/feeds/topics/1.rss", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.request(http.MethodGet, tt.path, "", nil).expectStatus(tt.wantStatus)
+		})
+	}
+}
@@ -0,0 +1,381 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"gorm.io/gorm"
+
+	"cvwo-backend/internal/cache"
+	"cvwo-backend/internal/controllers"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/events"
+	"cvwo-backend/internal/jobs"
+	"cvwo-backend/internal/middleware"
+	"cvwo-backend/internal/ratelimit"
+	"cvwo-backend/internal/repos"
+	"cvwo-backend/internal/routes"
+	"cvwo-backend/internal/services"
+	"cvwo-backend/internal/storage"
+	"cvwo-backend/internal/syndication"
+	"cvwo-backend/internal/tracing"
+)
+
+// Application settings that are not read directly from the environment by lower layers
+type Config struct {
+	// Origin allowed to make cross-origin requests
+	FrontendURL string
+	// Rate limits for each route group
+	WritesRateLimit ratelimit.Limit
+	VotesRateLimit  ratelimit.Limit
+	LoginRateLimit  ratelimit.Limit
+	// Maximum lengths of post and comment content
+	ContentLimits services.ContentLimits
+	// Where attachment files are stored, and the limits on uploading them
+	BlobStore        storage.BlobStore
+	AttachmentLimits services.AttachmentLimits
+	// Builds RSS and Atom feeds, linking to the forum's pages under a base URL
+	Feeds *syndication.Builder
+	// Where anonymous listings are cached, and for how long. Nil disables caching.
+	Cache    cache.Cache
+	CacheTTL time.Duration
+	// Whether Cache is shared by every instance, so that an event handled by one instance invalidates the listings of all of them
+	SharedCache bool
+	// Log the body of every response
+	LogResponses bool
+}
+
+// Read the config from environment variables, using defaults for unset rate limits and content limits
+// Rate limits are configured as "{requests}/{s|m|h}"
+// Attachments are stored in the STORAGE_DIR directory, or an S3-compatible bucket when STORAGE_BACKEND is "s3"
+// Anonymous listings are cached in memory for 30 seconds unless CACHE_BACKEND and CACHE_TTL say otherwise
+// Feeds link to pages under FEED_BASE_URL, or FRONTEND_URL if it is unset
+func ConfigFromEnv() (Config, error) {
+	config := Config{FrontendURL: os.Getenv("FRONTEND_URL"), LogResponses: true}
+
+	var err error
+	if config.WritesRateLimit, err = ratelimit.ParseLimitOrDefault(os.Getenv("RATE_LIMIT_WRITES"), ratelimit.Limit{Requests: 30, Period: time.Minute}); err != nil {
+		return Config{}, err
+	}
+	if config.VotesRateLimit, err = ratelimit.ParseLimitOrDefault(os.Getenv("RATE_LIMIT_VOTES"), ratelimit.Limit{Requests: 60, Period: time.Minute}); err != nil {
+		return Config{}, err
+	}
+	if config.LoginRateLimit, err = ratelimit.ParseLimitOrDefault(os.Getenv("RATE_LIMIT_LOGIN"), ratelimit.Limit{Requests: 10, Period: time.Minute}); err != nil {
+		return Config{}, err
+	}
+	if config.ContentLimits.Post, err = parseIntOrDefault("MAX_POST_LENGTH", services.DefaultContentLimits.Post); err != nil {
+		return Config{}, err
+	}
+	if config.ContentLimits.Comment, err = parseIntOrDefault("MAX_COMMENT_LENGTH", services.DefaultContentLimits.Comment); err != nil {
+		return Config{}, err
+	}
+	maxUploadSize, err := parseIntOrDefault("MAX_UPLOAD_SIZE", int(services.DefaultAttachmentLimits.MaxSize))
+	if err != nil {
+		return Config{}, err
+	}
+	config.AttachmentLimits.MaxSize = int64(maxUploadSize)
+	if config.BlobStore, err = blobStoreFromEnv(); err != nil {
+		return Config{}, err
+	}
+	feedBaseURL := os.Getenv("FEED_BASE_URL")
+	if feedBaseURL == "" {
+		feedBaseURL = config.FrontendURL
+	}
+	if config.Feeds, err = syndication.NewBuilder(feedBaseURL); err != nil {
+		return Config{}, err
+	}
+	if config.Cache, config.SharedCache, err = cacheFromEnv(); err != nil {
+		return Config{}, err
+	}
+	if config.CacheTTL, err = parseDurationOrDefault("CACHE_TTL", 30*time.Second); err != nil {
+		return Config{}, err
+	}
+
+	return config, nil
+}
+
+// Read the usernames of moderators from the comma-separated MODERATORS variable, ignoring blank entries
+func ModeratorsFromEnv() []string {
+	usernames := []string{}
+	for _, username := range strings.Split(os.Getenv("MODERATORS"), ",") {
+		if username = strings.TrimSpace(username); username != "" {
+			usernames = append(usernames, username)
+		}
+	}
+	return usernames
+}
+
+// Create the blob store selected by STORAGE_BACKEND (local or s3)
+func blobStoreFromEnv() (storage.BlobStore, error) {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "local":
+		dir := os.Getenv("STORAGE_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		return storage.NewLocalStore(dir)
+	case "s3":
+		return storage.NewS3Store(storage.S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			UseSSL:    os.Getenv("S3_USE_SSL") != "false",
+		})
+	default:
+		return nil, fmt.Errorf("invalid STORAGE_BACKEND %q: must be local or s3", backend)
+	}
+}
+
+// Create the cache selected by CACHE_BACKEND (memory, redis or none)
+// The memory cache holds up to CACHE_SIZE listings; the Redis cache connects to REDIS_ADDR, and is shared by every instance.
+func cacheFromEnv() (cache.Cache, bool, error) {
+	switch backend := os.Getenv("CACHE_BACKEND"); backend {
+	case "", "memory":
+		size, err := parseIntOrDefault("CACHE_SIZE", 1000)
+		if err != nil {
+			return nil, false, err
+		}
+		return cache.NewLRU(size), false, nil
+	case "redis":
+		addr := os.Getenv("REDIS_ADDR")
+		if addr == "" {
+			addr = "localhost:6379"
+		}
+		return cache.NewRedis(addr), true, nil
+	case "none":
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("invalid CACHE_BACKEND %q: must be memory, redis or none", backend)
+	}
+}
+
+// Read a positive duration such as "30s" from the given environment variable, or return the default if it is unset
+func parseDurationOrDefault(name string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive duration", name, value)
+	}
+	return parsed, nil
+}
+
+// Read a positive integer from the given environment variable, or return the default if it is unset
+func parseIntOrDefault(name string, defaultValue int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 1 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive integer", name, value)
+	}
+	return parsed, nil
+}
+
+// Build the router with every application layer wired up against the given database
+func NewRouter(db *gorm.DB, config Config) *gin.Engine {
+	// Initialize application layers
+	// Repositories (data access)
+	userRepo := repos.NewUserRepo(db)
+	postRepo := repos.NewPostRepo(db)
+	commentRepo := repos.NewCommentRepo(db)
+	topicRepo := repos.NewTopicRepo(db)
+	postVoteRepo := repos.NewPostVoteRepo(db)
+	commentVoteRepo := repos.NewCommentVoteRepo(db)
+	savedItemRepo := repos.NewSavedItemRepo(db)
+	subscriptionRepo := repos.NewSubscriptionRepo(db)
+	followRepo := repos.NewFollowRepo(db)
+	activityRepo := repos.NewActivityRepo(db)
+	blockRepo := repos.NewBlockRepo(db)
+	conversationRepo := repos.NewConversationRepo(db)
+	attachmentRepo := repos.NewAttachmentRepo(db)
+	pollRepo := repos.NewPollRepo(db)
+	outboxRepo := repos.NewOutboxRepo(db)
+	archiveRepo := repos.NewArchiveRepo(db)
+	transactor := repos.NewTransactor(db)
+
+	// Services (business logic)
+	listings := services.NewListingCache(config.Cache, config.CacheTTL)
+	// Topics only change when the database is seeded or imported before the server starts, so a shared cache may hold the topics of before
+	if err := listings.InvalidateTopics(context.Background()); err != nil {
+		log.Printf("Failed to invalidate cached topics: %v", err)
+	}
+	userService := services.NewUserService(userRepo)
+	attachmentService := services.NewAttachmentService(attachmentRepo, postRepo, commentRepo, config.BlobStore, config.AttachmentLimits)
+	pollService := services.NewPollService(pollRepo, postRepo)
+	postService := services.NewPostService(postRepo, userRepo, topicRepo, outboxRepo, transactor, pollService, attachmentService, config.ContentLimits, listings)
+	commentService := services.NewCommentService(commentRepo, postRepo, userRepo, blockRepo, outboxRepo, transactor, attachmentService, config.ContentLimits)
+	topicService := services.NewTopicService(topicRepo, listings)
+	taggingService := services.NewTaggingService(postRepo, topicRepo, outboxRepo, transactor, listings)
+	votingService := services.NewVotingService(postVoteRepo, commentVoteRepo, postRepo, commentRepo, outboxRepo, transactor, listings)
+	savingService := services.NewSavingService(savedItemRepo, postRepo, commentRepo)
+	subscriptionService := services.NewSubscriptionService(subscriptionRepo, topicRepo)
+	feedService := services.NewFeedService(postRepo, subscriptionRepo, followRepo)
+	followService := services.NewFollowService(followRepo, userRepo)
+	activityService := services.NewActivityService(activityRepo, userRepo)
+	blockService := services.NewBlockService(blockRepo, userRepo)
+	conversationService := services.NewConversationService(conversationRepo, userRepo, blockRepo)
+	moderationService := services.NewModerationService(postRepo, userRepo, topicRepo, listings)
+	archiveService := services.NewArchiveService(archiveRepo, userRepo)
+	// Lock out a username for 1 minute after 5 consecutive failed logins, doubling with each lockout up to 1 hour
+	authService := services.NewAuthService(userRepo, ratelimit.NewLockout(5, time.Minute, time.Hour))
+
+	// Controllers (route handlers)
+	userController := controllers.NewUserController(*userService, *savingService, *subscriptionService, *followService, *activityService)
+	postController := controllers.NewPostController(*postService, *taggingService, *votingService, *savingService, *pollService)
+	commentController := controllers.NewCommentController(*commentService, *votingService, *savingService)
+	topicController := controllers.NewTopicController(*topicService, *subscriptionService)
+	feedController := controllers.NewFeedController(*feedService)
+	blockController := controllers.NewBlockController(*blockService)
+	conversationController := controllers.NewConversationController(*conversationService)
+	attachmentController := controllers.NewAttachmentController(*attachmentService)
+	moderationController := controllers.NewModerationController(*moderationService)
+	archiveController := controllers.NewArchiveController(*archiveService)
+	authController := controllers.NewAuthController(authService)
+	syndicationController := controllers.NewSyndicationController(*postService, *topicService, *userService, config.Feeds)
+	docsController := controllers.NewDocsController()
+
+	// Initialize router
+	router := gin.Default()
+
+	// Request ID middleware to correlate responses and logs
+	router.Use(middleware.RequestID)
+
+	// Tracing middleware to start a span for every request
+	router.Use(otelgin.Middleware(tracing.ServiceName))
+
+	// Metrics middleware to record request counts and latencies
+	router.Use(middleware.Metrics)
+
+	// Logger middleware to log request body of all requests
+	if config.LogResponses {
+		router.Use(middleware.ResponseLogger)
+	}
+
+	// CORS middleware
+	router.Use(cors.New(cors.Config{
+		AllowOrigins:     []string{config.FrontendURL},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Request-ID", "If-Match", "If-None-Match"},
+		ExposeHeaders:    []string{"Content-Length", "X-Request-ID", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", "ETag"},
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	}))
+
+	// Authentication middleware to validate jwt from requests
+	authMiddleware := middleware.NewAuthMiddleware(authService)
+	router.Use(authMiddleware.Authenticate())
+
+	// Rate limits for each route group
+	rateLimitStore := ratelimit.NewMemoryStore()
+	rateLimits := &middleware.RateLimits{
+		Writes: middleware.NewRateLimiter(rateLimitStore, "writes", config.WritesRateLimit).Limit(),
+		Votes:  middleware.NewRateLimiter(rateLimitStore, "votes", config.VotesRateLimit).Limit(),
+		Login:  middleware.NewRateLimiter(rateLimitStore, "login", config.LoginRateLimit).Limit(),
+	}
+
+	// Register route handlers
+	routes.RegisterUserRoutes(router, userController, rateLimits)
+	routes.RegisterPostRoutes(router, postController, rateLimits)
+	routes.RegisterCommentRoutes(router, commentController, rateLimits)
+	routes.RegisterTopicRoutes(router, topicController, rateLimits)
+	routes.RegisterFeedRoutes(router, feedController)
+	routes.RegisterSyndicationRoutes(router, syndicationController)
+	routes.RegisterBlockRoutes(router, blockController, rateLimits)
+	routes.RegisterConversationRoutes(router, conversationController, rateLimits)
+	routes.RegisterAttachmentRoutes(router, attachmentController, rateLimits)
+	routes.RegisterModerationRoutes(router, moderationController, rateLimits)
+	routes.RegisterArchiveRoutes(router, archiveController)
+	routes.RegisterAuthRoutes(router, authController, rateLimits)
+	routes.RegisterMetricsRoutes(router)
+	routes.RegisterDocsRoutes(router, docsController)
+
+	// Respond to unknown routes with problem details like every other error
+	router.NoRoute(func(ctx *gin.Context) {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrNotFound, "Route not found"))
+	})
+
+	return router
+}
+
+// Types of the application's background jobs
+const (
+	JobCleanupAttachments    = "cleanup_attachments"
+	JobPublishScheduledPosts = "publish_scheduled_posts"
+)
+
+// Build the background job runner with the application's job handlers and recurring jobs registered
+func NewJobRunner(db *gorm.DB, config Config, options jobs.Options) (*jobs.Runner, error) {
+	postRepo := repos.NewPostRepo(db)
+	commentRepo := repos.NewCommentRepo(db)
+	attachmentService := services.NewAttachmentService(repos.NewAttachmentRepo(db), postRepo, commentRepo, config.BlobStore, config.AttachmentLimits)
+	pollService := services.NewPollService(repos.NewPollRepo(db), postRepo)
+	postService := services.NewPostService(postRepo, repos.NewUserRepo(db), repos.NewTopicRepo(db), repos.NewOutboxRepo(db), repos.NewTransactor(db), pollService, attachmentService, config.ContentLimits, services.NewListingCache(config.Cache, config.CacheTTL))
+
+	runner := jobs.NewRunner(db, options)
+
+	// Delete attachment files that no attachment refers to
+	// Files are kept for an hour so that uploads in progress are not mistaken for orphans
+	jobs.Handle(runner, JobCleanupAttachments, func(ctx context.Context, _ struct{}) error {
+		deleted, err := attachmentService.CleanupOrphans(ctx, time.Hour)
+		if deleted > 0 {
+			log.Printf("Deleted %d orphaned attachment files", deleted)
+		}
+		return err
+	})
+
+	// Publish scheduled posts that are due
+	jobs.Handle(runner, JobPublishScheduledPosts, func(ctx context.Context, _ struct{}) error {
+		published, err := postService.PublishDue(ctx)
+		if published > 0 {
+			log.Printf("Published %d scheduled posts", published)
+		}
+		return err
+	})
+
+	if err := runner.Schedule("@hourly", JobCleanupAttachments, struct{}{}); err != nil {
+		return nil, err
+	}
+	if err := runner.Schedule("* * * * *", JobPublishScheduledPosts, struct{}{}); err != nil {
+		return nil, err
+	}
+	return runner, nil
+}
+
+// Subscribe the application's event handlers to the dispatcher
+// Must be called before the dispatcher's job runner is started
+func SubscribeToEvents(dispatcher *events.Dispatcher, config Config) {
+	// The services drop the cached listings of the instance making a change as soon as it commits. Events are handled
+	// by whichever instance claims them, so they can only drop the listings of a shared cache, which they do again in
+	// case the services failed to, since event handlers are retried.
+	listings := services.NewListingCache(config.Cache, config.CacheTTL)
+	if listings == nil || !config.SharedCache {
+		return
+	}
+
+	// Changes made by moderators are not recorded as events.
+	invalidatePostsOn[events.PostCreated](dispatcher, listings)
+	invalidatePostsOn[events.PostEdited](dispatcher, listings)
+	invalidatePostsOn[events.PostDeleted](dispatcher, listings)
+	invalidatePostsOn[events.PostTagged](dispatcher, listings)
+	invalidatePostsOn[events.PostVoted](dispatcher, listings)
+}
+
+func invalidatePostsOn[T events.Event](dispatcher *events.Dispatcher, listings *services.ListingCache) {
+	events.Subscribe(dispatcher, "listing-cache", func(ctx context.Context, _ T) error {
+		return listings.InvalidatePosts(ctx)
+	})
+}
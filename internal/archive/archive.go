@@ -0,0 +1,197 @@
+// Package archive exports the forum's data as a versioned JSON Lines archive and imports it into an empty database.
+//
+// The first line of an archive is a header, followed by one line per record and a trailer with the number of records:
+//
+//	{"type":"header","version":1,"exported_at":"2026-10-19T12:00:00Z","passwords":false}
+//	{"type":"user","data":{"id":1,"username":"alice","public_votes":false,"moderator":false}}
+//	...
+//	{"type":"trailer","records":42}
+//
+// Records of each type are written in order of their keys, with every record after the records it refers to, so that they can be inserted in order.
+// A missing trailer means the archive was cut short, such as when an export failed while being streamed.
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Version of the archive format, incremented by changes that older versions of Import cannot read
+const Version = 1
+
+// Number of records inserted by each statement when importing
+const batchSize = 100
+
+// Returned by Import when the database already has users, topics, posts or comments
+var ErrNotEmpty = errors.New("database is not empty")
+
+type Options struct {
+	// Whether to include the users' password hashes, so that they can log in with their passwords after an import
+	// Users imported without them cannot log in.
+	Passwords bool
+}
+
+type header struct {
+	Type       string    `json:"type"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Passwords  bool      `json:"passwords"`
+}
+
+type record struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+type trailer struct {
+	Type    string `json:"type"`
+	Records int    `json:"records"`
+}
+
+// Any line of an archive, as read by Import
+type line struct {
+	Type    string          `json:"type"`
+	Version int             `json:"version"`
+	Data    json.RawMessage `json:"data"`
+	Records int             `json:"records"`
+}
+
+// Write every user, topic, post, post_topic, topic pin, poll, comment and vote to w, reading them in a single transaction so that the archive is consistent
+func Export(ctx context.Context, db *gorm.DB, w io.Writer, options Options) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := encoder.Encode(header{Type: "header", Version: Version, ExportedAt: time.Now().UTC(), Passwords: options.Passwords}); err != nil {
+			return err
+		}
+		count := 0
+		for _, table := range tables {
+			n, err := table.export(tx, encoder, options)
+			if err != nil {
+				return fmt.Errorf("failed to export %s: %w", table.name, err)
+			}
+			count += n
+		}
+		return encoder.Encode(trailer{Type: "trailer", Records: count})
+	}, snapshot(db))
+}
+
+// Options of a read-only transaction that sees a snapshot of the database, where the database supports them
+func snapshot(db *gorm.DB) *sql.TxOptions {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+	return &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+}
+
+// Restore an archive written by Export into a database without users, topics, posts or comments, keeping the records' IDs
+// Nothing is imported unless the whole archive is valid.
+func Import(ctx context.Context, db *gorm.DB, r io.Reader) error {
+	decoder := json.NewDecoder(r)
+
+	var first line
+	if err := decoder.Decode(&first); err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	if first.Type != "header" {
+		return fmt.Errorf("expected a header, got %q", first.Type)
+	}
+	if first.Version != Version {
+		return fmt.Errorf("unsupported archive version %d, expected %d", first.Version, Version)
+	}
+
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The IDs of users, topics, posts, poll options and comments can only be kept if none are taken
+		for _, table := range tables {
+			if !table.sequence {
+				continue
+			}
+			var count int64
+			if err := tx.Table(table.name).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return fmt.Errorf("%w: %s has %d records", ErrNotEmpty, table.name, count)
+			}
+		}
+
+		// Records are inserted in batches of consecutive records of the same type
+		var current *table
+		var batch []json.RawMessage
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			if err := current.insert(tx, batch); err != nil {
+				return fmt.Errorf("failed to import %s: %w", current.name, err)
+			}
+			batch = batch[:0]
+			return nil
+		}
+
+		count := 0
+		for {
+			var next line
+			if err := decoder.Decode(&next); err != nil {
+				if errors.Is(err, io.EOF) {
+					return errors.New("archive is truncated: missing trailer")
+				}
+				return fmt.Errorf("failed to read record %d: %w", count+1, err)
+			}
+
+			if next.Type == "trailer" {
+				if next.Records != count {
+					return fmt.Errorf("archive has %d records, but its trailer counts %d", count, next.Records)
+				}
+				if decoder.More() {
+					return errors.New("unexpected data after trailer")
+				}
+				break
+			}
+
+			table, ok := tablesByType[next.Type]
+			if !ok {
+				return fmt.Errorf("unknown type %q of record %d", next.Type, count+1)
+			}
+			if table != current || len(batch) == batchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+				current = table
+			}
+			batch = append(batch, next.Data)
+			count++
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+
+		if tx.Dialector.Name() == "postgres" {
+			return resetSequences(tx)
+		}
+		return nil
+	})
+}
+
+// Advance the sequences generating IDs past the imported IDs, so that new records do not reuse them
+// SQLite's AUTOINCREMENT keeps track of the largest ID by itself.
+func resetSequences(tx *gorm.DB) error {
+	for _, table := range tables {
+		if !table.sequence {
+			continue
+		}
+		query := fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE((SELECT MAX(id) FROM %[1]s), 0) + 1, false)", table.name)
+		if err := tx.Exec(query).Error; err != nil {
+			return fmt.Errorf("failed to reset the ID sequence of %s: %w", table.name, err)
+		}
+	}
+	return nil
+}
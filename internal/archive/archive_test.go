@@ -0,0 +1,208 @@
+package archive_test
+
+import (
+	"bytes"
+	"context"
+	"cvwo-backend/internal/archive"
+	"cvwo-backend/internal/data"
+	"cvwo-backend/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Open a migrated in-memory SQLite database, named so that databases of a test are not shared
+func newTestDB(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:%s_%s?mode=memory&cache=shared&_foreign_keys=on", strings.ReplaceAll(t.Name(), "/", "_"), name)
+	db, err := data.Open(sqlite.Open(dsn))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get database handle: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// Fill a database with the seeded users, posts and topics, and records of every other type in the archive
+func seed(t *testing.T, db *gorm.DB) {
+	t.Helper()
+
+	if err := data.SeedData(db); err != nil {
+		t.Fatalf("failed to seed database: %v", err)
+	}
+	publishAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	position := 0
+	records := []any{
+		&models.Post{Title: "Scheduled", Content: "To be published <later>", AuthorID: 1, Status: models.PostStatusScheduled, PublishAt: &publishAt},
+		&models.Post{Title: "Pinned", Content: "Read this first", AuthorID: 2, PinPosition: &position, Locked: true},
+		&models.Comment{Content: "A reply", PostID: 1, AuthorID: 2},
+		&models.PostVote{PostID: 1, UserID: 2, Value: 1},
+		&models.PostVote{PostID: 1, UserID: 3, Value: -1},
+		&models.CommentVote{CommentID: 1, UserID: 1, Value: 1},
+	}
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("failed to create %T: %v", record, err)
+		}
+	}
+	if err := db.Exec("INSERT INTO post_topics (post_id, topic_id) VALUES (1, 1), (1, 2), (2, 1)").Error; err != nil {
+		t.Fatalf("failed to tag posts: %v", err)
+	}
+
+	pollPost := models.Post{Title: "Poll", Content: "Which one?", AuthorID: 1, Type: models.PostTypePoll}
+	if err := db.Create(&pollPost).Error; err != nil {
+		t.Fatalf("failed to create poll post: %v", err)
+	}
+	poll := models.Poll{PostID: pollPost.ID, MultipleChoice: true, ClosesAt: &publishAt, Options: []models.PollOption{{Position: 0, Text: "This"}, {Position: 1, Text: "That"}}}
+	if err := db.Create(&poll).Error; err != nil {
+		t.Fatalf("failed to create poll: %v", err)
+	}
+	records = []any{
+		&models.PollVote{PostID: pollPost.ID, UserID: 2, Choices: []models.PollChoice{{OptionID: poll.Options[1].ID}}},
+		&models.TopicPin{TopicID: 1, PostID: pollPost.ID, Position: 0},
+	}
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("failed to create %T: %v", record, err)
+		}
+	}
+}
+
+func export(t *testing.T, db *gorm.DB, options archive.Options) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := archive.Export(context.Background(), db, &buf, options); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	return buf.String()
+}
+
+// Lines of an archive after its header, which has the time of the export
+func records(exported string) string {
+	_, rest, _ := strings.Cut(exported, "\n")
+	return rest
+}
+
+// Exporting a database imported from an archive gives the same records
+func TestRoundTrip(t *testing.T) {
+	source := newTestDB(t, "source")
+	seed(t, source)
+	exported := export(t, source, archive.Options{Passwords: true})
+
+	target := newTestDB(t, "target")
+	if err := archive.Import(context.Background(), target, strings.NewReader(exported)); err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	if got := export(t, target, archive.Options{Passwords: true}); records(got) != records(exported) {
+		t.Errorf("expected the same records after a round trip\nexported:\n%s\nreimported:\n%s", exported, got)
+	}
+	if !strings.HasSuffix(exported, `{"type":"trailer","records":28}`+"\n") {
+		t.Errorf("expected a trailer counting every record, got %s", exported)
+	}
+
+	// IDs and relationships are kept
+	var post models.Post
+	if err := target.Preload("Author").Preload("Topics").First(&post, 1).Error; err != nil {
+		t.Fatalf("failed to get post: %v", err)
+	}
+	if post.Author.Username != "Viktor" || len(post.Topics) != 2 || post.Topics[1].Name != "Literature" {
+		t.Errorf("expected the post's author and topics, got %+v", post)
+	}
+	var poll models.Poll
+	if err := target.Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).Joins("JOIN posts ON posts.id = polls.post_id").Where("posts.title = ?", "Poll").First(&poll).Error; err != nil {
+		t.Fatalf("failed to get poll: %v", err)
+	}
+	if !poll.MultipleChoice || len(poll.Options) != 2 || poll.Options[1].Text != "That" {
+		t.Errorf("expected the poll with its options, got %+v", poll)
+	}
+	var choices, pins int64
+	target.Model(&models.PollChoice{}).Where("user_id = ? AND option_id = ?", 2, poll.Options[1].ID).Count(&choices)
+	target.Model(&models.TopicPin{}).Where("topic_id = ? AND post_id = ?", 1, poll.PostID).Count(&pins)
+	if choices != 1 || pins != 1 {
+		t.Errorf("expected the poll's vote and the topic pin, got %d choices and %d pins", choices, pins)
+	}
+	// New records are given IDs after the imported ones
+	created := models.Topic{Name: "History"}
+	if err := target.Create(&created).Error; err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+	if created.ID != 4 {
+		t.Errorf("expected the next ID, got %d", created.ID)
+	}
+}
+
+func TestExportWithoutPasswords(t *testing.T) {
+	source := newTestDB(t, "source")
+	seed(t, source)
+	exported := export(t, source, archive.Options{})
+	if strings.Contains(exported, `"password"`) || !strings.Contains(exported, `"passwords":false`) {
+		t.Fatalf("expected no password hashes, got %s", exported)
+	}
+
+	// Users are imported without passwords, so that they cannot log in
+	target := newTestDB(t, "target")
+	if err := archive.Import(context.Background(), target, strings.NewReader(exported)); err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	var user models.User
+	if err := target.First(&user, 1).Error; err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	if user.Username != "Viktor" || user.Password != "" {
+		t.Errorf("expected the user without a password, got %+v", user)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	source := newTestDB(t, "source")
+	seed(t, source)
+	exported := export(t, source, archive.Options{})
+	lines := strings.SplitAfter(strings.TrimSuffix(exported, "\n"), "\n")
+	header, trailer := lines[0], lines[len(lines)-1]
+
+	tests := []struct {
+		name    string
+		archive string
+	}{
+		{"empty", ""},
+		{"no header", strings.Join(lines[1:], "")},
+		{"newer version", strings.Replace(header, `"version":1`, `"version":2`, 1) + strings.Join(lines[1:], "")},
+		{"truncated", strings.Join(lines[:len(lines)-1], "")},
+		{"wrong count", strings.Join(lines[:len(lines)-2], "") + trailer},
+		{"unknown type", header + `{"type":"message","data":{}}` + "\n" + trailer},
+		{"data after trailer", exported + trailer},
+		{"missing topic", header + `{"type":"post_topic","data":{"post_id":1,"topic_id":1}}` + "\n" + `{"type":"trailer","records":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := newTestDB(t, "target")
+			if err := archive.Import(context.Background(), target, strings.NewReader(tt.archive)); err == nil {
+				t.Fatalf("expected the archive to be rejected")
+			}
+			// Nothing is imported from invalid archives
+			var count int64
+			if err := target.Model(&models.User{}).Count(&count).Error; err != nil {
+				t.Fatalf("failed to count users: %v", err)
+			}
+			if count != 0 {
+				t.Errorf("expected no users, got %d", count)
+			}
+		})
+	}
+
+	// Archives are only imported into empty databases
+	if err := archive.Import(context.Background(), source, strings.NewReader(exported)); !errors.Is(err, archive.ErrNotEmpty) {
+		t.Errorf("expected ErrNotEmpty, got %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package archive
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Records of the archive, which mirror the columns of their tables rather than the API's models, so that the archive format only changes with the schema
+
+type user struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	// Hashed password, only included if requested
+	Password    string `json:"password,omitempty"`
+	PublicVotes bool   `json:"public_votes"`
+	Moderator   bool   `json:"moderator"`
+}
+
+type topic struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+type post struct {
+	ID          uint       `json:"id"`
+	Type        string     `json:"type"`
+	Title       string     `json:"title"`
+	Content     string     `json:"content"`
+	ContentHTML string     `json:"content_html"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	AuthorID    *uint      `json:"author_id"` // Null if the author was deleted
+	Version     uint       `json:"version"`
+	Status      string     `json:"status"`
+	PublishAt   *time.Time `json:"publish_at"`
+	PinPosition *int       `json:"pin_position"`
+	Locked      bool       `json:"locked"`
+}
+
+type postTopic struct {
+	PostID  uint `json:"post_id"`
+	TopicID uint `json:"topic_id"`
+}
+
+type topicPin struct {
+	TopicID   uint      `json:"topic_id"`
+	PostID    uint      `json:"post_id"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type poll struct {
+	PostID         uint       `json:"post_id"`
+	MultipleChoice bool       `json:"multiple_choice"`
+	ClosesAt       *time.Time `json:"closes_at"`
+}
+
+type pollOption struct {
+	ID       uint   `json:"id"`
+	PostID   uint   `json:"post_id"`
+	Position int    `json:"position"`
+	Text     string `json:"text"`
+}
+
+type pollVote struct {
+	PostID    uint      `json:"post_id"`
+	UserID    uint      `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type pollChoice struct {
+	PostID   uint `json:"post_id"`
+	UserID   uint `json:"user_id"`
+	OptionID uint `json:"option_id"`
+}
+
+type comment struct {
+	ID          uint      `json:"id"`
+	Content     string    `json:"content"`
+	ContentHTML string    `json:"content_html"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	PostID      *uint     `json:"post_id"`   // Null if the post was deleted
+	AuthorID    *uint     `json:"author_id"` // Null if the author was deleted
+	Version     uint      `json:"version"`
+}
+
+type postVote struct {
+	PostID    uint      `json:"post_id"`
+	UserID    uint      `json:"user_id"`
+	Value     int       `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type commentVote struct {
+	CommentID uint      `json:"comment_id"`
+	UserID    uint      `json:"user_id"`
+	Value     int       `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// A table in the archive, with its records of one type
+type table struct {
+	name       string
+	recordType string
+	// Whether the table's IDs are generated by a sequence
+	sequence bool
+	// Write the table's records, returning how many were written
+	export func(tx *gorm.DB, encoder *json.Encoder, options Options) (int, error)
+	// Insert records of the table's type
+	insert func(tx *gorm.DB, records []json.RawMessage) error
+}
+
+// Tables in the order they are exported and imported, each after the tables it refers to
+var tables = []*table{
+	newTable[user]("users", "user", "id", true),
+	newTable[topic]("topics", "topic", "id", true),
+	newTable[post]("posts", "post", "id", true),
+	newTable[postTopic]("post_topics", "post_topic", "post_id, topic_id", false),
+	newTable[topicPin]("topic_pins", "topic_pin", "topic_id, post_id", false),
+	newTable[poll]("polls", "poll", "post_id", false),
+	newTable[pollOption]("poll_options", "poll_option", "id", true),
+	newTable[pollVote]("poll_votes", "poll_vote", "post_id, user_id", false),
+	newTable[pollChoice]("poll_choices", "poll_choice", "post_id, user_id, option_id", false),
+	newTable[comment]("comments", "comment", "id", true),
+	newTable[postVote]("post_votes", "post_vote", "post_id, user_id", false),
+	newTable[commentVote]("comment_votes", "comment_vote", "comment_id, user_id", false),
+}
+
+var tablesByType = func() map[string]*table {
+	byType := make(map[string]*table, len(tables))
+	for _, table := range tables {
+		byType[table.recordType] = table
+	}
+	return byType
+}()
+
+// Implemented by records holding secrets that are left out of archives unless requested
+type redactable interface {
+	redact()
+}
+
+func (user *user) redact() {
+	user.Password = ""
+}
+
+// Table of records of type T, exported in the given order of its columns
+func newTable[T any](name, recordType, order string, sequence bool) *table {
+	return &table{
+		name:       name,
+		recordType: recordType,
+		sequence:   sequence,
+		export: func(tx *gorm.DB, encoder *json.Encoder, options Options) (int, error) {
+			// Read the records one by one so that large tables are streamed
+			rows, err := tx.Table(name).Order(order).Rows()
+			if err != nil {
+				return 0, err
+			}
+			defer rows.Close()
+
+			count := 0
+			for rows.Next() {
+				var data T
+				if err := tx.ScanRows(rows, &data); err != nil {
+					return count, err
+				}
+				if secret, ok := any(&data).(redactable); ok && !options.Passwords {
+					secret.redact()
+				}
+				if err := encoder.Encode(record{Type: recordType, Data: data}); err != nil {
+					return count, err
+				}
+				count++
+			}
+			return count, rows.Err()
+		},
+		insert: func(tx *gorm.DB, records []json.RawMessage) error {
+			batch := make([]T, len(records))
+			for i, data := range records {
+				if err := json.Unmarshal(data, &batch[i]); err != nil {
+					return err
+				}
+			}
+			return tx.Table(name).Create(&batch).Error
+		},
+	}
+}
@@ -0,0 +1,19 @@
+// Package cache stores values that are expensive to compute, such as listings, for a limited time.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Store of byte values with an expiry, addressed by keys such as "posts:3:list:new:10:0"
+// Caches may drop values before they expire, so callers must be able to recompute any value.
+type Cache interface {
+	// Get the value stored under key. ok is false if there is none, or it has expired or been evicted.
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Store a value under key for ttl, replacing any existing value
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Increment the counter stored under key, which starts at 0, and return its new value
+	// Counters do not expire. Their value is returned by Get as a decimal number.
+	Incr(ctx context.Context, key string) (int64, error)
+}
@@ -0,0 +1,217 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// In-process server speaking the Redis protocol, as a stand-in for a real Redis server
+// Supports the commands used by the Redis cache.
+type fakeRedis struct {
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+func newTestRedis(t *testing.T) *Redis {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeRedis{values: make(map[string]string), expires: make(map[string]time.Time)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	cache := NewRedis(listener.Addr().String())
+	t.Cleanup(func() { cache.Close() })
+	return cache
+}
+
+func (server *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, server.execute(args)); err != nil {
+			return
+		}
+	}
+}
+
+// Read a command sent as an array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	var count int
+	if _, err := fmt.Fscanf(reader, "*%d\r\n", &count); err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		var size int
+		if _, err := fmt.Fscanf(reader, "$%d\r\n", &size); err != nil {
+			return nil, err
+		}
+		arg := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, arg); err != nil {
+			return nil, err
+		}
+		args[i] = string(arg[:size])
+	}
+	return args, nil
+}
+
+func (server *fakeRedis) execute(args []string) string {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	key := args[1]
+	if expiresAt, exists := server.expires[key]; exists && !time.Now().Before(expiresAt) {
+		delete(server.values, key)
+		delete(server.expires, key)
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		value, exists := server.values[key]
+		if !exists {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case "SET":
+		server.values[key] = args[2]
+		delete(server.expires, key)
+		if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+			ms, _ := strconv.Atoi(args[4])
+			server.expires[key] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		return "+OK\r\n"
+	case "INCR":
+		counter, err := strconv.ParseInt(server.values[key], 10, 64)
+		if err != nil && server.values[key] != "" {
+			return "-ERR value is not an integer or out of range\r\n"
+		}
+		counter++
+		server.values[key] = strconv.FormatInt(counter, 10)
+		return fmt.Sprintf(":%d\r\n", counter)
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+// Every implementation must behave the same way
+func TestCaches(t *testing.T) {
+	caches := []struct {
+		name  string
+		cache func(t *testing.T) Cache
+	}{
+		{"lru", func(t *testing.T) Cache { return NewLRU(10) }},
+		{"redis", func(t *testing.T) Cache { return newTestRedis(t) }},
+	}
+
+	for _, tt := range caches {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := tt.cache(t)
+			ctx := context.Background()
+
+			get := func(key string) (string, bool) {
+				t.Helper()
+				value, ok, err := cache.Get(ctx, key)
+				if err != nil {
+					t.Fatalf("failed to get %s: %v", key, err)
+				}
+				return string(value), ok
+			}
+
+			if _, ok := get("missing"); ok {
+				t.Errorf("expected a missing key to have no value")
+			}
+
+			if err := cache.Set(ctx, "key", []byte("first"), time.Minute); err != nil {
+				t.Fatalf("failed to set: %v", err)
+			}
+			if err := cache.Set(ctx, "key", []byte("line\r\nbreak"), time.Minute); err != nil {
+				t.Fatalf("failed to set: %v", err)
+			}
+			if value, ok := get("key"); !ok || value != "line\r\nbreak" {
+				t.Errorf("expected the latest value, got %q, %v", value, ok)
+			}
+
+			for want := int64(1); want <= 3; want++ {
+				counter, err := cache.Incr(ctx, "counter")
+				if err != nil || counter != want {
+					t.Fatalf("expected counter %d, got %d, %v", want, counter, err)
+				}
+			}
+			if value, ok := get("counter"); !ok || value != "3" {
+				t.Errorf("expected the counter's value, got %q, %v", value, ok)
+			}
+
+			if err := cache.Set(ctx, "short", []byte("value"), 20*time.Millisecond); err != nil {
+				t.Fatalf("failed to set: %v", err)
+			}
+			time.Sleep(40 * time.Millisecond)
+			if _, ok := get("short"); ok {
+				t.Errorf("expected the value to expire")
+			}
+		})
+	}
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewLRU(2)
+	ctx := context.Background()
+
+	cache.Set(ctx, "a", []byte("a"), time.Minute)
+	cache.Set(ctx, "b", []byte("b"), time.Minute)
+	// Using a makes b the least recently used
+	cache.Get(ctx, "a")
+	cache.Set(ctx, "c", []byte("c"), time.Minute)
+	// Counters do not take up room
+	cache.Incr(ctx, "counter")
+
+	for key, wantOK := range map[string]bool{"a": true, "b": false, "c": true, "counter": true} {
+		if _, ok, _ := cache.Get(ctx, key); ok != wantOK {
+			t.Errorf("expected %s to be cached: %v, got %v", key, wantOK, ok)
+		}
+	}
+	if cache.Len() != 2 {
+		t.Errorf("expected 2 values, got %d", cache.Len())
+	}
+}
+
+// Errors replied by the server do not break the connection
+func TestRedisErrorReplies(t *testing.T) {
+	cache := newTestRedis(t)
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "text", []byte("not a number"), time.Minute); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	if _, err := cache.Incr(ctx, "text"); err == nil || !strings.Contains(err.Error(), "not an integer") {
+		t.Errorf("expected the error replied by the server, got %v", err)
+	}
+	if value, ok, err := cache.Get(ctx, "text"); err != nil || !ok || string(value) != "not a number" {
+		t.Errorf("expected the value after an error, got %q, %v, %v", value, ok, err)
+	}
+}
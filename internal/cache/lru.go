@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// In-memory cache holding up to a fixed number of values, evicting the least recently used value when full
+// Each instance of the server has its own, so values are not shared between instances.
+type LRU struct {
+	mu       sync.Mutex
+	capacity int
+	// Most recently used first
+	order   *list.List
+	entries map[string]*list.Element
+	// Counters are kept apart from values, so that they are never evicted
+	counters map[string]int64
+	// Current time, replaceable in tests
+	now func() time.Time
+}
+
+func NewLRU(capacity int) *LRU {
+	return &LRU{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		counters: make(map[string]int64),
+		now:      time.Now,
+	}
+}
+
+func (cache *LRU) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if counter, exists := cache.counters[key]; exists {
+		return strconv.AppendInt(nil, counter, 10), true, nil
+	}
+	element, exists := cache.entries[key]
+	if !exists {
+		return nil, false, nil
+	}
+	entry := element.Value.(*lruEntry)
+	if !cache.now().Before(entry.expiresAt) {
+		cache.remove(element)
+		return nil, false, nil
+	}
+	cache.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (cache *LRU) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	expiresAt := cache.now().Add(ttl)
+	if element, exists := cache.entries[key]; exists {
+		entry := element.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		cache.order.MoveToFront(element)
+		return nil
+	}
+
+	cache.entries[key] = cache.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	for cache.order.Len() > cache.capacity {
+		cache.remove(cache.order.Back())
+	}
+	return nil
+}
+
+func (cache *LRU) Incr(ctx context.Context, key string) (int64, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.counters[key]++
+	return cache.counters[key], nil
+}
+
+// Number of values currently held, including expired values that have not been evicted yet
+func (cache *LRU) Len() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	return cache.order.Len()
+}
+
+// Must be called with the lock held
+func (cache *LRU) remove(element *list.Element) {
+	cache.order.Remove(element)
+	delete(cache.entries, element.Value.(*lruEntry).key)
+}
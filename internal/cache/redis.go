@@ -0,0 +1,194 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Maximum number of idle connections kept open
+const maxIdleConns = 8
+
+// Deadline of commands whose context has none
+const commandTimeout = time.Second
+
+// Cache backed by a Redis server, or any server speaking its protocol (RESP), shared by all instances of the server
+// Only the few commands the cache needs are implemented.
+type Redis struct {
+	addr   string
+	dialer net.Dialer
+
+	mu   sync.Mutex
+	idle []*redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Error reply from the server, such as "ERR unknown command"
+type redisError string
+
+func (err redisError) Error() string {
+	return "redis: " + string(err)
+}
+
+// Connect to the server at addr (host:port) when first needed
+func NewRedis(addr string) *Redis {
+	return &Redis{addr: addr}
+}
+
+func (cache *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := cache.do(ctx, "GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis: unexpected reply to GET: %v", reply)
+	}
+	return value, true, nil
+}
+
+func (cache *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	// PX takes a whole number of milliseconds, of at least 1
+	_, err := cache.do(ctx, "SET", key, string(value), "PX", strconv.FormatInt(max(ttl.Milliseconds(), 1), 10))
+	return err
+}
+
+func (cache *Redis) Incr(ctx context.Context, key string) (int64, error) {
+	reply, err := cache.do(ctx, "INCR", key)
+	if err != nil {
+		return 0, err
+	}
+	counter, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected reply to INCR: %v", reply)
+	}
+	return counter, nil
+}
+
+// Close idle connections
+func (cache *Redis) Close() error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	var err error
+	for _, conn := range cache.idle {
+		err = errors.Join(err, conn.conn.Close())
+	}
+	cache.idle = nil
+	return err
+}
+
+// Send a command and read its reply: a string, []byte, int64, or nil for a missing value
+func (cache *Redis) do(ctx context.Context, args ...string) (any, error) {
+	conn, err := cache.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(commandTimeout)
+	}
+	conn.conn.SetDeadline(deadline)
+
+	reply, err := conn.roundTrip(args)
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		// The connection may be left in the middle of a reply, so it cannot be reused
+		conn.conn.Close()
+		return nil, err
+	}
+	cache.release(conn)
+	return reply, err
+}
+
+// Take an idle connection, or open a new one
+func (cache *Redis) conn(ctx context.Context) (*redisConn, error) {
+	cache.mu.Lock()
+	if n := len(cache.idle); n > 0 {
+		conn := cache.idle[n-1]
+		cache.idle = cache.idle[:n-1]
+		cache.mu.Unlock()
+		return conn, nil
+	}
+	cache.mu.Unlock()
+
+	conn, err := cache.dialer.DialContext(ctx, "tcp", cache.addr)
+	if err != nil {
+		return nil, err
+	}
+	return &redisConn{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// Return a connection to the idle connections, or close it if there are enough
+func (cache *Redis) release(conn *redisConn) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if len(cache.idle) >= maxIdleConns {
+		conn.conn.Close()
+		return
+	}
+	cache.idle = append(cache.idle, conn)
+}
+
+func (conn *redisConn) roundTrip(args []string) (any, error) {
+	// Commands are sent as arrays of bulk strings
+	command := fmt.Appendf(nil, "*%d\r\n", len(args))
+	for _, arg := range args {
+		command = fmt.Appendf(command, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := conn.conn.Write(command); err != nil {
+		return nil, err
+	}
+	return conn.readReply()
+}
+
+func (conn *redisConn) readReply() (any, error) {
+	line, err := conn.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk string size %q", body)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		// The value is followed by \r\n
+		value := make([]byte, size+2)
+		if _, err := io.ReadFull(conn.reader, value); err != nil {
+			return nil, err
+		}
+		return value[:size], nil
+	default:
+		return nil, fmt.Errorf("redis: unsupported reply type %q", kind)
+	}
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"bufio"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/middleware"
+	"cvwo-backend/internal/services"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ArchiveController struct {
+	service services.ArchiveService
+}
+
+func NewArchiveController(service services.ArchiveService) *ArchiveController {
+	return &ArchiveController{service}
+}
+
+// GET /admin/export
+// Download the forum's data as a JSON Lines archive as a moderator, always without the users' password hashes
+func (controller *ArchiveController) Export(ctx *gin.Context) {
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	// The archive is streamed, so the headers are sent with its first buffered chunk
+	ctx.Header("Content-Type", "application/x-ndjson")
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="forum-%s.jsonl"`, time.Now().UTC().Format(time.DateOnly)))
+	writer := bufio.NewWriterSize(ctx.Writer, 64*1024)
+	err = controller.service.Export(ctx.Request.Context(), writer, userID)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		// Errors before anything was sent, such as the user not being a moderator, are sent as usual
+		if !ctx.Writer.Written() {
+			ctx.Writer.Header().Del("Content-Disposition")
+			errs.HTTPErrorResponse(ctx, err)
+			return
+		}
+		// Otherwise the archive is left without its trailer, so that it is not mistaken for a complete one
+		log.Printf("Failed to export archive: %v", err)
+		ctx.Abort()
+	}
+}
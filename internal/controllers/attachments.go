@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/middleware"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/services"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Allowance for the multipart boundaries and headers around the uploaded file
+const multipartOverhead = 64 << 10
+
+type AttachmentController struct {
+	service services.AttachmentService
+}
+
+func NewAttachmentController(service services.AttachmentService) *AttachmentController {
+	return &AttachmentController{service}
+}
+
+// POST /posts/:post_id/attachments
+// Upload a file in the "file" field of a multipart form and attach it to a post
+func (controller *AttachmentController) AttachToPost(ctx *gin.Context) {
+	// Validate post_id param
+	postID, err := strconv.Atoi(ctx.Param("post_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid post ID"))
+		return
+	}
+
+	controller.upload(ctx, func(filename string, content io.Reader, userID uint) (*models.Attachment, error) {
+		return controller.service.AttachToPost(ctx.Request.Context(), uint(postID), filename, content, userID)
+	})
+}
+
+// POST /comments/:comment_id/attachments
+// Upload a file in the "file" field of a multipart form and attach it to a comment
+func (controller *AttachmentController) AttachToComment(ctx *gin.Context) {
+	// Validate comment_id param
+	commentID, err := strconv.Atoi(ctx.Param("comment_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid comment ID"))
+		return
+	}
+
+	controller.upload(ctx, func(filename string, content io.Reader, userID uint) (*models.Attachment, error) {
+		return controller.service.AttachToComment(ctx.Request.Context(), uint(commentID), filename, content, userID)
+	})
+}
+
+// Stream the uploaded file to attach, which is given the file name, content and the authenticated user's ID
+func (controller *AttachmentController) upload(ctx *gin.Context, attach func(string, io.Reader, uint) (*models.Attachment, error)) {
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	// Stop reading bodies that are too large to hold an allowed file, rather than reading them to the end
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, controller.service.Limits().MaxSize+multipartOverhead)
+
+	file, err := fileField(ctx.Request)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, uploadError(err))
+		return
+	}
+
+	attachment, err := attach(file.FileName(), file, userID)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, uploadError(err))
+		return
+	}
+
+	respondJSON(ctx, http.StatusCreated, attachment)
+}
+
+// Find the "file" field of a multipart form without buffering the other fields
+func fileField(request *http.Request) (*multipart.Part, error) {
+	reader, err := request.MultipartReader()
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrInvalid, "Request body must be a multipart form", err)
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, errs.NewValidation("Invalid request body", []errs.FieldError{{Field: "file", Message: "file is required"}})
+		}
+		if err != nil {
+			return nil, errs.Wrap(errs.ErrInvalid, "Malformed multipart form", err)
+		}
+		if part.FormName() == "file" && part.FileName() != "" {
+			return part, nil
+		}
+	}
+}
+
+// Report bodies cut off by the size limit as too large, whichever layer was reading the body when it was hit
+func uploadError(err error) error {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return errs.Wrap(errs.ErrTooLarge, "Request body is too large", err)
+	}
+	return err
+}
+
+// GET /attachments/:attachment_id
+// Download an attachment. Images are shown inline, while other files are downloaded.
+func (controller *AttachmentController) Get(ctx *gin.Context) {
+	controller.serve(ctx, false)
+}
+
+// GET /attachments/:attachment_id/thumbnail
+// Get the JPEG thumbnail of an image attachment
+func (controller *AttachmentController) GetThumbnail(ctx *gin.Context) {
+	controller.serve(ctx, true)
+}
+
+func (controller *AttachmentController) serve(ctx *gin.Context, thumbnail bool) {
+	// Validate attachment_id param
+	attachmentID, err := strconv.Atoi(ctx.Param("attachment_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid attachment ID"))
+		return
+	}
+
+	attachment, file, public, err := controller.service.Open(ctx.Request.Context(), uint(attachmentID), thumbnail, middleware.GetUserIDOrZero(ctx))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+	defer file.Close()
+
+	contentType, contentLength := attachment.ContentType, attachment.Size
+	if thumbnail {
+		contentType, contentLength = "image/jpeg", -1
+	}
+
+	// Only images are shown inline, so that other files cannot be rendered as pages of this site
+	disposition := "attachment"
+	if strings.HasPrefix(contentType, "image/") {
+		disposition = "inline"
+	}
+	// Attachments never change, since uploading a new file creates a new attachment
+	// Those of unpublished posts are only cached by the author's browser.
+	cacheControl := "public, max-age=31536000, immutable"
+	if !public {
+		cacheControl = "private, no-cache"
+	}
+	ctx.DataFromReader(http.StatusOK, contentLength, contentType, file, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}),
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          cacheControl,
+	})
+}
+
+// DELETE /attachments/:attachment_id
+// Delete an attachment uploaded by the authenticated user
+func (controller *AttachmentController) Delete(ctx *gin.Context) {
+	// Validate attachment_id param
+	attachmentID, err := strconv.Atoi(ctx.Param("attachment_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid attachment ID"))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	if err := controller.service.Delete(ctx.Request.Context(), uint(attachmentID), userID); err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
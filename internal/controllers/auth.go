@@ -22,11 +22,11 @@ func (controller *AuthController) Login(ctx *gin.Context) {
 	var authInput models.AuthInput
 
 	if err := ctx.ShouldBindJSON(&authInput); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errs.HTTPErrorResponse(ctx, errs.FromBindingError(err))
 		return
 	}
 
-	user, token, err := controller.service.Authenticate(&authInput)
+	user, token, err := controller.service.Authenticate(ctx.Request.Context(), &authInput, ctx.ClientIP())
 	if err != nil {
 		errs.HTTPErrorResponse(ctx, err)
 		return
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/middleware"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BlockController struct {
+	service services.BlockService
+}
+
+func NewBlockController(service services.BlockService) *BlockController {
+	return &BlockController{service}
+}
+
+// GET /users/me/blocks or /users/me/blocks?type=mute&page=1&limit=10
+// Get the users the authenticated user has blocked or muted, most recent first
+func (controller *BlockController) GetAll(ctx *gin.Context) {
+	// Get the "page" query param and validate it
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1 // If invalid, just set to default
+	}
+
+	// Limit refers to number of records per page
+	// Get the "limit" query param and validate it
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		limit = 10 // If invalid, just set to default
+	}
+
+	// Pagination offset: The DB will fetch {limit} number of records starting from the record at this index.
+	offset := (page - 1) * limit
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	blocks, totalCount, err := controller.service.GetBlocks(ctx.Request.Context(), ctx.Query("type"), limit, offset, userID)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	// Send list of blocks together with total count
+	respondJSON(ctx, http.StatusOK, gin.H{"data": blocks, "total_count": totalCount})
+}
+
+// PUT /users/me/blocks/:user_id
+// Block or mute a user as the authenticated user
+func (controller *BlockController) Block(ctx *gin.Context) {
+	// Validate user_id param
+	blockedUserID, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid user ID"))
+		return
+	}
+
+	// Validate request body
+	var requestBody models.BlockUpdate
+	if err := ctx.ShouldBindJSON(&requestBody); err != nil {
+		errs.HTTPErrorResponse(ctx, errs.FromBindingError(err))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	if err := controller.service.Block(ctx.Request.Context(), uint(blockedUserID), requestBody.Type, userID); err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// DELETE /users/me/blocks/:user_id
+// Remove the authenticated user's block or mute of a user
+func (controller *BlockController) Unblock(ctx *gin.Context) {
+	// Validate user_id param
+	blockedUserID, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid user ID"))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	if err := controller.service.Unblock(ctx.Request.Context(), uint(blockedUserID), userID); err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
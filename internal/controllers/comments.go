@@ -14,10 +14,11 @@ import (
 type CommentController struct {
 	commentService services.CommentService
 	votingService  services.VotingService
+	savingService  services.SavingService
 }
 
-func NewCommentController(commentService services.CommentService, votingService services.VotingService) *CommentController {
-	return &CommentController{commentService, votingService}
+func NewCommentController(commentService services.CommentService, votingService services.VotingService, savingService services.SavingService) *CommentController {
+	return &CommentController{commentService, votingService, savingService}
 }
 
 // GET /posts/:id/comments
@@ -25,7 +26,7 @@ func (controller *CommentController) GetByPostID(ctx *gin.Context) {
 	// Validate postId param
 	postId, err := strconv.Atoi(ctx.Param("post_id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid post ID"))
 		return
 	}
 
@@ -51,14 +52,14 @@ func (controller *CommentController) GetByPostID(ctx *gin.Context) {
 	userID := middleware.GetUserIDOrZero(ctx)
 
 	// Get the list of comments
-	comments, totalCount, err := controller.commentService.GetByPostID(uint(postId), limit, offset, sortBy, userID)
+	comments, totalCount, err := controller.commentService.GetByPostID(ctx.Request.Context(), uint(postId), limit, offset, sortBy, userID)
 	if err != nil {
 		errs.HTTPErrorResponse(ctx, err)
 		return
 	}
 
-	// Send list of comments together with total count
-	ctx.IndentedJSON(http.StatusOK, gin.H{"data": comments, "total_count": totalCount})
+	// Send list of comments together with total count, unless the client's copy is current
+	respondCacheable(ctx, 0, gin.H{"data": comments, "total_count": totalCount})
 }
 
 // POST /comments
@@ -66,7 +67,7 @@ func (controller *CommentController) Create(ctx *gin.Context) {
 	// Validate request body
 	var requestBody models.NewComment
 	if err := ctx.ShouldBindJSON(&requestBody); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errs.HTTPErrorResponse(ctx, errs.FromBindingError(err))
 		return
 	}
 
@@ -85,13 +86,13 @@ func (controller *CommentController) Create(ctx *gin.Context) {
 	}
 
 	// Create new comment
-	newComment, err := controller.commentService.Create(&comment)
+	newComment, err := controller.commentService.Create(ctx.Request.Context(), &comment)
 	if err != nil {
 		errs.HTTPErrorResponse(ctx, err)
 		return
 	}
 
-	ctx.IndentedJSON(http.StatusCreated, newComment)
+	respondVersioned(ctx, http.StatusCreated, newComment.Version, newComment)
 }
 
 // PATCH /comments/:id
@@ -99,7 +100,7 @@ func (controller *CommentController) Update(ctx *gin.Context) {
 	// Validate commentID
 	id, err := strconv.Atoi(ctx.Param("comment_id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid comment ID"))
 		return
 	}
 
@@ -113,18 +114,25 @@ func (controller *CommentController) Update(ctx *gin.Context) {
 	// Validate request body
 	var requestBody models.CommentUpdate
 	if err := ctx.ShouldBindJSON(&requestBody); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errs.HTTPErrorResponse(ctx, errs.FromBindingError(err))
+		return
+	}
+
+	// Only apply the edit to the version it is based on, if any
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
 		return
 	}
 
 	// Update the comment
-	updatedComment, err := controller.commentService.Update(uint(id), requestBody.Content, userID)
+	updatedComment, err := controller.commentService.Update(ctx.Request.Context(), uint(id), version, requestBody.Content, userID)
 	if err != nil {
 		errs.HTTPErrorResponse(ctx, err)
 		return
 	}
 
-	ctx.IndentedJSON(http.StatusOK, updatedComment)
+	respondVersioned(ctx, http.StatusOK, updatedComment.Version, updatedComment)
 }
 
 // DELETE /comments/:id
@@ -132,7 +140,7 @@ func (controller *CommentController) Delete(ctx *gin.Context) {
 	// Validate commentID
 	id, err := strconv.Atoi(ctx.Param("comment_id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid comment ID"))
 		return
 	}
 
@@ -144,7 +152,7 @@ func (controller *CommentController) Delete(ctx *gin.Context) {
 	}
 
 	// Delete the comment
-	if err := controller.commentService.Delete(uint(id), userID); err != nil {
+	if err := controller.commentService.Delete(ctx.Request.Context(), uint(id), userID); err != nil {
 		errs.HTTPErrorResponse(ctx, err)
 		return
 	}
@@ -158,20 +166,21 @@ func (controller *CommentController) Vote(ctx *gin.Context) {
 	// Validate comment_id param
 	commentID, err := strconv.Atoi(ctx.Param("comment_id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid comment ID"))
 		return
 	}
 
 	// Validate user_id param
 	userID, err := strconv.Atoi(ctx.Param("user_id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid user ID"))
+		return
 	}
 
 	// Validate request body
 	var requestBody models.VoteUpdate
 	if err := ctx.ShouldBindJSON(&requestBody); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errs.HTTPErrorResponse(ctx, errs.FromBindingError(err))
 		return
 	}
 
@@ -182,7 +191,57 @@ func (controller *CommentController) Vote(ctx *gin.Context) {
 		return
 	}
 
-	if err := controller.votingService.VoteComment(uint(commentID), uint(userID), requestBody.Value, authenticatedUserID); err != nil {
+	if err := controller.votingService.VoteComment(ctx.Request.Context(), uint(commentID), uint(userID), requestBody.Value, authenticatedUserID); err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// PUT /comments/:comment_id/save
+// Save a comment for the authenticated user
+func (controller *CommentController) Save(ctx *gin.Context) {
+	// Validate comment_id param
+	commentID, err := strconv.Atoi(ctx.Param("comment_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid comment ID"))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	if err := controller.savingService.SaveComment(ctx.Request.Context(), uint(commentID), userID); err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// DELETE /comments/:comment_id/save
+// Remove a comment from the authenticated user's saved items
+func (controller *CommentController) Unsave(ctx *gin.Context) {
+	// Validate comment_id param
+	commentID, err := strconv.Atoi(ctx.Param("comment_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid comment ID"))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	if err := controller.savingService.UnsaveComment(ctx.Request.Context(), uint(commentID), userID); err != nil {
 		errs.HTTPErrorResponse(ctx, err)
 		return
 	}
@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/middleware"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ConversationController struct {
+	service services.ConversationService
+}
+
+func NewConversationController(service services.ConversationService) *ConversationController {
+	return &ConversationController{service}
+}
+
+// GET /conversations or /conversations?cursor=...&limit=10
+// Get the authenticated user's conversations, most recently active first, with unread counts
+// The response includes a cursor to request the next page with, which is empty on the last page
+func (controller *ConversationController) GetAll(ctx *gin.Context) {
+	// Get the "limit" query param and validate it
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		limit = 10 // If invalid, just set to default
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	conversations, nextCursor, err := controller.service.GetConversations(ctx.Request.Context(), ctx.Query("cursor"), limit, userID)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	// Send the page of conversations together with the cursor of the next page
+	respondJSON(ctx, http.StatusOK, gin.H{"data": conversations, "next_cursor": nextCursor})
+}
+
+// POST /conversations
+// Start a conversation with other users, or continue the existing conversation when messaging a single user
+func (controller *ConversationController) Create(ctx *gin.Context) {
+	// Validate request body
+	var requestBody models.NewConversation
+	if err := ctx.ShouldBindJSON(&requestBody); err != nil {
+		errs.HTTPErrorResponse(ctx, errs.FromBindingError(err))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	conversation, err := controller.service.Create(ctx.Request.Context(), &requestBody, userID)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	respondJSON(ctx, http.StatusCreated, conversation)
+}
+
+// GET /conversations/:conversation_id/messages or /conversations/:conversation_id/messages?cursor=...&limit=20
+// Get the messages in a conversation, newest first, with read receipts
+// The response includes a cursor to request older messages with, which is empty on the last page
+func (controller *ConversationController) GetMessages(ctx *gin.Context) {
+	// Validate conversation_id param
+	conversationID, err := strconv.Atoi(ctx.Param("conversation_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid conversation ID"))
+		return
+	}
+
+	// Get the "limit" query param and validate it
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 {
+		limit = 20 // If invalid, just set to default
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	messages, nextCursor, err := controller.service.GetMessages(ctx.Request.Context(), uint(conversationID), ctx.Query("cursor"), limit, userID)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	// Send the page of messages together with the cursor of the next page
+	respondJSON(ctx, http.StatusOK, gin.H{"data": messages, "next_cursor": nextCursor})
+}
+
+// POST /conversations/:conversation_id/messages
+// Send a message in a conversation as the authenticated user
+func (controller *ConversationController) Send(ctx *gin.Context) {
+	// Validate conversation_id param
+	conversationID, err := strconv.Atoi(ctx.Param("conversation_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid conversation ID"))
+		return
+	}
+
+	// Validate request body
+	var requestBody models.NewMessage
+	if err := ctx.ShouldBindJSON(&requestBody); err != nil {
+		errs.HTTPErrorResponse(ctx, errs.FromBindingError(err))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	message, err := controller.service.Send(ctx.Request.Context(), uint(conversationID), &requestBody, userID)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	respondJSON(ctx, http.StatusCreated, message)
+}
+
+// PUT /conversations/:conversation_id/read
+// Mark all messages in a conversation as read by the authenticated user
+func (controller *ConversationController) MarkRead(ctx *gin.Context) {
+	// Validate conversation_id param
+	conversationID, err := strconv.Atoi(ctx.Param("conversation_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid conversation ID"))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	if err := controller.service.MarkRead(ctx.Request.Context(), uint(conversationID), userID); err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
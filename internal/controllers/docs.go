@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"cvwo-backend/internal/openapi"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DocsController struct {
+	spec *openapi.Document
+}
+
+func NewDocsController() *DocsController {
+	return &DocsController{openapi.Build()}
+}
+
+// GET /openapi.json
+func (controller *DocsController) GetSpec(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, controller.spec)
+}
+
+// GET /docs
+// Swagger UI page for browsing the API documentation
+func (controller *DocsController) GetUI(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", []byte(openapi.SwaggerUIHTML))
+}
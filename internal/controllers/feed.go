@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/middleware"
+	"cvwo-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FeedController struct {
+	service services.FeedService
+}
+
+func NewFeedController(service services.FeedService) *FeedController {
+	return &FeedController{service}
+}
+
+// GET /feed or /feed?sort=votes&page=1&limit=10
+// Get the authenticated user's home feed, which is paginated and sorted like GET /posts
+func (controller *FeedController) GetFeed(ctx *gin.Context) {
+	// Get the "page" query param and validate it
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1 // If invalid, just set to default
+	}
+
+	// Limit refers to number of records per page
+	// Get the "limit" query param and validate it
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		limit = 10 // If invalid, just set to default
+	}
+
+	// Pagination offset: The DB will fetch {limit} number of records starting from the record at this index.
+	offset := (page - 1) * limit
+
+	// Get the "sort" query param and validate it
+	sortBy := ctx.DefaultQuery("sort", "new")
+
+	// Retrieve the authenticated userID from context. If not authenticated, userID is 0 and the global listing is returned.
+	userID := middleware.GetUserIDOrZero(ctx)
+
+	posts, totalCount, err := controller.service.GetFeed(ctx.Request.Context(), limit, offset, sortBy, userID)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	// Send list of posts together with total count
+	respondJSON(ctx, http.StatusOK, gin.H{"data": posts, "total_count": totalCount})
+}
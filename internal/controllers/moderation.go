@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/middleware"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ModerationController struct {
+	service services.ModerationService
+}
+
+func NewModerationController(service services.ModerationService) *ModerationController {
+	return &ModerationController{service}
+}
+
+// PUT /posts/:post_id/pin
+// Pin a post to the top of GET /posts as a moderator
+func (controller *ModerationController) Pin(ctx *gin.Context) {
+	// Validate post_id param
+	postID, err := strconv.Atoi(ctx.Param("post_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid post ID"))
+		return
+	}
+
+	// Validate request body
+	var requestBody models.PinUpdate
+	if err := ctx.ShouldBindJSON(&requestBody); err != nil {
+		errs.HTTPErrorResponse(ctx, errs.FromBindingError(err))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	if err := controller.service.Pin(ctx.Request.Context(), uint(postID), requestBody.Position, userID); err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// DELETE /posts/:post_id/pin
+// Unpin a post from the top of GET /posts as a moderator
+func (controller *ModerationController) Unpin(ctx *gin.Context) {
+	// Validate post_id param
+	postID, err := strconv.Atoi(ctx.Param("post_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid post ID"))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	if err := controller.service.Unpin(ctx.Request.Context(), uint(postID), userID); err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// PUT /topics/:topic_id/pins/:post_id
+// Pin a post to the top of a topic's listing as a moderator
+func (controller *ModerationController) PinToTopic(ctx *gin.Context) {
+	// Validate topic_id and post_id params
+	topicID, err := strconv.Atoi(ctx.Param("topic_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid topic ID"))
+		return
+	}
+	postID, err := strconv.Atoi(ctx.Param("post_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid post ID"))
+		return
+	}
+
+	// Validate request body
+	var requestBody models.PinUpdate
+	if err := ctx.ShouldBindJSON(&requestBody); err != nil {
+		errs.HTTPErrorResponse(ctx, errs.FromBindingError(err))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	if err := controller.service.PinToTopic(ctx.Request.Context(), uint(topicID), uint(postID), requestBody.Position, userID); err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// DELETE /topics/:topic_id/pins/:post_id
+// Unpin a post from the top of a topic's listing as a moderator
+func (controller *ModerationController) UnpinFromTopic(ctx *gin.Context) {
+	// Validate topic_id and post_id params
+	topicID, err := strconv.Atoi(ctx.Param("topic_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid topic ID"))
+		return
+	}
+	postID, err := strconv.Atoi(ctx.Param("post_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid post ID"))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	if err := controller.service.UnpinFromTopic(ctx.Request.Context(), uint(topicID), uint(postID), userID); err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// PUT /posts/:post_id/lock
+// Lock a post as a moderator, so that it cannot be commented on or voted on
+func (controller *ModerationController) Lock(ctx *gin.Context) {
+	controller.setLocked(ctx, true)
+}
+
+// DELETE /posts/:post_id/lock
+// Unlock a post as a moderator
+func (controller *ModerationController) Unlock(ctx *gin.Context) {
+	controller.setLocked(ctx, false)
+}
+
+func (controller *ModerationController) setLocked(ctx *gin.Context, locked bool) {
+	// Validate post_id param
+	postID, err := strconv.Atoi(ctx.Param("post_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid post ID"))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	if err := controller.service.SetLocked(ctx.Request.Context(), uint(postID), locked, userID); err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -16,10 +16,12 @@ type PostController struct {
 	postService    services.PostService
 	taggingService services.TaggingService
 	votingService  services.VotingService
+	savingService  services.SavingService
+	pollService    services.PollService
 }
 
-func NewPostController(postService services.PostService, taggingService services.TaggingService, votingService services.VotingService) *PostController {
-	return &PostController{postService, taggingService, votingService}
+func NewPostController(postService services.PostService, taggingService services.TaggingService, votingService services.VotingService, savingService services.SavingService, pollService services.PollService) *PostController {
+	return &PostController{postService, taggingService, votingService, savingService, pollService}
 }
 
 // GET /posts or /posts?topic_id=1&page=1&limit=10
@@ -56,7 +58,7 @@ func (controller *PostController) GetList(ctx *gin.Context) {
 
 	// If no tags are specified, don't filter
 	if len(tags) == 0 {
-		posts, totalCount, err = controller.postService.GetList(limit, offset, sortBy, userID)
+		posts, totalCount, err = controller.postService.GetList(ctx.Request.Context(), limit, offset, sortBy, userID)
 		if err != nil {
 			errs.HTTPErrorResponse(ctx, err)
 			return
@@ -67,40 +69,40 @@ func (controller *PostController) GetList(ctx *gin.Context) {
 		for _, tag := range tags {
 			topicID, err := strconv.Atoi(tag)
 			if err != nil {
-				ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid topic ID: %v", topicID)})
+				errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, fmt.Sprintf("Invalid topic ID: %v", tag)))
 				return
 			}
 			topicIDs = append(topicIDs, uint(topicID))
 		}
 
-		posts, totalCount, err = controller.postService.GetByTags(topicIDs, limit, offset, sortBy, userID)
+		posts, totalCount, err = controller.postService.GetByTags(ctx.Request.Context(), topicIDs, limit, offset, sortBy, userID)
 		if err != nil {
 			errs.HTTPErrorResponse(ctx, err)
 			return
 		}
 	}
 
-	// Send list of posts together with total count
-	ctx.IndentedJSON(http.StatusOK, gin.H{"data": posts, "total_count": totalCount})
+	// Send list of posts together with total count, unless the client's copy is current
+	respondCacheable(ctx, 0, gin.H{"data": posts, "total_count": totalCount})
 }
 
 // GET /posts/:id
 func (controller *PostController) GetByID(ctx *gin.Context) {
 	id, err := strconv.Atoi(ctx.Param("post_id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid post ID"))
 		return
 	}
 
 	// Retrieve the authenticated userID from context. If not authenticated, userID is 0.
 	userID := middleware.GetUserIDOrZero(ctx)
 
-	post, err := controller.postService.GetByIDWithAuth(uint(id), userID)
+	post, err := controller.postService.GetByIDWithAuth(ctx.Request.Context(), uint(id), userID)
 	if err != nil {
 		errs.HTTPErrorResponse(ctx, err)
 		return
 	}
-	ctx.IndentedJSON(http.StatusOK, post)
+	respondCacheable(ctx, post.Version, post)
 }
 
 // POST /posts
@@ -108,7 +110,7 @@ func (controller *PostController) Create(ctx *gin.Context) {
 	// Validate request body
 	var requestBody models.NewPost
 	if err := ctx.ShouldBindJSON(&requestBody); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errs.HTTPErrorResponse(ctx, errs.FromBindingError(err))
 		return
 	}
 
@@ -121,25 +123,28 @@ func (controller *PostController) Create(ctx *gin.Context) {
 
 	// Map fields from request body to Post model
 	post := models.Post{
-		Title:    requestBody.Title,
-		Content:  requestBody.Content,
-		AuthorID: userID,
+		Title:     requestBody.Title,
+		Content:   requestBody.Content,
+		AuthorID:  userID,
+		Status:    requestBody.Status,
+		PublishAt: requestBody.PublishAt,
 	}
-
-	// Create the post
-	newPost, err := controller.postService.Create(&post)
-	if err != nil {
-		errs.HTTPErrorResponse(ctx, err)
-		return
+	// Map the poll of a poll post, if any
+	if requestBody.Poll != nil {
+		post.Poll = &models.Poll{MultipleChoice: requestBody.Poll.MultipleChoice, ClosesAt: requestBody.Poll.ClosesAt}
+		for _, text := range requestBody.Poll.Options {
+			post.Poll.Options = append(post.Poll.Options, models.PollOption{Text: text})
+		}
 	}
 
-	// Set the post tags
-	if err := controller.taggingService.TagPostWithTopics(uint(post.ID), requestBody.TopicIDs, userID); err != nil {
+	// Create the post together with its tags
+	newPost, err := controller.postService.Create(ctx.Request.Context(), &post, requestBody.TopicIDs)
+	if err != nil {
 		errs.HTTPErrorResponse(ctx, err)
 		return
 	}
 
-	ctx.IndentedJSON(http.StatusCreated, newPost)
+	respondVersioned(ctx, http.StatusCreated, newPost.Version, newPost)
 }
 
 // PATCH /posts/:id
@@ -147,14 +152,14 @@ func (controller *PostController) Update(ctx *gin.Context) {
 	// Validate postID
 	postID, err := strconv.Atoi(ctx.Param("post_id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid post ID"))
 		return
 	}
 
 	// Validate request body
 	var requestBody models.PostUpdate
 	if err := ctx.ShouldBindJSON(&requestBody); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errs.HTTPErrorResponse(ctx, errs.FromBindingError(err))
 		return
 	}
 
@@ -165,14 +170,90 @@ func (controller *PostController) Update(ctx *gin.Context) {
 		return
 	}
 
+	// Only apply the edit to the version it is based on, if any
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
 	// Update the post
-	updatedPost, err := controller.postService.Update(uint(postID), requestBody.Title, requestBody.Content, userID)
+	updatedPost, err := controller.postService.Update(ctx.Request.Context(), uint(postID), version, requestBody.Title, requestBody.Content, userID)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	respondVersioned(ctx, http.StatusOK, updatedPost.Version, updatedPost)
+}
+
+// PUT /posts/:post_id/status
+// Publish, schedule or unschedule one of the authenticated user's draft or scheduled posts
+func (controller *PostController) UpdateStatus(ctx *gin.Context) {
+	// Validate post_id param
+	postID, err := strconv.Atoi(ctx.Param("post_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid post ID"))
+		return
+	}
+
+	// Validate request body
+	var requestBody models.PostStatusUpdate
+	if err := ctx.ShouldBindJSON(&requestBody); err != nil {
+		errs.HTTPErrorResponse(ctx, errs.FromBindingError(err))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	post, err := controller.postService.UpdateStatus(ctx.Request.Context(), uint(postID), requestBody.Status, requestBody.PublishAt, userID)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	respondJSON(ctx, http.StatusOK, post)
+}
+
+// GET /users/me/drafts or /users/me/drafts?page=1&limit=10
+// Get the authenticated user's draft and scheduled posts, most recently updated first
+func (controller *PostController) GetDrafts(ctx *gin.Context) {
+	// Get the "page" query param and validate it
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1 // If invalid, just set to default
+	}
+
+	// Limit refers to number of records per page
+	// Get the "limit" query param and validate it
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		limit = 10 // If invalid, just set to default
+	}
+
+	// Pagination offset: The DB will fetch {limit} number of records starting from the record at this index.
+	offset := (page - 1) * limit
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	posts, totalCount, err := controller.postService.GetDrafts(ctx.Request.Context(), limit, offset, userID)
 	if err != nil {
 		errs.HTTPErrorResponse(ctx, err)
 		return
 	}
 
-	ctx.IndentedJSON(http.StatusOK, updatedPost)
+	// Send list of posts together with total count
+	respondJSON(ctx, http.StatusOK, gin.H{"data": posts, "total_count": totalCount})
 }
 
 // DELETE /posts/:id
@@ -180,7 +261,7 @@ func (controller *PostController) Delete(ctx *gin.Context) {
 	// Validate postID
 	postID, err := strconv.Atoi(ctx.Param("post_id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid post ID"))
 		return
 	}
 
@@ -192,7 +273,7 @@ func (controller *PostController) Delete(ctx *gin.Context) {
 	}
 
 	// Delete the post
-	if err := controller.postService.Delete(uint(postID), userID); err != nil {
+	if err := controller.postService.Delete(ctx.Request.Context(), uint(postID), userID); err != nil {
 		errs.HTTPErrorResponse(ctx, err)
 		return
 	}
@@ -206,14 +287,14 @@ func (controller *PostController) UpdateTags(ctx *gin.Context) {
 	// Validate postID
 	postID, err := strconv.Atoi(ctx.Param("post_id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid post ID"))
 		return
 	}
 
 	// Validate request body
 	var requestBody models.PostTagsUpdate
 	if err := ctx.ShouldBindJSON(&requestBody); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errs.HTTPErrorResponse(ctx, errs.FromBindingError(err))
 		return
 	}
 
@@ -225,7 +306,7 @@ func (controller *PostController) UpdateTags(ctx *gin.Context) {
 	}
 
 	// Update the post tags
-	if err := controller.taggingService.TagPostWithTopics(uint(postID), requestBody.TopicIDs, userID); err != nil {
+	if err := controller.taggingService.TagPostWithTopics(ctx.Request.Context(), uint(postID), requestBody.TopicIDs, userID); err != nil {
 		errs.HTTPErrorResponse(ctx, err)
 		return
 	}
@@ -239,20 +320,21 @@ func (controller *PostController) Vote(ctx *gin.Context) {
 	// Validate post_id param
 	postID, err := strconv.Atoi(ctx.Param("post_id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid post ID"))
 		return
 	}
 
 	// Validate user_id param
 	userID, err := strconv.Atoi(ctx.Param("user_id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid user ID"))
+		return
 	}
 
 	// Validate request body
 	var requestBody models.VoteUpdate
 	if err := ctx.ShouldBindJSON(&requestBody); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errs.HTTPErrorResponse(ctx, errs.FromBindingError(err))
 		return
 	}
 
@@ -263,7 +345,90 @@ func (controller *PostController) Vote(ctx *gin.Context) {
 		return
 	}
 
-	if err := controller.votingService.VotePost(uint(postID), uint(userID), requestBody.Value, authenticatedUserID); err != nil {
+	if err := controller.votingService.VotePost(ctx.Request.Context(), uint(postID), uint(userID), requestBody.Value, authenticatedUserID); err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// POST /posts/:post_id/poll/votes
+// Vote in the poll of a poll post as the authenticated user, and get the poll with its results
+func (controller *PostController) VotePoll(ctx *gin.Context) {
+	// Validate post_id param
+	postID, err := strconv.Atoi(ctx.Param("post_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid post ID"))
+		return
+	}
+
+	// Validate request body
+	var requestBody models.PollVoteInput
+	if err := ctx.ShouldBindJSON(&requestBody); err != nil {
+		errs.HTTPErrorResponse(ctx, errs.FromBindingError(err))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	poll, err := controller.pollService.Vote(ctx.Request.Context(), uint(postID), requestBody.OptionIDs, userID)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	respondJSON(ctx, http.StatusCreated, poll)
+}
+
+// PUT /posts/:post_id/save
+// Save a post for the authenticated user
+func (controller *PostController) Save(ctx *gin.Context) {
+	// Validate post_id param
+	postID, err := strconv.Atoi(ctx.Param("post_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid post ID"))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	if err := controller.savingService.SavePost(ctx.Request.Context(), uint(postID), userID); err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// DELETE /posts/:post_id/save
+// Remove a post from the authenticated user's saved items
+func (controller *PostController) Unsave(ctx *gin.Context) {
+	// Validate post_id param
+	postID, err := strconv.Atoi(ctx.Param("post_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid post ID"))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	if err := controller.savingService.UnsavePost(ctx.Request.Context(), uint(postID), userID); err != nil {
 		errs.HTTPErrorResponse(ctx, err)
 		return
 	}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/middleware"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cache-Control of responses to anonymous requests, which are the same for everyone and may be cached by shared caches
+const publicCacheControl = "public, max-age=60"
+
+// Cache-Control of responses to authenticated requests, which include the user's own votes and saves.
+// Only the user's browser may store them, and must revalidate them before each use.
+const privateCacheControl = "private, no-cache"
+
+// Strong entity tag of an encoded response body
+// For a post or comment, the tag starts with its version, so that edits can be based on it with If-Match.
+// version is 0 for other responses.
+func entityTag(version uint, body []byte) string {
+	hash := sha256.Sum256(body)
+	if version == 0 {
+		return fmt.Sprintf(`"%s"`, hex.EncodeToString(hash[:8]))
+	}
+	return fmt.Sprintf(`"%d-%s"`, version, hex.EncodeToString(hash[:8]))
+}
+
+// Send a JSON response with the ETag of a post or comment, for a later edit to be based on
+func respondVersioned(ctx *gin.Context, status int, version uint, body any) {
+	encoded, err := encodeJSON(body)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+	ctx.Header("ETag", entityTag(version, encoded))
+	ctx.Data(status, "application/json; charset=utf-8", encoded)
+}
+
+// Send a JSON response to a GET request with an ETag that clients and caches can revalidate it with.
+// Responds with 304 Not Modified instead if the request's If-None-Match shows that the client's copy is current.
+// version is the version of the post the response is about, if any.
+// There is no Last-Modified, since votes, pins, locks and deletions change responses without changing any record's UpdatedAt.
+func respondCacheable(ctx *gin.Context, version uint, body any) {
+	encoded, err := encodeJSON(body)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	// Responses include the current user's votes, so they differ by user
+	ctx.Header("Vary", "Authorization")
+	cacheControl := publicCacheControl
+	if middleware.GetUserIDOrZero(ctx) != 0 {
+		cacheControl = privateCacheControl
+	}
+	respondWithValidators(ctx, entityTag(version, encoded), cacheControl, "application/json; charset=utf-8", encoded)
+}
+
+// Send an encoded response to a GET request with the given ETag, or 304 Not Modified if the client's copy is current
+func respondWithValidators(ctx *gin.Context, etag string, cacheControl string, contentType string, body []byte) {
+	ctx.Header("ETag", etag)
+	ctx.Header("Cache-Control", cacheControl)
+
+	if notModified(ctx.Request, etag) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	ctx.Data(http.StatusOK, contentType, body)
+}
+
+// Whether the client's copy of a response is current, following RFC 9110's evaluation of If-None-Match
+// If-Modified-Since is ignored, since responses have no Last-Modified to compare it with.
+func notModified(request *http.Request, etag string) bool {
+	for _, tag := range strings.Split(request.Header.Get("If-None-Match"), ",") {
+		tag = strings.TrimSpace(tag)
+		// Weak comparison, which ignores the weakness indicator
+		if tag == "*" || (tag != "" && strings.TrimPrefix(tag, "W/") == etag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the version an edit is based on from the If-Match header, as sent back from the ETag of a previous response
+// Returns 0 if the header is absent or "*", in which case the edit applies to whatever the current version is.
+// Only the version of the tag is compared, so that votes cast since the tag was received do not prevent the edit.
+// Weak tags and lists of several tags never match, since an edit can only be based on a single version.
+func ifMatchVersion(ctx *gin.Context) (uint, error) {
+	header := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	// A single strong tag is one quoted string, so weak tags (W/"...") and lists ("...", "...") are rejected here
+	conflict := errs.New(errs.ErrPreconditionFailed, "If-Match does not match the current version")
+	if len(header) < 2 || !strings.HasPrefix(header, `"`) || !strings.HasSuffix(header, `"`) {
+		return 0, conflict
+	}
+	tag := header[1 : len(header)-1]
+	if strings.Contains(tag, `"`) {
+		return 0, conflict
+	}
+	versionPart, _, found := strings.Cut(tag, "-")
+	version, err := strconv.ParseUint(versionPart, 10, 32)
+	if !found || err != nil || version == 0 {
+		return 0, conflict
+	}
+	return uint(version), nil
+}
@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Send a JSON response, indented in debug mode for readability and compact otherwise
+func respondJSON(ctx *gin.Context, status int, body any) {
+	if gin.IsDebugging() {
+		ctx.IndentedJSON(status, body)
+		return
+	}
+	ctx.JSON(status, body)
+}
+
+// Encode a response body like respondJSON would send it
+func encodeJSON(body any) ([]byte, error) {
+	if gin.IsDebugging() {
+		return json.MarshalIndent(body, "", "    ")
+	}
+	return json.Marshal(body)
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/services"
+	"cvwo-backend/internal/syndication"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Number of posts in each feed
+const feedSize = 20
+
+type SyndicationController struct {
+	postService  services.PostService
+	topicService services.TopicService
+	userService  services.UserService
+	feeds        *syndication.Builder
+}
+
+func NewSyndicationController(postService services.PostService, topicService services.TopicService, userService services.UserService, feeds *syndication.Builder) *SyndicationController {
+	return &SyndicationController{postService, topicService, userService, feeds}
+}
+
+// GET /feeds/posts.rss
+// Get the newest posts as an RSS feed, like GET /posts?sort=new for an anonymous user
+func (controller *SyndicationController) GetPosts(ctx *gin.Context) {
+	posts, _, err := controller.postService.GetList(ctx.Request.Context(), feedSize, 0, "new", 0)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	feed := controller.feeds.Feed("/", "Latest posts", "The newest posts on the forum", posts)
+	respondFeed(ctx, syndication.RSS, "application/rss+xml; charset=utf-8", feed)
+}
+
+// GET /feeds/topics/:topic_id.atom
+// Get the newest posts tagged with a topic as an Atom feed, like GET /posts?tag=:topic_id&sort=new
+func (controller *SyndicationController) GetTopic(ctx *gin.Context) {
+	topicID, err := atomFeedID(ctx, "topic_id", "topic")
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	topic, err := controller.topicService.GetByID(ctx.Request.Context(), topicID)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+	posts, _, err := controller.postService.GetByTags(ctx.Request.Context(), []uint{topicID}, feedSize, 0, "new", 0)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	feed := controller.feeds.Feed(fmt.Sprintf("/topics/%d", topicID), "Posts in "+topic.Name, "The newest posts tagged with "+topic.Name, posts)
+	respondFeed(ctx, syndication.Atom, "application/atom+xml; charset=utf-8", feed)
+}
+
+// GET /feeds/users/:id.atom
+// Get the newest posts by a user as an Atom feed
+func (controller *SyndicationController) GetUser(ctx *gin.Context) {
+	userID, err := atomFeedID(ctx, "id", "user")
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	user, err := controller.userService.GetByID(ctx.Request.Context(), userID)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+	posts, _, err := controller.postService.GetByAuthor(ctx.Request.Context(), userID, feedSize, 0, "new", 0)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	feed := controller.feeds.Feed(fmt.Sprintf("/users/%d", userID), "Posts by "+user.Username, "The newest posts by "+user.Username, posts)
+	respondFeed(ctx, syndication.Atom, "application/atom+xml; charset=utf-8", feed)
+}
+
+// Get the ID in the file name of an Atom feed, such as 1 in /feeds/topics/1.atom
+// The route's param is named after the whole file name, e.g. :topic_id.atom, since gin params extend to the next slash.
+func atomFeedID(ctx *gin.Context, param string, name string) (uint, error) {
+	file, ok := strings.CutSuffix(ctx.Param(param+".atom"), ".atom")
+	if !ok {
+		return 0, errs.New(errs.ErrNotFound, "Route not found")
+	}
+	id, err := strconv.Atoi(file)
+	if err != nil || id < 1 {
+		return 0, errs.New(errs.ErrInvalid, fmt.Sprintf("Invalid %s ID", name))
+	}
+	return uint(id), nil
+}
+
+// Send a feed rendered by render, which feed readers can revalidate with its ETag
+// Feeds are the same for everyone, so they may always be cached by shared caches.
+func respondFeed(ctx *gin.Context, render func(syndication.Feed) ([]byte, error), contentType string, feed syndication.Feed) {
+	encoded, err := render(feed)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+	respondWithValidators(ctx, entityTag(0, encoded), publicCacheControl, contentType, encoded)
+}
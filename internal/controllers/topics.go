@@ -2,26 +2,79 @@ package controllers
 
 import (
 	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/middleware"
 	"cvwo-backend/internal/services"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
 type TopicController struct {
-	service services.TopicService
+	service             services.TopicService
+	subscriptionService services.SubscriptionService
 }
 
-func NewTopicController(service services.TopicService) *TopicController {
-	return &TopicController{service}
+func NewTopicController(service services.TopicService, subscriptionService services.SubscriptionService) *TopicController {
+	return &TopicController{service, subscriptionService}
 }
 
 // GET /topics
 func (controller *TopicController) GetAll(ctx *gin.Context) {
-	topics, err := controller.service.GetAll()
+	topics, err := controller.service.GetAll(ctx.Request.Context())
 	if err != nil {
 		errs.HTTPErrorResponse(ctx, err)
 		return
 	}
-	ctx.IndentedJSON(http.StatusOK, topics)
+	respondCacheable(ctx, 0, topics)
+}
+
+// PUT /topics/:topic_id/subscription
+// Subscribe the authenticated user to a topic
+func (controller *TopicController) Subscribe(ctx *gin.Context) {
+	// Validate topic_id param
+	topicID, err := strconv.Atoi(ctx.Param("topic_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid topic ID"))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	if err := controller.subscriptionService.Subscribe(ctx.Request.Context(), uint(topicID), userID); err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// DELETE /topics/:topic_id/subscription
+// Unsubscribe the authenticated user from a topic
+func (controller *TopicController) Unsubscribe(ctx *gin.Context) {
+	// Validate topic_id param
+	topicID, err := strconv.Atoi(ctx.Param("topic_id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid topic ID"))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	if err := controller.subscriptionService.Unsubscribe(ctx.Request.Context(), uint(topicID), userID); err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
 }
@@ -1,7 +1,9 @@
 package controllers
 
 import (
+	"context"
 	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/middleware"
 	"cvwo-backend/internal/models"
 	"cvwo-backend/internal/services"
 	"net/http"
@@ -11,37 +13,45 @@ import (
 )
 
 type UserController struct {
-	service services.UserService
+	service             services.UserService
+	savingService       services.SavingService
+	subscriptionService services.SubscriptionService
+	followService       services.FollowService
+	activityService     services.ActivityService
 }
 
-func NewUserController(service services.UserService) *UserController {
-	return &UserController{service}
+func NewUserController(service services.UserService, savingService services.SavingService, subscriptionService services.SubscriptionService, followService services.FollowService, activityService services.ActivityService) *UserController {
+	return &UserController{service, savingService, subscriptionService, followService, activityService}
 }
 
 // GET /users
 func (controller *UserController) GetAll(ctx *gin.Context) {
-	users, err := controller.service.GetAll()
+	users, err := controller.service.GetAll(ctx.Request.Context())
 	if err != nil {
 		errs.HTTPErrorResponse(ctx, err)
 		return
 	}
-	ctx.IndentedJSON(http.StatusOK, users)
+	respondJSON(ctx, http.StatusOK, users)
 }
 
 // GET /users/:id
+// Get a user's profile, including follower counts
 func (controller *UserController) GetByID(ctx *gin.Context) {
 	id, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid user ID"))
 		return
 	}
 
-	user, err := controller.service.GetByID(uint(id))
+	// Retrieve the authenticated userID from context. If not authenticated, userID is 0.
+	userID := middleware.GetUserIDOrZero(ctx)
+
+	profile, err := controller.followService.GetProfile(ctx.Request.Context(), uint(id), userID)
 	if err != nil {
 		errs.HTTPErrorResponse(ctx, err)
 		return
 	}
-	ctx.IndentedJSON(http.StatusOK, user)
+	respondJSON(ctx, http.StatusOK, profile)
 }
 
 // POST /users
@@ -49,15 +59,224 @@ func (controller *UserController) Create(ctx *gin.Context) {
 	var user models.AuthInput
 
 	if err := ctx.ShouldBindJSON(&user); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errs.HTTPErrorResponse(ctx, errs.FromBindingError(err))
+		return
+	}
+
+	newUser, err := controller.service.Create(ctx.Request.Context(), &user)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	respondJSON(ctx, http.StatusCreated, newUser)
+}
+
+// GET /users/me/saved or /users/me/saved?type=post&page=1&limit=10
+// Get the authenticated user's saved posts and comments, most recently saved first
+func (controller *UserController) GetSaved(ctx *gin.Context) {
+	// Get the "page" query param and validate it
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1 // If invalid, just set to default
+	}
+
+	// Limit refers to number of records per page
+	// Get the "limit" query param and validate it
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		limit = 10 // If invalid, just set to default
+	}
+
+	// Pagination offset: The DB will fetch {limit} number of records starting from the record at this index.
+	offset := (page - 1) * limit
+
+	// Get the "type" query param. If empty, don't filter.
+	itemType := ctx.Query("type")
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	items, totalCount, err := controller.savingService.GetSaved(ctx.Request.Context(), itemType, limit, offset, userID)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	// Send list of saved items together with total count
+	respondJSON(ctx, http.StatusOK, gin.H{"data": items, "total_count": totalCount})
+}
+
+// GET /users/me/subscriptions
+// Get the topics the authenticated user subscribes to
+func (controller *UserController) GetSubscriptions(ctx *gin.Context) {
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	topics, err := controller.subscriptionService.GetTopics(ctx.Request.Context(), userID)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+	respondJSON(ctx, http.StatusOK, topics)
+}
+
+// PUT /users/me/settings
+// Update the authenticated user's settings
+func (controller *UserController) UpdateSettings(ctx *gin.Context) {
+	// Validate request body
+	var settings models.UserSettings
+	if err := ctx.ShouldBindJSON(&settings); err != nil {
+		errs.HTTPErrorResponse(ctx, errs.FromBindingError(err))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	user, err := controller.service.UpdateSettings(ctx.Request.Context(), &settings, userID)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+	respondJSON(ctx, http.StatusOK, user)
+}
+
+// PUT /users/:id/follow
+// Follow a user as the authenticated user
+func (controller *UserController) Follow(ctx *gin.Context) {
+	// Validate id param
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid user ID"))
 		return
 	}
 
-	newUser, err := controller.service.Create(&user)
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	if err := controller.followService.Follow(ctx.Request.Context(), uint(id), userID); err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// DELETE /users/:id/follow
+// Stop following a user as the authenticated user
+func (controller *UserController) Unfollow(ctx *gin.Context) {
+	// Validate id param
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid user ID"))
+		return
+	}
+
+	// Retrieve the authenticated userID from context
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	if err := controller.followService.Unfollow(ctx.Request.Context(), uint(id), userID); err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// GET /users/:id/followers or /users/:id/followers?page=1&limit=10
+// Get the users who follow a user, most recent first
+func (controller *UserController) GetFollowers(ctx *gin.Context) {
+	controller.getFollows(ctx, controller.followService.GetFollowers)
+}
+
+// GET /users/:id/following or /users/:id/following?page=1&limit=10
+// Get the users a user follows, most recent first
+func (controller *UserController) GetFollowing(ctx *gin.Context) {
+	controller.getFollows(ctx, controller.followService.GetFollowing)
+}
+
+// Send a paginated list of users obtained from the given service function
+func (controller *UserController) getFollows(ctx *gin.Context, getUsers func(context.Context, uint, int, int) ([]models.User, int64, error)) {
+	// Validate id param
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid user ID"))
+		return
+	}
+
+	// Get the "page" query param and validate it
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1 // If invalid, just set to default
+	}
+
+	// Limit refers to number of records per page
+	// Get the "limit" query param and validate it
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		limit = 10 // If invalid, just set to default
+	}
+
+	// Pagination offset: The DB will fetch {limit} number of records starting from the record at this index.
+	offset := (page - 1) * limit
+
+	users, totalCount, err := getUsers(ctx.Request.Context(), uint(id), limit, offset)
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, err)
+		return
+	}
+
+	// Send list of users together with total count
+	respondJSON(ctx, http.StatusOK, gin.H{"data": users, "total_count": totalCount})
+}
+
+// GET /users/:id/activity or /users/:id/activity?cursor=...&limit=10
+// Get a user's posts, comments and public votes, most recent first
+// The response includes a cursor to request the next page with, which is empty on the last page
+func (controller *UserController) GetActivity(ctx *gin.Context) {
+	// Validate id param
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		errs.HTTPErrorResponse(ctx, errs.New(errs.ErrInvalid, "Invalid user ID"))
+		return
+	}
+
+	// Get the "limit" query param and validate it
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		limit = 10 // If invalid, just set to default
+	}
+
+	// Retrieve the authenticated userID from context. If not authenticated, userID is 0.
+	userID := middleware.GetUserIDOrZero(ctx)
+
+	activities, nextCursor, err := controller.activityService.GetActivity(ctx.Request.Context(), uint(id), ctx.Query("cursor"), limit, userID)
 	if err != nil {
 		errs.HTTPErrorResponse(ctx, err)
 		return
 	}
 
-	ctx.IndentedJSON(http.StatusCreated, newUser)
+	// Send the page of activity together with the cursor of the next page
+	respondJSON(ctx, http.StatusOK, gin.H{"data": activities, "next_cursor": nextCursor})
 }
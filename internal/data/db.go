@@ -1,23 +1,28 @@
 package data
 
 import (
+	"cvwo-backend/internal/markdown"
+	"cvwo-backend/internal/metrics"
 	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/tracing"
 	"log"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+// Connect to the PostgreSQL database, migrate it and seed it with initial data
+// Exits if any step fails
 func InitDB(dsn string) *gorm.DB {
 	// Open database
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
+	db, err := Open(postgres.Open(dsn))
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		log.Fatalf("Failed to open database: %v", err)
 	}
 
-	// Migrate tables based on models
-	if err := db.AutoMigrate(&models.User{}, &models.Post{}, &models.Comment{}, &models.Topic{}, &models.PostVote{}, &models.CommentVote{}); err != nil {
-		log.Fatalf("Failed to migrate tables: %v", err)
+	// Expose connection pool stats
+	if err := metrics.RegisterDBStats(db, "forum"); err != nil {
+		log.Fatalf("Failed to register database stats: %v", err)
 	}
 
 	// Seed database with initial data
@@ -25,5 +30,94 @@ func InitDB(dsn string) *gorm.DB {
 		log.Fatalf("Failed to seed database: %v", err)
 	}
 
+	// Render content written before Markdown support, including the seeded posts
+	if err := RenderMissingHTML(db); err != nil {
+		log.Fatalf("Failed to render content: %v", err)
+	}
+
 	return db
 }
+
+// Open a database with the given dialector, instrument it and migrate tables
+// Works with any dialector supported by gorm, e.g. SQLite for tests
+func Open(dialector gorm.Dialector) (*gorm.DB, error) {
+	db, err := gorm.Open(dialector, &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+
+	// Record query durations and errors
+	if err := db.Use(metrics.NewGormPlugin()); err != nil {
+		return nil, err
+	}
+
+	// Create a span for every query
+	if err := db.Use(tracing.NewGormPlugin()); err != nil {
+		return nil, err
+	}
+
+	// Migrate tables based on models
+	if err := Migrate(db); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Migrate tables based on models
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&models.User{}, &models.Post{}, &models.Comment{}, &models.Topic{}, &models.PostVote{}, &models.CommentVote{}, &models.SavedItem{}, &models.TopicSubscription{}, &models.Follow{}, &models.Block{}, &models.Conversation{}, &models.ConversationParticipant{}, &models.Message{}, &models.Attachment{}, &models.Poll{}, &models.PollOption{}, &models.PollVote{}, &models.PollChoice{}, &models.TopicPin{}, &models.Job{}, &models.OutboxEvent{})
+}
+
+// Render the HTML of posts and comments that do not have it yet
+// Uses UpdateColumn so that updated_at is left unchanged
+func RenderMissingHTML(db *gorm.DB) error {
+	var posts []models.Post
+	err := db.Where("content_html = ''").FindInBatches(&posts, 100, func(tx *gorm.DB, batch int) error {
+		for _, post := range posts {
+			contentHTML, err := markdown.Render(post.Content)
+			if err != nil {
+				return err
+			}
+			if err := db.Model(&post).UpdateColumn("content_html", contentHTML).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	var comments []models.Comment
+	return db.Where("content_html = ''").FindInBatches(&comments, 100, func(tx *gorm.DB, batch int) error {
+		for _, comment := range comments {
+			contentHTML, err := markdown.Render(comment.Content)
+			if err != nil {
+				return err
+			}
+			if err := db.Model(&comment).UpdateColumn("content_html", contentHTML).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+// Make the users with the given usernames moderators, and revoke moderator from every other user
+// Users that register later become moderators the next time this runs, i.e. when the server restarts
+func GrantModerators(db *gorm.DB, usernames []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		revoked := tx.Model(&models.User{}).Where("moderator")
+		if len(usernames) > 0 {
+			revoked = revoked.Where("username NOT IN ?", usernames)
+		}
+		if err := revoked.UpdateColumn("moderator", false).Error; err != nil {
+			return err
+		}
+		if len(usernames) == 0 {
+			return nil
+		}
+		return tx.Model(&models.User{}).Where("username IN ?", usernames).UpdateColumn("moderator", true).Error
+	})
+}
@@ -1,8 +1,22 @@
 package errs
 
+import "time"
+
 type Error struct {
 	Code    uint
 	Message string
+	// Per-field validation errors, if any
+	Fields []FieldError
+	// Underlying error that caused this error, if any. Not included in responses.
+	Cause error
+	// Time after which a request rejected with ErrTooManyRequests may be retried, sent as the Retry-After header. Zero if unknown.
+	RetryAfter time.Duration
+}
+
+// Validation error for a single field of a request body
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 const (
@@ -11,12 +25,36 @@ const (
 	ErrUnauthorized
 	ErrConflict
 	ErrInternal
+	ErrTooManyRequests
+	ErrForbidden
+	ErrTooLarge
+	ErrUnsupportedMediaType
+	ErrPreconditionFailed
 )
 
 func New(code uint, message string) *Error {
 	return &Error{Code: code, Message: message}
 }
 
+// Create an error that wraps the underlying cause
+func Wrap(code uint, message string, cause error) *Error {
+	return &Error{Code: code, Message: message, Cause: cause}
+}
+
+// Create a too many requests error for a request that may be retried after the given time
+func NewTooManyRequests(message string, retryAfter time.Duration) *Error {
+	return &Error{Code: ErrTooManyRequests, Message: message, RetryAfter: retryAfter}
+}
+
+// Create an invalid input error with per-field validation errors
+func NewValidation(message string, fields []FieldError) *Error {
+	return &Error{Code: ErrInvalid, Message: message, Fields: fields}
+}
+
 func (e *Error) Error() string {
 	return e.Message
 }
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
@@ -2,35 +2,89 @@ package errs
 
 import (
 	"errors"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Sends a error response in JSON with status code and message
+// Media type of problem details responses, see RFC 7807
+const ProblemContentType = "application/problem+json"
+
+// Body of an error response, following RFC 7807 problem details
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail"`
+	// Stable, machine-readable error code
+	Code      string       `json:"code"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// HTTP status and stable error code for each custom error code
+var errorCodes = map[uint]struct {
+	status int
+	code   string
+}{
+	ErrInvalid:              {http.StatusBadRequest, "invalid"},
+	ErrNotFound:             {http.StatusNotFound, "not_found"},
+	ErrUnauthorized:         {http.StatusUnauthorized, "unauthorized"},
+	ErrForbidden:            {http.StatusForbidden, "forbidden"},
+	ErrConflict:             {http.StatusConflict, "conflict"},
+	ErrTooManyRequests:      {http.StatusTooManyRequests, "too_many_requests"},
+	ErrTooLarge:             {http.StatusRequestEntityTooLarge, "too_large"},
+	ErrUnsupportedMediaType: {http.StatusUnsupportedMediaType, "unsupported_media_type"},
+	ErrPreconditionFailed:   {http.StatusPreconditionFailed, "precondition_failed"},
+	ErrInternal:             {http.StatusInternalServerError, "internal"},
+}
+
+// Sends an error response as problem details with status code, error code and message
+// Aborts the request so that later handlers do not write to the response
 func HTTPErrorResponse(ctx *gin.Context, err error) {
+	problem := NewProblem(err)
+	problem.Instance = ctx.Request.URL.Path
+	problem.RequestID = ctx.Writer.Header().Get("X-Request-ID")
+
+	// Errors that are not one of the defined custom errors are unexpected, so log them instead of exposing them
+	var e *Error
+	if !errors.As(err, &e) || e.Code == ErrInternal {
+		log.Printf("Internal error [request %s]: %v", problem.RequestID, err)
+	}
+
+	// Tell clients when to retry, see RFC 9110 section 10.2.3
+	if errors.As(err, &e) && e.RetryAfter > 0 {
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(e.RetryAfter.Seconds()))))
+	}
+
+	ctx.Header("Content-Type", ProblemContentType)
+	ctx.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// Build the problem details for the given error
+func NewProblem(err error) *Problem {
 	var e *Error
 	// If the error is not one of the defined custom errors, return a generic internal server error
 	if !errors.As(err, &e) {
-		ctx.JSON(http.StatusInternalServerError, errorResponseBody(err))
+		e = New(ErrInternal, "Internal server error")
 	}
+
 	// Choose appropriate status code based on custom error code
-	switch e.Code {
-	case ErrInvalid:
-		ctx.JSON(http.StatusBadRequest, errorResponseBody(e))
-	case ErrUnauthorized:
-		ctx.JSON(http.StatusUnauthorized, errorResponseBody(e))
-	case ErrNotFound:
-		ctx.JSON(http.StatusNotFound, errorResponseBody(e))
-	case ErrConflict:
-		ctx.JSON(http.StatusConflict, errorResponseBody(e))
-	case ErrInternal:
-		ctx.JSON(http.StatusInternalServerError, errorResponseBody(e))
-	default:
-		ctx.JSON(http.StatusInternalServerError, errorResponseBody(e))
+	mapping, exists := errorCodes[e.Code]
+	if !exists {
+		mapping = errorCodes[ErrInternal]
 	}
-}
 
-func errorResponseBody(err error) any {
-	return gin.H{"error": err.Error()}
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(mapping.status),
+		Status: mapping.status,
+		Detail: e.Message,
+		Code:   mapping.code,
+		Errors: e.Fields,
+	}
 }
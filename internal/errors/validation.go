@@ -0,0 +1,76 @@
+package errs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Report validation errors using the JSON names of fields rather than the Go struct field names
+	if validate, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+// Convert an error returned by ShouldBindJSON into an invalid input error with per-field messages
+func FromBindingError(err error) *Error {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			fields = append(fields, FieldError{Field: fieldErr.Field(), Message: validationMessage(fieldErr)})
+		}
+		return &Error{Code: ErrInvalid, Message: "Invalid request body", Fields: fields, Cause: err}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		fields := []FieldError{{Field: typeErr.Field, Message: fmt.Sprintf("must be of type %s", typeErr.Type)}}
+		return &Error{Code: ErrInvalid, Message: "Invalid request body", Fields: fields, Cause: err}
+	}
+
+	if errors.Is(err, io.EOF) {
+		return Wrap(ErrInvalid, "Request body is required", err)
+	}
+
+	return Wrap(ErrInvalid, "Malformed request body", err)
+}
+
+// Human-readable message for a failed validation rule
+func validationMessage(fieldErr validator.FieldError) string {
+	isString := fieldErr.Kind() == reflect.String
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if isString {
+			return fmt.Sprintf("must be at least %s characters", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fieldErr.Param())
+	case "max":
+		if isString {
+			return fmt.Sprintf("must be at most %s characters", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fieldErr.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fieldErr.Param())
+	default:
+		return fmt.Sprintf("failed the %s validation", fieldErr.Tag())
+	}
+}
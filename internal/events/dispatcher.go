@@ -0,0 +1,115 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"cvwo-backend/internal/jobs"
+	"cvwo-backend/internal/metrics"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/tracing"
+)
+
+// Maximum number of events dispatched per transaction
+const batchSize = 100
+
+// Delivers events from the outbox to in-process subscribers
+// Each event is handed to each of its subscribers as a background job, so that subscribers are retried with backoff
+// and dead-lettered independently of each other. Delivery is at least once and unordered: subscribers must
+// tolerate receiving an event more than once, and events arriving in a different order than they happened.
+type Dispatcher struct {
+	db     *gorm.DB
+	runner *jobs.Runner
+	// Event type to the job types of its subscribers
+	subscribers  map[string][]string
+	pollInterval time.Duration
+}
+
+func NewDispatcher(db *gorm.DB, runner *jobs.Runner, pollInterval time.Duration) *Dispatcher {
+	return &Dispatcher{
+		db:           db,
+		runner:       runner,
+		subscribers:  make(map[string][]string),
+		pollInterval: pollInterval,
+	}
+}
+
+// Subscribe to events of type T under the given name, which must be unique among T's subscribers
+// Must be called before the dispatcher's job runner is started
+func Subscribe[T Event](dispatcher *Dispatcher, name string, handle func(ctx context.Context, event T) error) {
+	var event T
+	jobType := fmt.Sprintf("event:%s:%s", event.EventType(), name)
+	jobs.Handle(dispatcher.runner, jobType, handle)
+	dispatcher.subscribers[event.EventType()] = append(dispatcher.subscribers[event.EventType()], jobType)
+}
+
+// Dispatch events as they are added to the outbox, until the context is cancelled
+func (dispatcher *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(dispatcher.pollInterval)
+	defer ticker.Stop()
+	for {
+		dispatched, err := dispatcher.Dispatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to dispatch events: %v", err)
+		}
+		// Keep going while the outbox has a backlog
+		if dispatched == batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Hand the oldest events in the outbox to their subscribers and remove them from the outbox
+// Returns the number of events dispatched
+func (dispatcher *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "Dispatcher.Dispatch")
+	defer span.End()
+
+	dispatched := 0
+	err := dispatcher.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Order("id").Limit(batchSize)
+		// Postgres can skip events being dispatched by other instances
+		if tx.Dialector.Name() == "postgres" {
+			query = query.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+		}
+		var events []models.OutboxEvent
+		if err := query.Find(&events).Error; err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			// Without row locks, another instance may have dispatched the event since it was read
+			result := tx.Delete(&models.OutboxEvent{}, event.ID)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+			for _, jobType := range dispatcher.subscribers[event.Type] {
+				if err := jobs.Enqueue(ctx, tx, jobs.Job{Type: jobType, Payload: json.RawMessage(event.Payload)}); err != nil {
+					return err
+				}
+			}
+			dispatched++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	metrics.EventsDispatched.Add(float64(dispatched))
+	return dispatched, nil
+}
@@ -0,0 +1,156 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"cvwo-backend/internal/jobs"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+)
+
+// Open an in-memory SQLite database with the outbox and jobs tables
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{TranslateError: true})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get database handle: %v", err)
+	}
+	// SQLite allows a single writer at a time
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&models.OutboxEvent{}, &models.Job{}); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return db
+}
+
+// Record events in a transaction, failing it with err
+func record(db *gorm.DB, err error, events ...Event) error {
+	return repos.NewTransactor(db).InTransaction(context.Background(), func(ctx context.Context) error {
+		records, encodeErr := Encode(events...)
+		if encodeErr != nil {
+			return encodeErr
+		}
+		if addErr := repos.NewOutboxRepo(db).Add(ctx, records); addErr != nil {
+			return addErr
+		}
+		return err
+	})
+}
+
+func TestOutboxIsTransactional(t *testing.T) {
+	db := newTestDB(t)
+
+	failed := errors.New("failed")
+	if err := record(db, failed, PostCreated{PostID: 1, AuthorID: 1}); !errors.Is(err, failed) {
+		t.Fatalf("expected the transaction to fail, got %v", err)
+	}
+	if err := record(db, nil, PostCreated{PostID: 2, AuthorID: 1}, PostDeleted{PostID: 2, AuthorID: 1}); err != nil {
+		t.Fatalf("failed to record events: %v", err)
+	}
+
+	var records []models.OutboxEvent
+	if err := db.Order("id").Find(&records).Error; err != nil {
+		t.Fatalf("failed to get events: %v", err)
+	}
+	if len(records) != 2 || records[0].Type != "post.created" || records[0].Payload != `{"post_id":2,"author_id":1}` || records[1].Type != "post.deleted" {
+		t.Errorf("expected only the events of the committed transaction, got %+v", records)
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	db := newTestDB(t)
+	runner := jobs.NewRunner(db, jobs.Options{PollInterval: 5 * time.Millisecond, BaseBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond})
+	dispatcher := NewDispatcher(db, runner, 5*time.Millisecond)
+
+	var mu sync.Mutex
+	received := map[string][]uint{}
+	receive := func(subscriber string, postID uint) int {
+		mu.Lock()
+		defer mu.Unlock()
+		received[subscriber] = append(received[subscriber], postID)
+		return len(received[subscriber])
+	}
+	Subscribe(dispatcher, "counter", func(ctx context.Context, event PostCreated) error {
+		receive("counter", event.PostID)
+		return nil
+	})
+	// Fails the first time, which must not cause the event to be delivered to the other subscriber again
+	Subscribe(dispatcher, "flaky", func(ctx context.Context, event PostCreated) error {
+		if receive("flaky", event.PostID) == 1 {
+			return errors.New("try again")
+		}
+		return nil
+	})
+	Subscribe(dispatcher, "cleaner", func(ctx context.Context, event PostDeleted) error {
+		receive("cleaner", event.PostID)
+		return nil
+	})
+
+	// Events without subscribers are dropped
+	if err := record(db, nil, PostCreated{PostID: 1, AuthorID: 1}, PostVoted{PostID: 1, UserID: 2, Value: 1}, PostDeleted{PostID: 1, AuthorID: 1}); err != nil {
+		t.Fatalf("failed to record events: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		runner.Run(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		dispatcher.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	want := map[string][]uint{"counter": {1}, "flaky": {1, 1}, "cleaner": {1}}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		done := len(received["counter"]) == 1 && len(received["flaky"]) == 2 && len(received["cleaner"]) == 1
+		mu.Unlock()
+		if done {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	// Give duplicate deliveries a chance to show up
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	for subscriber, postIDs := range want {
+		if fmt.Sprint(received[subscriber]) != fmt.Sprint(postIDs) {
+			t.Errorf("expected %s to receive %v, got %v", subscriber, postIDs, received[subscriber])
+		}
+	}
+
+	var remaining int64
+	if err := db.Model(&models.OutboxEvent{}).Count(&remaining).Error; err != nil {
+		t.Fatalf("failed to count events: %v", err)
+	}
+	if remaining != 0 {
+		t.Errorf("expected the outbox to be empty, got %d events", remaining)
+	}
+}
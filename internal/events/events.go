@@ -0,0 +1,86 @@
+package events
+
+import (
+	"cvwo-backend/internal/models"
+	"encoding/json"
+	"fmt"
+)
+
+// A domain event, describing a change that other parts of the application may react to
+// Events are recorded in the outbox in the same transaction as the change, and dispatched to subscribers once it commits.
+type Event interface {
+	// Name of the event, under which it is stored in the outbox and subscribed to
+	EventType() string
+}
+
+// A post was published, either when it was created or later as a draft or scheduled post
+type PostCreated struct {
+	PostID   uint `json:"post_id"`
+	AuthorID uint `json:"author_id"`
+}
+
+func (PostCreated) EventType() string { return "post.created" }
+
+// A post was deleted by its author
+type PostDeleted struct {
+	PostID   uint `json:"post_id"`
+	AuthorID uint `json:"author_id"`
+}
+
+func (PostDeleted) EventType() string { return "post.deleted" }
+
+// A post's title and content were edited by its author
+type PostEdited struct {
+	PostID  uint `json:"post_id"`
+	Version uint `json:"version"`
+}
+
+func (PostEdited) EventType() string { return "post.edited" }
+
+// A post's topics were replaced
+type PostTagged struct {
+	PostID   uint   `json:"post_id"`
+	TopicIDs []uint `json:"topic_ids"`
+}
+
+func (PostTagged) EventType() string { return "post.tagged" }
+
+// A user cast, changed or removed (value 0) their vote on a post
+type PostVoted struct {
+	PostID uint `json:"post_id"`
+	UserID uint `json:"user_id"`
+	Value  int  `json:"value"`
+}
+
+func (PostVoted) EventType() string { return "post.voted" }
+
+// A comment was created on a post
+type CommentCreated struct {
+	CommentID uint `json:"comment_id"`
+	PostID    uint `json:"post_id"`
+	AuthorID  uint `json:"author_id"`
+}
+
+func (CommentCreated) EventType() string { return "comment.created" }
+
+// A user cast, changed or removed (value 0) their vote on a comment
+type CommentVoted struct {
+	CommentID uint `json:"comment_id"`
+	UserID    uint `json:"user_id"`
+	Value     int  `json:"value"`
+}
+
+func (CommentVoted) EventType() string { return "comment.voted" }
+
+// Encode events as outbox records
+func Encode(events ...Event) ([]models.OutboxEvent, error) {
+	records := make([]models.OutboxEvent, 0, len(events))
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s event: %w", event.EventType(), err)
+		}
+		records = append(records, models.OutboxEvent{Type: event.EventType(), Payload: string(payload)})
+	}
+	return records, nil
+}
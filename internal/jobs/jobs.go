@@ -0,0 +1,430 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"cvwo-backend/internal/metrics"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/tracing"
+)
+
+// Number of times a job is attempted before it is dead-lettered, unless the job sets its own maximum
+const DefaultMaxAttempts = 5
+
+// Settings of a runner. Zero values are replaced by the corresponding value in DefaultOptions.
+type Options struct {
+	// Number of jobs run concurrently
+	Workers int
+	// How often idle workers check for new jobs
+	PollInterval time.Duration
+	// How long a job may run before it is assumed to have been abandoned, e.g. because its instance crashed, and is run again
+	LockTimeout time.Duration
+	// Delay before the first retry of a failed job, doubling with every further attempt up to MaxBackoff
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// How long running jobs are given to finish on shutdown before their context is cancelled
+	DrainTimeout time.Duration
+	// How long succeeded jobs are kept before they are deleted
+	Retention time.Duration
+}
+
+var DefaultOptions = Options{
+	Workers:      4,
+	PollInterval: time.Second,
+	LockTimeout:  10 * time.Minute,
+	BaseBackoff:  10 * time.Second,
+	MaxBackoff:   time.Hour,
+	DrainTimeout: 30 * time.Second,
+	Retention:    24 * time.Hour,
+}
+
+// A job to enqueue
+type Job struct {
+	Type string
+	// Argument of the job's handler, encoded as JSON
+	Payload any
+	// When to run the job, or as soon as possible if zero
+	RunAt time.Time
+	// If set, the job is not enqueued when a job with the same key already exists
+	Key string
+	// Number of attempts before the job is dead-lettered, or DefaultMaxAttempts if zero
+	MaxAttempts int
+}
+
+// Add a job to the queue
+// Pass a transaction as db to enqueue the job only if the transaction commits
+func Enqueue(ctx context.Context, db *gorm.DB, job Job) error {
+	payload, err := json.Marshal(job.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload of %s job: %w", job.Type, err)
+	}
+
+	record := models.Job{
+		Type:        job.Type,
+		Payload:     string(payload),
+		Status:      models.JobStatusPending,
+		RunAt:       job.RunAt,
+		MaxAttempts: job.MaxAttempts,
+	}
+	if record.RunAt.IsZero() {
+		record.RunAt = time.Now()
+	}
+	if record.MaxAttempts < 1 {
+		record.MaxAttempts = DefaultMaxAttempts
+	}
+	if job.Key != "" {
+		record.Key = &job.Key
+	}
+	return db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&record).Error
+}
+
+// Error that marks a job as failed without retrying it, e.g. because its payload is invalid
+type permanentError struct {
+	err error
+}
+
+func (err permanentError) Error() string {
+	return err.err.Error()
+}
+
+func (err permanentError) Unwrap() error {
+	return err.err
+}
+
+// Wrap an error returned by a handler so that the job is dead-lettered immediately instead of retried
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+type handler func(ctx context.Context, payload []byte) error
+
+type recurringJob struct {
+	schedule Schedule
+	jobType  string
+	payload  any
+}
+
+// Runs jobs from the jobs table with a pool of workers
+// Any number of runners, in any number of instances, can share the same table
+type Runner struct {
+	db        *gorm.DB
+	options   Options
+	handlers  map[string]handler
+	recurring []recurringJob
+	now       func() time.Time
+}
+
+func NewRunner(db *gorm.DB, options Options) *Runner {
+	if options.Workers < 1 {
+		options.Workers = DefaultOptions.Workers
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = DefaultOptions.PollInterval
+	}
+	if options.LockTimeout <= 0 {
+		options.LockTimeout = DefaultOptions.LockTimeout
+	}
+	if options.BaseBackoff <= 0 {
+		options.BaseBackoff = DefaultOptions.BaseBackoff
+	}
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = DefaultOptions.MaxBackoff
+	}
+	if options.DrainTimeout <= 0 {
+		options.DrainTimeout = DefaultOptions.DrainTimeout
+	}
+	if options.Retention <= 0 {
+		options.Retention = DefaultOptions.Retention
+	}
+	return &Runner{
+		db:       db,
+		options:  options,
+		handlers: make(map[string]handler),
+		now:      time.Now,
+	}
+}
+
+// Register the handler of a job type, which receives the job's payload decoded into T
+// Must be called before the runner is started. Panics if the job type already has a handler.
+func Handle[T any](runner *Runner, jobType string, handle func(ctx context.Context, payload T) error) {
+	if _, exists := runner.handlers[jobType]; exists {
+		panic(fmt.Sprintf("jobs: handler for %s registered twice", jobType))
+	}
+	runner.handlers[jobType] = func(ctx context.Context, payload []byte) error {
+		var value T
+		if err := json.Unmarshal(payload, &value); err != nil {
+			return Permanent(fmt.Errorf("invalid payload: %w", err))
+		}
+		return handle(ctx, value)
+	}
+}
+
+// Enqueue a job of the given type with the given payload whenever the schedule is due, while the runner is running
+// The spec is parsed by ParseSchedule. Must be called before the runner is started, after the job type's handler is registered.
+// Each run is keyed by its time, so runners in several instances do not enqueue it more than once.
+func (runner *Runner) Schedule(spec string, jobType string, payload any) error {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	if _, exists := runner.handlers[jobType]; !exists {
+		return fmt.Errorf("no handler registered for %s", jobType)
+	}
+	runner.recurring = append(runner.recurring, recurringJob{schedule, jobType, payload})
+	return nil
+}
+
+// Run jobs until the context is cancelled, then wait for running jobs to finish
+// Running jobs are given DrainTimeout to finish, after which their context is cancelled.
+func (runner *Runner) Run(ctx context.Context) {
+	// Jobs outlive the runner's context so that they can finish while draining
+	jobCtx, cancelJobs := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelJobs()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-jobCtx.Done():
+			return
+		}
+		timer := time.NewTimer(runner.options.DrainTimeout)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancelJobs()
+		case <-jobCtx.Done():
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range runner.options.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runner.work(ctx, jobCtx)
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		runner.schedule(ctx)
+	}()
+	wg.Wait()
+}
+
+// Claim and run jobs one at a time until the context is cancelled, polling while there are none
+func (runner *Runner) work(ctx context.Context, jobCtx context.Context) {
+	for ctx.Err() == nil {
+		job, err := runner.claim(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to claim job: %v", err)
+		}
+		if job != nil {
+			runner.process(jobCtx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(runner.options.PollInterval):
+		}
+	}
+}
+
+// Enqueue recurring jobs when they are due and delete old succeeded jobs, until the context is cancelled
+func (runner *Runner) schedule(ctx context.Context) {
+	now := runner.now()
+	next := make([]time.Time, len(runner.recurring))
+	for i, recurring := range runner.recurring {
+		next[i] = recurring.schedule.Next(now)
+	}
+	runner.prune(ctx)
+	lastPruned := now
+
+	for {
+		wake := now.Add(runner.options.PollInterval)
+		for _, at := range next {
+			if !at.IsZero() && at.Before(wake) {
+				wake = at
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wake.Sub(now)):
+		}
+
+		now = runner.now()
+		for i, recurring := range runner.recurring {
+			if next[i].IsZero() || next[i].After(now) {
+				continue
+			}
+			job := Job{
+				Type:    recurring.jobType,
+				Payload: recurring.payload,
+				RunAt:   next[i],
+				Key:     fmt.Sprintf("cron:%s:%s", recurring.jobType, next[i].UTC().Format(time.RFC3339)),
+			}
+			if err := Enqueue(ctx, runner.db, job); err != nil {
+				log.Printf("Failed to enqueue %s job: %v", recurring.jobType, err)
+				continue
+			}
+			// Skip runs that were missed, e.g. while the machine was asleep
+			next[i] = recurring.schedule.Next(now)
+		}
+		if now.Sub(lastPruned) >= time.Hour {
+			runner.prune(ctx)
+			lastPruned = now
+		}
+	}
+}
+
+// Delete succeeded jobs older than the retention period
+func (runner *Runner) prune(ctx context.Context) {
+	err := runner.db.WithContext(ctx).
+		Where("status = ? AND updated_at < ?", models.JobStatusSucceeded, runner.now().Add(-runner.options.Retention)).
+		Delete(&models.Job{}).Error
+	if err != nil && ctx.Err() == nil {
+		log.Printf("Failed to delete old jobs: %v", err)
+	}
+}
+
+// Claim the job that has been due the longest, if any, by marking it as running
+// Running jobs whose lock has expired are claimed again. Only jobs with a registered handler are claimed,
+// so that instances running different versions can share the table.
+func (runner *Runner) claim(ctx context.Context) (*models.Job, error) {
+	now := runner.now()
+	jobTypes := make([]string, 0, len(runner.handlers))
+	for jobType := range runner.handlers {
+		jobTypes = append(jobTypes, jobType)
+	}
+	claimable := runner.db.Where("type IN ?", jobTypes).Where(
+		runner.db.Where("status = ? AND run_at <= ?", models.JobStatusPending, now).
+			Or("status = ? AND locked_at < ?", models.JobStatusRunning, now.Add(-runner.options.LockTimeout)),
+	)
+
+	// Postgres can skip rows locked by other workers, so that workers never wait on each other
+	if runner.db.Dialector.Name() == "postgres" {
+		var job models.Job
+		err := runner.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where(claimable).Order("run_at").Take(&job).Error
+			if err != nil {
+				return err
+			}
+			return tx.Model(&job).Updates(map[string]any{"status": models.JobStatusRunning, "attempts": job.Attempts + 1, "locked_at": now}).Error
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &job, nil
+	}
+
+	// Other databases, i.e. SQLite, have no row locks, so claim the job with a conditional update instead
+	// If another worker claimed it first, nothing is updated and the next job is tried
+	for range 3 {
+		var job models.Job
+		err := runner.db.WithContext(ctx).Where(claimable).Order("run_at").Take(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		result := runner.db.WithContext(ctx).Model(&models.Job{}).
+			Where("id = ? AND status = ? AND attempts = ?", job.ID, job.Status, job.Attempts).
+			Updates(map[string]any{"status": models.JobStatusRunning, "attempts": job.Attempts + 1, "locked_at": now})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			job.Status = models.JobStatusRunning
+			job.Attempts++
+			job.LockedAt = &now
+			return &job, nil
+		}
+	}
+	return nil, nil
+}
+
+// Run a claimed job and record the outcome
+func (runner *Runner) process(ctx context.Context, job *models.Job) {
+	ctx, span := tracing.Tracer.Start(ctx, "Runner.process")
+	defer span.End()
+	span.SetAttributes(attribute.String("job.type", job.Type), attribute.Int("job.attempt", job.Attempts))
+
+	var err error
+	if job.Attempts > job.MaxAttempts {
+		// The job was abandoned on its last attempt
+		err = Permanent(errors.New("job did not finish before its lock expired"))
+	} else {
+		start := time.Now()
+		err = runner.run(ctx, job)
+		metrics.JobDuration.WithLabelValues(job.Type).Observe(time.Since(start).Seconds())
+	}
+
+	// Only update the job if it has not been claimed again in the meantime
+	current := runner.db.WithContext(context.WithoutCancel(ctx)).Model(&models.Job{}).Where("id = ? AND attempts = ?", job.ID, job.Attempts)
+	if err == nil {
+		metrics.JobsProcessed.WithLabelValues(job.Type, "succeeded").Inc()
+		if err := current.Updates(map[string]any{"status": models.JobStatusSucceeded, "locked_at": nil, "last_error": ""}).Error; err != nil {
+			log.Printf("Failed to mark %s job %d as succeeded: %v", job.Type, job.ID, err)
+		}
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	var permanent permanentError
+	if errors.As(err, &permanent) || job.Attempts >= job.MaxAttempts {
+		metrics.JobsProcessed.WithLabelValues(job.Type, "dead").Inc()
+		log.Printf("Job %d (%s) failed on attempt %d and was dead-lettered: %v", job.ID, job.Type, job.Attempts, err)
+		if err := current.Updates(map[string]any{"status": models.JobStatusDead, "locked_at": nil, "last_error": err.Error()}).Error; err != nil {
+			log.Printf("Failed to mark %s job %d as dead: %v", job.Type, job.ID, err)
+		}
+		return
+	}
+
+	metrics.JobsProcessed.WithLabelValues(job.Type, "retried").Inc()
+	runAt := runner.now().Add(runner.backoff(job.Attempts))
+	log.Printf("Job %d (%s) failed on attempt %d and will be retried at %s: %v", job.ID, job.Type, job.Attempts, runAt.Format(time.RFC3339), err)
+	if err := current.Updates(map[string]any{"status": models.JobStatusPending, "run_at": runAt, "locked_at": nil, "last_error": err.Error()}).Error; err != nil {
+		log.Printf("Failed to reschedule %s job %d: %v", job.Type, job.ID, err)
+	}
+}
+
+// Call the job's handler, turning panics into errors
+func (runner *Runner) run(ctx context.Context, job *models.Job) (err error) {
+	handle, exists := runner.handlers[job.Type]
+	if !exists {
+		return Permanent(fmt.Errorf("no handler registered for %s", job.Type))
+	}
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return handle(ctx, []byte(job.Payload))
+}
+
+// Delay before retrying a job that failed on the given attempt
+func (runner *Runner) backoff(attempt int) time.Duration {
+	delay := runner.options.BaseBackoff
+	for i := 1; i < attempt && delay < runner.options.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, runner.options.MaxBackoff)
+}
@@ -0,0 +1,386 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"cvwo-backend/internal/models"
+)
+
+// Open an in-memory SQLite database with the jobs table
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{TranslateError: true})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get database handle: %v", err)
+	}
+	// SQLite allows a single writer at a time
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&models.Job{}); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return db
+}
+
+// Options that make jobs run and retry quickly
+var testOptions = Options{PollInterval: 5 * time.Millisecond, BaseBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+// Run the runner until the test ends
+func start(t *testing.T, runner *Runner) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func enqueue(t *testing.T, db *gorm.DB, job Job) {
+	t.Helper()
+
+	if err := Enqueue(context.Background(), db, job); err != nil {
+		t.Fatalf("failed to enqueue job: %v", err)
+	}
+}
+
+// Wait until every job is succeeded or dead, and return them in the order they were enqueued
+func waitForJobs(t *testing.T, db *gorm.DB) []models.Job {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var jobs []models.Job
+		if err := db.Order("id").Find(&jobs).Error; err != nil {
+			t.Fatalf("failed to get jobs: %v", err)
+		}
+		finished := true
+		for _, job := range jobs {
+			finished = finished && (job.Status == models.JobStatusSucceeded || job.Status == models.JobStatusDead)
+		}
+		if finished {
+			return jobs
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for jobs, got %+v", jobs)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+type greeting struct {
+	Name string `json:"name"`
+}
+
+func TestRunJobs(t *testing.T) {
+	db := newTestDB(t)
+	runner := NewRunner(db, testOptions)
+
+	var mu sync.Mutex
+	greeted := map[string]int{}
+	Handle(runner, "greet", func(ctx context.Context, payload greeting) error {
+		mu.Lock()
+		defer mu.Unlock()
+		greeted[payload.Name]++
+		return nil
+	})
+
+	for i := range 20 {
+		enqueue(t, db, Job{Type: "greet", Payload: greeting{fmt.Sprintf("user%d", i)}})
+	}
+	// Jobs with the same key are only enqueued once
+	enqueue(t, db, Job{Type: "greet", Payload: greeting{"alice"}, Key: "alice"})
+	enqueue(t, db, Job{Type: "greet", Payload: greeting{"alice"}, Key: "alice"})
+	// Jobs are not run before they are due
+	enqueue(t, db, Job{Type: "greet", Payload: greeting{"later"}, RunAt: time.Now().Add(time.Hour)})
+	start(t, runner)
+
+	deadline := time.Now().Add(5 * time.Second)
+	var succeeded int64
+	for succeeded < 21 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		db.Model(&models.Job{}).Where("status = ?", models.JobStatusSucceeded).Count(&succeeded)
+	}
+	if succeeded != 21 {
+		t.Fatalf("expected 21 jobs to succeed, got %d", succeeded)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i := range 20 {
+		if name := fmt.Sprintf("user%d", i); greeted[name] != 1 {
+			t.Errorf("expected %s to be greeted once, got %d", name, greeted[name])
+		}
+	}
+	if greeted["alice"] != 1 || greeted["later"] != 0 {
+		t.Errorf("expected alice to be greeted once and later not at all, got %v", greeted)
+	}
+}
+
+func TestRetryJobs(t *testing.T) {
+	db := newTestDB(t)
+	runner := NewRunner(db, testOptions)
+
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	attempt := func(name string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[name]++
+		return attempts[name]
+	}
+	Handle(runner, "flaky", func(ctx context.Context, name string) error {
+		if attempt(name) < 3 {
+			return errors.New("try again")
+		}
+		return nil
+	})
+	Handle(runner, "broken", func(ctx context.Context, name string) error {
+		attempt(name)
+		return errors.New("always fails")
+	})
+	Handle(runner, "hopeless", func(ctx context.Context, name string) error {
+		attempt(name)
+		return Permanent(errors.New("never going to work"))
+	})
+	Handle(runner, "panics", func(ctx context.Context, name string) error {
+		if attempt(name) < 2 {
+			panic("oops")
+		}
+		return nil
+	})
+
+	enqueue(t, db, Job{Type: "flaky", Payload: "flaky"})
+	enqueue(t, db, Job{Type: "broken", Payload: "broken", MaxAttempts: 4})
+	enqueue(t, db, Job{Type: "hopeless", Payload: "hopeless"})
+	enqueue(t, db, Job{Type: "panics", Payload: "panics"})
+	// Payloads that do not decode are not retried
+	enqueue(t, db, Job{Type: "flaky", Payload: 42})
+	start(t, runner)
+
+	tests := []struct {
+		name         string
+		wantStatus   string
+		wantAttempts int
+		wantError    string
+	}{
+		{"succeeds on the third attempt", models.JobStatusSucceeded, 3, ""},
+		{"dead-lettered after every attempt fails", models.JobStatusDead, 4, "always fails"},
+		{"dead-lettered after a permanent failure", models.JobStatusDead, 1, "never going to work"},
+		{"retried after a panic", models.JobStatusSucceeded, 2, ""},
+		{"dead-lettered with an invalid payload", models.JobStatusDead, 1, "invalid payload"},
+	}
+
+	jobs := waitForJobs(t, db)
+	if len(jobs) != len(tests) {
+		t.Fatalf("expected %d jobs, got %d", len(tests), len(jobs))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := jobs[i]
+			if job.Status != tt.wantStatus || job.Attempts != tt.wantAttempts || !strings.Contains(job.LastError, tt.wantError) {
+				t.Errorf("expected status %s after %d attempts with error %q, got %+v", tt.wantStatus, tt.wantAttempts, tt.wantError, job)
+			}
+		})
+	}
+}
+
+// Jobs whose worker disappeared are run again once their lock expires
+func TestReclaimAbandonedJobs(t *testing.T) {
+	db := newTestDB(t)
+	runner := NewRunner(db, Options{PollInterval: 5 * time.Millisecond, LockTimeout: time.Minute})
+	Handle(runner, "noop", func(ctx context.Context, _ struct{}) error { return nil })
+
+	expired, recent := time.Now().Add(-time.Hour), time.Now()
+	abandoned := []models.Job{
+		{Type: "noop", Payload: "{}", Status: models.JobStatusRunning, RunAt: expired, Attempts: 1, MaxAttempts: 3, LockedAt: &expired},
+		{Type: "noop", Payload: "{}", Status: models.JobStatusRunning, RunAt: expired, Attempts: 3, MaxAttempts: 3, LockedAt: &expired},
+	}
+	if err := db.Create(&abandoned).Error; err != nil {
+		t.Fatalf("failed to create jobs: %v", err)
+	}
+	running := models.Job{Type: "noop", Payload: "{}", Status: models.JobStatusRunning, RunAt: expired, Attempts: 1, MaxAttempts: 3, LockedAt: &recent}
+	if err := db.Create(&running).Error; err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	start(t, runner)
+
+	deadline := time.Now().Add(5 * time.Second)
+	var jobs []models.Job
+	for time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		if err := db.Order("id").Find(&jobs).Error; err != nil {
+			t.Fatalf("failed to get jobs: %v", err)
+		}
+		if jobs[0].Status != models.JobStatusRunning && jobs[1].Status != models.JobStatusRunning {
+			break
+		}
+	}
+	if jobs[0].Status != models.JobStatusSucceeded || jobs[0].Attempts != 2 {
+		t.Errorf("expected the abandoned job to be run again, got %+v", jobs[0])
+	}
+	// The job was abandoned on its last attempt
+	if jobs[1].Status != models.JobStatusDead {
+		t.Errorf("expected the job abandoned on its last attempt to be dead, got %+v", jobs[1])
+	}
+	if jobs[2].Status != models.JobStatusRunning || jobs[2].Attempts != 1 {
+		t.Errorf("expected the job that is still running to be left alone, got %+v", jobs[2])
+	}
+}
+
+func TestRecurringJobs(t *testing.T) {
+	db := newTestDB(t)
+	runner := NewRunner(db, testOptions)
+
+	ticks := make(chan string, 100)
+	Handle(runner, "tick", func(ctx context.Context, payload string) error {
+		ticks <- payload
+		return nil
+	})
+	if err := runner.Schedule("@every 20ms", "tick", "tock"); err != nil {
+		t.Fatalf("failed to schedule job: %v", err)
+	}
+	if err := runner.Schedule("@every 20ms", "untick", nil); err == nil {
+		t.Errorf("expected scheduling a job without a handler to fail")
+	}
+	if err := runner.Schedule("every minute", "tick", nil); err == nil {
+		t.Errorf("expected an invalid schedule to fail")
+	}
+	start(t, runner)
+
+	for range 3 {
+		select {
+		case payload := <-ticks:
+			if payload != "tock" {
+				t.Errorf("expected payload tock, got %q", payload)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the recurring job")
+		}
+	}
+}
+
+// Several runners, e.g. in different instances, enqueue each run of a recurring job once
+func TestRecurringJobsAcrossRunners(t *testing.T) {
+	db := newTestDB(t)
+	// The run at 10:01 is due shortly after the runners start
+	base, started := time.Date(2024, time.January, 10, 10, 0, 59, 990_000_000, time.UTC), time.Now()
+	clock := func() time.Time { return base.Add(time.Since(started)) }
+	for range 3 {
+		runner := NewRunner(db, testOptions)
+		Handle(runner, "tick", func(ctx context.Context, _ struct{}) error { return nil })
+		if err := runner.Schedule("* * * * *", "tick", nil); err != nil {
+			t.Fatalf("failed to schedule job: %v", err)
+		}
+		runner.now = clock
+		start(t, runner)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	var count int64
+	for count == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		db.Model(&models.Job{}).Count(&count)
+	}
+	time.Sleep(50 * time.Millisecond)
+	db.Model(&models.Job{}).Count(&count)
+	if count != 1 {
+		t.Errorf("expected the run to be enqueued once, got %d jobs", count)
+	}
+}
+
+// Shutting down waits for running jobs, and cancels them if they take longer than the drain timeout
+func TestDrain(t *testing.T) {
+	tests := []struct {
+		name          string
+		jobDuration   time.Duration
+		wantCancelled bool
+	}{
+		{"finishes in time", 20 * time.Millisecond, false},
+		{"takes too long", time.Hour, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			options := testOptions
+			options.DrainTimeout = 100 * time.Millisecond
+			runner := NewRunner(db, options)
+
+			started := make(chan struct{})
+			var cancelled bool
+			Handle(runner, "slow", func(ctx context.Context, _ struct{}) error {
+				close(started)
+				select {
+				case <-time.After(tt.jobDuration):
+				case <-ctx.Done():
+					cancelled = true
+				}
+				return ctx.Err()
+			})
+			enqueue(t, db, Job{Type: "slow"})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				runner.Run(ctx)
+				close(done)
+			}()
+			<-started
+			cancel()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out waiting for the runner to stop")
+			}
+
+			if cancelled != tt.wantCancelled {
+				t.Errorf("expected cancelled to be %v, got %v", tt.wantCancelled, cancelled)
+			}
+			var job models.Job
+			if err := db.First(&job).Error; err != nil {
+				t.Fatalf("failed to get job: %v", err)
+			}
+			wantStatus := models.JobStatusSucceeded
+			if tt.wantCancelled {
+				wantStatus = models.JobStatusPending
+			}
+			if job.Status != wantStatus {
+				t.Errorf("expected status %s, got %+v", wantStatus, job)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	runner := NewRunner(nil, Options{BaseBackoff: time.Second, MaxBackoff: 10 * time.Second})
+	for attempt, want := range []time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		if attempt == 0 {
+			continue
+		}
+		if got := runner.backoff(attempt); got != want {
+			t.Errorf("expected backoff %s after attempt %d, got %s", want, attempt, got)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// When a recurring job runs
+type Schedule interface {
+	// Returns the first time strictly after the given time that the job should run
+	Next(after time.Time) time.Time
+}
+
+// Shorthands for common cron expressions
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse a schedule, which is either a standard 5-field cron expression ("minute hour day-of-month month day-of-week"),
+// one of the macros such as "@hourly" or "@daily", or "@every <duration>" such as "@every 30s"
+// Fields support "*", single values, ranges ("1-5"), steps ("*/15", "0-30/10") and comma-separated lists of these.
+// Day of week is 0-7 where both 0 and 7 are Sunday. Names of months and days are not supported.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid schedule %q: interval must be a positive duration", spec)
+		}
+		return every(interval), nil
+	}
+	if expanded, ok := macros[spec]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields", spec)
+	}
+	var schedule cronSchedule
+	var err error
+	if schedule.minutes, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid minute in schedule %q: %w", spec, err)
+	}
+	if schedule.hours, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid hour in schedule %q: %w", spec, err)
+	}
+	if schedule.days, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid day of month in schedule %q: %w", spec, err)
+	}
+	if schedule.months, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid month in schedule %q: %w", spec, err)
+	}
+	if schedule.weekdays, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid day of week in schedule %q: %w", spec, err)
+	}
+	// 7 is another name for Sunday
+	if schedule.weekdays&(1<<7) != 0 {
+		schedule.weekdays |= 1
+	}
+	schedule.anyDay = fields[2] == "*"
+	schedule.anyWeekday = fields[4] == "*"
+	return schedule, nil
+}
+
+// Runs at a fixed interval
+type every time.Duration
+
+func (interval every) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(interval))
+}
+
+// Runs at the times matching a cron expression, in the location of the time passed to Next
+// Each field is a bitset of the values it matches
+type cronSchedule struct {
+	minutes, hours, days, months, weekdays uint64
+	// Whether the day of month and day of week fields are "*"
+	anyDay, anyWeekday bool
+}
+
+func (schedule cronSchedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	// Every schedule matches at least once every few years, e.g. on February 29th, so give up after that
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if schedule.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !schedule.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if schedule.hours&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if schedule.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// Like cron, when both the day of month and day of week are restricted, a day matching either of them matches
+func (schedule cronSchedule) matchesDay(t time.Time) bool {
+	day := schedule.days&(1<<uint(t.Day())) != 0
+	weekday := schedule.weekdays&(1<<uint(t.Weekday())) != 0
+	if schedule.anyDay || schedule.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+// Parse a comma-separated list of values, ranges and steps into a bitset of the values between min and max it matches
+func parseField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		start, end := min, max
+		if rangePart != "*" {
+			low, high, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = strconv.Atoi(low); err != nil {
+				return 0, fmt.Errorf("invalid value %q", low)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(high); err != nil {
+					return 0, fmt.Errorf("invalid value %q", high)
+				}
+			} else if hasStep {
+				// "5/15" means every 15 starting from 5
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+
+		for value := start; value <= end; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
@@ -0,0 +1,125 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	// A Wednesday
+	start := time.Date(2024, time.January, 10, 10, 17, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want []time.Time
+	}{
+		{"* * * * *", []time.Time{
+			time.Date(2024, time.January, 10, 10, 18, 0, 0, time.UTC),
+			time.Date(2024, time.January, 10, 10, 19, 0, 0, time.UTC),
+		}},
+		{"*/15 * * * *", []time.Time{
+			time.Date(2024, time.January, 10, 10, 30, 0, 0, time.UTC),
+			time.Date(2024, time.January, 10, 10, 45, 0, 0, time.UTC),
+			time.Date(2024, time.January, 10, 11, 0, 0, 0, time.UTC),
+		}},
+		{"5,10-12 9 * * *", []time.Time{
+			time.Date(2024, time.January, 11, 9, 5, 0, 0, time.UTC),
+			time.Date(2024, time.January, 11, 9, 10, 0, 0, time.UTC),
+			time.Date(2024, time.January, 11, 9, 11, 0, 0, time.UTC),
+			time.Date(2024, time.January, 11, 9, 12, 0, 0, time.UTC),
+			time.Date(2024, time.January, 12, 9, 5, 0, 0, time.UTC),
+		}},
+		{"0 0-12/6 * * *", []time.Time{
+			time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC),
+			time.Date(2024, time.January, 11, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, time.January, 11, 6, 0, 0, 0, time.UTC),
+		}},
+		{"30 8 * * 1-5", []time.Time{
+			time.Date(2024, time.January, 11, 8, 30, 0, 0, time.UTC),
+			time.Date(2024, time.January, 12, 8, 30, 0, 0, time.UTC),
+			time.Date(2024, time.January, 15, 8, 30, 0, 0, time.UTC),
+		}},
+		// 7 is Sunday
+		{"0 0 * * 7", []time.Time{
+			time.Date(2024, time.January, 14, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, time.January, 21, 0, 0, 0, 0, time.UTC),
+		}},
+		// Either the 1st of the month or a Friday
+		{"0 0 1 * 5", []time.Time{
+			time.Date(2024, time.January, 12, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, time.January, 19, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, time.January, 26, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, time.February, 2, 0, 0, 0, 0, time.UTC),
+		}},
+		{"0 12 29 2 *", []time.Time{
+			time.Date(2024, time.February, 29, 12, 0, 0, 0, time.UTC),
+			time.Date(2028, time.February, 29, 12, 0, 0, 0, time.UTC),
+		}},
+		{"@hourly", []time.Time{
+			time.Date(2024, time.January, 10, 11, 0, 0, 0, time.UTC),
+			time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC),
+		}},
+		{"@monthly", []time.Time{
+			time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		}},
+		{"@every 90s", []time.Time{
+			time.Date(2024, time.January, 10, 10, 19, 0, 0, time.UTC),
+			time.Date(2024, time.January, 10, 10, 20, 30, 0, time.UTC),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.spec)
+			if err != nil {
+				t.Fatalf("failed to parse schedule: %v", err)
+			}
+			at := start
+			for _, want := range tt.want {
+				at = schedule.Next(at)
+				if !at.Equal(want) {
+					t.Fatalf("expected %s, got %s", want, at)
+				}
+			}
+		})
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@sometimes",
+		"@every",
+		"@every -1m",
+		"@every soon",
+	} {
+		t.Run(spec, func(t *testing.T) {
+			if _, err := ParseSchedule(spec); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+// Schedules that can never match give up instead of looping forever
+func TestScheduleNeverDue(t *testing.T) {
+	schedule, err := ParseSchedule("0 0 31 2 *")
+	if err != nil {
+		t.Fatalf("failed to parse schedule: %v", err)
+	}
+	if next := schedule.Next(time.Now()); !next.IsZero() {
+		t.Errorf("expected no next time, got %s", next)
+	}
+}
@@ -0,0 +1,41 @@
+// Package markdown renders user-written Markdown to HTML that is safe to embed in pages.
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// CommonMark with GitHub-style tables, strikethrough and autolinks, and ||spoilers||
+// Raw HTML in the source is omitted by goldmark's default (safe) renderer
+var converter = goldmark.New(
+	goldmark.WithExtensions(extension.Table, extension.Strikethrough, extension.Linkify, Spoiler),
+)
+
+// Allowlist of elements and attributes that may appear in rendered content
+// The renderer already drops raw HTML, but sanitizing the output as well guards against anything the renderer lets through, such as javascript: links
+var policy = newPolicy()
+
+func newPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	// Spoilers, which clients hide until clicked
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^spoiler$`)).OnElements("span")
+	// Language of fenced code blocks, for syntax highlighting by clients
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	// Table column alignment
+	policy.AllowStyles("text-align").MatchingEnum("left", "center", "right").OnElements("th", "td")
+	return policy
+}
+
+// Render Markdown source to sanitized HTML
+func Render(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := converter.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	return policy.Sanitize(buf.String()), nil
+}
@@ -0,0 +1,45 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		want    []string // Substrings expected in the output
+		notWant []string // Substrings that must not appear in the output
+	}{
+		{"emphasis", "**bold** and _italic_", []string{"<strong>bold</strong>", "<em>italic</em>"}, nil},
+		{"code fence keeps language", "```go\nx := 1\n```", []string{`<pre><code class="language-go">x := 1`}, nil},
+		{"table with alignment", "| a | b |\n|:-|-:|\n| 1 | 2 |", []string{"<table>", `<th style="text-align: left">a</th>`, `<td style="text-align: right">2</td>`}, nil},
+		{"spoiler", "the butler ||did **it**||", []string{`<span class="spoiler">did <strong>it</strong></span>`}, nil},
+		{"single pipes are text", "a | b || c", []string{"a | b || c"}, []string{"spoiler"}},
+		{"strikethrough", "~~gone~~", []string{"<del>gone</del>"}, nil},
+		{"links are nofollow", "[site](https://example.com)", []string{`href="https://example.com"`, `rel="nofollow"`}, nil},
+		{"script tags are removed", "<script>alert(1)</script>", nil, []string{"<script", "alert"}},
+		{"event handlers are removed", `<img src="x" onerror="alert(1)">`, nil, []string{"onerror"}},
+		{"javascript links are removed", "[click](javascript:alert(1))", []string{"click"}, []string{"javascript:"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := Render(tt.source)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(html, want) {
+					t.Errorf("expected %q in %q", want, html)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(html, notWant) {
+					t.Errorf("expected no %q in %q", notWant, html)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package markdown
+
+import (
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Inline spoiler written as ||hidden text||, rendered as <span class="spoiler">
+// Parsed with delimiters in the same way as ~~strikethrough~~, so spoilers may contain other inline formatting
+type spoilerNode struct {
+	ast.BaseInline
+}
+
+var spoilerKind = ast.NewNodeKind("Spoiler")
+
+func (node *spoilerNode) Kind() ast.NodeKind {
+	return spoilerKind
+}
+
+func (node *spoilerNode) Dump(source []byte, level int) {
+	ast.DumpHelper(node, source, level, nil, nil)
+}
+
+type spoilerDelimiterProcessor struct{}
+
+func (processor *spoilerDelimiterProcessor) IsDelimiter(b byte) bool {
+	return b == '|'
+}
+
+func (processor *spoilerDelimiterProcessor) CanOpenCloser(opener, closer *parser.Delimiter) bool {
+	return opener.Char == closer.Char
+}
+
+func (processor *spoilerDelimiterProcessor) OnMatch(consumes int) ast.Node {
+	return &spoilerNode{}
+}
+
+type spoilerParser struct{}
+
+func (spoilerParser) Trigger() []byte {
+	return []byte{'|'}
+}
+
+func (spoilerParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	before := block.PrecendingCharacter()
+	line, segment := block.PeekLine()
+	delimiter := parser.ScanDelimiter(line, before, 1, &spoilerDelimiterProcessor{})
+	// Only exactly 2 pipes open or close a spoiler
+	if delimiter == nil || delimiter.OriginalLength != 2 || before == '|' {
+		return nil
+	}
+
+	delimiter.Segment = segment.WithStop(segment.Start + delimiter.OriginalLength)
+	block.Advance(delimiter.OriginalLength)
+	pc.PushDelimiter(delimiter)
+	return delimiter
+}
+
+func (spoilerParser) CloseBlock(parent ast.Node, pc parser.Context) {}
+
+type spoilerRenderer struct{}
+
+func (spoilerRenderer) RegisterFuncs(registerer renderer.NodeRendererFuncRegisterer) {
+	registerer.Register(spoilerKind, func(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			_, _ = w.WriteString(`<span class="spoiler">`)
+		} else {
+			_, _ = w.WriteString("</span>")
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+type spoiler struct{}
+
+// Goldmark extension for ||spoilers||
+var Spoiler goldmark.Extender = spoiler{}
+
+func (spoiler) Extend(markdown goldmark.Markdown) {
+	markdown.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(spoilerParser{}, 500)))
+	markdown.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(spoilerRenderer{}, 500)))
+}
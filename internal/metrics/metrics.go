@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Registry that all application metrics are registered to and that is exposed at GET /metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	// Number of HTTP requests handled, labeled by method, route template and response status
+	HTTPRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total number of HTTP requests handled.",
+	}, []string{"method", "route", "status"})
+
+	// Latency of HTTP requests, labeled by method, route template and response status
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// Duration of database queries, labeled by operation (create, query, update, delete, row, raw) and table
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Duration of database queries in seconds.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	// Number of database queries that returned an error, excluding "record not found"
+	DBQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Total number of database queries that failed.",
+	}, []string{"operation", "table"})
+
+	// Domain counters
+	PostsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "forum_posts_created_total",
+		Help: "Total number of posts created.",
+	})
+	CommentsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "forum_comments_created_total",
+		Help: "Total number of comments created.",
+	})
+	MessagesSent = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "forum_messages_sent_total",
+		Help: "Total number of direct messages sent.",
+	})
+	// Labeled by the target of the vote (post or comment) and the vote value (up, down or removed)
+	VotesCast = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "forum_votes_cast_total",
+		Help: "Total number of votes cast on posts and comments.",
+	}, []string{"target", "value"})
+	PollVotesCast = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "forum_poll_votes_cast_total",
+		Help: "Total number of votes cast in polls.",
+	})
+	// Labeled by the reason the login failed
+	LoginFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "forum_login_failures_total",
+		Help: "Total number of failed login attempts.",
+	}, []string{"reason"})
+
+	// Number of background jobs run, labeled by job type and outcome (succeeded, retried or dead)
+	JobsProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "jobs_processed_total",
+		Help: "Total number of background jobs run.",
+	}, []string{"type", "outcome"})
+
+	// Duration of background jobs, labeled by job type
+	JobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "job_duration_seconds",
+		Help:    "Duration of background jobs in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"type"})
+
+	// Number of domain events handed from the outbox to their subscribers
+	EventsDispatched = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "events_dispatched_total",
+		Help: "Total number of domain events dispatched from the outbox.",
+	})
+
+	// Number of lookups of cached listings, labeled by cache namespace (posts or topics) and result (hit, miss or error)
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_requests_total",
+		Help: "Total number of lookups of cached listings.",
+	}, []string{"cache", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestsTotal,
+		HTTPRequestDuration,
+		DBQueryDuration,
+		DBQueryErrors,
+		PostsCreated,
+		CommentsCreated,
+		MessagesSent,
+		VotesCast,
+		PollVotesCast,
+		LoginFailures,
+		JobsProcessed,
+		JobDuration,
+		EventsDispatched,
+		CacheRequests,
+	)
+}
+
+// Label value for a vote, used by VotesCast
+func VoteLabel(value int) string {
+	switch {
+	case value > 0:
+		return "up"
+	case value < 0:
+		return "down"
+	default:
+		return "removed"
+	}
+}
@@ -35,7 +35,7 @@ func (middleware *AuthMiddleware) Authenticate() gin.HandlerFunc {
 		}
 	
 		// Check if token is valid and if so, retrieve the authenticated user
-		user, err := middleware.service.ValidateToken(bearerToken[1])
+		user, err := middleware.service.ValidateToken(ctx.Request.Context(), bearerToken[1])
 		if err != nil {
 			return
 		}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"cvwo-backend/internal/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware to record the count and latency of every request
+// Requests are labeled by route template (e.g. /posts/:post_id) rather than the raw path to keep label cardinality bounded
+func Metrics(ctx *gin.Context) {
+	start := time.Now()
+
+	// Process request
+	ctx.Next()
+
+	route := ctx.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	status := strconv.Itoa(ctx.Writer.Status())
+
+	metrics.HTTPRequestsTotal.WithLabelValues(ctx.Request.Method, route, status).Inc()
+	metrics.HTTPRequestDuration.WithLabelValues(ctx.Request.Method, route, status).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RateLimiter struct {
+	store ratelimit.Store
+	group string
+	limit ratelimit.Limit
+}
+
+// Create a rate limiter for a group of routes. Each group has its own buckets, so being limited in one group does not affect others.
+func NewRateLimiter(store ratelimit.Store, group string, limit ratelimit.Limit) *RateLimiter {
+	return &RateLimiter{store, group, limit}
+}
+
+// Middleware to limit the rate of requests per client within the route group
+// Clients are identified by user ID if authenticated and by IP address otherwise
+func (limiter *RateLimiter) Limit() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := fmt.Sprintf("%s:ip:%s", limiter.group, ctx.ClientIP())
+		if userID := GetUserIDOrZero(ctx); userID != 0 {
+			key = fmt.Sprintf("%s:user:%d", limiter.group, userID)
+		}
+
+		result, err := limiter.store.Take(ctx.Request.Context(), key, limiter.limit)
+		if err != nil {
+			// Fail open so that an unavailable store does not take down the API
+			log.Printf("Rate limit store error: %v", err)
+			ctx.Next()
+			return
+		}
+
+		// Standard rate limit headers, see draft-ietf-httpapi-ratelimit-headers
+		ctx.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		ctx.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+		if !result.Allowed {
+			errs.HTTPErrorResponse(ctx, errs.NewTooManyRequests("Too many requests", result.RetryAfter))
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// Rate limiting middleware for each group of routes
+type RateLimits struct {
+	// Creating, updating and deleting content
+	Writes gin.HandlerFunc
+	// Voting on posts and comments
+	Votes gin.HandlerFunc
+	// Logging in
+	Login gin.HandlerFunc
+}
+
+func ceilSeconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/ratelimit"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Router with a single rate limited route, authenticated as the user with the ID in the X-User-ID header if any
+func newRateLimitedRouter(store ratelimit.Store, limit ratelimit.Limit) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		if ctx.GetHeader("X-User-ID") == "1" {
+			ctx.Set("user", &models.User{ID: 1})
+		}
+	})
+	router.GET("/", NewRateLimiter(store, "test", limit).Limit(), func(ctx *gin.Context) {
+		ctx.Status(http.StatusNoContent)
+	})
+	return router
+}
+
+func get(router *gin.Engine, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestRateLimiterHeaders(t *testing.T) {
+	router := newRateLimitedRouter(ratelimit.NewMemoryStore(), ratelimit.Limit{Requests: 2, Period: time.Minute})
+
+	tests := []struct {
+		wantStatus    int
+		wantRemaining string
+		wantReset     string
+	}{
+		{http.StatusNoContent, "1", "30"},
+		{http.StatusNoContent, "0", "60"},
+		{http.StatusTooManyRequests, "0", "60"},
+	}
+	for i, tt := range tests {
+		res := get(router, nil)
+		if res.Code != tt.wantStatus {
+			t.Fatalf("request %d: expected status %d, got %d", i+1, tt.wantStatus, res.Code)
+		}
+		if got := res.Header().Get("RateLimit-Limit"); got != "2" {
+			t.Errorf("request %d: expected RateLimit-Limit 2, got %q", i+1, got)
+		}
+		if got := res.Header().Get("RateLimit-Remaining"); got != tt.wantRemaining {
+			t.Errorf("request %d: expected RateLimit-Remaining %s, got %q", i+1, tt.wantRemaining, got)
+		}
+		if got := res.Header().Get("RateLimit-Reset"); got != tt.wantReset {
+			t.Errorf("request %d: expected RateLimit-Reset %s, got %q", i+1, tt.wantReset, got)
+		}
+	}
+
+	res := get(router, nil)
+	if got := res.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("expected Retry-After 30, got %q", got)
+	}
+	if got := res.Header().Get("Content-Type"); got != "application/problem+json" {
+		t.Errorf("expected problem details, got %q", got)
+	}
+
+	// Authenticated users have buckets of their own
+	if res := get(router, http.Header{"X-User-Id": {"1"}}); res.Code != http.StatusNoContent || res.Header().Get("Retry-After") != "" {
+		t.Errorf("expected the user not to be limited by the IP address's bucket, got %d", res.Code)
+	}
+}
+
+// Store that is down
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+// Requests are allowed when the store is down
+func TestRateLimiterFailsOpen(t *testing.T) {
+	router := newRateLimitedRouter(failingStore{}, ratelimit.Limit{Requests: 1, Period: time.Minute})
+	for i := 0; i < 2; i++ {
+		if res := get(router, nil); res.Code != http.StatusNoContent || res.Header().Get("RateLimit-Limit") != "" {
+			t.Errorf("expected the request to be allowed without rate limit headers, got %d", res.Code)
+		}
+	}
+}
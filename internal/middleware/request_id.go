@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Header used to correlate a request with its response and logs
+const RequestIDHeader = "X-Request-ID"
+
+// Middleware to assign every request an ID, reusing the client's ID if one was sent
+// The ID is echoed in the response header and included in error responses
+func RequestID(ctx *gin.Context) {
+	requestID := ctx.GetHeader(RequestIDHeader)
+	if requestID == "" || len(requestID) > 64 {
+		requestID = newRequestID()
+	}
+
+	ctx.Set("request_id", requestID)
+	ctx.Header(RequestIDHeader, requestID)
+
+	ctx.Next()
+}
+
+func newRequestID() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(bytes)
+}
@@ -6,26 +6,110 @@ type User struct {
 	ID       uint   `json:"id"`
 	Username string `gorm:"uniqueIndex;not null" json:"username"`
 	Password string `gorm:"not null" json:"-"` // Hashed password, excluded from JSON
+	// Whether the user's votes are shown to others in their activity stream
+	PublicVotes bool `gorm:"not null;default:false" json:"public_votes"`
+	// Whether the user can pin and lock posts. Granted with the MODERATORS environment variable.
+	Moderator bool `gorm:"not null;default:false" json:"moderator"`
+}
+
+// A user together with their follower counts, returned when viewing a user's profile
+type UserProfile struct {
+	User
+	FollowerCount  int64 `json:"follower_count"`
+	FollowingCount int64 `json:"following_count"`
+	// Indicates whether the current user follows this user
+	Followed bool `json:"followed"`
+}
+
+// Request body for updating the current user's settings
+type UserSettings struct {
+	PublicVotes *bool `json:"public_votes" binding:"required"`
+}
+
+// Types of blocks
+const (
+	BlockTypeBlock = "block" // Blocked users cannot reply to or mention the user, and their content is hidden from the user's listings
+	BlockTypeMute  = "mute"  // Muted users' content is hidden from the user's listings
+)
+
+// Record of a user blocking or muting another user
+type Block struct {
+	// Composite primary key using user_id and blocked_user_id
+	UserID        uint      `json:"-" gorm:"primaryKey;autoIncrement:false;"`
+	BlockedUserID uint      `json:"blocked_user_id" gorm:"primaryKey;autoIncrement:false;"`
+	Type          string    `json:"type" gorm:"not null;size:16"` // "block" or "mute"
+	CreatedAt     time.Time `json:"created_at"`
+
+	// When either user is deleted, the block is deleted
+	User        *User `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+	BlockedUser *User `json:"blocked_user,omitempty" gorm:"foreignKey:BlockedUserID;constraint:OnDelete:CASCADE;"`
+}
+
+// Request body for blocking or muting a user
+type BlockUpdate struct {
+	Type string `json:"type" binding:"required,oneof=block mute"`
+}
+
+// Record of a user following another user
+type Follow struct {
+	// Composite primary key using follower_id and followee_id
+	FollowerID uint      `json:"follower_id" gorm:"primaryKey;autoIncrement:false;"`
+	FolloweeID uint      `json:"followee_id" gorm:"primaryKey;autoIncrement:false;"`
+	CreatedAt  time.Time `json:"followed_at"`
+
+	// When either user is deleted, the follow is deleted. Not included in json.
+	Follower *User `json:"-" gorm:"foreignKey:FollowerID;constraint:OnDelete:CASCADE;"`
+	Followee *User `json:"-" gorm:"foreignKey:FolloweeID;constraint:OnDelete:CASCADE;"`
 }
 
 // Request body for login/register
 type AuthInput struct {
-	Username string `binding:"required,max=20"`
-	Password string `binding:"required,min=5,max=20"`
+	Username string `json:"username" binding:"required,max=20"`
+	Password string `json:"password" binding:"required,min=5,max=20"`
 }
 
+// Types of posts
+const (
+	PostTypeText = "text" // Title and content only
+	PostTypePoll = "poll" // Also carries a poll
+)
+
+// Statuses of posts. Draft and scheduled posts are only visible to their author.
+const (
+	PostStatusDraft     = "draft"
+	PostStatusScheduled = "scheduled" // Published automatically at its publish_at time
+	PostStatusPublished = "published"
+)
+
 type Post struct {
-	ID        uint      `json:"id"`
-	Title     string    `json:"title" gorm:"not null" `
-	Content   string    `json:"content" gorm:"not null"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	AuthorID  uint      `json:"author_id"`
+	ID          uint      `json:"id"`
+	Type        string    `json:"type" gorm:"not null;size:16;default:'text'"` // "text" or "poll"
+	Title       string    `json:"title" gorm:"not null" `
+	Content     string    `json:"content" gorm:"not null"`                 // Markdown source
+	ContentHTML string    `json:"content_html" gorm:"not null;default:''"` // Sanitized HTML rendered from Content
+	CreatedAt   time.Time `json:"created_at"`                              // Time the post was published, for posts that were drafts or scheduled
+	UpdatedAt   time.Time `json:"updated_at"`
+	AuthorID    uint      `json:"author_id"`
+	// Incremented by each edit of the title or content, to detect conflicting edits
+	Version uint `json:"version" gorm:"not null;default:1"`
+	// "draft", "scheduled" or "published"
+	Status string `json:"status" gorm:"not null;size:16;default:'published';index"`
+	// Time a scheduled post is to be published. Null for posts that were never scheduled.
+	PublishAt *time.Time `json:"publish_at"`
+	// Position of the post among posts pinned to the top of GET /posts, lowest first. Null if the post is not pinned globally.
+	PinPosition *int `json:"pin_position" gorm:"index"`
+	// Locked posts cannot be commented on or voted on
+	Locked bool `json:"locked" gorm:"not null;default:false"`
 	// One post has one author (user).
 	// If the associated user is deleted, set the author field to null
 	Author *User `json:"author" gorm:"constraint:OnDelete:SET NULL;"`
 	// Implicitly create a many2many join table between posts and topics. When a post is deleted, the post_topic record in the join table is deleted. The associated topics themselves are not deleted.
 	Topics []Topic `json:"topics" gorm:"many2many:post_topics;constraint:OnDelete:CASCADE;"`
+	// Files attached to the post. When the post is deleted, the attachment records are deleted.
+	Attachments []Attachment `json:"attachments" gorm:"constraint:OnDelete:CASCADE;"`
+	// The poll carried by a poll post. When the post is deleted, the poll is deleted.
+	// Only included when getting an individual post
+	Poll *Poll `json:"poll,omitempty" gorm:"constraint:OnDelete:CASCADE;"`
 	// Array of votes associated with this post. Not included in json.
 	Votes []PostVote `json:"-" gorm:"constraint:OnDelete:CASCADE;"` // When this post is deleted, the associated comments are deleted.
 
@@ -36,19 +120,133 @@ type Post struct {
 	// Indicates whether the current user has upvoted (1), downvoted (-1) or not voted (0) the post
 	// Computed field, not included in database
 	UserVote int `json:"user_vote" gorm:"->;-:migration"`
+
+	// Indicates whether the current user has saved the post
+	// Computed field, not included in database
+	Saved bool `json:"saved" gorm:"->;-:migration"`
+
+	// Indicates whether the post is pinned in the listing it was returned in: globally in GET /posts, or to one of the filtered topics
+	// Computed field, not included in database
+	Pinned bool `json:"pinned" gorm:"->;-:migration"`
+}
+
+// Record of a post pinned to the top of the listing of a topic
+type TopicPin struct {
+	// Composite primary key using topic_id and post_id
+	TopicID   uint      `json:"topic_id" gorm:"primaryKey;autoIncrement:false;"`
+	PostID    uint      `json:"post_id" gorm:"primaryKey;autoIncrement:false;"`
+	Position  int       `json:"position" gorm:"not null"` // Order among the posts pinned to the topic, lowest first
+	CreatedAt time.Time `json:"pinned_at"`
+
+	// When the topic or post is deleted, the pin is deleted
+	Topic *Topic `json:"-" gorm:"constraint:OnDelete:CASCADE;"`
+	Post  *Post  `json:"-" gorm:"constraint:OnDelete:CASCADE;"`
+}
+
+// Request body for pinning a post, globally or to a topic
+type PinUpdate struct {
+	Position int `json:"position" binding:"min=0"` // Order among pinned posts, lowest first
 }
 
 // Request body for creating a new post
+// The maximum content length is configurable, so it is checked by PostService rather than here
 type NewPost struct {
 	Title    string `json:"title" binding:"required,max=200"`
-	Content  string `json:"content" binding:"required,min=10,max=1000"`
+	Content  string `json:"content" binding:"required,min=10"`
 	TopicIDs []uint `json:"topic_ids"`
+	// Makes the post a poll post
+	Poll *NewPoll `json:"poll"`
+	// Defaults to publishing the post immediately
+	Status    string     `json:"status" binding:"omitempty,oneof=draft scheduled published"`
+	PublishAt *time.Time `json:"publish_at"` // Required for scheduled posts
+}
+
+// Request body for publishing, scheduling or unscheduling a draft or scheduled post
+type PostStatusUpdate struct {
+	Status    string     `json:"status" binding:"required,oneof=draft scheduled published"`
+	PublishAt *time.Time `json:"publish_at"` // Required for scheduled posts
 }
 
 // Request body for updating a post
 type PostUpdate struct {
 	Title   string `json:"title" binding:"required,max=200"`
-	Content string `json:"content" binding:"required,min=10,max=1000"`
+	Content string `json:"content" binding:"required,min=10"`
+}
+
+// A poll carried by a post
+type Poll struct {
+	// The post is the poll's primary key, since a post carries at most 1 poll
+	PostID         uint         `json:"-" gorm:"primaryKey;autoIncrement:false;"`
+	MultipleChoice bool         `json:"multiple_choice" gorm:"not null;default:false"`
+	ClosesAt       *time.Time   `json:"closes_at"` // Null if the poll never closes
+	Options        []PollOption `json:"options" gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE;"`
+
+	// Indicates whether the poll has closed
+	// Computed field, not included in database
+	Closed bool `json:"closed" gorm:"-"`
+
+	// Indicates whether the current user has voted
+	// Computed field, not included in database
+	Voted bool `json:"voted" gorm:"-"`
+
+	// Number of users who have voted, or null while the results are hidden from the current user
+	// Results are hidden until the current user votes or the poll closes
+	// Computed field, not included in database
+	TotalVoters *int64 `json:"total_voters" gorm:"-"`
+}
+
+// An option that can be voted for in a poll
+type PollOption struct {
+	ID       uint   `json:"id"`
+	PostID   uint   `json:"-" gorm:"index;not null"`
+	Position int    `json:"-" gorm:"not null"` // Order of the option within the poll
+	Text     string `json:"text" gorm:"not null"`
+
+	// Number of users who voted for the option, or null while the results are hidden from the current user
+	// Computed field, not included in database
+	Votes *int64 `json:"votes" gorm:"-"`
+
+	// Indicates whether the current user voted for the option
+	// Computed field, not included in database
+	Selected bool `json:"selected" gorm:"-"`
+}
+
+// Record of a user's vote in a poll
+type PollVote struct {
+	// Composite primary key using post_id and user_id, so that each user votes once in a poll
+	PostID    uint      `json:"post_id" gorm:"primaryKey;autoIncrement:false;"`
+	UserID    uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false;"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// The options chosen in the vote. Only 1 for single choice polls.
+	Choices []PollChoice `json:"-" gorm:"foreignKey:PostID,UserID;references:PostID,UserID;constraint:OnDelete:CASCADE;"`
+
+	// When the poll or user is deleted, the vote is deleted
+	Poll *Poll `json:"-" gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE;"`
+	User *User `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+}
+
+// Record of an option chosen in a user's vote in a poll
+type PollChoice struct {
+	// Composite primary key using post_id, user_id and option_id, so that an option is chosen at most once per vote
+	PostID   uint `gorm:"primaryKey;autoIncrement:false;"`
+	UserID   uint `gorm:"primaryKey;autoIncrement:false;"`
+	OptionID uint `gorm:"primaryKey;autoIncrement:false;index"`
+
+	// When the option is deleted, the choice is deleted
+	Option *PollOption `gorm:"foreignKey:OptionID;constraint:OnDelete:CASCADE;"`
+}
+
+// Request body for the poll of a new poll post
+type NewPoll struct {
+	Options        []string   `json:"options" binding:"required,min=2,max=10,dive,required,max=100"`
+	MultipleChoice bool       `json:"multiple_choice"`
+	ClosesAt       *time.Time `json:"closes_at"` // Omit for a poll that never closes
+}
+
+// Request body for voting in a poll
+type PollVoteInput struct {
+	OptionIDs []uint `json:"option_ids" binding:"required,min=1,max=10"`
 }
 
 // Request body for updating the topics associated with a post
@@ -57,13 +255,19 @@ type PostTagsUpdate struct {
 }
 
 type Comment struct {
-	ID        uint      `json:"id"`
-	Content   string    `json:"content" gorm:"not null"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	PostID    uint      `json:"post_id" gorm:"constraint:OnDelete:SET NULL;" ` // When the associated post is deleted, the comment remains but the post_id is set to null
-	AuthorID  uint      `json:"author_id"`
-	Author    User      `json:"author" gorm:"constraint:OnDelete:SET NULL;"` // When the associated user is deleted, set the author field to null
+	ID          uint      `json:"id"`
+	Content     string    `json:"content" gorm:"not null"`                 // Markdown source
+	ContentHTML string    `json:"content_html" gorm:"not null;default:''"` // Sanitized HTML rendered from Content
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	PostID      uint      `json:"post_id" gorm:"constraint:OnDelete:SET NULL;" ` // When the associated post is deleted, the comment remains but the post_id is set to null
+	AuthorID    uint      `json:"author_id"`
+	Author      User      `json:"author" gorm:"constraint:OnDelete:SET NULL;"` // When the associated user is deleted, set the author field to null
+	// Incremented by each edit of the content, to detect conflicting edits
+	Version uint `json:"version" gorm:"not null;default:1"`
+
+	// Files attached to the comment. When the comment is deleted, the attachment records are deleted.
+	Attachments []Attachment `json:"attachments" gorm:"constraint:OnDelete:CASCADE;"`
 
 	// Array of votes associated with this comment. Not included in json.
 	Votes []CommentVote `json:"-" gorm:"constraint:OnDelete:CASCADE;"` // When this comment is deleted, the associated votes are deleted.
@@ -75,17 +279,22 @@ type Comment struct {
 	// Indicates whether the current user has upvoted (1), downvoted (-1) or not voted (0) the comment
 	// Computed field, not included in database
 	UserVote int `json:"user_vote" gorm:"->;-:migration"`
+
+	// Indicates whether the current user has saved the comment
+	// Computed field, not included in database
+	Saved bool `json:"saved" gorm:"->;-:migration"`
 }
 
 // Request body for creating a new comment
+// The maximum content length is configurable, so it is checked by CommentService rather than here
 type NewComment struct {
-	Content string `json:"content" binding:"required,max=1000"`
+	Content string `json:"content" binding:"required"`
 	PostID  uint   `json:"post_id" binding:"required"`
 }
 
 // Request body for updating a comment
 type CommentUpdate struct {
-	Content string `json:"content" binding:"required,max=1000"`
+	Content string `json:"content" binding:"required"`
 }
 
 // Record for a user's vote for a post
@@ -94,6 +303,8 @@ type PostVote struct {
 	PostID uint `json:"post_id" gorm:"primaryKey;autoIncrement:false;"`
 	UserID uint `json:"user_id" gorm:"primaryKey;autoIncrement:false;"`
 	Value  int  `json:"value" gorm:"not null"` //upvote: 1, downvote: -1
+	// When the vote was last cast or changed
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // Record for a user's vote for a comment
@@ -102,6 +313,8 @@ type CommentVote struct {
 	CommentID uint `json:"comment_id" gorm:"primaryKey;autoIncrement:false;" `
 	UserID    uint `json:"user_id" gorm:"primaryKey;autoIncrement:false;"`
 	Value     int  `json:"value" gorm:"not null"` //upvote: 1, downvote: -1
+	// When the vote was last cast or changed
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // Request body for voting on a post or comment
@@ -109,7 +322,219 @@ type VoteUpdate struct {
 	Value int `json:"value"`
 }
 
+// Types of items that can be saved
+const (
+	SavedItemPost    = "post"
+	SavedItemComment = "comment"
+)
+
+// Record of a post or comment saved by a user for later
+type SavedItem struct {
+	// Composite primary key using user_id, item_type and item_id
+	UserID    uint      `json:"-" gorm:"primaryKey;autoIncrement:false;"`
+	ItemType  string    `json:"type" gorm:"primaryKey;size:16"` // "post" or "comment"
+	ItemID    uint      `json:"item_id" gorm:"primaryKey;autoIncrement:false;"`
+	CreatedAt time.Time `json:"saved_at"`
+
+	// The saved post or comment, depending on the item type. Not stored in the database.
+	Post    *Post    `json:"post,omitempty" gorm:"-"`
+	Comment *Comment `json:"comment,omitempty" gorm:"-"`
+}
+
 type Topic struct {
 	ID   uint   `json:"id"`
 	Name string `gorm:"uniqueIndex;not null" json:"name"`
 }
+
+// Record of a user's subscription to a topic, used to build their home feed
+type TopicSubscription struct {
+	// Composite primary key using user_id and topic_id
+	UserID    uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false;"`
+	TopicID   uint      `json:"topic_id" gorm:"primaryKey;autoIncrement:false;"`
+	CreatedAt time.Time `json:"subscribed_at"`
+
+	// When the user or topic is deleted, the subscription is deleted. Not included in json.
+	User  *User  `json:"-" gorm:"constraint:OnDelete:CASCADE;"`
+	Topic *Topic `json:"-" gorm:"constraint:OnDelete:CASCADE;"`
+}
+
+// Types of entries in a user's activity stream
+const (
+	ActivityPost        = "post"
+	ActivityComment     = "comment"
+	ActivityPostVote    = "post_vote"
+	ActivityCommentVote = "comment_vote"
+)
+
+// An entry in a user's activity stream: a post or comment they wrote, or a vote they cast
+type Activity struct {
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"` // When the post or comment was created, or the vote was last changed
+
+	// The post or comment that was written or voted on, depending on the type
+	Post    *Post    `json:"post,omitempty"`
+	Comment *Comment `json:"comment,omitempty"`
+	// Value of the vote for vote entries
+	Vote int `json:"vote,omitempty"`
+}
+
+// ID of the post or comment the entry refers to, used together with the time and type to order entries
+func (activity Activity) TargetID() uint {
+	if activity.Post != nil {
+		return activity.Post.ID
+	}
+	if activity.Comment != nil {
+		return activity.Comment.ID
+	}
+	return 0
+}
+
+// Position of the activity in the stream
+func (activity Activity) Cursor() ActivityCursor {
+	return ActivityCursor{CreatedAt: activity.CreatedAt, Type: activity.Type, TargetID: activity.TargetID()}
+}
+
+// Position in an activity stream. Entries are ordered by time, then type, then target ID, all descending.
+type ActivityCursor struct {
+	CreatedAt time.Time
+	Type      string
+	TargetID  uint
+}
+
+// Whether the cursor comes before the activity in the stream, i.e. the activity belongs to the next page
+func (cursor ActivityCursor) Precedes(activity Activity) bool {
+	if !activity.CreatedAt.Equal(cursor.CreatedAt) {
+		return activity.CreatedAt.Before(cursor.CreatedAt)
+	}
+	if activity.Type != cursor.Type {
+		return activity.Type < cursor.Type
+	}
+	return activity.TargetID() < cursor.TargetID
+}
+
+// A private conversation between 2 or more users
+type Conversation struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"` // Updated whenever a message is sent, to order conversations by recent activity
+
+	// Users taking part in the conversation, including the current user
+	// When the conversation is deleted, the participant records are deleted
+	Participants []ConversationParticipant `json:"participants" gorm:"constraint:OnDelete:CASCADE;"`
+
+	// The most recent message in the conversation. Not stored in the database.
+	LastMessage *Message `json:"last_message" gorm:"-"`
+
+	// Number of messages sent by others that the current user has not read
+	// Computed field, not included in database
+	UnreadCount int64 `json:"unread_count" gorm:"->;-:migration"`
+}
+
+// Record of a user taking part in a conversation
+type ConversationParticipant struct {
+	// Composite primary key using conversation_id and user_id
+	ConversationID uint `json:"-" gorm:"primaryKey;autoIncrement:false;"`
+	UserID         uint `json:"user_id" gorm:"primaryKey;autoIncrement:false;"`
+	// ID of the latest message the user has read, used for read receipts and unread counts
+	LastReadMessageID uint `json:"last_read_message_id" gorm:"not null;default:0"`
+
+	// When the user is deleted, they are removed from the conversation
+	User *User `json:"user,omitempty" gorm:"constraint:OnDelete:CASCADE;"`
+}
+
+// A message sent in a conversation
+type Message struct {
+	ID             uint      `json:"id"`
+	ConversationID uint      `json:"conversation_id" gorm:"index;not null"`
+	SenderID       uint      `json:"sender_id"`
+	Content        string    `json:"content" gorm:"not null"`
+	CreatedAt      time.Time `json:"created_at"`
+
+	// When the conversation is deleted, its messages are deleted
+	Conversation *Conversation `json:"-" gorm:"constraint:OnDelete:CASCADE;"`
+	// When the sender is deleted, set the sender field to null
+	Sender *User `json:"sender,omitempty" gorm:"constraint:OnDelete:SET NULL;"`
+
+	// IDs of the other participants who have read the message
+	// Computed from the participants' last read message, not stored in the database
+	ReadBy []uint `json:"read_by" gorm:"-"`
+}
+
+// Position in a list of conversations, which are ordered by last update, then ID, both descending
+type ConversationCursor struct {
+	UpdatedAt time.Time
+	ID        uint
+}
+
+// Request body for starting a conversation
+type NewConversation struct {
+	// The other participants; conversations have at most 10 participants including the current user
+	ParticipantIDs []uint `json:"participant_ids" binding:"required,min=1,max=9"`
+	// The first message of the conversation
+	Content string `json:"content" binding:"required,max=1000"`
+}
+
+// Request body for sending a message
+type NewMessage struct {
+	Content string `json:"content" binding:"required,max=1000"`
+}
+
+// A file attached to a post or comment
+// The file itself is kept in a blob store; the record holds its metadata and key
+type Attachment struct {
+	ID uint `json:"id"`
+	// Exactly 1 of PostID and CommentID is set
+	PostID      *uint  `json:"post_id" gorm:"index"`
+	CommentID   *uint  `json:"comment_id" gorm:"index"`
+	UploaderID  uint   `json:"uploader_id"`
+	Filename    string `json:"filename" gorm:"not null"`
+	ContentType string `json:"content_type" gorm:"not null"` // Sniffed from the content, not taken from the request
+	Size        int64  `json:"size" gorm:"not null"`         // In bytes
+	// Dimensions of images, or 0 for other files
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Whether a thumbnail was generated, which is the case for images
+	HasThumbnail bool      `json:"has_thumbnail"`
+	BlobKey      string    `json:"-" gorm:"not null;uniqueIndex"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// When the uploader is deleted, set the uploader field to null
+	Uploader *User `json:"-" gorm:"constraint:OnDelete:SET NULL;"`
+}
+
+// States of a background job
+const (
+	JobStatusPending   = "pending"   // Waiting to be run, possibly after a failed attempt
+	JobStatusRunning   = "running"   // Claimed by a worker
+	JobStatusSucceeded = "succeeded" // Kept for a while so that its key still prevents the same work being enqueued again
+	JobStatusDead      = "dead"      // Failed every attempt, or failed permanently, and will not be retried
+)
+
+// A unit of background work in the persistent job queue
+type Job struct {
+	ID   uint   `json:"id"`
+	Type string `json:"type" gorm:"not null;size:64"`
+	// JSON-encoded argument of the job's handler
+	Payload string `json:"payload" gorm:"not null"`
+	// Optional key that at most 1 job may have at a time, used to avoid enqueueing the same work twice
+	Key         *string   `json:"key" gorm:"uniqueIndex"`
+	Status      string    `json:"status" gorm:"not null;size:16;index:idx_jobs_status_run_at,priority:1"`
+	RunAt       time.Time `json:"run_at" gorm:"not null;index:idx_jobs_status_run_at,priority:2"`
+	Attempts    int       `json:"attempts" gorm:"not null"`
+	MaxAttempts int       `json:"max_attempts" gorm:"not null"`
+	// When a worker claimed the job. Running jobs whose lock has expired are assumed to have been abandoned.
+	LockedAt  *time.Time `json:"locked_at"`
+	LastError string     `json:"last_error"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// A domain event waiting in the transactional outbox to be dispatched to its subscribers
+// Events are written in the same transaction as the change they describe, and deleted once dispatched
+type OutboxEvent struct {
+	ID   uint   `json:"id"`
+	Type string `json:"type" gorm:"not null;size:64"`
+	// JSON-encoded event
+	Payload   string    `json:"payload" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
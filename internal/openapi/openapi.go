@@ -0,0 +1,219 @@
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	errs "cvwo-backend/internal/errors"
+)
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+	Tags       []Tag               `json:"tags,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// Operations of a path, keyed by lowercase HTTP method
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Summary     string                `json:"summary"`
+	OperationID string                `json:"operationId"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Whether an operation needs the request to be authenticated
+type authRequirement int
+
+const (
+	authNone authRequirement = iota
+	// Authentication is optional and only changes user-specific fields such as user_vote
+	authOptional
+	authRequired
+)
+
+// Description of a single route, from which an OpenAPI operation is generated
+type route struct {
+	method  string
+	path    string // gin path syntax, e.g. /posts/:post_id
+	summary string
+	tag     string
+	auth    authRequirement
+	query   []Parameter
+	// Request body type, or nil if the route has no body
+	request any
+	// Whether the request body is a multipart form with a file in the "file" field, instead of JSON
+	upload bool
+	status int
+	// Response body schema, or nil if the route responds with no content
+	response func(*schemaGenerator) *Schema
+	// Whether the response body is a file of any media type, instead of JSON
+	file bool
+	// Media type of the text, such as an XML feed, the route responds with instead of JSON
+	text string
+}
+
+// Matches gin path params such as :post_id
+var pathParamPattern = regexp.MustCompile(`:(\w+)`)
+
+// Convert a gin path to an OpenAPI path, e.g. /posts/:post_id to /posts/{post_id}
+func ToOpenAPIPath(ginPath string) string {
+	return pathParamPattern.ReplaceAllString(ginPath, "{$1}")
+}
+
+// Build the OpenAPI document for all documented routes
+func Build() *Document {
+	generator := newSchemaGenerator()
+	problem := generator.schemaOf(errs.Problem{})
+
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:       "Forum API",
+			Description: "Errors are returned as RFC 7807 problem details with the application/problem+json media type.",
+			Version:     "1.0.0",
+		},
+		Paths: make(map[string]PathItem),
+		Components: Components{
+			SecuritySchemes: map[string]*SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+
+	seenTags := make(map[string]bool)
+	for _, r := range routes {
+		path := ToOpenAPIPath(r.path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(PathItem)
+		}
+		doc.Paths[path][strings.ToLower(r.method)] = r.operation(generator, problem)
+
+		if !seenTags[r.tag] {
+			seenTags[r.tag] = true
+			doc.Tags = append(doc.Tags, Tag{Name: r.tag})
+		}
+	}
+
+	doc.Components.Schemas = generator.components
+	return doc
+}
+
+func (r *route) operation(generator *schemaGenerator, problem *Schema) *Operation {
+	op := &Operation{
+		Summary:     r.summary,
+		OperationID: operationID(r.method, r.path),
+		Tags:        []string{r.tag},
+		Responses:   make(map[string]*Response),
+	}
+
+	for _, match := range pathParamPattern.FindAllStringSubmatch(r.path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{Name: match[1], In: "path", Required: true, Schema: &Schema{Type: "integer"}})
+	}
+	op.Parameters = append(op.Parameters, r.query...)
+
+	if r.request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"application/json": {Schema: generator.schemaOf(r.request)}},
+		}
+	}
+	if r.upload {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]*MediaType{"multipart/form-data": {Schema: &Schema{
+				Type:       "object",
+				Properties: map[string]*Schema{"file": {Type: "string", Format: "binary"}},
+				Required:   []string{"file"},
+			}}},
+		}
+	}
+
+	success := &Response{Description: http.StatusText(r.status)}
+	if r.response != nil {
+		success.Content = map[string]*MediaType{"application/json": {Schema: r.response(generator)}}
+	}
+	if r.file {
+		success.Content = map[string]*MediaType{"*/*": {Schema: &Schema{Type: "string", Format: "binary"}}}
+	}
+	if r.text != "" {
+		success.Content = map[string]*MediaType{r.text: {Schema: &Schema{Type: "string"}}}
+	}
+	op.Responses[statusKey(r.status)] = success
+
+	// Every operation may fail; the specific causes are described by the problem's code
+	op.Responses["default"] = &Response{
+		Description: "Error",
+		Content:     map[string]*MediaType{errs.ProblemContentType: {Schema: problem}},
+	}
+
+	switch r.auth {
+	case authRequired:
+		op.Security = []map[string][]string{{"bearerAuth": {}}}
+	case authOptional:
+		op.Security = []map[string][]string{{"bearerAuth": {}}, {}}
+	}
+
+	return op
+}
+
+// Unique operation ID derived from the method and path, e.g. GET /posts/:post_id becomes get_posts_post_id
+func operationID(method string, path string) string {
+	id := strings.ToLower(method) + strings.NewReplacer("/", "_", ":", "").Replace(path)
+	return strings.TrimSuffix(id, "_")
+}
+
+func statusKey(status int) string {
+	return strconv.Itoa(status)
+}
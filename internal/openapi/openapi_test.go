@@ -0,0 +1,88 @@
+package openapi
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestRequestSchemaIncludesBindingConstraints(t *testing.T) {
+	doc := Build()
+
+	newPost, exists := doc.Components.Schemas["NewPost"]
+	if !exists {
+		t.Fatal("NewPost schema missing from components")
+	}
+
+	if !slices.Contains(newPost.Required, "title") || !slices.Contains(newPost.Required, "content") {
+		t.Errorf("expected title and content to be required, got %v", newPost.Required)
+	}
+	if slices.Contains(newPost.Required, "topic_ids") {
+		t.Error("expected topic_ids to be optional")
+	}
+
+	title := newPost.Properties["title"]
+	if title.MaxLength == nil || *title.MaxLength != 200 {
+		t.Errorf("expected title maxLength 200, got %v", title.MaxLength)
+	}
+	content := newPost.Properties["content"]
+	if content.MinLength == nil || *content.MinLength != 10 {
+		t.Errorf("expected content minLength 10, got %v", content.MinLength)
+	}
+}
+
+func TestResponseSchemaExcludesHiddenFields(t *testing.T) {
+	doc := Build()
+
+	if _, exists := doc.Components.Schemas["User"].Properties["password"]; exists {
+		t.Error("expected password to be excluded from the User schema")
+	}
+	if _, exists := doc.Components.Schemas["Post"].Properties["Votes"]; exists {
+		t.Error("expected vote records to be excluded from the Post schema")
+	}
+	if !doc.Components.Schemas["Post"].Properties["votes"].ReadOnly {
+		t.Error("expected computed votes field to be read-only")
+	}
+}
+
+func TestPathParamsAreConverted(t *testing.T) {
+	doc := Build()
+
+	op, exists := doc.Paths["/posts/{post_id}/votes/{user_id}"]["put"]
+	if !exists {
+		t.Fatal("expected PUT /posts/{post_id}/votes/{user_id} to be documented")
+	}
+	if len(op.Parameters) != 2 || op.Parameters[0].Name != "post_id" || op.Parameters[1].Name != "user_id" {
+		t.Errorf("unexpected parameters: %+v", op.Parameters)
+	}
+
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatalf("failed to marshal document: %v", err)
+	}
+}
+
+func TestEmbeddedStructFieldsArePromoted(t *testing.T) {
+	doc := Build()
+
+	profile := doc.Components.Schemas["UserProfile"]
+	for _, name := range []string{"id", "username", "follower_count", "following_count"} {
+		if _, exists := profile.Properties[name]; !exists {
+			t.Errorf("expected %s in the UserProfile schema", name)
+		}
+	}
+	if _, exists := profile.Properties["User"]; exists {
+		t.Error("expected the embedded User to be promoted rather than nested")
+	}
+}
+
+func TestDiveRulesApplyToArrayItems(t *testing.T) {
+	doc := Build()
+
+	options := doc.Components.Schemas["NewPoll"].Properties["options"]
+	if options.MinItems == nil || *options.MinItems != 2 || options.MaxItems == nil || *options.MaxItems != 10 {
+		t.Errorf("expected 2 to 10 options, got %v to %v", options.MinItems, options.MaxItems)
+	}
+	if options.Items.MaxLength == nil || *options.Items.MaxLength != 100 {
+		t.Errorf("expected options of at most 100 characters, got %v", options.Items.MaxLength)
+	}
+}
@@ -0,0 +1,179 @@
+package openapi
+
+import (
+	"cvwo-backend/internal/models"
+	"net/http"
+)
+
+// Query params shared by paginated listings
+var paginationParams = []Parameter{
+	{Name: "page", In: "query", Description: "Page number, starting from 1", Schema: &Schema{Type: "integer"}},
+	{Name: "limit", In: "query", Description: "Number of records per page", Schema: &Schema{Type: "integer"}},
+	{Name: "sort", In: "query", Description: "Sort order", Schema: &Schema{Type: "string", Enum: []any{"new", "old", "votes"}}},
+}
+
+// Header of edits that must only be applied to the version of a post or comment they are based on
+var ifMatchParam = Parameter{Name: "If-Match", In: "header", Description: "ETag of the version the edit is based on, as returned when getting, creating or editing the post or comment. The edit fails with 412 if there has been another edit since. Omit to overwrite any concurrent edits.", Schema: &Schema{Type: "string"}}
+
+// Headers of GETs revalidating a cached response, which respond with 304 Not Modified if it is still current
+var conditionalParams = []Parameter{
+	{Name: "If-None-Match", In: "header", Description: "ETag of the cached response", Schema: &Schema{Type: "string"}},
+}
+
+// Schema of a paginated list response: {"data": [...], "total_count": n}
+func listOf(item any) func(*schemaGenerator) *Schema {
+	return func(generator *schemaGenerator) *Schema {
+		return &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"data":        {Type: "array", Items: generator.schemaOf(item)},
+				"total_count": {Type: "integer", Description: "Total number of records across all pages"},
+			},
+			Required: []string{"data", "total_count"},
+		}
+	}
+}
+
+// Schema of a response containing a single value or an array of values
+func bodyOf(value any) func(*schemaGenerator) *Schema {
+	return func(generator *schemaGenerator) *Schema {
+		return generator.schemaOf(value)
+	}
+}
+
+// Schema of a cursor-paginated list response: {"data": [...], "next_cursor": "..."}
+func cursorListOf(item any) func(*schemaGenerator) *Schema {
+	return func(generator *schemaGenerator) *Schema {
+		return &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"data":        {Type: "array", Items: generator.schemaOf(item)},
+				"next_cursor": {Type: "string", Description: "Cursor to request the next page with; empty on the last page"},
+			},
+			Required: []string{"data", "next_cursor"},
+		}
+	}
+}
+
+// Schema of the login response: {"token": "...", "user": {...}}
+func loginResponse(generator *schemaGenerator) *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"token": {Type: "string", Description: "JWT to send as a bearer token"},
+			"user":  generator.schemaOf(models.User{}),
+		},
+		Required: []string{"token", "user"},
+	}
+}
+
+// Every route registered in routes.go must be documented here
+var routes = []route{
+	// Users
+	{method: http.MethodGet, path: "/users", summary: "List all users", tag: "users", status: http.StatusOK, response: bodyOf([]models.User{})},
+	{method: http.MethodGet, path: "/users/:id", summary: "Get a user's profile, including follower counts", tag: "users", auth: authOptional, status: http.StatusOK, response: bodyOf(models.UserProfile{})},
+	{method: http.MethodPost, path: "/users", summary: "Register a new user", tag: "users", request: models.AuthInput{}, status: http.StatusCreated, response: bodyOf(models.User{})},
+	{method: http.MethodGet, path: "/users/me/saved", summary: "List the current user's saved posts and comments, most recently saved first", tag: "users", auth: authRequired, status: http.StatusOK, response: listOf(models.SavedItem{}),
+		query: []Parameter{
+			{Name: "type", In: "query", Description: "Only include saved items of this type", Schema: &Schema{Type: "string", Enum: []any{models.SavedItemPost, models.SavedItemComment}}},
+			paginationParams[0], paginationParams[1],
+		}},
+	{method: http.MethodPut, path: "/users/me/settings", summary: "Update the current user's settings", tag: "users", auth: authRequired, request: models.UserSettings{}, status: http.StatusOK, response: bodyOf(models.User{})},
+	{method: http.MethodPut, path: "/users/:id/follow", summary: "Follow a user", tag: "users", auth: authRequired, status: http.StatusNoContent},
+	{method: http.MethodDelete, path: "/users/:id/follow", summary: "Unfollow a user", tag: "users", auth: authRequired, status: http.StatusNoContent},
+	{method: http.MethodGet, path: "/users/:id/followers", summary: "List the users who follow a user, most recent first", tag: "users", query: paginationParams[:2], status: http.StatusOK, response: listOf(models.User{})},
+	{method: http.MethodGet, path: "/users/:id/following", summary: "List the users a user follows, most recent first", tag: "users", query: paginationParams[:2], status: http.StatusOK, response: listOf(models.User{})},
+	{method: http.MethodGet, path: "/users/:id/activity", summary: "List a user's posts, comments and votes, most recent first. Votes are only included if the user made them public or is the current user.", tag: "users", auth: authOptional, status: http.StatusOK, response: cursorListOf(models.Activity{}),
+		query: []Parameter{
+			{Name: "cursor", In: "query", Description: "Cursor returned with the previous page; omit for the first page", Schema: &Schema{Type: "string"}},
+			paginationParams[1],
+		}},
+	{method: http.MethodGet, path: "/users/me/blocks", summary: "List the users the current user has blocked or muted, most recent first", tag: "users", auth: authRequired, status: http.StatusOK, response: listOf(models.Block{}),
+		query: []Parameter{
+			{Name: "type", In: "query", Description: "Only include blocks or mutes", Schema: &Schema{Type: "string", Enum: []any{models.BlockTypeBlock, models.BlockTypeMute}}},
+			paginationParams[0], paginationParams[1],
+		}},
+	{method: http.MethodPut, path: "/users/me/blocks/:user_id", summary: "Block or mute a user. Content by blocked and muted users is hidden from listings, and blocked users cannot reply to or mention the current user.", tag: "users", auth: authRequired, request: models.BlockUpdate{}, status: http.StatusNoContent},
+	{method: http.MethodDelete, path: "/users/me/blocks/:user_id", summary: "Unblock or unmute a user", tag: "users", auth: authRequired, status: http.StatusNoContent},
+	{method: http.MethodGet, path: "/users/me/subscriptions", summary: "List the topics the current user subscribes to", tag: "users", auth: authRequired, status: http.StatusOK, response: bodyOf([]models.Topic{})},
+
+	// Auth
+	{method: http.MethodPost, path: "/login", summary: "Log in and get a JWT", tag: "auth", request: models.AuthInput{}, status: http.StatusOK, response: loginResponse},
+
+	// Posts
+	{method: http.MethodGet, path: "/posts", summary: "List published posts, optionally filtered by topic. Pinned posts come first, regardless of sort.", tag: "posts", auth: authOptional, status: http.StatusOK, response: listOf(models.Post{}),
+		query: append([]Parameter{
+			{Name: "tag", In: "query", Description: "Topic ID to filter by; may be repeated to match any of several topics", Schema: &Schema{Type: "array", Items: &Schema{Type: "integer"}}},
+		}, append(paginationParams, conditionalParams...)...)},
+	{method: http.MethodGet, path: "/posts/:post_id", summary: "Get a post", tag: "posts", auth: authOptional, query: conditionalParams, status: http.StatusOK, response: bodyOf(models.Post{})},
+	{method: http.MethodPost, path: "/posts", summary: "Create a post, published immediately or saved as a draft or scheduled post", tag: "posts", auth: authRequired, request: models.NewPost{}, status: http.StatusCreated, response: bodyOf(models.Post{})},
+	{method: http.MethodPatch, path: "/posts/:post_id", summary: "Update the title and content of a post", tag: "posts", auth: authRequired, query: []Parameter{ifMatchParam}, request: models.PostUpdate{}, status: http.StatusOK, response: bodyOf(models.Post{})},
+	{method: http.MethodPut, path: "/posts/:post_id/topics", summary: "Replace the topics of a post", tag: "posts", auth: authRequired, request: models.PostTagsUpdate{}, status: http.StatusNoContent},
+	{method: http.MethodPut, path: "/posts/:post_id/status", summary: "Publish, schedule or unschedule one of the current user's draft or scheduled posts. Published posts cannot be unpublished.", tag: "posts", auth: authRequired, request: models.PostStatusUpdate{}, status: http.StatusOK, response: bodyOf(models.Post{})},
+	{method: http.MethodGet, path: "/users/me/drafts", summary: "List the current user's draft and scheduled posts, most recently updated first", tag: "posts", auth: authRequired, query: paginationParams, status: http.StatusOK, response: listOf(models.Post{})},
+	{method: http.MethodDelete, path: "/posts/:post_id", summary: "Delete a post", tag: "posts", auth: authRequired, status: http.StatusNoContent},
+	{method: http.MethodPut, path: "/posts/:post_id/votes/:user_id", summary: "Upvote (1), downvote (-1) or remove a vote (0) on a post", tag: "posts", auth: authRequired, request: models.VoteUpdate{}, status: http.StatusNoContent},
+	{method: http.MethodPost, path: "/posts/:post_id/poll/votes", summary: "Vote once in the poll of a poll post, for 1 option or, in a multiple choice poll, several. Responds with the poll and its results.", tag: "posts", auth: authRequired, request: models.PollVoteInput{}, status: http.StatusCreated, response: bodyOf(models.Poll{})},
+	{method: http.MethodPut, path: "/posts/:post_id/save", summary: "Save a post for later", tag: "posts", auth: authRequired, status: http.StatusNoContent},
+	{method: http.MethodDelete, path: "/posts/:post_id/save", summary: "Remove a post from saved items", tag: "posts", auth: authRequired, status: http.StatusNoContent},
+
+	// Comments
+	{method: http.MethodGet, path: "/posts/:post_id/comments", summary: "List the comments of a post", tag: "comments", auth: authOptional, query: append(paginationParams, conditionalParams...), status: http.StatusOK, response: listOf(models.Comment{})},
+	{method: http.MethodPost, path: "/comments", summary: "Create a comment", tag: "comments", auth: authRequired, request: models.NewComment{}, status: http.StatusCreated, response: bodyOf(models.Comment{})},
+	{method: http.MethodPatch, path: "/comments/:comment_id", summary: "Update the content of a comment", tag: "comments", auth: authRequired, query: []Parameter{ifMatchParam}, request: models.CommentUpdate{}, status: http.StatusOK, response: bodyOf(models.Comment{})},
+	{method: http.MethodDelete, path: "/comments/:comment_id", summary: "Delete a comment", tag: "comments", auth: authRequired, status: http.StatusNoContent},
+	{method: http.MethodPut, path: "/comments/:comment_id/votes/:user_id", summary: "Upvote (1), downvote (-1) or remove a vote (0) on a comment", tag: "comments", auth: authRequired, request: models.VoteUpdate{}, status: http.StatusNoContent},
+	{method: http.MethodPut, path: "/comments/:comment_id/save", summary: "Save a comment for later", tag: "comments", auth: authRequired, status: http.StatusNoContent},
+	{method: http.MethodDelete, path: "/comments/:comment_id/save", summary: "Remove a comment from saved items", tag: "comments", auth: authRequired, status: http.StatusNoContent},
+
+	// Topics
+	{method: http.MethodGet, path: "/topics", summary: "List all topics", tag: "topics", query: conditionalParams, status: http.StatusOK, response: bodyOf([]models.Topic{})},
+	{method: http.MethodPut, path: "/topics/:topic_id/subscription", summary: "Subscribe to a topic so that its posts appear in the home feed", tag: "topics", auth: authRequired, status: http.StatusNoContent},
+	{method: http.MethodDelete, path: "/topics/:topic_id/subscription", summary: "Unsubscribe from a topic", tag: "topics", auth: authRequired, status: http.StatusNoContent},
+
+	// Feed
+	{method: http.MethodGet, path: "/feed", summary: "List posts from the current user's subscribed topics, or all posts for anonymous users and users without subscriptions", tag: "posts", auth: authOptional, query: paginationParams, status: http.StatusOK, response: listOf(models.Post{})},
+
+	// Syndication
+	{method: http.MethodGet, path: "/feeds/posts.rss", summary: "RSS feed of the 20 newest posts, like GET /posts?sort=new for an anonymous user", tag: "syndication", query: conditionalParams, status: http.StatusOK, text: "application/rss+xml"},
+	{method: http.MethodGet, path: "/feeds/topics/:topic_id.atom", summary: "Atom feed of the 20 newest posts tagged with a topic", tag: "syndication", query: conditionalParams, status: http.StatusOK, text: "application/atom+xml"},
+	{method: http.MethodGet, path: "/feeds/users/:id.atom", summary: "Atom feed of the 20 newest posts by a user", tag: "syndication", query: conditionalParams, status: http.StatusOK, text: "application/atom+xml"},
+
+	// Conversations
+	{method: http.MethodGet, path: "/conversations", summary: "List the current user's conversations, most recently active first, with unread counts", tag: "conversations", auth: authRequired, status: http.StatusOK, response: cursorListOf(models.Conversation{}),
+		query: []Parameter{
+			{Name: "cursor", In: "query", Description: "Cursor returned with the previous page; omit for the first page", Schema: &Schema{Type: "string"}},
+			paginationParams[1],
+		}},
+	{method: http.MethodPost, path: "/conversations", summary: "Start a conversation with a first message. Messaging a single user continues the existing conversation with them, if any.", tag: "conversations", auth: authRequired, request: models.NewConversation{}, status: http.StatusCreated, response: bodyOf(models.Conversation{})},
+	{method: http.MethodGet, path: "/conversations/:conversation_id/messages", summary: "List the messages in a conversation, newest first, with the participants who have read each message", tag: "conversations", auth: authRequired, status: http.StatusOK, response: cursorListOf(models.Message{}),
+		query: []Parameter{
+			{Name: "cursor", In: "query", Description: "Cursor returned with the previous page; omit for the first page", Schema: &Schema{Type: "string"}},
+			paginationParams[1],
+		}},
+	{method: http.MethodPost, path: "/conversations/:conversation_id/messages", summary: "Send a message in a conversation", tag: "conversations", auth: authRequired, request: models.NewMessage{}, status: http.StatusCreated, response: bodyOf(models.Message{})},
+	{method: http.MethodPut, path: "/conversations/:conversation_id/read", summary: "Mark all messages in a conversation as read", tag: "conversations", auth: authRequired, status: http.StatusNoContent},
+
+	// Attachments
+	{method: http.MethodPost, path: "/posts/:post_id/attachments", summary: "Attach an image (JPEG, PNG, GIF or WebP), PDF or text file to a post. The type is detected from the file's content.", tag: "attachments", auth: authRequired, upload: true, status: http.StatusCreated, response: bodyOf(models.Attachment{})},
+	{method: http.MethodPost, path: "/comments/:comment_id/attachments", summary: "Attach an image (JPEG, PNG, GIF or WebP), PDF or text file to a comment. The type is detected from the file's content.", tag: "attachments", auth: authRequired, upload: true, status: http.StatusCreated, response: bodyOf(models.Attachment{})},
+	{method: http.MethodGet, path: "/attachments/:attachment_id", summary: "Download an attachment. Attachments of drafts and scheduled posts are only served to the post's author.", tag: "attachments", auth: authOptional, status: http.StatusOK, file: true},
+	{method: http.MethodGet, path: "/attachments/:attachment_id/thumbnail", summary: "Get the JPEG thumbnail of an image attachment. Thumbnails of drafts' and scheduled posts' images are only served to the post's author.", tag: "attachments", auth: authOptional, status: http.StatusOK, file: true},
+	{method: http.MethodDelete, path: "/attachments/:attachment_id", summary: "Delete an attachment", tag: "attachments", auth: authRequired, status: http.StatusNoContent},
+
+	// Moderation
+	{method: http.MethodPut, path: "/posts/:post_id/pin", summary: "Pin a post to the top of the list of all posts, ordered by position among pinned posts. Moderators only.", tag: "moderation", auth: authRequired, request: models.PinUpdate{}, status: http.StatusNoContent},
+	{method: http.MethodDelete, path: "/posts/:post_id/pin", summary: "Unpin a post from the top of the list of all posts. Moderators only.", tag: "moderation", auth: authRequired, status: http.StatusNoContent},
+	{method: http.MethodPut, path: "/topics/:topic_id/pins/:post_id", summary: "Pin a post tagged with a topic to the top of the topic's posts, ordered by position among pinned posts. Moderators only.", tag: "moderation", auth: authRequired, request: models.PinUpdate{}, status: http.StatusNoContent},
+	{method: http.MethodDelete, path: "/topics/:topic_id/pins/:post_id", summary: "Unpin a post from the top of a topic's posts. Moderators only.", tag: "moderation", auth: authRequired, status: http.StatusNoContent},
+	{method: http.MethodPut, path: "/posts/:post_id/lock", summary: "Lock a post so that it can no longer be commented on or voted on. Moderators only.", tag: "moderation", auth: authRequired, status: http.StatusNoContent},
+	{method: http.MethodDelete, path: "/posts/:post_id/lock", summary: "Unlock a post. Moderators only.", tag: "moderation", auth: authRequired, status: http.StatusNoContent},
+
+	// Administration
+	{method: http.MethodGet, path: "/admin/export", summary: "Download the users, topics, posts, polls, comments and votes as a versioned JSON Lines archive, which the import command restores into an empty database. Password hashes are never included, so imported users cannot log in. Moderators only.", tag: "admin", auth: authRequired, status: http.StatusOK, text: "application/x-ndjson"},
+
+	// Operations
+	{method: http.MethodGet, path: "/metrics", summary: "Prometheus metrics in text exposition format", tag: "operations", status: http.StatusOK},
+	{method: http.MethodGet, path: "/openapi.json", summary: "This OpenAPI document", tag: "operations", status: http.StatusOK},
+	{method: http.MethodGet, path: "/docs", summary: "Swagger UI for this OpenAPI document", tag: "operations", status: http.StatusOK},
+}
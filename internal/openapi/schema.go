@@ -0,0 +1,193 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Enum        []any              `json:"enum,omitempty"`
+	Nullable    bool               `json:"nullable,omitempty"`
+	ReadOnly    bool               `json:"readOnly,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	MinItems    *int               `json:"minItems,omitempty"`
+	MaxItems    *int               `json:"maxItems,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Generates schemas from Go types, collecting named struct types as reusable components
+type schemaGenerator struct {
+	components map[string]*Schema
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{components: make(map[string]*Schema)}
+}
+
+// Get a schema for the type of the given value, referencing a component if it is a named struct
+func (generator *schemaGenerator) schemaOf(value any) *Schema {
+	return generator.schema(reflect.TypeOf(value))
+}
+
+func (generator *schemaGenerator) schema(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer {
+		schema := generator.schema(t.Elem())
+		if schema.Ref == "" {
+			schema.Nullable = true
+		}
+		return schema
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: generator.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return generator.structSchema(t)
+		}
+		// Register the component before generating its fields so that recursive types terminate
+		if _, exists := generator.components[t.Name()]; !exists {
+			generator.components[t.Name()] = &Schema{}
+			*generator.components[t.Name()] = *generator.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	default:
+		return &Schema{}
+	}
+}
+
+func (generator *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		// Fields of embedded structs are promoted into the parent object, like encoding/json does
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			embedded := generator.structSchema(field.Type)
+			for name, fieldSchema := range embedded.Properties {
+				schema.Properties[name] = fieldSchema
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		name := jsonName(field)
+		if name == "" {
+			continue
+		}
+
+		fieldSchema := generator.schema(field.Type)
+		if applyBinding(fieldSchema, field) {
+			schema.Required = append(schema.Required, name)
+		}
+		// Fields computed by the database (e.g. votes) cannot be set by clients
+		if strings.Contains(field.Tag.Get("gorm"), "->") {
+			fieldSchema.ReadOnly = true
+		}
+		schema.Properties[name] = fieldSchema
+	}
+
+	return schema
+}
+
+// Name of the field in JSON, or empty if the field is excluded from JSON
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// Translate the validation rules in the binding tag of a field into schema constraints
+// Returns whether the field is required
+func applyBinding(schema *Schema, field reflect.StructField) bool {
+	return applyRules(schema, strings.Split(field.Tag.Get("binding"), ","))
+}
+
+func applyRules(schema *Schema, rules []string) bool {
+	required := false
+	for i, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			// The remaining rules apply to the elements of the array
+			if schema.Items != nil {
+				applyRules(schema.Items, rules[i+1:])
+			}
+			return required
+		case "required":
+			required = true
+		case "min", "max":
+			applyBound(schema, name, param)
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, value)
+			}
+		}
+	}
+	return required
+}
+
+func applyBound(schema *Schema, rule string, param string) {
+	bound, err := strconv.Atoi(param)
+	if err != nil {
+		return
+	}
+	switch schema.Type {
+	case "string":
+		if rule == "min" {
+			schema.MinLength = &bound
+		} else {
+			schema.MaxLength = &bound
+		}
+	case "array":
+		if rule == "min" {
+			schema.MinItems = &bound
+		} else {
+			schema.MaxItems = &bound
+		}
+	case "integer", "number":
+		value := float64(bound)
+		if rule == "min" {
+			schema.Minimum = &value
+		} else {
+			schema.Maximum = &value
+		}
+	}
+}
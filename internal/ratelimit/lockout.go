@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+type lockoutEntry struct {
+	failures    int
+	lockouts    int
+	lockedUntil time.Time
+	lastFailure time.Time
+}
+
+// Tracks failed login attempts per account and locks the account out after too many consecutive failures
+// Each successive lockout is twice as long as the previous one, up to a maximum
+type Lockout struct {
+	mu          sync.Mutex
+	entries     map[string]*lockoutEntry
+	maxFailures int
+	baseLockout time.Duration
+	maxLockout  time.Duration
+	lastSweep   time.Time
+	now         func() time.Time
+}
+
+func NewLockout(maxFailures int, baseLockout, maxLockout time.Duration) *Lockout {
+	return &Lockout{
+		entries:     make(map[string]*lockoutEntry),
+		maxFailures: maxFailures,
+		baseLockout: baseLockout,
+		maxLockout:  maxLockout,
+		lastSweep:   time.Now(),
+		now:         time.Now,
+	}
+}
+
+// Returns the remaining lockout duration for the given key, or zero if it is not locked out
+func (lockout *Lockout) LockedFor(key string) time.Duration {
+	lockout.mu.Lock()
+	defer lockout.mu.Unlock()
+
+	entry, exists := lockout.entries[key]
+	if !exists {
+		return 0
+	}
+	remaining := entry.lockedUntil.Sub(lockout.now())
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// Record a failed attempt for the given key, locking it out if it has reached the maximum number of failures
+func (lockout *Lockout) Fail(key string) {
+	lockout.mu.Lock()
+	defer lockout.mu.Unlock()
+
+	now := lockout.now()
+	lockout.sweep(now)
+
+	entry, exists := lockout.entries[key]
+	// Forget failures that happened long enough ago
+	if !exists || lockout.expired(entry, now) {
+		entry = &lockoutEntry{}
+		lockout.entries[key] = entry
+	}
+
+	entry.failures++
+	entry.lastFailure = now
+	if entry.failures < lockout.maxFailures {
+		return
+	}
+
+	// Double the lockout duration for every previous lockout
+	duration := lockout.baseLockout << entry.lockouts
+	if duration > lockout.maxLockout || duration <= 0 {
+		duration = lockout.maxLockout
+	}
+	entry.lockedUntil = now.Add(duration)
+	entry.lockouts++
+	entry.failures = 0
+}
+
+// Clear the failed attempts for the given key after a successful attempt
+func (lockout *Lockout) Reset(key string) {
+	lockout.mu.Lock()
+	defer lockout.mu.Unlock()
+
+	delete(lockout.entries, key)
+}
+
+// Whether an entry's failures are old enough to be forgotten
+func (lockout *Lockout) expired(entry *lockoutEntry, now time.Time) bool {
+	return now.After(entry.lockedUntil) && now.Sub(entry.lastFailure) > lockout.maxLockout
+}
+
+// Remove expired entries so that attempts against many different accounts do not grow the map indefinitely
+// Must be called with the lock held
+func (lockout *Lockout) sweep(now time.Time) {
+	if now.Sub(lockout.lastSweep) < sweepInterval {
+		return
+	}
+	for key, entry := range lockout.entries {
+		if lockout.expired(entry, now) {
+			delete(lockout.entries, key)
+		}
+	}
+	lockout.lastSweep = now
+}
@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestLockout(clock *fakeClock) *Lockout {
+	lockout := NewLockout(3, time.Minute, 4*time.Minute)
+	lockout.now = clock.Now
+	lockout.lastSweep = clock.Now()
+	return lockout
+}
+
+func failTimes(lockout *Lockout, key string, times int) {
+	for i := 0; i < times; i++ {
+		lockout.Fail(key)
+	}
+}
+
+// Each lockout is twice as long as the previous one, up to the maximum
+func TestLockoutDoubles(t *testing.T) {
+	clock := newFakeClock()
+	lockout := newTestLockout(clock)
+
+	failTimes(lockout, "alice", 2)
+	if got := lockout.LockedFor("alice"); got != 0 {
+		t.Fatalf("expected no lockout before the maximum number of failures, got %v", got)
+	}
+
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute} {
+		failTimes(lockout, "alice", 3)
+		if got := lockout.LockedFor("alice"); got != want {
+			t.Errorf("expected a lockout of %v, got %v", want, got)
+		}
+		clock.Advance(want)
+		if got := lockout.LockedFor("alice"); got != 0 {
+			t.Errorf("expected the lockout to end after %v, got %v left", want, got)
+		}
+	}
+
+	if got := lockout.LockedFor("bob"); got != 0 {
+		t.Errorf("expected other keys not to be locked out, got %v", got)
+	}
+}
+
+func TestLockoutLockedFor(t *testing.T) {
+	clock := newFakeClock()
+	lockout := newTestLockout(clock)
+
+	failTimes(lockout, "alice", 3)
+	clock.Advance(20 * time.Second)
+	if got := lockout.LockedFor("alice"); got != 40*time.Second {
+		t.Errorf("expected 40s left, got %v", got)
+	}
+}
+
+// Failures older than the maximum lockout are forgotten, along with previous lockouts
+func TestLockoutExpiry(t *testing.T) {
+	clock := newFakeClock()
+	lockout := newTestLockout(clock)
+
+	failTimes(lockout, "alice", 3)
+	clock.Advance(time.Minute)
+	failTimes(lockout, "alice", 2)
+	clock.Advance(4*time.Minute + time.Second)
+
+	// The two old failures are forgotten, so a third does not lock the key out
+	lockout.Fail("alice")
+	if got := lockout.LockedFor("alice"); got != 0 {
+		t.Fatalf("expected old failures to be forgotten, got a lockout of %v", got)
+	}
+	// Neither is the previous lockout, so the next one is as short as the first
+	failTimes(lockout, "alice", 2)
+	if got := lockout.LockedFor("alice"); got != time.Minute {
+		t.Errorf("expected a lockout of 1m, got %v", got)
+	}
+
+	// Expired entries are swept
+	clock.Advance(sweepInterval)
+	lockout.Fail("bob")
+	if _, exists := lockout.entries["alice"]; exists {
+		t.Errorf("expected the expired entry to be swept")
+	}
+}
+
+// Successful attempts clear failures and previous lockouts
+func TestLockoutReset(t *testing.T) {
+	clock := newFakeClock()
+	lockout := newTestLockout(clock)
+
+	failTimes(lockout, "alice", 3)
+	clock.Advance(time.Minute)
+	failTimes(lockout, "alice", 2)
+	lockout.Reset("alice")
+
+	lockout.Fail("alice")
+	if got := lockout.LockedFor("alice"); got != 0 {
+		t.Fatalf("expected failures before the reset to be cleared, got a lockout of %v", got)
+	}
+	failTimes(lockout, "alice", 2)
+	if got := lockout.LockedFor("alice"); got != time.Minute {
+		t.Errorf("expected the lockout duration to start over, got %v", got)
+	}
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// How often idle buckets are removed from the in-memory store
+const sweepInterval = 10 * time.Minute
+
+type bucket struct {
+	tokens     float64
+	lastRefill time.Time
+	limit      Limit
+}
+
+// In-memory token bucket store
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), lastSweep: time.Now(), now: time.Now}
+}
+
+func (store *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := store.now()
+	store.sweep(now)
+
+	b, exists := store.buckets[key]
+	if !exists {
+		b = &bucket{tokens: float64(limit.Requests), lastRefill: now, limit: limit}
+		store.buckets[key] = b
+	}
+
+	// Refill the tokens accumulated since the last request
+	rate := float64(limit.Requests) / limit.Period.Seconds() // tokens per second
+	b.tokens = math.Min(float64(limit.Requests), b.tokens+now.Sub(b.lastRefill).Seconds()*rate)
+	b.lastRefill = now
+	b.limit = limit
+
+	result := Result{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.ResetAfter = secondsToDuration((float64(limit.Requests) - b.tokens) / rate)
+
+	return result, nil
+}
+
+// Remove buckets that have been idle long enough to be full again, since they are indistinguishable from new buckets
+// Must be called with the lock held
+func (store *MemoryStore) sweep(now time.Time) {
+	if now.Sub(store.lastSweep) < sweepInterval {
+		return
+	}
+	for key, b := range store.buckets {
+		if now.Sub(b.lastRefill) >= b.limit.Period {
+			delete(store.buckets, key)
+		}
+	}
+	store.lastSweep = now
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A token bucket limit: the bucket holds up to Requests tokens and is refilled at Requests per Period
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Outcome of taking a token from a bucket
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Time until the bucket is full again
+	ResetAfter time.Duration
+	// Time until the next token is available; zero if the request was allowed
+	RetryAfter time.Duration
+}
+
+// Storage for token buckets. Implementations must be safe for concurrent use.
+// The in-memory store is suitable for a single instance; a shared store (e.g. Redis) is needed when running several.
+type Store interface {
+	// Take a token from the bucket identified by key, creating a full bucket if none exists
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Parse a limit of the form "{requests}/{unit}" where unit is s, m or h, e.g. "30/m"
+func ParseLimit(value string) (Limit, error) {
+	requests, unit, found := strings.Cut(value, "/")
+	if !found {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected {requests}/{unit}", value)
+	}
+
+	count, err := strconv.Atoi(requests)
+	if err != nil || count < 1 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive integer", value)
+	}
+
+	var period time.Duration
+	switch unit {
+	case "s":
+		period = time.Second
+	case "m":
+		period = time.Minute
+	case "h":
+		period = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("invalid rate limit %q: unit must be s, m or h", value)
+	}
+
+	return Limit{Requests: count, Period: period}, nil
+}
+
+// Parse the limit in the given environment variable value, falling back to the default if it is empty
+func ParseLimitOrDefault(value string, defaultLimit Limit) (Limit, error) {
+	if value == "" {
+		return defaultLimit, nil
+	}
+	return ParseLimit(value)
+}
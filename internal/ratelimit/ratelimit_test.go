@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Clock that only moves when advanced
+type fakeClock struct {
+	now time.Time
+}
+
+func (clock *fakeClock) Now() time.Time {
+	return clock.now
+}
+
+func (clock *fakeClock) Advance(duration time.Duration) {
+	clock.now = clock.now.Add(duration)
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Limit
+		wantErr bool
+	}{
+		{"30/m", Limit{Requests: 30, Period: time.Minute}, false},
+		{"5/s", Limit{Requests: 5, Period: time.Second}, false},
+		{"100/h", Limit{Requests: 100, Period: time.Hour}, false},
+		{"30", Limit{}, true},
+		{"0/m", Limit{}, true},
+		{"thirty/m", Limit{}, true},
+		{"30/d", Limit{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseLimit(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	clock := newFakeClock()
+	store := NewMemoryStore()
+	store.now = clock.Now
+	ctx := context.Background()
+	// A token every 20 seconds
+	limit := Limit{Requests: 3, Period: time.Minute}
+
+	take := func(key string) Result {
+		t.Helper()
+		result, err := store.Take(ctx, key, limit)
+		if err != nil {
+			t.Fatalf("failed to take a token: %v", err)
+		}
+		return result
+	}
+
+	// A new bucket is full
+	for want := 2; want >= 0; want-- {
+		result := take("alice")
+		if !result.Allowed || result.Remaining != want || result.Limit != 3 {
+			t.Errorf("expected to be allowed with %d remaining, got %+v", want, result)
+		}
+	}
+	if result := take("alice"); result.Allowed || result.RetryAfter != 20*time.Second || result.ResetAfter != time.Minute {
+		t.Errorf("expected to retry in 20s and be full in 1m, got %+v", result)
+	}
+	// Buckets are independent
+	if result := take("bob"); !result.Allowed {
+		t.Errorf("expected another key to be allowed, got %+v", result)
+	}
+
+	// Tokens are refilled at the limit's rate
+	clock.Advance(15 * time.Second)
+	if result := take("alice"); result.Allowed || result.RetryAfter != 5*time.Second {
+		t.Errorf("expected to retry in 5s, got %+v", result)
+	}
+	clock.Advance(5 * time.Second)
+	if result := take("alice"); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("expected the refilled token to be taken, got %+v", result)
+	}
+
+	// Buckets refill up to their capacity only
+	clock.Advance(time.Hour)
+	if result := take("alice"); !result.Allowed || result.Remaining != 2 {
+		t.Errorf("expected a full bucket, got %+v", result)
+	}
+}
+
+// Idle buckets are removed once they would be full again
+func TestMemoryStoreSweep(t *testing.T) {
+	clock := newFakeClock()
+	store := NewMemoryStore()
+	store.now = clock.Now
+	store.lastSweep = clock.Now()
+	limit := Limit{Requests: 1, Period: time.Minute}
+
+	if _, err := store.Take(context.Background(), "alice", limit); err != nil {
+		t.Fatalf("failed to take a token: %v", err)
+	}
+	clock.Advance(sweepInterval)
+	if _, err := store.Take(context.Background(), "bob", limit); err != nil {
+		t.Fatalf("failed to take a token: %v", err)
+	}
+	if _, exists := store.buckets["alice"]; exists || len(store.buckets) != 1 {
+		t.Errorf("expected only the active bucket to be kept, got %v", store.buckets)
+	}
+}
@@ -0,0 +1,126 @@
+package repos
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type activityRepo struct {
+	DB *gorm.DB
+}
+
+func NewActivityRepo(db *gorm.DB) ActivityRepo {
+	return &activityRepo{DB: db}
+}
+
+// Restrict a query to the records of one activity type that come after the cursor in the stream
+func afterCursor(db *gorm.DB, activityType string, timeColumn string, idColumn string, cursor *models.ActivityCursor) *gorm.DB {
+	if cursor == nil {
+		return db
+	}
+	switch {
+	// Records of a lower type at the cursor's time come after it
+	case activityType < cursor.Type:
+		return db.Where(timeColumn+" <= ?", cursor.CreatedAt)
+	case activityType > cursor.Type:
+		return db.Where(timeColumn+" < ?", cursor.CreatedAt)
+	default:
+		return db.Where(timeColumn+" < ? OR ("+timeColumn+" = ? AND "+idColumn+" < ?)", cursor.CreatedAt, cursor.CreatedAt, cursor.TargetID)
+	}
+}
+
+// Get up to limit of the user's most recent posts, comments and, if includeVotes is set, votes of each type after the cursor
+// The entries are not merged; callers sort them and take the first limit entries
+// Posts and comments include their author (and topics), but not the computed vote fields
+func (repo *activityRepo) GetByUserID(ctx context.Context, userID uint, after *models.ActivityCursor, limit int, includeVotes bool) ([]models.Activity, error) {
+	db := dbFrom(ctx, repo.DB)
+	var activities []models.Activity
+
+	// Posts written by the user
+	var posts []models.Post
+	err := afterCursor(db.Preload("Topics").Preload("Author").Where("author_id = ? AND status = ?", userID, models.PostStatusPublished), models.ActivityPost, "created_at", "id", after).
+		Order("created_at DESC, id DESC").Limit(limit).
+		Find(&posts).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range posts {
+		activities = append(activities, models.Activity{Type: models.ActivityPost, CreatedAt: posts[i].CreatedAt, Post: &posts[i]})
+	}
+
+	// Comments written by the user
+	var comments []models.Comment
+	err = afterCursor(db.Preload("Author").Where("author_id = ?", userID), models.ActivityComment, "created_at", "id", after).
+		Order("created_at DESC, id DESC").Limit(limit).
+		Find(&comments).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range comments {
+		activities = append(activities, models.Activity{Type: models.ActivityComment, CreatedAt: comments[i].CreatedAt, Comment: &comments[i]})
+	}
+
+	if !includeVotes {
+		return activities, nil
+	}
+
+	// Votes cast by the user on posts, together with the posts
+	var postVotes []models.PostVote
+	err = afterCursor(db.Where("user_id = ?", userID), models.ActivityPostVote, "updated_at", "post_id", after).
+		Order("updated_at DESC, post_id DESC").Limit(limit).
+		Find(&postVotes).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(postVotes) > 0 {
+		postIDs := make([]uint, 0, len(postVotes))
+		for _, vote := range postVotes {
+			postIDs = append(postIDs, vote.PostID)
+		}
+		var votedPosts []models.Post
+		if err := db.Preload("Topics").Preload("Author").Where("id IN ?", postIDs).Find(&votedPosts).Error; err != nil {
+			return nil, err
+		}
+		postsByID := make(map[uint]*models.Post, len(votedPosts))
+		for i := range votedPosts {
+			postsByID[votedPosts[i].ID] = &votedPosts[i]
+		}
+		for _, vote := range postVotes {
+			if post, exists := postsByID[vote.PostID]; exists {
+				activities = append(activities, models.Activity{Type: models.ActivityPostVote, CreatedAt: vote.UpdatedAt, Post: post, Vote: vote.Value})
+			}
+		}
+	}
+
+	// Votes cast by the user on comments, together with the comments
+	var commentVotes []models.CommentVote
+	err = afterCursor(db.Where("user_id = ?", userID), models.ActivityCommentVote, "updated_at", "comment_id", after).
+		Order("updated_at DESC, comment_id DESC").Limit(limit).
+		Find(&commentVotes).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(commentVotes) > 0 {
+		commentIDs := make([]uint, 0, len(commentVotes))
+		for _, vote := range commentVotes {
+			commentIDs = append(commentIDs, vote.CommentID)
+		}
+		var votedComments []models.Comment
+		if err := db.Preload("Author").Where("id IN ?", commentIDs).Find(&votedComments).Error; err != nil {
+			return nil, err
+		}
+		commentsByID := make(map[uint]*models.Comment, len(votedComments))
+		for i := range votedComments {
+			commentsByID[votedComments[i].ID] = &votedComments[i]
+		}
+		for _, vote := range commentVotes {
+			if comment, exists := commentsByID[vote.CommentID]; exists {
+				activities = append(activities, models.Activity{Type: models.ActivityCommentVote, CreatedAt: vote.UpdatedAt, Comment: comment, Vote: vote.Value})
+			}
+		}
+	}
+
+	return activities, nil
+}
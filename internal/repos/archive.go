@@ -0,0 +1,22 @@
+package repos
+
+import (
+	"context"
+	"cvwo-backend/internal/archive"
+	"io"
+
+	"gorm.io/gorm"
+)
+
+type archiveRepo struct {
+	DB *gorm.DB
+}
+
+func NewArchiveRepo(db *gorm.DB) ArchiveRepo {
+	return &archiveRepo{DB: db}
+}
+
+// Export every table in a single transaction of its own, so that the archive is consistent
+func (repo *archiveRepo) Export(ctx context.Context, w io.Writer, options archive.Options) error {
+	return archive.Export(ctx, repo.DB, w, options)
+}
@@ -0,0 +1,66 @@
+package repos
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type attachmentRepo struct {
+	DB *gorm.DB
+}
+
+func NewAttachmentRepo(db *gorm.DB) AttachmentRepo {
+	return &attachmentRepo{DB: db}
+}
+
+func (repo *attachmentRepo) Create(ctx context.Context, attachment *models.Attachment) error {
+	return dbFrom(ctx, repo.DB).Create(attachment).Error
+}
+
+func (repo *attachmentRepo) GetByID(ctx context.Context, id uint) (*models.Attachment, error) {
+	var attachment models.Attachment
+	if err := dbFrom(ctx, repo.DB).First(&attachment, id).Error; err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// Get the attachments of a post, in upload order
+func (repo *attachmentRepo) GetByPostID(ctx context.Context, postID uint) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	if err := dbFrom(ctx, repo.DB).Where("post_id = ?", postID).Order("id").Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// Get the attachments of a comment, in upload order
+func (repo *attachmentRepo) GetByCommentID(ctx context.Context, commentID uint) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	if err := dbFrom(ctx, repo.DB).Where("comment_id = ?", commentID).Order("id").Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// Get the attachments stored under any of the given blob keys
+func (repo *attachmentRepo) GetByBlobKeys(ctx context.Context, keys []string) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	if err := dbFrom(ctx, repo.DB).Where("blob_key IN ?", keys).Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (repo *attachmentRepo) Delete(ctx context.Context, id uint) error {
+	result := dbFrom(ctx, repo.DB).Delete(&models.Attachment{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
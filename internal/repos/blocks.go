@@ -0,0 +1,74 @@
+package repos
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type blockRepo struct {
+	DB *gorm.DB
+}
+
+func NewBlockRepo(db *gorm.DB) BlockRepo {
+	return &blockRepo{DB: db}
+}
+
+// Exclude records whose author has been blocked or muted by the current user
+// authorColumn is the column referring to the author of the records, e.g. "posts.author_id"
+func hideBlockedAuthors(db *gorm.DB, authorColumn string, currentUserID uint) *gorm.DB {
+	// Anonymous users have no blocks
+	if currentUserID == 0 {
+		return db
+	}
+	return db.Where("NOT EXISTS (SELECT 1 FROM blocks WHERE blocks.user_id = ? AND blocks.blocked_user_id = "+authorColumn+")", currentUserID)
+}
+
+// Block or mute a user, replacing any existing block or mute of the same user
+func (repo *blockRepo) Block(ctx context.Context, block *models.Block) error {
+	return dbFrom(ctx, repo.DB).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "blocked_user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"type", "created_at"}),
+	}).Create(block).Error
+}
+
+// Remove a block or mute
+func (repo *blockRepo) Unblock(ctx context.Context, userID, blockedUserID uint) error {
+	return dbFrom(ctx, repo.DB).Delete(&models.Block{}, "user_id = ? AND blocked_user_id = ?", userID, blockedUserID).Error
+}
+
+// Check whether the user has blocked (not just muted) the other user
+func (repo *blockRepo) IsBlocked(ctx context.Context, userID, blockedUserID uint) (bool, error) {
+	var count int64
+	err := dbFrom(ctx, repo.DB).Model(&models.Block{}).
+		Where("user_id = ? AND blocked_user_id = ? AND type = ?", userID, blockedUserID, models.BlockTypeBlock).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Get the users blocked or muted by the user, most recent first, optionally filtered by block type
+// Also returns the total number of blocks
+func (repo *blockRepo) GetByUserID(ctx context.Context, userID uint, blockType string, limit, offset int) ([]models.Block, int64, error) {
+	filteredDB := dbFrom(ctx, repo.DB).Model(&models.Block{}).Where("user_id = ?", userID)
+	if blockType != "" {
+		filteredDB = filteredDB.Where("type = ?", blockType)
+	}
+	filteredDB = filteredDB.Session(&gorm.Session{})
+
+	var blocks []models.Block
+	if err := filteredDB.Preload("BlockedUser").Order("created_at DESC").Limit(limit).Offset(offset).Find(&blocks).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var count int64
+	if err := filteredDB.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return blocks, count, nil
+}
@@ -1,37 +1,35 @@
 package repos
 
 import (
+	"context"
 	"cvwo-backend/internal/models"
-	"errors"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-type CommentVoteRepo struct {
+type commentVoteRepo struct {
 	DB *gorm.DB
 }
 
-func NewCommentVoteRepo(db *gorm.DB) *CommentVoteRepo {
-	return &CommentVoteRepo{DB: db}
+func NewCommentVoteRepo(db *gorm.DB) CommentVoteRepo {
+	return &commentVoteRepo{DB: db}
 }
 
 // Update existing vote or create new vote if the user has not voted for the comment
-func (repo *CommentVoteRepo) Upsert(vote *models.CommentVote) error {
-	var existingVote models.CommentVote
-	if err := repo.DB.First(&existingVote, "comment_id = ? AND user_id = ?", vote.CommentID, vote.UserID).Error; err != nil {
-		// If this user has not voted for this comment, create new vote
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return repo.DB.Create(&vote).Error
-		}
-		return err
-	}
-
-	// Update existing vote
-	existingVote.Value = vote.Value
-	return repo.DB.Save(&existingVote).Error
+// Done in a single statement, so that concurrent votes by the same user do not conflict
+func (repo *commentVoteRepo) Upsert(ctx context.Context, vote *models.CommentVote) error {
+	return dbFrom(ctx, repo.DB).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "comment_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(vote).Error
 }
 
 // Delete a vote, i.e. user removes their vote for a comment
-func (repo *CommentVoteRepo) Delete(commentID, userID uint) error {
-	return repo.DB.Delete(&models.CommentVote{}, "comment_id = ? AND user_id = ?", commentID, userID).Error
+func (repo *commentVoteRepo) Delete(ctx context.Context, commentID, userID uint) (bool, error) {
+	result := dbFrom(ctx, repo.DB).Delete(&models.CommentVote{}, "comment_id = ? AND user_id = ?", commentID, userID)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
 }
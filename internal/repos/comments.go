@@ -1,39 +1,45 @@
 package repos
 
 import (
+	"context"
 	"cvwo-backend/internal/models"
 
 	"gorm.io/gorm"
 )
 
-type CommentRepo struct {
+type commentRepo struct {
 	DB *gorm.DB
 }
 
-func NewCommentRepo(db *gorm.DB) *CommentRepo {
-	return &CommentRepo{DB: db}
+func NewCommentRepo(db *gorm.DB) CommentRepo {
+	return &commentRepo{DB: db}
 }
 
 // Get all comments associated with the given post
-func (repo *CommentRepo) GetByPostID(postID uint, limit int, offset int, sortBy string, currentUserID uint) ([]models.Comment, int64, error) {
+// Comments by users the current user has blocked or muted are hidden
+func (repo *commentRepo) GetByPostID(ctx context.Context, postID uint, limit int, offset int, sortBy string, currentUserID uint) ([]models.Comment, int64, error) {
 	var comments []models.Comment
 
 	// Apply filter
-	filteredDB := repo.DB.Where("post_id = ?", postID)
+	filteredDB := hideBlockedAuthors(dbFrom(ctx, repo.DB).Where("post_id = ?", postID), "comments.author_id", currentUserID)
 
-	err := repo.DB.Model(&models.Comment{}).
-		Preload("Author"). // Include comment author
+	err := hideBlockedAuthors(dbFrom(ctx, repo.DB), "comments.author_id", currentUserID).Model(&models.Comment{}).
+		Preload("Author").Preload("Attachments"). // Include comment author and attachments
 		Select("comments.*, "+
 			// Compute net votes of the comment
 			"COALESCE(SUM(votes.value),0) AS net_votes, "+
 			// Get the current user's vote for the comment
-			"COALESCE(MAX(user_votes.value),0) AS user_vote").
+			"COALESCE(MAX(user_votes.value),0) AS user_vote, "+
+			// Check whether the current user has saved the comment
+			"COUNT(saved.item_id) > 0 AS saved").
 		// Filter comments corresponding to the post
 		Where("comments.post_id = ?", postID). 
 		// Get all vote records associated to the comment
 		Joins("LEFT JOIN comment_votes AS votes ON comments.id = votes.comment_id"). 
 		// Get the single vote record made by the current user, that is associated to the comment
 		Joins("LEFT JOIN comment_votes AS user_votes ON comments.id = user_votes.comment_id AND user_votes.user_id = ?", currentUserID). 
+		// Get the save record made by the current user, if any
+		Joins("LEFT JOIN saved_items AS saved ON comments.id = saved.item_id AND saved.item_type = ? AND saved.user_id = ?", models.SavedItemComment, currentUserID).
 		Group("comments.id").
 		Limit(limit).Offset(offset).Order(sortBy).
 		Find(&comments).Error
@@ -52,9 +58,9 @@ func (repo *CommentRepo) GetByPostID(postID uint, limit int, offset int, sortBy
 }
 
 // Get an individual comment
-func (repo *CommentRepo) GetByID(id uint) (*models.Comment, error) {
+func (repo *commentRepo) GetByID(ctx context.Context, id uint) (*models.Comment, error) {
 	var comment models.Comment
-	if err := repo.DB.First(&comment, id).Error; err != nil {
+	if err := dbFrom(ctx, repo.DB).First(&comment, id).Error; err != nil {
 		return nil, err
 	}
 	return &comment, nil
@@ -62,18 +68,21 @@ func (repo *CommentRepo) GetByID(id uint) (*models.Comment, error) {
 
 // Similar to GetByID but includes additional computed fields and preloaded associations
 // Takes in currentUserID in order to compute user_vote field
-func (repo *CommentRepo) GetByIDWithAuth(commentID uint, currentUserID uint) (*models.Comment, error) {
+func (repo *commentRepo) GetByIDWithAuth(ctx context.Context, commentID uint, currentUserID uint) (*models.Comment, error) {
 	var comment models.Comment
 
-	err := repo.DB.Model(&models.Comment{}).
-		Preload("Author").
+	err := dbFrom(ctx, repo.DB).Model(&models.Comment{}).
+		Preload("Author").Preload("Attachments").
 		Select("comments.*, " +
 			// Compute net votes for the comment
 			"COALESCE(SUM(votes.value),0) AS net_votes, " +
 			// Get the current user's vote for the comment
-			"COALESCE(MAX(user_votes.value), 0) AS user_vote").
+			"COALESCE(MAX(user_votes.value), 0) AS user_vote, " +
+			// Check whether the current user has saved the comment
+			"COUNT(saved.item_id) > 0 AS saved").
 		Joins("LEFT JOIN comment_votes AS votes ON comments.id = votes.comment_id").
 		Joins("LEFT JOIN comment_votes AS user_votes ON comments.id = user_votes.comment_id AND user_votes.user_id = ?", currentUserID).
+		Joins("LEFT JOIN saved_items AS saved ON comments.id = saved.item_id AND saved.item_type = ? AND saved.user_id = ?", models.SavedItemComment, currentUserID).
 		Where("comments.id = ?", commentID).
 		Group("comments.id").
 		Find(&comment).Error
@@ -81,35 +90,47 @@ func (repo *CommentRepo) GetByIDWithAuth(commentID uint, currentUserID uint) (*m
 	if err != nil {
 		return nil, err
 	}
+	// Find does not return an error when no rows match
+	if comment.ID == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
 
 	return &comment, nil
 }
 
 // Create a new comment
-func (repo *CommentRepo) Create(comment *models.Comment) (*models.Comment, error) {
-	if err := repo.DB.Create(comment).Error; err != nil {
+func (repo *commentRepo) Create(ctx context.Context, comment *models.Comment) (*models.Comment, error) {
+	if err := dbFrom(ctx, repo.DB).Create(comment).Error; err != nil {
 		return nil, err
 	}
 	return comment, nil
 }
 
-// Update the content of the given comment
-func (repo *CommentRepo) Update(id uint, content string) (*models.Comment, error) {
-	var comment models.Comment
-	if err := repo.DB.First(&comment, id).Error; err != nil {
-		return nil, err
+// Update the content and rendered content of the given comment
+func (repo *commentRepo) Update(ctx context.Context, id uint, version uint, content string, contentHTML string) (*models.Comment, error) {
+	// Check and increment the version in the same statement, so that concurrent edits based on the same version cannot both succeed
+	query := dbFrom(ctx, repo.DB).Model(&models.Comment{}).Where("id = ?", id)
+	if version != 0 {
+		query = query.Where("version = ?", version)
+	}
+	result := query.Updates(map[string]any{"content": content, "content_html": contentHTML, "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		return nil, result.Error
 	}
 
-	if err := repo.DB.Model(&comment).Updates(models.Comment{Content: content}).Error; err != nil {
+	var comment models.Comment
+	if err := dbFrom(ctx, repo.DB).First(&comment, id).Error; err != nil {
 		return nil, err
 	}
-
+	if result.RowsAffected == 0 {
+		return nil, ErrVersionConflict
+	}
 	return &comment, nil
 }
 
 // Delete an individual comment
-func (repo *CommentRepo) Delete(id uint) error {
-	result := repo.DB.Delete(&models.Comment{}, id)
+func (repo *commentRepo) Delete(ctx context.Context, id uint) error {
+	result := dbFrom(ctx, repo.DB).Delete(&models.Comment{}, id)
 	if result.Error != nil {
 		return result.Error
 	}
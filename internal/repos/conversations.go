@@ -0,0 +1,150 @@
+package repos
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type conversationRepo struct {
+	DB *gorm.DB
+}
+
+func NewConversationRepo(db *gorm.DB) ConversationRepo {
+	return &conversationRepo{DB: db}
+}
+
+// Mark the message as read by its sender, since senders have read their own messages
+func markReadBySender(tx *gorm.DB, message *models.Message) error {
+	return tx.Model(&models.ConversationParticipant{}).
+		Where("conversation_id = ? AND user_id = ?", message.ConversationID, message.SenderID).
+		Update("last_read_message_id", message.ID).Error
+}
+
+// Create a conversation with its participants and first message
+func (repo *conversationRepo) Create(ctx context.Context, conversation *models.Conversation, message *models.Message) error {
+	return dbFrom(ctx, repo.DB).Transaction(func(tx *gorm.DB) error {
+		// Participants are created together with the conversation
+		if err := tx.Create(conversation).Error; err != nil {
+			return err
+		}
+
+		message.ConversationID = conversation.ID
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+		return markReadBySender(tx, message)
+	})
+}
+
+// Find the conversation between exactly the 2 given users, if any
+func (repo *conversationRepo) FindDirect(ctx context.Context, userID, otherUserID uint) (*models.Conversation, error) {
+	var conversationID uint
+	err := dbFrom(ctx, repo.DB).Model(&models.ConversationParticipant{}).
+		Select("conversation_id").
+		Group("conversation_id").
+		// Conversations with 2 participants, both of which are the given users
+		Having("COUNT(*) = 2 AND SUM(CASE WHEN user_id IN (?, ?) THEN 1 ELSE 0 END) = 2", userID, otherUserID).
+		Limit(1).
+		Scan(&conversationID).Error
+	if err != nil {
+		return nil, err
+	}
+	if conversationID == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return repo.GetByID(ctx, conversationID)
+}
+
+// Get a conversation including its participants
+func (repo *conversationRepo) GetByID(ctx context.Context, id uint) (*models.Conversation, error) {
+	var conversation models.Conversation
+	if err := dbFrom(ctx, repo.DB).Preload("Participants.User").First(&conversation, id).Error; err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+// Get the conversations the user takes part in, most recently updated first, starting after the cursor
+// Includes the participants, the last message and the number of messages the user has not read
+func (repo *conversationRepo) GetByUserID(ctx context.Context, userID uint, after *models.ConversationCursor, limit int) ([]models.Conversation, error) {
+	db := dbFrom(ctx, repo.DB)
+
+	query := db.Model(&models.Conversation{}).
+		Preload("Participants.User").
+		Select("conversations.*, "+
+			// Count the messages sent by others after the last message the user has read
+			"(SELECT COUNT(*) FROM messages WHERE messages.conversation_id = conversations.id AND messages.id > me.last_read_message_id AND messages.sender_id <> ?) AS unread_count", userID).
+		// Only include conversations the user takes part in
+		Joins("JOIN conversation_participants AS me ON me.conversation_id = conversations.id AND me.user_id = ?", userID)
+	if after != nil {
+		query = query.Where("conversations.updated_at < ? OR (conversations.updated_at = ? AND conversations.id < ?)", after.UpdatedAt, after.UpdatedAt, after.ID)
+	}
+
+	var conversations []models.Conversation
+	if err := query.Order("conversations.updated_at DESC, conversations.id DESC").Limit(limit).Find(&conversations).Error; err != nil {
+		return nil, err
+	}
+	if len(conversations) == 0 {
+		return conversations, nil
+	}
+
+	// Get the last message of each conversation in a single query
+	conversationIDs := make([]uint, 0, len(conversations))
+	for _, conversation := range conversations {
+		conversationIDs = append(conversationIDs, conversation.ID)
+	}
+	var lastMessages []models.Message
+	err := db.Preload("Sender").
+		Where("id IN (?)", db.Model(&models.Message{}).Select("MAX(id)").Where("conversation_id IN ?", conversationIDs).Group("conversation_id")).
+		Find(&lastMessages).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range lastMessages {
+		for j := range conversations {
+			if conversations[j].ID == lastMessages[i].ConversationID {
+				conversations[j].LastMessage = &lastMessages[i]
+			}
+		}
+	}
+
+	return conversations, nil
+}
+
+// Get the messages of a conversation, newest first, with IDs less than beforeID if it is not 0
+func (repo *conversationRepo) GetMessages(ctx context.Context, conversationID uint, beforeID uint, limit int) ([]models.Message, error) {
+	query := dbFrom(ctx, repo.DB).Preload("Sender").Where("conversation_id = ?", conversationID)
+	if beforeID != 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+
+	var messages []models.Message
+	if err := query.Order("id DESC").Limit(limit).Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// Send a message in a conversation, moving the conversation to the top of its participants' lists
+func (repo *conversationRepo) CreateMessage(ctx context.Context, message *models.Message) error {
+	return dbFrom(ctx, repo.DB).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Conversation{ID: message.ConversationID}).Update("updated_at", message.CreatedAt).Error; err != nil {
+			return err
+		}
+		return markReadBySender(tx, message)
+	})
+}
+
+// Record that the user has read the messages of a conversation up to the given message
+// Read receipts never move backwards
+func (repo *conversationRepo) MarkRead(ctx context.Context, conversationID, userID, messageID uint) error {
+	return dbFrom(ctx, repo.DB).Model(&models.ConversationParticipant{}).
+		Where("conversation_id = ? AND user_id = ? AND last_read_message_id < ?", conversationID, userID, messageID).
+		Update("last_read_message_id", messageID).Error
+}
@@ -0,0 +1,80 @@
+package repos
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type followRepo struct {
+	DB *gorm.DB
+}
+
+func NewFollowRepo(db *gorm.DB) FollowRepo {
+	return &followRepo{DB: db}
+}
+
+// Follow a user. Following a user who is already followed has no effect.
+func (repo *followRepo) Follow(ctx context.Context, follow *models.Follow) error {
+	return dbFrom(ctx, repo.DB).Clauses(clause.OnConflict{DoNothing: true}).Create(follow).Error
+}
+
+// Stop following a user
+func (repo *followRepo) Unfollow(ctx context.Context, followerID, followeeID uint) error {
+	return dbFrom(ctx, repo.DB).Delete(&models.Follow{}, "follower_id = ? AND followee_id = ?", followerID, followeeID).Error
+}
+
+// Check whether the follower follows the followee
+func (repo *followRepo) IsFollowing(ctx context.Context, followerID, followeeID uint) (bool, error) {
+	var count int64
+	if err := dbFrom(ctx, repo.DB).Model(&models.Follow{}).Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Get the number of followers of the given user and the number of users they follow
+func (repo *followRepo) GetCounts(ctx context.Context, userID uint) (followers int64, following int64, err error) {
+	if err := dbFrom(ctx, repo.DB).Model(&models.Follow{}).Where("followee_id = ?", userID).Count(&followers).Error; err != nil {
+		return 0, 0, err
+	}
+	if err := dbFrom(ctx, repo.DB).Model(&models.Follow{}).Where("follower_id = ?", userID).Count(&following).Error; err != nil {
+		return 0, 0, err
+	}
+	return followers, following, nil
+}
+
+// Get the users who follow the given user, most recent first
+// Also returns the total number of followers
+func (repo *followRepo) GetFollowers(ctx context.Context, userID uint, limit, offset int) ([]models.User, int64, error) {
+	return repo.getUsers(ctx, "follows.follower_id", "follows.followee_id", userID, limit, offset)
+}
+
+// Get the users the given user follows, most recent first
+// Also returns the total number of followed users
+func (repo *followRepo) GetFollowing(ctx context.Context, userID uint, limit, offset int) ([]models.User, int64, error) {
+	return repo.getUsers(ctx, "follows.followee_id", "follows.follower_id", userID, limit, offset)
+}
+
+// Get the users on one side of the follows of the given user
+// userColumn is the column referring to the listed users, and filterColumn the column referring to the given user
+func (repo *followRepo) getUsers(ctx context.Context, userColumn, filterColumn string, userID uint, limit, offset int) ([]models.User, int64, error) {
+	filteredDB := dbFrom(ctx, repo.DB).Model(&models.User{}).
+		Joins("JOIN follows ON "+userColumn+" = users.id").
+		Where(filterColumn+" = ?", userID).
+		Session(&gorm.Session{})
+
+	var users []models.User
+	if err := filteredDB.Order("follows.created_at DESC").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var count int64
+	if err := filteredDB.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return users, count, nil
+}
@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+)
+
+type activityRepo struct {
+	store *Store
+}
+
+func NewActivityRepo(store *Store) repos.ActivityRepo {
+	return &activityRepo{store}
+}
+
+// Unlike the database query, all entries after the cursor are returned, since callers only take the first limit entries
+func (repo *activityRepo) GetByUserID(ctx context.Context, userID uint, after *models.ActivityCursor, limit int, includeVotes bool) ([]models.Activity, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	all := []models.Activity{}
+	for _, post := range repo.store.posts {
+		if post.AuthorID == userID && post.Status == models.PostStatusPublished {
+			all = append(all, models.Activity{Type: models.ActivityPost, CreatedAt: post.CreatedAt, Post: repo.post(post)})
+		}
+	}
+	for _, comment := range repo.store.comments {
+		if comment.AuthorID == userID {
+			comment.Author = repo.store.users[comment.AuthorID]
+			all = append(all, models.Activity{Type: models.ActivityComment, CreatedAt: comment.CreatedAt, Comment: &comment})
+		}
+	}
+	if includeVotes {
+		for key, vote := range repo.store.postVotes {
+			if key.userID == userID {
+				all = append(all, models.Activity{Type: models.ActivityPostVote, CreatedAt: vote.updatedAt, Post: repo.post(repo.store.posts[key.targetID]), Vote: vote.value})
+			}
+		}
+		for key, vote := range repo.store.commentVotes {
+			if key.userID == userID {
+				comment := repo.store.comments[key.targetID]
+				comment.Author = repo.store.users[comment.AuthorID]
+				all = append(all, models.Activity{Type: models.ActivityCommentVote, CreatedAt: vote.updatedAt, Comment: &comment, Vote: vote.value})
+			}
+		}
+	}
+
+	activities := []models.Activity{}
+	for _, activity := range all {
+		if after == nil || after.Precedes(activity) {
+			activities = append(activities, activity)
+		}
+	}
+	return activities, nil
+}
+
+// Include the author and topics of the post
+// Must be called with the lock held
+func (repo *activityRepo) post(post models.Post) *models.Post {
+	if author, exists := repo.store.users[post.AuthorID]; exists {
+		post.Author = &author
+	}
+	post.Topics = []models.Topic{}
+	for _, topicID := range repo.store.postTopics[post.ID] {
+		post.Topics = append(post.Topics, repo.store.topics[topicID])
+	}
+	return &post
+}
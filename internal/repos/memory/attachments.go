@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+type attachmentRepo struct {
+	store *Store
+}
+
+func NewAttachmentRepo(store *Store) repos.AttachmentRepo {
+	return &attachmentRepo{store}
+}
+
+func (repo *attachmentRepo) Create(ctx context.Context, attachment *models.Attachment) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	_, uploaderExists := repo.store.users[attachment.UploaderID]
+	postExists, commentExists := true, true
+	if attachment.PostID != nil {
+		_, postExists = repo.store.posts[*attachment.PostID]
+	}
+	if attachment.CommentID != nil {
+		_, commentExists = repo.store.comments[*attachment.CommentID]
+	}
+	if !uploaderExists || !postExists || !commentExists {
+		return gorm.ErrForeignKeyViolated
+	}
+	for _, existing := range repo.store.attachments {
+		if existing.BlobKey == attachment.BlobKey {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	attachment.ID = repo.store.newID()
+	attachment.CreatedAt = repo.store.tick()
+	repo.store.attachments[attachment.ID] = *attachment
+	return nil
+}
+
+func (repo *attachmentRepo) GetByID(ctx context.Context, id uint) (*models.Attachment, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	attachment, exists := repo.store.attachments[id]
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &attachment, nil
+}
+
+func (repo *attachmentRepo) GetByPostID(ctx context.Context, postID uint) ([]models.Attachment, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	return repo.store.attachmentsOf(func(attachment models.Attachment) bool {
+		return attachment.PostID != nil && *attachment.PostID == postID
+	}), nil
+}
+
+func (repo *attachmentRepo) GetByCommentID(ctx context.Context, commentID uint) ([]models.Attachment, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	return repo.store.attachmentsOf(func(attachment models.Attachment) bool {
+		return attachment.CommentID != nil && *attachment.CommentID == commentID
+	}), nil
+}
+
+func (repo *attachmentRepo) GetByBlobKeys(ctx context.Context, keys []string) ([]models.Attachment, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	return repo.store.attachmentsOf(func(attachment models.Attachment) bool {
+		return slices.Contains(keys, attachment.BlobKey)
+	}), nil
+}
+
+func (repo *attachmentRepo) Delete(ctx context.Context, id uint) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if _, exists := repo.store.attachments[id]; !exists {
+		return gorm.ErrRecordNotFound
+	}
+	delete(repo.store.attachments, id)
+	return nil
+}
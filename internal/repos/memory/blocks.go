@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type blockRepo struct {
+	store *Store
+}
+
+func NewBlockRepo(store *Store) repos.BlockRepo {
+	return &blockRepo{store}
+}
+
+func (repo *blockRepo) Block(ctx context.Context, block *models.Block) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	_, userExists := repo.store.users[block.UserID]
+	_, blockedUserExists := repo.store.users[block.BlockedUserID]
+	if !userExists || !blockedUserExists {
+		return gorm.ErrForeignKeyViolated
+	}
+	block.CreatedAt = repo.store.tick()
+	repo.store.blocks[blockKey{block.UserID, block.BlockedUserID}] = *block
+	return nil
+}
+
+func (repo *blockRepo) Unblock(ctx context.Context, userID, blockedUserID uint) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	delete(repo.store.blocks, blockKey{userID, blockedUserID})
+	return nil
+}
+
+func (repo *blockRepo) IsBlocked(ctx context.Context, userID, blockedUserID uint) (bool, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	block, exists := repo.store.blocks[blockKey{userID, blockedUserID}]
+	return exists && block.Type == models.BlockTypeBlock, nil
+}
+
+func (repo *blockRepo) GetByUserID(ctx context.Context, userID uint, blockType string, limit, offset int) ([]models.Block, int64, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	blocks := []models.Block{}
+	for key, block := range repo.store.blocks {
+		if key.userID != userID || (blockType != "" && block.Type != blockType) {
+			continue
+		}
+		blockedUser := repo.store.users[block.BlockedUserID]
+		block.BlockedUser = &blockedUser
+		blocks = append(blocks, block)
+	}
+
+	sortBy(blocks, "created_at DESC",
+		func(block models.Block) time.Time { return block.CreatedAt },
+		func(models.Block) int { return 0 },
+		func(block models.Block) uint { return block.BlockedUserID })
+
+	return paginate(blocks, limit, offset), int64(len(blocks)), nil
+}
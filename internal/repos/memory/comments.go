@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type commentRepo struct {
+	store *Store
+}
+
+func NewCommentRepo(store *Store) repos.CommentRepo {
+	return &commentRepo{store}
+}
+
+// Include the author, attachments and computed vote and saved fields
+// Must be called with the lock held
+func (repo *commentRepo) withAssociations(comment models.Comment, currentUserID uint) models.Comment {
+	comment.Author = repo.store.users[comment.AuthorID]
+	comment.Attachments = repo.store.attachmentsOf(func(attachment models.Attachment) bool {
+		return attachment.CommentID != nil && *attachment.CommentID == comment.ID
+	})
+	comment.NetVotes, comment.UserVote = tallyVotes(repo.store.commentVotes, comment.ID, currentUserID)
+	_, comment.Saved = repo.store.savedItems[savedKey{currentUserID, models.SavedItemComment, comment.ID}]
+	return comment
+}
+
+func (repo *commentRepo) GetByPostID(ctx context.Context, postID uint, limit int, offset int, sortField string, currentUserID uint) ([]models.Comment, int64, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	comments := []models.Comment{}
+	for _, comment := range repo.store.comments {
+		if comment.PostID == postID && !repo.store.hidden(comment.AuthorID, currentUserID) {
+			comments = append(comments, repo.withAssociations(comment, currentUserID))
+		}
+	}
+
+	sortBy(comments, sortField,
+		func(comment models.Comment) time.Time { return comment.CreatedAt },
+		func(comment models.Comment) int { return comment.NetVotes },
+		func(comment models.Comment) uint { return comment.ID })
+
+	return paginate(comments, limit, offset), int64(len(comments)), nil
+}
+
+func (repo *commentRepo) GetByID(ctx context.Context, id uint) (*models.Comment, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	comment, exists := repo.store.comments[id]
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &comment, nil
+}
+
+func (repo *commentRepo) GetByIDWithAuth(ctx context.Context, commentID uint, currentUserID uint) (*models.Comment, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	comment, exists := repo.store.comments[commentID]
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+	comment = repo.withAssociations(comment, currentUserID)
+	return &comment, nil
+}
+
+func (repo *commentRepo) Create(ctx context.Context, comment *models.Comment) (*models.Comment, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	_, postExists := repo.store.posts[comment.PostID]
+	_, authorExists := repo.store.users[comment.AuthorID]
+	if !postExists || !authorExists {
+		return nil, gorm.ErrForeignKeyViolated
+	}
+
+	comment.ID = repo.store.newID()
+	comment.CreatedAt = repo.store.tick()
+	comment.UpdatedAt = comment.CreatedAt
+	comment.Version = 1
+	repo.store.comments[comment.ID] = *comment
+	return comment, nil
+}
+
+func (repo *commentRepo) Update(ctx context.Context, id uint, version uint, content string, contentHTML string) (*models.Comment, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	comment, exists := repo.store.comments[id]
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if version != 0 && version != comment.Version {
+		return nil, repos.ErrVersionConflict
+	}
+	comment.Version++
+	comment.Content = content
+	comment.ContentHTML = contentHTML
+	comment.UpdatedAt = repo.store.tick()
+	repo.store.comments[id] = comment
+	return &comment, nil
+}
+
+func (repo *commentRepo) Delete(ctx context.Context, id uint) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if _, exists := repo.store.comments[id]; !exists {
+		return gorm.ErrRecordNotFound
+	}
+	delete(repo.store.comments, id)
+	// Cascade to the comment's votes and attachments
+	for key := range repo.store.commentVotes {
+		if key.targetID == id {
+			delete(repo.store.commentVotes, key)
+		}
+	}
+	for attachmentID, attachment := range repo.store.attachments {
+		if attachment.CommentID != nil && *attachment.CommentID == id {
+			delete(repo.store.attachments, attachmentID)
+		}
+	}
+	return nil
+}
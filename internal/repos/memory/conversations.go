@@ -0,0 +1,208 @@
+package memory
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+type conversationRepo struct {
+	store *Store
+}
+
+func NewConversationRepo(store *Store) repos.ConversationRepo {
+	return &conversationRepo{store}
+}
+
+// Must be called with the lock held
+func (repo *conversationRepo) withUsers(conversation models.Conversation) models.Conversation {
+	participants := make([]models.ConversationParticipant, len(conversation.Participants))
+	for i, participant := range conversation.Participants {
+		user := repo.store.users[participant.UserID]
+		participant.User = &user
+		participants[i] = participant
+	}
+	conversation.Participants = participants
+	return conversation
+}
+
+// Must be called with the lock held
+func (repo *conversationRepo) insertMessage(message *models.Message) error {
+	conversation, exists := repo.store.conversations[message.ConversationID]
+	if _, senderExists := repo.store.users[message.SenderID]; !exists || !senderExists {
+		return gorm.ErrForeignKeyViolated
+	}
+
+	message.ID = repo.store.newID()
+	message.CreatedAt = repo.store.tick()
+	stored := *message
+	stored.Sender = nil
+	repo.store.messages[message.ID] = stored
+
+	conversation.UpdatedAt = message.CreatedAt
+	for i := range conversation.Participants {
+		if conversation.Participants[i].UserID == message.SenderID {
+			conversation.Participants[i].LastReadMessageID = message.ID
+		}
+	}
+	repo.store.conversations[conversation.ID] = conversation
+	return nil
+}
+
+func (repo *conversationRepo) Create(ctx context.Context, conversation *models.Conversation, message *models.Message) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	for _, participant := range conversation.Participants {
+		if _, exists := repo.store.users[participant.UserID]; !exists {
+			return gorm.ErrForeignKeyViolated
+		}
+	}
+
+	conversation.ID = repo.store.newID()
+	conversation.CreatedAt = repo.store.tick()
+	conversation.UpdatedAt = conversation.CreatedAt
+	participants := make([]models.ConversationParticipant, len(conversation.Participants))
+	for i, participant := range conversation.Participants {
+		participant.ConversationID = conversation.ID
+		participant.User = nil
+		participants[i] = participant
+	}
+	repo.store.conversations[conversation.ID] = models.Conversation{
+		ID:           conversation.ID,
+		CreatedAt:    conversation.CreatedAt,
+		UpdatedAt:    conversation.UpdatedAt,
+		Participants: participants,
+	}
+
+	message.ConversationID = conversation.ID
+	if err := repo.insertMessage(message); err != nil {
+		return err
+	}
+	*conversation = repo.withUsers(repo.store.conversations[conversation.ID])
+	return nil
+}
+
+func (repo *conversationRepo) FindDirect(ctx context.Context, userID, otherUserID uint) (*models.Conversation, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	for _, conversation := range repo.store.conversations {
+		participants := conversation.Participants
+		if len(participants) != 2 {
+			continue
+		}
+		if (participants[0].UserID == userID && participants[1].UserID == otherUserID) ||
+			(participants[0].UserID == otherUserID && participants[1].UserID == userID) {
+			conversation = repo.withUsers(conversation)
+			return &conversation, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (repo *conversationRepo) GetByID(ctx context.Context, id uint) (*models.Conversation, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	conversation, exists := repo.store.conversations[id]
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+	conversation = repo.withUsers(conversation)
+	return &conversation, nil
+}
+
+func (repo *conversationRepo) GetByUserID(ctx context.Context, userID uint, after *models.ConversationCursor, limit int) ([]models.Conversation, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	conversations := []models.Conversation{}
+	for _, conversation := range repo.store.conversations {
+		var lastReadMessageID uint
+		participating := false
+		for _, participant := range conversation.Participants {
+			if participant.UserID == userID {
+				participating = true
+				lastReadMessageID = participant.LastReadMessageID
+			}
+		}
+		if !participating {
+			continue
+		}
+		if after != nil && !(conversation.UpdatedAt.Before(after.UpdatedAt) ||
+			(conversation.UpdatedAt.Equal(after.UpdatedAt) && conversation.ID < after.ID)) {
+			continue
+		}
+
+		conversation = repo.withUsers(conversation)
+		for _, message := range repo.store.messages {
+			if message.ConversationID != conversation.ID {
+				continue
+			}
+			if message.ID > lastReadMessageID && message.SenderID != userID {
+				conversation.UnreadCount++
+			}
+			if conversation.LastMessage == nil || message.ID > conversation.LastMessage.ID {
+				sender := repo.store.users[message.SenderID]
+				message.Sender = &sender
+				conversation.LastMessage = &message
+			}
+		}
+		conversations = append(conversations, conversation)
+	}
+
+	sort.Slice(conversations, func(i, j int) bool {
+		a, b := conversations[i], conversations[j]
+		if !a.UpdatedAt.Equal(b.UpdatedAt) {
+			return a.UpdatedAt.After(b.UpdatedAt)
+		}
+		return a.ID > b.ID
+	})
+	return paginate(conversations, limit, 0), nil
+}
+
+func (repo *conversationRepo) GetMessages(ctx context.Context, conversationID uint, beforeID uint, limit int) ([]models.Message, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	messages := []models.Message{}
+	for _, message := range repo.store.messages {
+		if message.ConversationID != conversationID || (beforeID != 0 && message.ID >= beforeID) {
+			continue
+		}
+		sender := repo.store.users[message.SenderID]
+		message.Sender = &sender
+		messages = append(messages, message)
+	}
+
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID > messages[j].ID })
+	return paginate(messages, limit, 0), nil
+}
+
+func (repo *conversationRepo) CreateMessage(ctx context.Context, message *models.Message) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	return repo.insertMessage(message)
+}
+
+func (repo *conversationRepo) MarkRead(ctx context.Context, conversationID, userID, messageID uint) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	conversation, exists := repo.store.conversations[conversationID]
+	if !exists {
+		return nil
+	}
+	for i := range conversation.Participants {
+		participant := &conversation.Participants[i]
+		if participant.UserID == userID && participant.LastReadMessageID < messageID {
+			participant.LastReadMessageID = messageID
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type followRepo struct {
+	store *Store
+}
+
+func NewFollowRepo(store *Store) repos.FollowRepo {
+	return &followRepo{store}
+}
+
+func (repo *followRepo) Follow(ctx context.Context, follow *models.Follow) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	_, followerExists := repo.store.users[follow.FollowerID]
+	_, followeeExists := repo.store.users[follow.FolloweeID]
+	if !followerExists || !followeeExists {
+		return gorm.ErrForeignKeyViolated
+	}
+	key := followKey{follow.FollowerID, follow.FolloweeID}
+	if _, exists := repo.store.follows[key]; !exists {
+		repo.store.follows[key] = repo.store.tick()
+	}
+	return nil
+}
+
+func (repo *followRepo) Unfollow(ctx context.Context, followerID, followeeID uint) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	delete(repo.store.follows, followKey{followerID, followeeID})
+	return nil
+}
+
+func (repo *followRepo) IsFollowing(ctx context.Context, followerID, followeeID uint) (bool, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	_, exists := repo.store.follows[followKey{followerID, followeeID}]
+	return exists, nil
+}
+
+func (repo *followRepo) GetCounts(ctx context.Context, userID uint) (followers int64, following int64, err error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	for key := range repo.store.follows {
+		if key.followeeID == userID {
+			followers++
+		}
+		if key.followerID == userID {
+			following++
+		}
+	}
+	return followers, following, nil
+}
+
+func (repo *followRepo) GetFollowers(ctx context.Context, userID uint, limit, offset int) ([]models.User, int64, error) {
+	return repo.getUsers(func(key followKey) (uint, bool) { return key.followerID, key.followeeID == userID }, limit, offset)
+}
+
+func (repo *followRepo) GetFollowing(ctx context.Context, userID uint, limit, offset int) ([]models.User, int64, error) {
+	return repo.getUsers(func(key followKey) (uint, bool) { return key.followeeID, key.followerID == userID }, limit, offset)
+}
+
+// List the users selected by the given function, most recently followed first
+func (repo *followRepo) getUsers(selectUser func(followKey) (uint, bool), limit, offset int) ([]models.User, int64, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	type followedUser struct {
+		user       models.User
+		followedAt time.Time
+	}
+	followed := []followedUser{}
+	for key, followedAt := range repo.store.follows {
+		if userID, matches := selectUser(key); matches {
+			followed = append(followed, followedUser{repo.store.users[userID], followedAt})
+		}
+	}
+
+	sortBy(followed, "created_at DESC",
+		func(f followedUser) time.Time { return f.followedAt },
+		func(followedUser) int { return 0 },
+		func(f followedUser) uint { return f.user.ID })
+
+	users := []models.User{}
+	for _, f := range paginate(followed, limit, offset) {
+		users = append(users, f.user)
+	}
+	return users, int64(len(followed)), nil
+}
@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"slices"
+)
+
+type outboxRepo struct {
+	store *Store
+}
+
+func NewOutboxRepo(store *Store) repos.OutboxRepo {
+	return &outboxRepo{store}
+}
+
+func (repo *outboxRepo) Add(ctx context.Context, events []models.OutboxEvent) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	for _, event := range events {
+		event.ID = repo.store.newID()
+		event.CreatedAt = repo.store.tick()
+		repo.store.outbox = append(repo.store.outbox, event)
+	}
+	return nil
+}
+
+// Events added to the outbox, in the order they were added, since nothing dispatches them
+func (store *Store) OutboxEvents() []models.OutboxEvent {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	return slices.Clone(store.outbox)
+}
@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+
+	"gorm.io/gorm"
+)
+
+type pollRepo struct {
+	store *Store
+}
+
+func NewPollRepo(store *Store) repos.PollRepo {
+	return &pollRepo{store}
+}
+
+func (repo *pollRepo) GetByPostID(ctx context.Context, postID uint, currentUserID uint) (*models.Poll, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	poll, exists := repo.store.polls[postID]
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	votes := make(map[uint]int64)
+	var totalVoters int64
+	for key, vote := range repo.store.pollVotes {
+		if key.targetID != postID {
+			continue
+		}
+		totalVoters++
+		for _, choice := range vote.Choices {
+			votes[choice.OptionID]++
+		}
+	}
+	poll.TotalVoters = &totalVoters
+	currentUserVote, voted := repo.store.pollVotes[voteKey{postID, currentUserID}]
+	poll.Voted = voted
+
+	// Copy the options so that the stored poll is not modified
+	options := make([]models.PollOption, len(poll.Options))
+	for i, option := range poll.Options {
+		optionVotes := votes[option.ID]
+		option.Votes = &optionVotes
+		for _, choice := range currentUserVote.Choices {
+			if choice.OptionID == option.ID {
+				option.Selected = true
+			}
+		}
+		options[i] = option
+	}
+	poll.Options = options
+
+	return &poll, nil
+}
+
+func (repo *pollRepo) Vote(ctx context.Context, vote *models.PollVote) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	poll, pollExists := repo.store.polls[vote.PostID]
+	_, userExists := repo.store.users[vote.UserID]
+	if !pollExists || !userExists {
+		return gorm.ErrForeignKeyViolated
+	}
+	for _, choice := range vote.Choices {
+		optionExists := false
+		for _, option := range poll.Options {
+			optionExists = optionExists || option.ID == choice.OptionID
+		}
+		if !optionExists {
+			return gorm.ErrForeignKeyViolated
+		}
+	}
+
+	key := voteKey{vote.PostID, vote.UserID}
+	if _, exists := repo.store.pollVotes[key]; exists {
+		return gorm.ErrDuplicatedKey
+	}
+	vote.CreatedAt = repo.store.tick()
+	repo.store.pollVotes[key] = *vote
+	return nil
+}
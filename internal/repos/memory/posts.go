@@ -0,0 +1,388 @@
+package memory
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"slices"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type postRepo struct {
+	store *Store
+}
+
+func NewPostRepo(store *Store) repos.PostRepo {
+	return &postRepo{store}
+}
+
+// Include the author, topics, attachments and computed vote and saved fields, like buildPostsQuery
+// Must be called with the lock held
+func (repo *postRepo) withAssociations(post models.Post, currentUserID uint) models.Post {
+	if author, exists := repo.store.users[post.AuthorID]; exists {
+		post.Author = &author
+	}
+	post.Topics = []models.Topic{}
+	for _, topicID := range repo.store.postTopics[post.ID] {
+		post.Topics = append(post.Topics, repo.store.topics[topicID])
+	}
+	post.Attachments = repo.store.attachmentsOf(func(attachment models.Attachment) bool {
+		return attachment.PostID != nil && *attachment.PostID == post.ID
+	})
+	post.NetVotes, post.UserVote = tallyVotes(repo.store.postVotes, post.ID, currentUserID)
+	_, post.Saved = repo.store.savedItems[savedKey{currentUserID, models.SavedItemPost, post.ID}]
+	return post
+}
+
+// pinPosition gives the position of posts pinned in the listing like in buildPostsQuery, or is nil if nothing is pinned in the listing
+// Must be called with the lock held
+func (repo *postRepo) list(filter func(models.Post) bool, pinPosition func(models.Post) *int, limit, offset int, sortField string, currentUserID uint) ([]models.Post, int64) {
+	posts := []models.Post{}
+	for _, post := range repo.store.posts {
+		if post.Status == models.PostStatusPublished && filter(post) && !repo.store.hidden(post.AuthorID, currentUserID) {
+			post = repo.withAssociations(post, currentUserID)
+			post.Pinned = pinPosition != nil && pinPosition(post) != nil
+			posts = append(posts, post)
+		}
+	}
+
+	sortBy(posts, sortField,
+		func(post models.Post) time.Time { return post.CreatedAt },
+		func(post models.Post) int { return post.NetVotes },
+		func(post models.Post) uint { return post.ID })
+	if pinPosition != nil {
+		pinnedFirst(posts, pinPosition)
+	}
+
+	return paginate(posts, limit, offset), int64(len(posts))
+}
+
+func (repo *postRepo) GetList(ctx context.Context, limit, offset int, sortBy string, currentUserID uint) ([]models.Post, int64, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	// Posts pinned globally come first
+	pinPosition := func(post models.Post) *int { return post.PinPosition }
+	posts, count := repo.list(func(models.Post) bool { return true }, pinPosition, limit, offset, sortBy, currentUserID)
+	return posts, count, nil
+}
+
+func (repo *postRepo) GetByTopics(ctx context.Context, topicIDs []uint, limit, offset int, sortBy string, currentUserID uint) ([]models.Post, int64, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	hasTopic := func(post models.Post) bool {
+		for _, topicID := range repo.store.postTopics[post.ID] {
+			if slices.Contains(topicIDs, topicID) {
+				return true
+			}
+		}
+		return false
+	}
+	// Posts pinned to any of the topics come first, in the order of their lowest pin position
+	pinPosition := func(post models.Post) *int {
+		var position *int
+		for _, topicID := range topicIDs {
+			if pin, pinned := repo.store.topicPins[pinKey{topicID, post.ID}]; pinned && (position == nil || pin.Position < *position) {
+				position = &pin.Position
+			}
+		}
+		return position
+	}
+	posts, count := repo.list(hasTopic, pinPosition, limit, offset, sortBy, currentUserID)
+	return posts, count, nil
+}
+
+func (repo *postRepo) GetFeed(ctx context.Context, userID uint, limit, offset int, sortBy string) ([]models.Post, int64, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	inFeed := func(post models.Post) bool {
+		if _, followed := repo.store.follows[followKey{userID, post.AuthorID}]; followed {
+			return true
+		}
+		for _, topicID := range repo.store.postTopics[post.ID] {
+			if _, subscribed := repo.store.subscriptions[subscriptionKey{userID, topicID}]; subscribed {
+				return true
+			}
+		}
+		return false
+	}
+	posts, count := repo.list(inFeed, nil, limit, offset, sortBy, userID)
+	return posts, count, nil
+}
+
+func (repo *postRepo) GetByAuthor(ctx context.Context, authorID uint, limit, offset int, sortBy string, currentUserID uint) ([]models.Post, int64, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	byAuthor := func(post models.Post) bool { return post.AuthorID == authorID }
+	posts, count := repo.list(byAuthor, nil, limit, offset, sortBy, currentUserID)
+	return posts, count, nil
+}
+
+func (repo *postRepo) GetByID(ctx context.Context, id uint) (*models.Post, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	post, exists := repo.store.posts[id]
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &post, nil
+}
+
+func (repo *postRepo) GetByIDWithAuth(ctx context.Context, postID uint, currentUserID uint) (*models.Post, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	post, exists := repo.store.posts[postID]
+	// Unpublished posts are only visible to their author
+	if !exists || (post.Status != models.PostStatusPublished && post.AuthorID != currentUserID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	post = repo.withAssociations(post, currentUserID)
+	post.Pinned = post.PinPosition != nil
+	return &post, nil
+}
+
+func (repo *postRepo) Create(ctx context.Context, post *models.Post) (*models.Post, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if _, exists := repo.store.users[post.AuthorID]; !exists {
+		return nil, gorm.ErrForeignKeyViolated
+	}
+
+	post.ID = repo.store.newID()
+	post.CreatedAt = repo.store.tick()
+	post.UpdatedAt = post.CreatedAt
+	post.Version = 1
+	// Like the column default
+	if post.Status == "" {
+		post.Status = models.PostStatusPublished
+	}
+	// Create the poll with the post, like gorm creates associations
+	stored := *post
+	if post.Poll != nil {
+		post.Poll.PostID = post.ID
+		for i := range post.Poll.Options {
+			post.Poll.Options[i].ID = repo.store.newID()
+			post.Poll.Options[i].PostID = post.ID
+		}
+		poll := *post.Poll
+		poll.Options = append([]models.PollOption{}, post.Poll.Options...)
+		repo.store.polls[post.ID] = poll
+		stored.Poll = nil
+	}
+	repo.store.posts[post.ID] = stored
+	return post, nil
+}
+
+func (repo *postRepo) Update(ctx context.Context, id uint, version uint, title string, content string, contentHTML string) (*models.Post, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	post, exists := repo.store.posts[id]
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if version != 0 && version != post.Version {
+		return nil, repos.ErrVersionConflict
+	}
+	post.Version++
+	post.Title = title
+	post.Content = content
+	post.ContentHTML = contentHTML
+	post.UpdatedAt = repo.store.tick()
+	repo.store.posts[id] = post
+	return &post, nil
+}
+
+func (repo *postRepo) AssociatePostWithTopics(ctx context.Context, post *models.Post, topics []models.Topic) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	topicIDs := []uint{}
+	for _, topic := range topics {
+		if _, exists := repo.store.topics[topic.ID]; !exists {
+			return gorm.ErrForeignKeyViolated
+		}
+		topicIDs = append(topicIDs, topic.ID)
+	}
+	repo.store.postTopics[post.ID] = topicIDs
+	post.Topics = topics
+	return nil
+}
+
+func (repo *postRepo) Delete(ctx context.Context, id uint) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if _, exists := repo.store.posts[id]; !exists {
+		return gorm.ErrRecordNotFound
+	}
+	delete(repo.store.posts, id)
+	// Cascade to the post's topic associations, pins, votes, attachments and poll
+	delete(repo.store.postTopics, id)
+	for key := range repo.store.topicPins {
+		if key.postID == id {
+			delete(repo.store.topicPins, key)
+		}
+	}
+	delete(repo.store.polls, id)
+	for key := range repo.store.pollVotes {
+		if key.targetID == id {
+			delete(repo.store.pollVotes, key)
+		}
+	}
+	for key := range repo.store.postVotes {
+		if key.targetID == id {
+			delete(repo.store.postVotes, key)
+		}
+	}
+	for attachmentID, attachment := range repo.store.attachments {
+		if attachment.PostID != nil && *attachment.PostID == id {
+			delete(repo.store.attachments, attachmentID)
+		}
+	}
+	return nil
+}
+
+func (repo *postRepo) SetPinPosition(ctx context.Context, id uint, position *int) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	post, exists := repo.store.posts[id]
+	if !exists {
+		return gorm.ErrRecordNotFound
+	}
+	post.PinPosition = position
+	repo.store.posts[id] = post
+	return nil
+}
+
+func (repo *postRepo) SetLocked(ctx context.Context, id uint, locked bool) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	post, exists := repo.store.posts[id]
+	if !exists {
+		return gorm.ErrRecordNotFound
+	}
+	post.Locked = locked
+	repo.store.posts[id] = post
+	return nil
+}
+
+func (repo *postRepo) PinToTopic(ctx context.Context, pin *models.TopicPin) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if _, exists := repo.store.topics[pin.TopicID]; !exists {
+		return gorm.ErrForeignKeyViolated
+	}
+	if _, exists := repo.store.posts[pin.PostID]; !exists {
+		return gorm.ErrForeignKeyViolated
+	}
+	key := pinKey{pin.TopicID, pin.PostID}
+	// Keep the original pin time when moving an existing pin, like the upsert
+	if existing, exists := repo.store.topicPins[key]; exists {
+		pin.CreatedAt = existing.CreatedAt
+	} else {
+		pin.CreatedAt = repo.store.tick()
+	}
+	repo.store.topicPins[key] = *pin
+	return nil
+}
+
+func (repo *postRepo) UnpinFromTopic(ctx context.Context, topicID, postID uint) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	delete(repo.store.topicPins, pinKey{topicID, postID})
+	return nil
+}
+
+func (repo *postRepo) UnpinFromOtherTopics(ctx context.Context, postID uint, topicIDs []uint) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	for key := range repo.store.topicPins {
+		if key.postID == postID && !slices.Contains(topicIDs, key.topicID) {
+			delete(repo.store.topicPins, key)
+		}
+	}
+	return nil
+}
+
+func (repo *postRepo) GetUnpublished(ctx context.Context, authorID uint, limit, offset int) ([]models.Post, int64, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	posts := []models.Post{}
+	for _, post := range repo.store.posts {
+		if post.AuthorID == authorID && post.Status != models.PostStatusPublished {
+			posts = append(posts, repo.withAssociations(post, authorID))
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].UpdatedAt.Equal(posts[j].UpdatedAt) {
+			return posts[i].UpdatedAt.After(posts[j].UpdatedAt)
+		}
+		return posts[i].ID > posts[j].ID
+	})
+	return paginate(posts, limit, offset), int64(len(posts)), nil
+}
+
+func (repo *postRepo) GetDue(ctx context.Context, now time.Time) ([]models.Post, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	posts := []models.Post{}
+	for _, post := range repo.store.posts {
+		if post.Status == models.PostStatusScheduled && post.PublishAt != nil && !post.PublishAt.After(now) {
+			posts = append(posts, post)
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].PublishAt.Equal(*posts[j].PublishAt) {
+			return posts[i].PublishAt.Before(*posts[j].PublishAt)
+		}
+		return posts[i].ID < posts[j].ID
+	})
+	return posts, nil
+}
+
+func (repo *postRepo) SetStatus(ctx context.Context, id uint, status string, publishAt *time.Time) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	post, exists := repo.store.posts[id]
+	if !exists || post.Status == models.PostStatusPublished {
+		return gorm.ErrRecordNotFound
+	}
+	post.Status = status
+	post.PublishAt = publishAt
+	post.UpdatedAt = repo.store.tick()
+	repo.store.posts[id] = post
+	return nil
+}
+
+func (repo *postRepo) Publish(ctx context.Context, id uint, publishedAt time.Time) (bool, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	post, exists := repo.store.posts[id]
+	if !exists || post.Status == models.PostStatusPublished {
+		return false, nil
+	}
+	post.Status = models.PostStatusPublished
+	post.CreatedAt = publishedAt
+	post.UpdatedAt = publishedAt
+	repo.store.posts[id] = post
+	return true, nil
+}
@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"time"
+)
+
+type savedItemRepo struct {
+	store *Store
+}
+
+func NewSavedItemRepo(store *Store) repos.SavedItemRepo {
+	return &savedItemRepo{store}
+}
+
+func (repo *savedItemRepo) Save(ctx context.Context, item *models.SavedItem) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	key := savedKey{item.UserID, item.ItemType, item.ItemID}
+	if _, exists := repo.store.savedItems[key]; exists {
+		return nil
+	}
+	item.CreatedAt = repo.store.tick()
+	repo.store.savedItems[key] = *item
+	return nil
+}
+
+func (repo *savedItemRepo) Unsave(ctx context.Context, userID uint, itemType string, itemID uint) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	delete(repo.store.savedItems, savedKey{userID, itemType, itemID})
+	return nil
+}
+
+func (repo *savedItemRepo) GetByUserID(ctx context.Context, userID uint, itemType string, limit, offset int) ([]models.SavedItem, int64, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	items := []models.SavedItem{}
+	for key, item := range repo.store.savedItems {
+		if key.userID != userID || (itemType != "" && key.itemType != itemType) {
+			continue
+		}
+		// Exclude items whose post or comment has since been deleted
+		_, postExists := repo.store.posts[key.itemID]
+		_, commentExists := repo.store.comments[key.itemID]
+		if (key.itemType == models.SavedItemPost && !postExists) || (key.itemType == models.SavedItemComment && !commentExists) {
+			continue
+		}
+		items = append(items, item)
+	}
+
+	sortBy(items, "created_at DESC",
+		func(item models.SavedItem) time.Time { return item.CreatedAt },
+		func(models.SavedItem) int { return 0 },
+		func(item models.SavedItem) uint { return item.ItemID })
+
+	return paginate(items, limit, offset), int64(len(items)), nil
+}
@@ -0,0 +1,199 @@
+// Package memory provides in-memory implementations of the repository interfaces for testing services without a database.
+// The repositories share a Store so that relationships (authors, topics, votes) behave like the database's foreign keys and joins.
+package memory
+
+import (
+	"cvwo-backend/internal/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type voteKey struct {
+	targetID uint
+	userID   uint
+}
+
+// Value of a vote and when it was last cast or changed
+type storedVote struct {
+	value     int
+	updatedAt time.Time
+}
+
+type followKey struct {
+	followerID uint
+	followeeID uint
+}
+
+type blockKey struct {
+	userID        uint
+	blockedUserID uint
+}
+
+type subscriptionKey struct {
+	userID  uint
+	topicID uint
+}
+
+type pinKey struct {
+	topicID uint
+	postID  uint
+}
+
+type savedKey struct {
+	userID   uint
+	itemType string
+	itemID   uint
+}
+
+// Tables shared by all in-memory repositories
+type Store struct {
+	mu            sync.Mutex
+	nextID        uint
+	users         map[uint]models.User
+	posts         map[uint]models.Post
+	comments      map[uint]models.Comment
+	topics        map[uint]models.Topic
+	postTopics    map[uint][]uint // post ID to topic IDs
+	topicPins     map[pinKey]models.TopicPin
+	postVotes     map[voteKey]storedVote
+	commentVotes  map[voteKey]storedVote
+	savedItems    map[savedKey]models.SavedItem
+	subscriptions map[subscriptionKey]time.Time
+	follows       map[followKey]time.Time
+	blocks        map[blockKey]models.Block
+	conversations map[uint]models.Conversation // includes participants
+	messages      map[uint]models.Message
+	attachments   map[uint]models.Attachment
+	polls         map[uint]models.Poll // post ID to poll, including options
+	pollVotes     map[voteKey]models.PollVote
+	outbox        []models.OutboxEvent
+	// Clock used for CreatedAt and UpdatedAt; each call advances by a second so that ordering is deterministic
+	now time.Time
+}
+
+func NewStore() *Store {
+	return &Store{
+		users:         make(map[uint]models.User),
+		posts:         make(map[uint]models.Post),
+		comments:      make(map[uint]models.Comment),
+		topics:        make(map[uint]models.Topic),
+		postTopics:    make(map[uint][]uint),
+		topicPins:     make(map[pinKey]models.TopicPin),
+		postVotes:     make(map[voteKey]storedVote),
+		commentVotes:  make(map[voteKey]storedVote),
+		savedItems:    make(map[savedKey]models.SavedItem),
+		subscriptions: make(map[subscriptionKey]time.Time),
+		follows:       make(map[followKey]time.Time),
+		blocks:        make(map[blockKey]models.Block),
+		conversations: make(map[uint]models.Conversation),
+		messages:      make(map[uint]models.Message),
+		attachments:   make(map[uint]models.Attachment),
+		polls:         make(map[uint]models.Poll),
+		pollVotes:     make(map[voteKey]models.PollVote),
+		now:           time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// Must be called with the lock held
+func (store *Store) newID() uint {
+	store.nextID++
+	return store.nextID
+}
+
+// Must be called with the lock held
+func (store *Store) tick() time.Time {
+	store.now = store.now.Add(time.Second)
+	return store.now
+}
+
+// Insert a topic directly, since topics cannot be created through the repositories
+func (store *Store) AddTopic(name string) models.Topic {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	topic := models.Topic{ID: store.newID(), Name: name}
+	store.topics[topic.ID] = topic
+	return topic
+}
+
+// Whether content by the author is hidden from the current user because they blocked or muted the author
+// Must be called with the lock held
+func (store *Store) hidden(authorID uint, currentUserID uint) bool {
+	_, blocked := store.blocks[blockKey{currentUserID, authorID}]
+	return blocked
+}
+
+// Get the attachments matching the filter, in upload order
+// Must be called with the lock held
+func (store *Store) attachmentsOf(filter func(models.Attachment) bool) []models.Attachment {
+	attachments := []models.Attachment{}
+	for _, attachment := range store.attachments {
+		if filter(attachment) {
+			attachments = append(attachments, attachment)
+		}
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].ID < attachments[j].ID })
+	return attachments
+}
+
+// Compute the net votes and the given user's vote from a vote table
+// Must be called with the lock held
+func tallyVotes(votes map[voteKey]storedVote, targetID uint, currentUserID uint) (netVotes int, userVote int) {
+	for key, vote := range votes {
+		if key.targetID != targetID {
+			continue
+		}
+		netVotes += vote.value
+		if key.userID == currentUserID {
+			userVote = vote.value
+		}
+	}
+	return netVotes, userVote
+}
+
+// Sort records by one of the SQL orderBy clauses used by the services, breaking ties by ID
+func sortBy[T any](records []T, orderBy string, createdAt func(T) time.Time, netVotes func(T) int, id func(T) uint) {
+	field, direction, _ := strings.Cut(orderBy, " ")
+	descending := direction == "DESC"
+
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		switch field {
+		case "created_at":
+			if !createdAt(a).Equal(createdAt(b)) {
+				return createdAt(a).Before(createdAt(b)) != descending
+			}
+		case "net_votes":
+			if netVotes(a) != netVotes(b) {
+				return (netVotes(a) < netVotes(b)) != descending
+			}
+		}
+		return id(a) < id(b)
+	})
+}
+
+// Move pinned records to the front in order of their pin position, keeping the order of the rest
+// pinPosition returns nil for records that are not pinned
+func pinnedFirst[T any](records []T, pinPosition func(T) *int) {
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := pinPosition(records[i]), pinPosition(records[j])
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return *a < *b
+	})
+}
+
+// Apply limit and offset to a sorted list of records
+func paginate[T any](records []T, limit, offset int) []T {
+	if offset >= len(records) {
+		return []T{}
+	}
+	end := offset + limit
+	if limit < 0 || end > len(records) {
+		end = len(records)
+	}
+	return records[offset:end]
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+type subscriptionRepo struct {
+	store *Store
+}
+
+func NewSubscriptionRepo(store *Store) repos.SubscriptionRepo {
+	return &subscriptionRepo{store}
+}
+
+func (repo *subscriptionRepo) Subscribe(ctx context.Context, subscription *models.TopicSubscription) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	_, userExists := repo.store.users[subscription.UserID]
+	_, topicExists := repo.store.topics[subscription.TopicID]
+	if !userExists || !topicExists {
+		return gorm.ErrForeignKeyViolated
+	}
+	key := subscriptionKey{subscription.UserID, subscription.TopicID}
+	if _, exists := repo.store.subscriptions[key]; !exists {
+		repo.store.subscriptions[key] = repo.store.tick()
+	}
+	return nil
+}
+
+func (repo *subscriptionRepo) Unsubscribe(ctx context.Context, userID, topicID uint) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	delete(repo.store.subscriptions, subscriptionKey{userID, topicID})
+	return nil
+}
+
+func (repo *subscriptionRepo) GetTopicsByUserID(ctx context.Context, userID uint) ([]models.Topic, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	topics := []models.Topic{}
+	for key := range repo.store.subscriptions {
+		if key.userID == userID {
+			topics = append(topics, repo.store.topics[key.topicID])
+		}
+	}
+	sort.Slice(topics, func(i, j int) bool { return topics[i].Name < topics[j].Name })
+	return topics, nil
+}
@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+type topicRepo struct {
+	store *Store
+}
+
+func NewTopicRepo(store *Store) repos.TopicRepo {
+	return &topicRepo{store}
+}
+
+func (repo *topicRepo) GetAll(ctx context.Context) ([]models.Topic, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	topics := make([]models.Topic, 0, len(repo.store.topics))
+	for _, topic := range repo.store.topics {
+		topics = append(topics, topic)
+	}
+	sort.Slice(topics, func(i, j int) bool { return topics[i].ID < topics[j].ID })
+	return topics, nil
+}
+
+// Like the database query, IDs that do not match any topic are ignored
+func (repo *topicRepo) GetByIDs(ctx context.Context, ids []uint) ([]models.Topic, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	topics := []models.Topic{}
+	for _, id := range ids {
+		if topic, exists := repo.store.topics[id]; exists {
+			topics = append(topics, topic)
+		}
+	}
+	return topics, nil
+}
+
+func (repo *topicRepo) GetByID(ctx context.Context, id uint) (*models.Topic, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	topic, exists := repo.store.topics[id]
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &topic, nil
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"context"
+	"cvwo-backend/internal/repos"
+	"maps"
+	"slices"
+)
+
+// Context key marking that a transaction is in progress
+type txKey struct{}
+
+type transactor struct {
+	store *Store
+}
+
+// Transactor that rolls back by restoring a copy of the store taken when the transaction started
+// Unlike a database, changes made by other callers during the transaction are rolled back too, so tests must not run transactions concurrently
+func NewTransactor(store *Store) repos.Transactor {
+	return &transactor{store}
+}
+
+func (transactor *transactor) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) != nil {
+		return fn(ctx)
+	}
+	restore := transactor.store.snapshot()
+	if err := fn(context.WithValue(ctx, txKey{}, true)); err != nil {
+		restore()
+		return err
+	}
+	return nil
+}
+
+// Copy every table, returning a function that puts the copies back
+// Records are copied shallowly, which is enough since repositories replace records rather than modify them in place
+func (store *Store) snapshot() func() {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	users := maps.Clone(store.users)
+	posts := maps.Clone(store.posts)
+	comments := maps.Clone(store.comments)
+	topics := maps.Clone(store.topics)
+	postTopics := maps.Clone(store.postTopics)
+	topicPins := maps.Clone(store.topicPins)
+	postVotes := maps.Clone(store.postVotes)
+	commentVotes := maps.Clone(store.commentVotes)
+	savedItems := maps.Clone(store.savedItems)
+	subscriptions := maps.Clone(store.subscriptions)
+	follows := maps.Clone(store.follows)
+	blocks := maps.Clone(store.blocks)
+	conversations := maps.Clone(store.conversations)
+	messages := maps.Clone(store.messages)
+	attachments := maps.Clone(store.attachments)
+	polls := maps.Clone(store.polls)
+	pollVotes := maps.Clone(store.pollVotes)
+	outbox := slices.Clone(store.outbox)
+
+	return func() {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+
+		// IDs are not rolled back, like database sequences
+		store.users = users
+		store.posts = posts
+		store.comments = comments
+		store.topics = topics
+		store.postTopics = postTopics
+		store.topicPins = topicPins
+		store.postVotes = postVotes
+		store.commentVotes = commentVotes
+		store.savedItems = savedItems
+		store.subscriptions = subscriptions
+		store.follows = follows
+		store.blocks = blocks
+		store.conversations = conversations
+		store.messages = messages
+		store.attachments = attachments
+		store.polls = polls
+		store.pollVotes = pollVotes
+		store.outbox = outbox
+	}
+}
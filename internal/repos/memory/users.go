@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+type userRepo struct {
+	store *Store
+}
+
+func NewUserRepo(store *Store) repos.UserRepo {
+	return &userRepo{store}
+}
+
+func (repo *userRepo) GetAll(ctx context.Context) ([]models.User, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	users := make([]models.User, 0, len(repo.store.users))
+	for _, user := range repo.store.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (repo *userRepo) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	user, exists := repo.store.users[id]
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &user, nil
+}
+
+func (repo *userRepo) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	for _, user := range repo.store.users {
+		if user.Username == username {
+			return &user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (repo *userRepo) Create(ctx context.Context, user *models.User) (*models.User, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	// Usernames have a unique index
+	for _, existing := range repo.store.users {
+		if existing.Username == user.Username {
+			return nil, gorm.ErrDuplicatedKey
+		}
+	}
+
+	user.ID = repo.store.newID()
+	repo.store.users[user.ID] = *user
+	return user, nil
+}
+
+func (repo *userRepo) UpdateSettings(ctx context.Context, id uint, publicVotes bool) (*models.User, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	user, exists := repo.store.users[id]
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+	user.PublicVotes = publicVotes
+	repo.store.users[id] = user
+	return &user, nil
+}
+
+func (repo *userRepo) Delete(ctx context.Context, id uint) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if _, exists := repo.store.users[id]; !exists {
+		return gorm.ErrRecordNotFound
+	}
+	delete(repo.store.users, id)
+	// Cascade to the user's follows and blocks
+	for key := range repo.store.follows {
+		if key.followerID == id || key.followeeID == id {
+			delete(repo.store.follows, key)
+		}
+	}
+	for key := range repo.store.blocks {
+		if key.userID == id || key.blockedUserID == id {
+			delete(repo.store.blocks, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+
+	"gorm.io/gorm"
+)
+
+type postVoteRepo struct {
+	store *Store
+}
+
+func NewPostVoteRepo(store *Store) repos.PostVoteRepo {
+	return &postVoteRepo{store}
+}
+
+func (repo *postVoteRepo) Upsert(ctx context.Context, vote *models.PostVote) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	_, postExists := repo.store.posts[vote.PostID]
+	_, userExists := repo.store.users[vote.UserID]
+	if !postExists || !userExists {
+		return gorm.ErrForeignKeyViolated
+	}
+	repo.store.postVotes[voteKey{vote.PostID, vote.UserID}] = storedVote{vote.Value, repo.store.tick()}
+	return nil
+}
+
+func (repo *postVoteRepo) Delete(ctx context.Context, postID, userID uint) (bool, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	_, ok := repo.store.postVotes[voteKey{postID, userID}]
+	delete(repo.store.postVotes, voteKey{postID, userID})
+	return ok, nil
+}
+
+type commentVoteRepo struct {
+	store *Store
+}
+
+func NewCommentVoteRepo(store *Store) repos.CommentVoteRepo {
+	return &commentVoteRepo{store}
+}
+
+func (repo *commentVoteRepo) Upsert(ctx context.Context, vote *models.CommentVote) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	_, commentExists := repo.store.comments[vote.CommentID]
+	_, userExists := repo.store.users[vote.UserID]
+	if !commentExists || !userExists {
+		return gorm.ErrForeignKeyViolated
+	}
+	repo.store.commentVotes[voteKey{vote.CommentID, vote.UserID}] = storedVote{vote.Value, repo.store.tick()}
+	return nil
+}
+
+func (repo *commentVoteRepo) Delete(ctx context.Context, commentID, userID uint) (bool, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	_, ok := repo.store.commentVotes[voteKey{commentID, userID}]
+	delete(repo.store.commentVotes, voteKey{commentID, userID})
+	return ok, nil
+}
@@ -0,0 +1,24 @@
+package repos
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type outboxRepo struct {
+	DB *gorm.DB
+}
+
+func NewOutboxRepo(db *gorm.DB) OutboxRepo {
+	return &outboxRepo{DB: db}
+}
+
+// Add events to the outbox, to be dispatched once the caller's transaction commits
+func (repo *outboxRepo) Add(ctx context.Context, events []models.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return dbFrom(ctx, repo.DB).Create(&events).Error
+}
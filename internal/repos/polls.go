@@ -0,0 +1,74 @@
+package repos
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type pollRepo struct {
+	DB *gorm.DB
+}
+
+func NewPollRepo(db *gorm.DB) PollRepo {
+	return &pollRepo{DB: db}
+}
+
+// Get the poll carried by a post with its options in order, the number of votes for each option, and the current user's choices
+func (repo *pollRepo) GetByPostID(ctx context.Context, postID uint, currentUserID uint) (*models.Poll, error) {
+	db := dbFrom(ctx, repo.DB)
+
+	var poll models.Poll
+	err := db.Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Where("post_id = ?", postID).First(&poll).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Count the choices of each option
+	var tallies []struct {
+		OptionID uint
+		Votes    int64
+	}
+	if err := db.Model(&models.PollChoice{}).Select("option_id, COUNT(*) AS votes").Where("post_id = ?", postID).Group("option_id").Scan(&tallies).Error; err != nil {
+		return nil, err
+	}
+	votes := make(map[uint]int64, len(tallies))
+	for _, tally := range tallies {
+		votes[tally.OptionID] = tally.Votes
+	}
+
+	var totalVoters int64
+	if err := db.Model(&models.PollVote{}).Where("post_id = ?", postID).Count(&totalVoters).Error; err != nil {
+		return nil, err
+	}
+	poll.TotalVoters = &totalVoters
+
+	// Get the options chosen by the current user, if they have voted
+	var selected []uint
+	if err := db.Model(&models.PollChoice{}).Where("post_id = ? AND user_id = ?", postID, currentUserID).Pluck("option_id", &selected).Error; err != nil {
+		return nil, err
+	}
+	// Every vote chooses at least 1 option
+	poll.Voted = len(selected) > 0
+
+	for i := range poll.Options {
+		optionVotes := votes[poll.Options[i].ID]
+		poll.Options[i].Votes = &optionVotes
+		for _, optionID := range selected {
+			if optionID == poll.Options[i].ID {
+				poll.Options[i].Selected = true
+			}
+		}
+	}
+
+	return &poll, nil
+}
+
+// Record a user's vote together with its choices
+// Returns gorm.ErrDuplicatedKey if the user has already voted in the poll
+func (repo *pollRepo) Vote(ctx context.Context, vote *models.PollVote) error {
+	// The choices are created in the same transaction as the vote
+	return dbFrom(ctx, repo.DB).Create(vote).Error
+}
@@ -1,37 +1,35 @@
 package repos
 
 import (
+	"context"
 	"cvwo-backend/internal/models"
-	"errors"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-type PostVoteRepo struct {
+type postVoteRepo struct {
 	DB *gorm.DB
 }
 
-func NewPostVoteRepo(db *gorm.DB) *PostVoteRepo {
-	return &PostVoteRepo{DB: db}
+func NewPostVoteRepo(db *gorm.DB) PostVoteRepo {
+	return &postVoteRepo{DB: db}
 }
 
 // Update existing vote or create new vote if the user has not voted for the post
-func (repo *PostVoteRepo) Upsert(vote *models.PostVote) error {
-	var existingVote models.PostVote
-	if err := repo.DB.First(&existingVote, "post_id = ? AND user_id = ?", vote.PostID, vote.UserID).Error; err != nil {
-		// If this user has not voted for this post, create new vote
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return repo.DB.Create(&vote).Error
-		}
-		return err
-	}
-
-	// Update existing vote
-	existingVote.Value = vote.Value
-	return repo.DB.Save(&existingVote).Error
+// Done in a single statement, so that concurrent votes by the same user do not conflict
+func (repo *postVoteRepo) Upsert(ctx context.Context, vote *models.PostVote) error {
+	return dbFrom(ctx, repo.DB).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "post_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(vote).Error
 }
 
 // Delete a vote, i.e. user removes their vote for a post
-func (repo *PostVoteRepo) Delete(postID, userID uint) error {
-	return repo.DB.Delete(&models.PostVote{}, "post_id = ? AND user_id = ?", postID, userID).Error
+func (repo *postVoteRepo) Delete(ctx context.Context, postID, userID uint) (bool, error) {
+	result := dbFrom(ctx, repo.DB).Delete(&models.PostVote{}, "post_id = ? AND user_id = ?", postID, userID)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
 }
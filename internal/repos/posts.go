@@ -1,31 +1,52 @@
 package repos
 
 import (
+	"context"
 	"cvwo-backend/internal/models"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-type PostRepo struct {
+type postRepo struct {
 	DB *gorm.DB
 }
 
-func NewPostRepo(db *gorm.DB) *PostRepo {
-	return &PostRepo{DB: db}
+func NewPostRepo(db *gorm.DB) PostRepo {
+	return &postRepo{DB: db}
+}
+
+// Only list published posts; drafts and scheduled posts are only visible to their author
+func published(db *gorm.DB) *gorm.DB {
+	return db.Where("posts.status = ?", models.PostStatusPublished)
 }
 
 // Helper function that can be used by all repository functions that involve getting a list of posts
-// Helps to calculate computed fields and preload associations
-func buildPostsQuery(db *gorm.DB, limit, offset int, sortBy string, currentUserID uint) *gorm.DB {
-	return db.Model(&models.Post{}).
-		Preload("Topics").Preload("Author"). // Include these fields in the returned post
+// Helps to calculate computed fields and preload associations, and hides unpublished posts and posts by users the current user has blocked or muted
+// pinPosition is an SQL expression for the post's position among the posts pinned in the listing (null if the post is not pinned),
+// or empty if nothing is pinned in the listing. Pinned posts come first regardless of sortBy.
+func buildPostsQuery(db *gorm.DB, pinPosition string, limit, offset int, sortBy string, currentUserID uint) *gorm.DB {
+	pinned := "FALSE AS pinned"
+	if pinPosition != "" {
+		pinned = pinPosition + " IS NOT NULL AS pinned"
+		sortBy = pinPosition + " IS NULL, " + pinPosition + ", " + sortBy
+	}
+
+	return published(hideBlockedAuthors(db, "posts.author_id", currentUserID)).Model(&models.Post{}).
+		Preload("Topics").Preload("Author").Preload("Attachments"). // Include these fields in the returned post
 		Select("posts.*, "+
 			// Compute net votes of the post
 			"COALESCE(SUM(votes.value),0) AS net_votes, "+
 			// Get the current user's vote for the post
-			"COALESCE(MAX(user_votes.value),0) AS user_vote").
+			"COALESCE(MAX(user_votes.value),0) AS user_vote, "+
+			// Check whether the current user has saved the post
+			"COUNT(saved.item_id) > 0 AS saved, "+
+			// Check whether the post is pinned in the listing
+			pinned).
 		Joins("LEFT JOIN post_votes AS votes ON posts.id = votes.post_id").                                                              // Get all vote records associated to the post
 		Joins("LEFT JOIN post_votes AS user_votes ON posts.id = user_votes.post_id AND user_votes.user_id = ?", currentUserID). // Get the single vote record made by the current user, that is associated to the post
+		Joins("LEFT JOIN saved_items AS saved ON posts.id = saved.item_id AND saved.item_type = ? AND saved.user_id = ?", models.SavedItemPost, currentUserID). // Get the save record made by the current user, if any
 		Group("posts.id").
 		Limit(limit).Offset(offset).Order(sortBy).
 		Session(&gorm.Session{}) // Prevent query contamination
@@ -33,15 +54,16 @@ func buildPostsQuery(db *gorm.DB, limit, offset int, sortBy string, currentUserI
 
 // Get a list of all posts including their associated topics
 // Also returns the total number of posts
-func (repo *PostRepo) GetList(limit, offset int, sortBy string, currentUserID uint) ([]models.Post, int64, error) {
+func (repo *postRepo) GetList(ctx context.Context, limit, offset int, sortBy string, currentUserID uint) ([]models.Post, int64, error) {
 	var posts []models.Post
-	if err := buildPostsQuery(repo.DB, limit, offset, sortBy, currentUserID).Find(&posts).Error; err != nil {
+	// Posts pinned globally come first
+	if err := buildPostsQuery(dbFrom(ctx, repo.DB), "posts.pin_position", limit, offset, sortBy, currentUserID).Find(&posts).Error; err != nil {
 		return nil, 0, err
 	}
 
-	// Get the total number of posts
+	// Get the total number of posts, excluding hidden posts like buildPostsQuery
 	var count int64
-	if err := repo.DB.Model(&models.Post{}).Count(&count).Error; err != nil {
+	if err := published(hideBlockedAuthors(dbFrom(ctx, repo.DB), "posts.author_id", currentUserID)).Model(&models.Post{}).Count(&count).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -50,20 +72,73 @@ func (repo *PostRepo) GetList(limit, offset int, sortBy string, currentUserID ui
 
 // Get all posts associated with at least 1 of the topics in the given list of topics
 // Also returns the total number of posts filtered
-func (repo *PostRepo) GetByTopics(topicIDs []uint, limit, offset int, sortBy string, currentUserID uint) ([]models.Post, int64, error) {
+func (repo *postRepo) GetByTopics(ctx context.Context, topicIDs []uint, limit, offset int, sortBy string, currentUserID uint) ([]models.Post, int64, error) {
 	var posts []models.Post
 
 	// Filter out the posts associated with the given topics
-	filteredDB := repo.DB.Joins("JOIN post_topics ON posts.id = post_topics.post_id").
-		Where("post_topics.topic_id IN ?", topicIDs)
+	// A subquery is used rather than a join so that posts with several matching topics are not duplicated in the vote sums and count
+	// The session allows the filter to be reused for both the list and the count without contaminating each other
+	filteredDB := dbFrom(ctx, repo.DB).
+		Where("posts.id IN (?)", repo.DB.Table("post_topics").Select("post_id").Where("topic_id IN ?", topicIDs)).
+		Session(&gorm.Session{})
 
-	if err := buildPostsQuery(filteredDB, limit, offset, sortBy, currentUserID).Find(&posts).Error; err != nil {
+	// Posts pinned to any of the topics come first, in the order of their lowest pin position
+	// The pins are aggregated before joining so that posts pinned to several of the topics are not duplicated in the vote sums
+	pinnedDB := filteredDB.Joins("LEFT JOIN (?) AS pins ON pins.post_id = posts.id",
+		repo.DB.Model(&models.TopicPin{}).Select("post_id, MIN(position) AS position").Where("topic_id IN ?", topicIDs).Group("post_id"))
+	if err := buildPostsQuery(pinnedDB, "MIN(pins.position)", limit, offset, sortBy, currentUserID).Find(&posts).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// Get the total count of filtered posts
 	var count int64
-	if err := filteredDB.Model(&models.Post{}).Count(&count).Error; err != nil {
+	if err := published(hideBlockedAuthors(filteredDB, "posts.author_id", currentUserID)).Model(&models.Post{}).Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return posts, count, nil
+}
+
+// Get the posts in the given user's home feed: posts tagged with at least 1 of the topics the user subscribes to, and posts by users they follow
+// Also returns the total number of posts in the feed
+func (repo *postRepo) GetFeed(ctx context.Context, userID uint, limit, offset int, sortBy string) ([]models.Post, int64, error) {
+	var posts []models.Post
+
+	// Like GetByTopics, but with the topics taken from the user's subscriptions
+	subscribedTopicIDs := repo.DB.Table("topic_subscriptions").Select("topic_id").Where("user_id = ?", userID)
+	followedUserIDs := repo.DB.Table("follows").Select("followee_id").Where("follower_id = ?", userID)
+	filteredDB := dbFrom(ctx, repo.DB).
+		Where("posts.id IN (?) OR posts.author_id IN (?)",
+			repo.DB.Table("post_topics").Select("post_id").Where("topic_id IN (?)", subscribedTopicIDs),
+			followedUserIDs).
+		Session(&gorm.Session{})
+
+	if err := buildPostsQuery(filteredDB, "", limit, offset, sortBy, userID).Find(&posts).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get the total count of posts in the feed
+	var count int64
+	if err := published(hideBlockedAuthors(filteredDB, "posts.author_id", userID)).Model(&models.Post{}).Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return posts, count, nil
+}
+
+// Get the published posts by the given author, without any pinned posts first
+// Also returns the total number of posts by the author
+func (repo *postRepo) GetByAuthor(ctx context.Context, authorID uint, limit, offset int, sortBy string, currentUserID uint) ([]models.Post, int64, error) {
+	var posts []models.Post
+
+	filteredDB := dbFrom(ctx, repo.DB).Where("posts.author_id = ?", authorID).Session(&gorm.Session{})
+	if err := buildPostsQuery(filteredDB, "", limit, offset, sortBy, currentUserID).Find(&posts).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get the total count of posts by the author
+	var count int64
+	if err := published(hideBlockedAuthors(filteredDB, "posts.author_id", currentUserID)).Model(&models.Post{}).Count(&count).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -71,68 +146,112 @@ func (repo *PostRepo) GetByTopics(topicIDs []uint, limit, offset int, sortBy str
 }
 
 // Get an individual post
-func (repo *PostRepo) GetByID(id uint) (*models.Post, error) {
+func (repo *postRepo) GetByID(ctx context.Context, id uint) (*models.Post, error) {
 	var post models.Post
-	if err := repo.DB.First(&post, id).Error; err != nil {
+	if err := dbFrom(ctx, repo.DB).First(&post, id).Error; err != nil {
 		return nil, err
 	}
 	return &post, nil
 }
 
 // Similar to GetByID but includes additional computed fields and preloaded associations
-// Takes in currentUserID in order to compute user_vote field
-func (repo *PostRepo) GetByIDWithAuth(postID uint, currentUserID uint) (*models.Post, error) {
+// Takes in currentUserID in order to compute user_vote field, and to only return an unpublished post to its author
+func (repo *postRepo) GetByIDWithAuth(ctx context.Context, postID uint, currentUserID uint) (*models.Post, error) {
 	var post models.Post
 
-	err := repo.DB.Model(&models.Post{}).
-		Preload("Topics").Preload("Author"). // Include these fields in the returned post
+	err := dbFrom(ctx, repo.DB).Model(&models.Post{}).
+		Preload("Topics").Preload("Author").Preload("Attachments"). // Include these fields in the returned post
 		Select("posts.*, "+
 			// Compute net votes for the post
 			"COALESCE(SUM(votes.value),0) AS net_votes, "+
 																	// Get the current user's vote for the post
-																	"COALESCE(MAX(user_votes.value),0) AS user_vote").
+																	"COALESCE(MAX(user_votes.value),0) AS user_vote, "+
+			// Check whether the current user has saved the post
+			"COUNT(saved.item_id) > 0 AS saved, "+
+			// Check whether the post is pinned globally
+			"posts.pin_position IS NOT NULL AS pinned").
 		Joins("LEFT JOIN post_votes AS votes ON posts.id = votes.post_id").                                                              // Get all vote records associated to the post
 		Joins("LEFT JOIN post_votes AS user_votes ON posts.id = user_votes.post_id AND user_votes.user_id = ?", currentUserID). // Get the single vote record made by the current user, that is associated to the post
+		Joins("LEFT JOIN saved_items AS saved ON posts.id = saved.item_id AND saved.item_type = ? AND saved.user_id = ?", models.SavedItemPost, currentUserID). // Get the save record made by the current user, if any
 		Where("posts.id = ?", postID).
-		Group("posts.id, posts.title, posts.content, posts.created_at, posts.updated_at, posts.author_id").
+		Where("posts.status = ? OR posts.author_id = ?", models.PostStatusPublished, currentUserID).
+		Group("posts.id").
 		Find(&post).Error
 
 	if err != nil {
 		return nil, err
 	}
+	// Find does not return an error when no rows match
+	if post.ID == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
 
 	return &post, nil
 }
 
 // Create a new post
-func (repo *PostRepo) Create(post *models.Post) (*models.Post, error) {
-	if err := repo.DB.Create(post).Error; err != nil {
+func (repo *postRepo) Create(ctx context.Context, post *models.Post) (*models.Post, error) {
+	if err := dbFrom(ctx, repo.DB).Create(post).Error; err != nil {
 		return nil, err
 	}
 	return post, nil
 }
 
-// Update the title and content of the given post
-func (repo *PostRepo) Update(id uint, title string, content string) (*models.Post, error) {
-	var post models.Post
-	if err := repo.DB.First(&post, id).Error; err != nil {
-		return nil, err
+// Update the title, content and rendered content of the given post
+func (repo *postRepo) Update(ctx context.Context, id uint, version uint, title string, content string, contentHTML string) (*models.Post, error) {
+	// Check and increment the version in the same statement, so that concurrent edits based on the same version cannot both succeed
+	query := dbFrom(ctx, repo.DB).Model(&models.Post{}).Where("id = ?", id)
+	if version != 0 {
+		query = query.Where("version = ?", version)
+	}
+	result := query.Updates(map[string]any{"title": title, "content": content, "content_html": contentHTML, "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		return nil, result.Error
 	}
 
-	if err := repo.DB.Model(&post).Updates(models.Post{Title: title, Content: content}).Error; err != nil {
+	var post models.Post
+	if err := dbFrom(ctx, repo.DB).First(&post, id).Error; err != nil {
 		return nil, err
 	}
-
+	if result.RowsAffected == 0 {
+		return nil, ErrVersionConflict
+	}
 	return &post, nil
 }
 
 // Replace the current list of topics associated with the given post with the given new list of topics
-func (repo *PostRepo) AssociatePostWithTopics(post *models.Post, topics []models.Topic) error {
-	return repo.DB.Model(post).Association("Topics").Replace(topics)
+func (repo *postRepo) AssociatePostWithTopics(ctx context.Context, post *models.Post, topics []models.Topic) error {
+	return dbFrom(ctx, repo.DB).Model(post).Association("Topics").Replace(topics)
+}
+
+func (repo *postRepo) Delete(ctx context.Context, id uint) error {
+	result := dbFrom(ctx, repo.DB).Delete(&models.Post{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Pin the post globally at the given position, or unpin it if the position is nil
+// Uses UpdateColumn so that updated_at is left unchanged
+func (repo *postRepo) SetPinPosition(ctx context.Context, id uint, position *int) error {
+	result := dbFrom(ctx, repo.DB).Model(&models.Post{ID: id}).UpdateColumn("pin_position", position)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
-func (repo *PostRepo) Delete(id uint) error {
-	result := repo.DB.Delete(&models.Post{}, id)
+// Lock or unlock the post
+// Uses UpdateColumn so that updated_at is left unchanged
+func (repo *postRepo) SetLocked(ctx context.Context, id uint, locked bool) error {
+	result := dbFrom(ctx, repo.DB).Model(&models.Post{ID: id}).UpdateColumn("locked", locked)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -141,3 +260,86 @@ func (repo *PostRepo) Delete(id uint) error {
 	}
 	return nil
 }
+
+// Pin the post to the topic, or move it to the given position if it is already pinned
+func (repo *postRepo) PinToTopic(ctx context.Context, pin *models.TopicPin) error {
+	return dbFrom(ctx, repo.DB).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "topic_id"}, {Name: "post_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"position"}),
+	}).Create(pin).Error
+}
+
+// Unpin the post from the topic. Unpinning a post that is not pinned is not an error.
+func (repo *postRepo) UnpinFromTopic(ctx context.Context, topicID, postID uint) error {
+	return dbFrom(ctx, repo.DB).Delete(&models.TopicPin{}, "topic_id = ? AND post_id = ?", topicID, postID).Error
+}
+
+// Unpin the post from every topic not in topicIDs, such as the topics it is no longer tagged with
+func (repo *postRepo) UnpinFromOtherTopics(ctx context.Context, postID uint, topicIDs []uint) error {
+	db := dbFrom(ctx, repo.DB).Where("post_id = ?", postID)
+	if len(topicIDs) > 0 {
+		db = db.Where("topic_id NOT IN ?", topicIDs)
+	}
+	return db.Delete(&models.TopicPin{}).Error
+}
+
+// Get the user's draft and scheduled posts, most recently updated first
+// Also returns the total number of such posts
+func (repo *postRepo) GetUnpublished(ctx context.Context, authorID uint, limit, offset int) ([]models.Post, int64, error) {
+	filteredDB := dbFrom(ctx, repo.DB).Model(&models.Post{}).
+		Where("author_id = ? AND status <> ?", authorID, models.PostStatusPublished).
+		Session(&gorm.Session{})
+
+	var posts []models.Post
+	if err := filteredDB.Preload("Topics").Preload("Author").Preload("Attachments").
+		Order("updated_at DESC, id DESC").Limit(limit).Offset(offset).
+		Find(&posts).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var count int64
+	if err := filteredDB.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return posts, count, nil
+}
+
+// Get the scheduled posts whose publish time is at or before the given time, earliest first
+func (repo *postRepo) GetDue(ctx context.Context, now time.Time) ([]models.Post, error) {
+	var posts []models.Post
+	if err := dbFrom(ctx, repo.DB).
+		Where("status = ? AND publish_at <= ?", models.PostStatusScheduled, now).
+		Order("publish_at, id").
+		Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// Change the status and publish time of an unpublished post to draft or scheduled
+// Returns gorm.ErrRecordNotFound if the post does not exist or has been published
+func (repo *postRepo) SetStatus(ctx context.Context, id uint, status string, publishAt *time.Time) error {
+	result := dbFrom(ctx, repo.DB).Model(&models.Post{}).
+		Where("id = ? AND status <> ?", id, models.PostStatusPublished).
+		Updates(map[string]any{"status": status, "publish_at": publishAt})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Publish an unpublished post, setting created_at to the given publish time so that it is listed as new
+// Returns whether the post was published by this call, which is false if it does not exist or was already published
+func (repo *postRepo) Publish(ctx context.Context, id uint, publishedAt time.Time) (bool, error) {
+	result := dbFrom(ctx, repo.DB).Model(&models.Post{}).
+		Where("id = ? AND status <> ?", id, models.PostStatusPublished).
+		Updates(map[string]any{"status": models.PostStatusPublished, "created_at": publishedAt, "updated_at": publishedAt})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,87 @@
+package repos_test
+
+import (
+	"context"
+	"cvwo-backend/internal/data"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Open a migrated in-memory SQLite database, named after the test so that tests do not share data
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_foreign_keys=on", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := data.Open(sqlite.Open(dsn))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get database handle: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// Posts tagged with several of the topics are listed, counted and have their votes summed once
+func TestPostRepoGetByTopics(t *testing.T) {
+	db := newTestDB(t)
+	repo := repos.NewPostRepo(db)
+	ctx := context.Background()
+
+	users := []models.User{{Username: "alice", Password: "hash"}, {Username: "bob", Password: "hash"}}
+	topics := []models.Topic{{Name: "Philosophy"}, {Name: "Literature"}, {Name: "History"}}
+	posts := []models.Post{{Title: "Both", Content: "Tagged with both topics", AuthorID: 1}, {Title: "One", Content: "Tagged with one topic", AuthorID: 1}}
+	for _, records := range []any{&users, &topics, &posts} {
+		if err := db.Create(records).Error; err != nil {
+			t.Fatalf("failed to create %T: %v", records, err)
+		}
+	}
+	if err := repo.AssociatePostWithTopics(ctx, &posts[0], topics[:2]); err != nil {
+		t.Fatalf("failed to tag post: %v", err)
+	}
+	if err := repo.AssociatePostWithTopics(ctx, &posts[1], topics[1:2]); err != nil {
+		t.Fatalf("failed to tag post: %v", err)
+	}
+	votes := []models.PostVote{{PostID: posts[0].ID, UserID: users[0].ID, Value: 1}, {PostID: posts[0].ID, UserID: users[1].ID, Value: 1}}
+	if err := db.Create(&votes).Error; err != nil {
+		t.Fatalf("failed to vote: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		topicIDs  []uint
+		wantTitle []string
+	}{
+		{"both topics", []uint{topics[0].ID, topics[1].ID}, []string{"Both", "One"}},
+		{"one topic", []uint{topics[0].ID}, []string{"Both"}},
+		{"no posts", []uint{topics[2].ID}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total, err := repo.GetByTopics(ctx, tt.topicIDs, 10, 0, "net_votes DESC", users[0].ID)
+			if err != nil {
+				t.Fatalf("failed to get posts: %v", err)
+			}
+			if total != int64(len(tt.wantTitle)) || len(got) != len(tt.wantTitle) {
+				t.Fatalf("expected %d posts, got %d of %d: %+v", len(tt.wantTitle), len(got), total, got)
+			}
+			for i, post := range got {
+				if post.Title != tt.wantTitle[i] {
+					t.Errorf("expected post %q at %d, got %q", tt.wantTitle[i], i, post.Title)
+				}
+				if post.Title == "Both" && (post.NetVotes != 2 || post.UserVote != 1) {
+					t.Errorf("expected 2 votes including the user's, got %d and %d", post.NetVotes, post.UserVote)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package repos
+
+import (
+	"context"
+	"cvwo-backend/internal/archive"
+	"cvwo-backend/internal/models"
+	"errors"
+	"io"
+	"time"
+)
+
+// Repositories are defined as interfaces so that services can be tested without a database
+// Implementations return gorm errors (e.g. gorm.ErrRecordNotFound), which the services map to errs.Error
+
+// Returned by updates based on a version of a record that is no longer its current version
+var ErrVersionConflict = errors.New("version conflict")
+
+type UserRepo interface {
+	GetAll(ctx context.Context) ([]models.User, error)
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	Create(ctx context.Context, user *models.User) (*models.User, error)
+	UpdateSettings(ctx context.Context, id uint, publicVotes bool) (*models.User, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+type PostRepo interface {
+	GetList(ctx context.Context, limit, offset int, sortBy string, currentUserID uint) ([]models.Post, int64, error)
+	GetByTopics(ctx context.Context, topicIDs []uint, limit, offset int, sortBy string, currentUserID uint) ([]models.Post, int64, error)
+	GetFeed(ctx context.Context, userID uint, limit, offset int, sortBy string) ([]models.Post, int64, error)
+	GetByAuthor(ctx context.Context, authorID uint, limit, offset int, sortBy string, currentUserID uint) ([]models.Post, int64, error)
+	GetByID(ctx context.Context, id uint) (*models.Post, error)
+	GetByIDWithAuth(ctx context.Context, postID uint, currentUserID uint) (*models.Post, error)
+	Create(ctx context.Context, post *models.Post) (*models.Post, error)
+	// Update a post and increment its version. version is the version the update is based on, or 0 to update any version.
+	Update(ctx context.Context, id uint, version uint, title string, content string, contentHTML string) (*models.Post, error)
+	AssociatePostWithTopics(ctx context.Context, post *models.Post, topics []models.Topic) error
+	Delete(ctx context.Context, id uint) error
+	SetPinPosition(ctx context.Context, id uint, position *int) error
+	SetLocked(ctx context.Context, id uint, locked bool) error
+	PinToTopic(ctx context.Context, pin *models.TopicPin) error
+	UnpinFromTopic(ctx context.Context, topicID, postID uint) error
+	// Unpin the post from every topic not in topicIDs
+	UnpinFromOtherTopics(ctx context.Context, postID uint, topicIDs []uint) error
+	GetUnpublished(ctx context.Context, authorID uint, limit, offset int) ([]models.Post, int64, error)
+	GetDue(ctx context.Context, now time.Time) ([]models.Post, error)
+	SetStatus(ctx context.Context, id uint, status string, publishAt *time.Time) error
+	Publish(ctx context.Context, id uint, publishedAt time.Time) (bool, error)
+}
+
+type CommentRepo interface {
+	GetByPostID(ctx context.Context, postID uint, limit int, offset int, sortBy string, currentUserID uint) ([]models.Comment, int64, error)
+	GetByID(ctx context.Context, id uint) (*models.Comment, error)
+	GetByIDWithAuth(ctx context.Context, commentID uint, currentUserID uint) (*models.Comment, error)
+	Create(ctx context.Context, comment *models.Comment) (*models.Comment, error)
+	// Update a comment and increment its version. version is the version the update is based on, or 0 to update any version.
+	Update(ctx context.Context, id uint, version uint, content string, contentHTML string) (*models.Comment, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+type TopicRepo interface {
+	GetAll(ctx context.Context) ([]models.Topic, error)
+	GetByIDs(ctx context.Context, ids []uint) ([]models.Topic, error)
+	GetByID(ctx context.Context, id uint) (*models.Topic, error)
+}
+
+type PostVoteRepo interface {
+	Upsert(ctx context.Context, vote *models.PostVote) error
+	// Returns whether there was a vote to delete
+	Delete(ctx context.Context, postID, userID uint) (bool, error)
+}
+
+type CommentVoteRepo interface {
+	Upsert(ctx context.Context, vote *models.CommentVote) error
+	// Returns whether there was a vote to delete
+	Delete(ctx context.Context, commentID, userID uint) (bool, error)
+}
+
+type SavedItemRepo interface {
+	Save(ctx context.Context, item *models.SavedItem) error
+	Unsave(ctx context.Context, userID uint, itemType string, itemID uint) error
+	GetByUserID(ctx context.Context, userID uint, itemType string, limit, offset int) ([]models.SavedItem, int64, error)
+}
+
+type SubscriptionRepo interface {
+	Subscribe(ctx context.Context, subscription *models.TopicSubscription) error
+	Unsubscribe(ctx context.Context, userID, topicID uint) error
+	GetTopicsByUserID(ctx context.Context, userID uint) ([]models.Topic, error)
+}
+
+type FollowRepo interface {
+	Follow(ctx context.Context, follow *models.Follow) error
+	Unfollow(ctx context.Context, followerID, followeeID uint) error
+	IsFollowing(ctx context.Context, followerID, followeeID uint) (bool, error)
+	GetCounts(ctx context.Context, userID uint) (followers int64, following int64, err error)
+	GetFollowers(ctx context.Context, userID uint, limit, offset int) ([]models.User, int64, error)
+	GetFollowing(ctx context.Context, userID uint, limit, offset int) ([]models.User, int64, error)
+}
+
+type ActivityRepo interface {
+	GetByUserID(ctx context.Context, userID uint, after *models.ActivityCursor, limit int, includeVotes bool) ([]models.Activity, error)
+}
+
+type BlockRepo interface {
+	Block(ctx context.Context, block *models.Block) error
+	Unblock(ctx context.Context, userID, blockedUserID uint) error
+	IsBlocked(ctx context.Context, userID, blockedUserID uint) (bool, error)
+	GetByUserID(ctx context.Context, userID uint, blockType string, limit, offset int) ([]models.Block, int64, error)
+}
+
+type ConversationRepo interface {
+	Create(ctx context.Context, conversation *models.Conversation, message *models.Message) error
+	FindDirect(ctx context.Context, userID, otherUserID uint) (*models.Conversation, error)
+	GetByID(ctx context.Context, id uint) (*models.Conversation, error)
+	GetByUserID(ctx context.Context, userID uint, after *models.ConversationCursor, limit int) ([]models.Conversation, error)
+	GetMessages(ctx context.Context, conversationID uint, beforeID uint, limit int) ([]models.Message, error)
+	CreateMessage(ctx context.Context, message *models.Message) error
+	MarkRead(ctx context.Context, conversationID, userID, messageID uint) error
+}
+
+type AttachmentRepo interface {
+	Create(ctx context.Context, attachment *models.Attachment) error
+	GetByID(ctx context.Context, id uint) (*models.Attachment, error)
+	GetByPostID(ctx context.Context, postID uint) ([]models.Attachment, error)
+	GetByCommentID(ctx context.Context, commentID uint) ([]models.Attachment, error)
+	GetByBlobKeys(ctx context.Context, keys []string) ([]models.Attachment, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+type PollRepo interface {
+	GetByPostID(ctx context.Context, postID uint, currentUserID uint) (*models.Poll, error)
+	Vote(ctx context.Context, vote *models.PollVote) error
+}
+
+type OutboxRepo interface {
+	Add(ctx context.Context, events []models.OutboxEvent) error
+}
+
+type ArchiveRepo interface {
+	// Write the forum's data to w as an archive
+	Export(ctx context.Context, w io.Writer, options archive.Options) error
+}
+
+// Runs several repository calls as a single unit of work
+type Transactor interface {
+	// Run fn in a transaction, which is committed if fn returns nil and rolled back otherwise
+	// Repository calls made with the context passed to fn take part in the transaction. Nested calls join the outer transaction.
+	InTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
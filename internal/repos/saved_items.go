@@ -0,0 +1,54 @@
+package repos
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type savedItemRepo struct {
+	DB *gorm.DB
+}
+
+func NewSavedItemRepo(db *gorm.DB) SavedItemRepo {
+	return &savedItemRepo{DB: db}
+}
+
+// Save an item for the user. Saving an item that is already saved has no effect.
+func (repo *savedItemRepo) Save(ctx context.Context, item *models.SavedItem) error {
+	return dbFrom(ctx, repo.DB).Clauses(clause.OnConflict{DoNothing: true}).Create(item).Error
+}
+
+// Remove an item from the user's saved items
+func (repo *savedItemRepo) Unsave(ctx context.Context, userID uint, itemType string, itemID uint) error {
+	return dbFrom(ctx, repo.DB).Delete(&models.SavedItem{}, "user_id = ? AND item_type = ? AND item_id = ?", userID, itemType, itemID).Error
+}
+
+// Get the items saved by the user, most recently saved first, optionally filtered by item type
+// Items whose post or comment has since been deleted are excluded
+// Also returns the total number of saved items
+func (repo *savedItemRepo) GetByUserID(ctx context.Context, userID uint, itemType string, limit, offset int) ([]models.SavedItem, int64, error) {
+	filteredDB := dbFrom(ctx, repo.DB).Model(&models.SavedItem{}).
+		Where("user_id = ?", userID).
+		Where("(item_type = ? AND item_id IN (?)) OR (item_type = ? AND item_id IN (?))",
+			models.SavedItemPost, repo.DB.Table("posts").Select("id"),
+			models.SavedItemComment, repo.DB.Table("comments").Select("id"))
+	if itemType != "" {
+		filteredDB = filteredDB.Where("item_type = ?", itemType)
+	}
+	filteredDB = filteredDB.Session(&gorm.Session{})
+
+	var items []models.SavedItem
+	if err := filteredDB.Order("created_at DESC").Limit(limit).Offset(offset).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var count int64
+	if err := filteredDB.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return items, count, nil
+}
@@ -0,0 +1,40 @@
+package repos
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type subscriptionRepo struct {
+	DB *gorm.DB
+}
+
+func NewSubscriptionRepo(db *gorm.DB) SubscriptionRepo {
+	return &subscriptionRepo{DB: db}
+}
+
+// Subscribe the user to a topic. Subscribing to a topic that is already subscribed to has no effect.
+func (repo *subscriptionRepo) Subscribe(ctx context.Context, subscription *models.TopicSubscription) error {
+	return dbFrom(ctx, repo.DB).Clauses(clause.OnConflict{DoNothing: true}).Create(subscription).Error
+}
+
+// Unsubscribe the user from a topic
+func (repo *subscriptionRepo) Unsubscribe(ctx context.Context, userID, topicID uint) error {
+	return dbFrom(ctx, repo.DB).Delete(&models.TopicSubscription{}, "user_id = ? AND topic_id = ?", userID, topicID).Error
+}
+
+// Get the topics the user subscribes to, ordered by name
+func (repo *subscriptionRepo) GetTopicsByUserID(ctx context.Context, userID uint) ([]models.Topic, error) {
+	var topics []models.Topic
+	err := dbFrom(ctx, repo.DB).
+		Joins("JOIN topic_subscriptions ON topic_subscriptions.topic_id = topics.id AND topic_subscriptions.user_id = ?", userID).
+		Order("topics.name").
+		Find(&topics).Error
+	if err != nil {
+		return nil, err
+	}
+	return topics, nil
+}
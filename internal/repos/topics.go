@@ -1,40 +1,41 @@
 package repos
 
 import (
+	"context"
 	"cvwo-backend/internal/models"
 
 	"gorm.io/gorm"
 )
 
-type TopicRepo struct {
+type topicRepo struct {
 	DB *gorm.DB
 }
 
-func NewTopicRepo(db *gorm.DB) *TopicRepo {
-	return &TopicRepo{DB: db}
+func NewTopicRepo(db *gorm.DB) TopicRepo {
+	return &topicRepo{DB: db}
 }
 
-func (repo *TopicRepo) GetAll() ([]models.Topic, error) {
+func (repo *topicRepo) GetAll(ctx context.Context) ([]models.Topic, error) {
 	var topics []models.Topic
-	if err := repo.DB.Find(&topics).Error; err != nil {
+	if err := dbFrom(ctx, repo.DB).Find(&topics).Error; err != nil {
 		return nil, err
 	}
 	return topics, nil
 }
 
 // Get the list of topics with the given IDs
-func (repo *TopicRepo) GetByIDs(ids []uint) ([]models.Topic, error){
+func (repo *topicRepo) GetByIDs(ctx context.Context, ids []uint) ([]models.Topic, error){
 	var topics []models.Topic
-	if err := repo.DB.Where("id IN ?", ids).Find(&topics).Error; err != nil {
+	if err := dbFrom(ctx, repo.DB).Where("id IN ?", ids).Find(&topics).Error; err != nil {
 		return nil, err
 	}
 	return topics, nil
 }
 
 // Get the topic with the given ID
-func (repo *TopicRepo) GetByID(id uint) (*models.Topic, error) {
+func (repo *topicRepo) GetByID(ctx context.Context, id uint) (*models.Topic, error) {
 	var topic models.Topic
-	if err := repo.DB.First(&topic, id).Error; err != nil {
+	if err := dbFrom(ctx, repo.DB).First(&topic, id).Error; err != nil {
 		return nil, err
 	}
 	return &topic, nil
@@ -0,0 +1,36 @@
+package repos
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Context key of the transaction started by InTransaction
+type txKey struct{}
+
+type transactor struct {
+	DB *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) Transactor {
+	return &transactor{DB: db}
+}
+
+func (transactor *transactor) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+	return transactor.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// The transaction the context is part of, or db if there is none
+// Every repository query goes through this so that it takes part in the caller's transaction
+func dbFrom(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
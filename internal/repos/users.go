@@ -1,59 +1,74 @@
 package repos
 
 import (
+	"context"
 	"cvwo-backend/internal/models"
-	"fmt"
 
 	"gorm.io/gorm"
 )
 
-type UserRepo struct {
+type userRepo struct {
 	DB *gorm.DB
 }
 
-func NewUserRepo(db *gorm.DB) *UserRepo {
-	return &UserRepo{DB: db}
+func NewUserRepo(db *gorm.DB) UserRepo {
+	return &userRepo{DB: db}
 }
 
-func (repo *UserRepo) GetAll() ([]models.User, error) {
+func (repo *userRepo) GetAll(ctx context.Context) ([]models.User, error) {
 	var users []models.User
-	if err := repo.DB.Find(&users).Error; err != nil {
+	if err := dbFrom(ctx, repo.DB).Find(&users).Error; err != nil {
 		return nil, err
 	}
 	return users, nil
 }
 
-func (repo *UserRepo) GetByID(id uint) (*models.User, error) {
+func (repo *userRepo) GetByID(ctx context.Context, id uint) (*models.User, error) {
 	var user models.User
-	if err := repo.DB.First(&user, id).Error; err != nil {
+	if err := dbFrom(ctx, repo.DB).First(&user, id).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
 // Get a single user by username
-func (repo *UserRepo) GetByUsername(username string) (*models.User, error) {
+func (repo *userRepo) GetByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	if err := repo.DB.Where("username=?", username).First(&user).Error; err != nil {
+	if err := dbFrom(ctx, repo.DB).Where("username=?", username).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (repo *UserRepo) Create(user *models.User) (*models.User, error) {
-	if err := repo.DB.Create(user).Error; err != nil {
+func (repo *userRepo) Create(ctx context.Context, user *models.User) (*models.User, error) {
+	if err := dbFrom(ctx, repo.DB).Create(user).Error; err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (repo *UserRepo) Delete(id uint) error {
-	result := repo.DB.Delete(&models.User{}, id)
+// Update the user's settings
+func (repo *userRepo) UpdateSettings(ctx context.Context, id uint, publicVotes bool) (*models.User, error) {
+	var user models.User
+	if err := dbFrom(ctx, repo.DB).First(&user, id).Error; err != nil {
+		return nil, err
+	}
+
+	// Select the column so that false is not skipped as a zero value
+	if err := dbFrom(ctx, repo.DB).Model(&user).Select("PublicVotes").Updates(models.User{PublicVotes: publicVotes}).Error; err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (repo *userRepo) Delete(ctx context.Context, id uint) error {
+	result := dbFrom(ctx, repo.DB).Delete(&models.User{}, id)
 	if result.Error != nil {
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("user not found")
+		return gorm.ErrRecordNotFound
 	}
 	return nil
 }
@@ -2,49 +2,155 @@ package routes
 
 import (
 	"cvwo-backend/internal/controllers"
+	"cvwo-backend/internal/metrics"
+	"cvwo-backend/internal/middleware"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func RegisterUserRoutes(router *gin.Engine, controller *controllers.UserController) {
+func RegisterUserRoutes(router *gin.Engine, controller *controllers.UserController, limits *middleware.RateLimits) {
 	router.GET("/users", controller.GetAll)
 	router.GET("/users/:id", controller.GetByID)
-	router.POST("/users", controller.Create)
+	// Get the authenticated user's saved posts and comments
+	router.GET("/users/me/saved", controller.GetSaved)
+	// Get the topics the authenticated user subscribes to
+	router.GET("/users/me/subscriptions", controller.GetSubscriptions)
+	// Update the authenticated user's settings
+	router.PUT("/users/me/settings", limits.Writes, controller.UpdateSettings)
+	router.POST("/users", limits.Writes, controller.Create)
+	// Follow/unfollow user
+	router.PUT("/users/:id/follow", limits.Writes, controller.Follow)
+	router.DELETE("/users/:id/follow", limits.Writes, controller.Unfollow)
+	// Get followers and followed users
+	router.GET("/users/:id/followers", controller.GetFollowers)
+	router.GET("/users/:id/following", controller.GetFollowing)
+	// Get the user's posts, comments and public votes
+	router.GET("/users/:id/activity", controller.GetActivity)
 }
 
-func RegisterAuthRoutes(router *gin.Engine, controller *controllers.AuthController) {
-	router.POST("/login", controller.Login)
+func RegisterAuthRoutes(router *gin.Engine, controller *controllers.AuthController, limits *middleware.RateLimits) {
+	router.POST("/login", limits.Login, controller.Login)
 }
 
-func RegisterPostRoutes(router *gin.Engine, controller *controllers.PostController) {
+func RegisterPostRoutes(router *gin.Engine, controller *controllers.PostController, limits *middleware.RateLimits) {
 	router.GET("/posts", controller.GetList)
 	// Get individual post
 	router.GET("/posts/:post_id", controller.GetByID)
 	// Create new post
-	router.POST("/posts", controller.Create)
+	router.POST("/posts", limits.Writes, controller.Create)
 	// Update post title and content
-	router.PATCH("/posts/:post_id", controller.Update)
+	router.PATCH("/posts/:post_id", limits.Writes, controller.Update)
 	// Update post tags
-	router.PUT("/posts/:post_id/topics", controller.UpdateTags)
+	router.PUT("/posts/:post_id/topics", limits.Writes, controller.UpdateTags)
+	// Publish, schedule or unschedule draft or scheduled post
+	router.PUT("/posts/:post_id/status", limits.Writes, controller.UpdateStatus)
+	// Get the authenticated user's draft and scheduled posts
+	router.GET("/users/me/drafts", controller.GetDrafts)
 	// Delete post
-	router.DELETE("/posts/:post_id", controller.Delete)
+	router.DELETE("/posts/:post_id", limits.Writes, controller.Delete)
 	// Upvote/downvote post
-	router.PUT("/posts/:post_id/votes/:user_id", controller.Vote)
+	router.PUT("/posts/:post_id/votes/:user_id", limits.Votes, controller.Vote)
+	// Vote in the poll of a poll post
+	router.POST("/posts/:post_id/poll/votes", limits.Votes, controller.VotePoll)
+	// Save/unsave post
+	router.PUT("/posts/:post_id/save", limits.Writes, controller.Save)
+	router.DELETE("/posts/:post_id/save", limits.Writes, controller.Unsave)
 }
 
-func RegisterCommentRoutes(router *gin.Engine, controller *controllers.CommentController) {
+func RegisterCommentRoutes(router *gin.Engine, controller *controllers.CommentController, limits *middleware.RateLimits) {
 	// Get comments associated with a post
 	router.GET("/posts/:post_id/comments", controller.GetByPostID)
 	// Create new comment
-	router.POST("/comments", controller.Create)
+	router.POST("/comments", limits.Writes, controller.Create)
 	// Update comment content
-	router.PATCH("/comments/:comment_id", controller.Update)
+	router.PATCH("/comments/:comment_id", limits.Writes, controller.Update)
 	// Delete comment
-	router.DELETE("/comments/:comment_id", controller.Delete)
+	router.DELETE("/comments/:comment_id", limits.Writes, controller.Delete)
 	// Upvote/downvote comment
-	router.PUT("/comments/:comment_id/votes/:user_id", controller.Vote)
+	router.PUT("/comments/:comment_id/votes/:user_id", limits.Votes, controller.Vote)
+	// Save/unsave comment
+	router.PUT("/comments/:comment_id/save", limits.Writes, controller.Save)
+	router.DELETE("/comments/:comment_id/save", limits.Writes, controller.Unsave)
 }
 
-func RegisterTopicRoutes(router *gin.Engine, controller *controllers.TopicController) {
+func RegisterTopicRoutes(router *gin.Engine, controller *controllers.TopicController, limits *middleware.RateLimits) {
 	router.GET("/topics", controller.GetAll)
+	// Subscribe/unsubscribe to topic
+	router.PUT("/topics/:topic_id/subscription", limits.Writes, controller.Subscribe)
+	router.DELETE("/topics/:topic_id/subscription", limits.Writes, controller.Unsubscribe)
+}
+
+func RegisterBlockRoutes(router *gin.Engine, controller *controllers.BlockController, limits *middleware.RateLimits) {
+	// Get the users the authenticated user has blocked or muted
+	router.GET("/users/me/blocks", controller.GetAll)
+	// Block/mute or unblock/unmute user
+	router.PUT("/users/me/blocks/:user_id", limits.Writes, controller.Block)
+	router.DELETE("/users/me/blocks/:user_id", limits.Writes, controller.Unblock)
+}
+
+func RegisterConversationRoutes(router *gin.Engine, controller *controllers.ConversationController, limits *middleware.RateLimits) {
+	// Get the authenticated user's conversations
+	router.GET("/conversations", controller.GetAll)
+	// Start a conversation
+	router.POST("/conversations", limits.Writes, controller.Create)
+	// Get the messages in a conversation
+	router.GET("/conversations/:conversation_id/messages", controller.GetMessages)
+	// Send a message in a conversation
+	router.POST("/conversations/:conversation_id/messages", limits.Writes, controller.Send)
+	// Mark a conversation as read
+	router.PUT("/conversations/:conversation_id/read", limits.Writes, controller.MarkRead)
+}
+
+func RegisterAttachmentRoutes(router *gin.Engine, controller *controllers.AttachmentController, limits *middleware.RateLimits) {
+	// Upload an attachment to a post or comment
+	router.POST("/posts/:post_id/attachments", limits.Writes, controller.AttachToPost)
+	router.POST("/comments/:comment_id/attachments", limits.Writes, controller.AttachToComment)
+	// Download an attachment or its thumbnail
+	router.GET("/attachments/:attachment_id", controller.Get)
+	router.GET("/attachments/:attachment_id/thumbnail", controller.GetThumbnail)
+	// Delete attachment
+	router.DELETE("/attachments/:attachment_id", limits.Writes, controller.Delete)
+}
+
+func RegisterModerationRoutes(router *gin.Engine, controller *controllers.ModerationController, limits *middleware.RateLimits) {
+	// Pin/unpin post to the top of GET /posts
+	router.PUT("/posts/:post_id/pin", limits.Writes, controller.Pin)
+	router.DELETE("/posts/:post_id/pin", limits.Writes, controller.Unpin)
+	// Pin/unpin post to the top of a topic's listing
+	router.PUT("/topics/:topic_id/pins/:post_id", limits.Writes, controller.PinToTopic)
+	router.DELETE("/topics/:topic_id/pins/:post_id", limits.Writes, controller.UnpinFromTopic)
+	// Lock/unlock post
+	router.PUT("/posts/:post_id/lock", limits.Writes, controller.Lock)
+	router.DELETE("/posts/:post_id/lock", limits.Writes, controller.Unlock)
+}
+
+func RegisterArchiveRoutes(router *gin.Engine, controller *controllers.ArchiveController) {
+	// Download the forum's data as an archive
+	router.GET("/admin/export", controller.Export)
+}
+
+func RegisterFeedRoutes(router *gin.Engine, controller *controllers.FeedController) {
+	// Get the authenticated user's home feed
+	router.GET("/feed", controller.GetFeed)
+}
+
+func RegisterSyndicationRoutes(router *gin.Engine, controller *controllers.SyndicationController) {
+	// RSS feed of the newest posts
+	router.GET("/feeds/posts.rss", controller.GetPosts)
+	// Atom feeds of the newest posts in a topic and by a user
+	// The params are named after the whole file name, since gin params extend to the next slash
+	router.GET("/feeds/topics/:topic_id.atom", controller.GetTopic)
+	router.GET("/feeds/users/:id.atom", controller.GetUser)
+}
+
+func RegisterDocsRoutes(router *gin.Engine, controller *controllers.DocsController) {
+	// OpenAPI document
+	router.GET("/openapi.json", controller.GetSpec)
+	// Swagger UI
+	router.GET("/docs", controller.GetUI)
+}
+
+func RegisterMetricsRoutes(router *gin.Engine) {
+	router.GET("/metrics", gin.WrapH(promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})))
 }
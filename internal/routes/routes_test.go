@@ -0,0 +1,56 @@
+package routes_test
+
+import (
+	"cvwo-backend/internal/app"
+	"cvwo-backend/internal/data"
+	"cvwo-backend/internal/openapi"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+)
+
+// Build the application's router, so that every route group wired up in app.NewRouter is checked. Handlers are never invoked.
+func newTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := data.Open(sqlite.Open("file:routes?mode=memory&cache=shared"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get database handle: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	return app.NewRouter(db, app.Config{FrontendURL: "http://localhost:5173"})
+}
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	doc := openapi.Build()
+
+	for _, route := range newTestRouter(t).Routes() {
+		path := openapi.ToOpenAPIPath(route.Path)
+		if _, exists := doc.Paths[path][strings.ToLower(route.Method)]; !exists {
+			t.Errorf("%s %s is registered but missing from the OpenAPI document", route.Method, route.Path)
+		}
+	}
+}
+
+func TestEveryDocumentedRouteIsRegistered(t *testing.T) {
+	registered := make(map[string]bool)
+	for _, route := range newTestRouter(t).Routes() {
+		registered[strings.ToLower(route.Method)+" "+openapi.ToOpenAPIPath(route.Path)] = true
+	}
+
+	for path, item := range openapi.Build().Paths {
+		for method := range item {
+			if !registered[method+" "+path] {
+				t.Errorf("%s %s is documented but not registered", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
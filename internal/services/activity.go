@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"cvwo-backend/internal/tracing"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ActivityService struct {
+	activityRepo repos.ActivityRepo
+	userRepo     repos.UserRepo
+}
+
+func NewActivityService(activityRepo repos.ActivityRepo, userRepo repos.UserRepo) *ActivityService {
+	return &ActivityService{activityRepo, userRepo}
+}
+
+// Encode a cursor as an opaque string for clients to send back
+func encodeActivityCursor(cursor models.ActivityCursor) string {
+	raw := fmt.Sprintf("%s|%s|%d", cursor.CreatedAt.Format(time.RFC3339Nano), cursor.Type, cursor.TargetID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Decode a cursor sent by a client
+func decodeActivityCursor(encoded string) (*models.ActivityCursor, error) {
+	invalid := errs.New(errs.ErrInvalid, "Invalid cursor")
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, invalid
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return nil, invalid
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, invalid
+	}
+	targetID, err := strconv.ParseUint(parts[2], 10, 0)
+	if err != nil {
+		return nil, invalid
+	}
+	return &models.ActivityCursor{CreatedAt: createdAt, Type: parts[1], TargetID: uint(targetID)}, nil
+}
+
+// Get a page of the user's posts, comments and votes, most recent first
+// Votes are only included if the user has made them public or is the current user
+// cursor is empty for the first page, or the cursor returned with the previous page. The returned cursor is empty on the last page.
+func (service *ActivityService) GetActivity(ctx context.Context, userID uint, cursor string, limit int, currentUserID uint) ([]models.Activity, string, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "ActivityService.GetActivity")
+	defer span.End()
+
+	var after *models.ActivityCursor
+	if cursor != "" {
+		var err error
+		if after, err = decodeActivityCursor(cursor); err != nil {
+			return nil, "", err
+		}
+	}
+
+	user, err := service.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", errs.Wrap(errs.ErrNotFound, "User not found", err)
+		}
+		return nil, "", err
+	}
+	includeVotes := user.PublicVotes || userID == currentUserID
+
+	// Fetch 1 more entry than needed to tell whether there is a next page
+	activities, err := service.activityRepo.GetByUserID(ctx, userID, after, limit+1, includeVotes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Merge the entries of each type into a single stream
+	sort.Slice(activities, func(i, j int) bool {
+		return activities[i].Cursor().Precedes(activities[j])
+	})
+
+	if len(activities) <= limit {
+		return activities, "", nil
+	}
+	activities = activities[:limit]
+	return activities, encodeActivityCursor(activities[limit-1].Cursor()), nil
+}
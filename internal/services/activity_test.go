@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestActivityServiceGetActivity(t *testing.T) {
+	f := newFixture(t)
+	service := NewActivityService(f.activity, f.users)
+	votingService := NewVotingService(f.postVotes, f.commentVotes, f.posts, f.comments, f.outbox, f.transactor, nil)
+	ctx := context.Background()
+
+	// Alice's activity in chronological order
+	first := f.createPost(t, f.alice.ID, "first")
+	comment := f.createComment(t, f.alice.ID, first.ID)
+	bobsPost := f.createPost(t, f.bob.ID, "bob's post")
+	if err := votingService.VotePost(ctx, bobsPost.ID, f.alice.ID, -1, f.alice.ID); err != nil {
+		t.Fatalf("failed to vote: %v", err)
+	}
+	second := f.createPost(t, f.alice.ID, "second")
+
+	// Collect every page of the stream, returning the type and target of each entry
+	collect := func(t *testing.T, limit int, currentUserID uint) []string {
+		t.Helper()
+		entries := []string{}
+		cursor := ""
+		for page := 0; page < 10; page++ {
+			activities, next, err := service.GetActivity(ctx, f.alice.ID, cursor, limit, currentUserID)
+			assertCode(t, err, noError)
+			if len(activities) > limit {
+				t.Fatalf("expected at most %d entries, got %d", limit, len(activities))
+			}
+			for _, activity := range activities {
+				entries = append(entries, fmt.Sprintf("%s:%d", activity.Type, activity.TargetID()))
+			}
+			if next == "" {
+				return entries
+			}
+			cursor = next
+		}
+		t.Fatal("expected the stream to end")
+		return nil
+	}
+
+	withVotes := []string{
+		fmt.Sprintf("%s:%d", models.ActivityPost, second.ID),
+		fmt.Sprintf("%s:%d", models.ActivityPostVote, bobsPost.ID),
+		fmt.Sprintf("%s:%d", models.ActivityComment, comment.ID),
+		fmt.Sprintf("%s:%d", models.ActivityPost, first.ID),
+	}
+	withoutVotes := slices.Delete(slices.Clone(withVotes), 1, 2)
+
+	tests := []struct {
+		name        string
+		limit       int
+		currentUser uint
+		publicVotes bool
+		want        []string
+	}{
+		{"own activity includes votes", 10, f.alice.ID, false, withVotes},
+		{"own activity paginated", 1, f.alice.ID, false, withVotes},
+		{"private votes are hidden from others", 10, f.bob.ID, false, withoutVotes},
+		{"private votes are hidden from anonymous users", 3, 0, false, withoutVotes},
+		{"public votes are shown to others", 3, f.bob.ID, true, withVotes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := f.users.UpdateSettings(ctx, f.alice.ID, tt.publicVotes); err != nil {
+				t.Fatalf("failed to update settings: %v", err)
+			}
+			if got := collect(t, tt.limit, tt.currentUser); !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	_, _, err := service.GetActivity(ctx, f.alice.ID, "not a cursor", 10, 0)
+	assertCode(t, err, errs.ErrInvalid)
+	_, _, err = service.GetActivity(ctx, 999, "", 10, 0)
+	assertCode(t, err, errs.ErrNotFound)
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"context"
+	"cvwo-backend/internal/archive"
+	"cvwo-backend/internal/repos"
+	"cvwo-backend/internal/tracing"
+	"io"
+)
+
+type ArchiveService struct {
+	archiveRepo repos.ArchiveRepo
+	userRepo    repos.UserRepo
+}
+
+func NewArchiveService(archiveRepo repos.ArchiveRepo, userRepo repos.UserRepo) *ArchiveService {
+	return &ArchiveService{archiveRepo, userRepo}
+}
+
+// Write the forum's users, topics, posts, polls, comments and votes to w as an archive, as a moderator
+// Nothing is written if the current user is not a moderator. Password hashes are never included, since moderating
+// the forum does not entitle anyone to the users' credentials; only the export command can include them.
+func (service *ArchiveService) Export(ctx context.Context, w io.Writer, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "ArchiveService.Export")
+	defer span.End()
+
+	if err := authorizeModerator(ctx, service.userRepo, currentUserID); err != nil {
+		return err
+	}
+	return service.archiveRepo.Export(ctx, w, archive.Options{})
+}
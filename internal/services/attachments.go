@@ -0,0 +1,423 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"cvwo-backend/internal/storage"
+	"cvwo-backend/internal/tracing"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // Register decoders for image.Decode
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gabriel-vasile/mimetype"
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"gorm.io/gorm"
+)
+
+// Limits on uploaded attachments
+type AttachmentLimits struct {
+	MaxSize int64 // In bytes
+}
+
+var DefaultAttachmentLimits = AttachmentLimits{MaxSize: 10 << 20}
+
+// Types of files that may be attached, mapped to the extension of their blob keys
+// The type is sniffed from the content, so a file cannot pass as another type by its name
+var attachmentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+}
+
+const (
+	// Thumbnails fit within a square of this size
+	thumbnailSize = 320
+	// Images with more pixels than this are rejected to avoid decoding huge images into memory
+	maxImagePixels = 50_000_000
+)
+
+type AttachmentService struct {
+	attachmentRepo repos.AttachmentRepo
+	postRepo       repos.PostRepo
+	commentRepo    repos.CommentRepo
+	blobs          storage.BlobStore
+	limits         AttachmentLimits
+}
+
+func NewAttachmentService(attachmentRepo repos.AttachmentRepo, postRepo repos.PostRepo, commentRepo repos.CommentRepo, blobs storage.BlobStore, limits AttachmentLimits) *AttachmentService {
+	return &AttachmentService{attachmentRepo, postRepo, commentRepo, blobs, limits}
+}
+
+func (service *AttachmentService) Limits() AttachmentLimits {
+	return service.limits
+}
+
+const (
+	thumbnailPrefix = "thumbnails/"
+	thumbnailSuffix = ".jpg"
+)
+
+// Key of the thumbnail of the blob with the given key
+func thumbnailKey(blobKey string) string {
+	return thumbnailPrefix + blobKey + thumbnailSuffix
+}
+
+// Random blob key with the extension of the file type
+func newBlobKey(extension string) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(random) + extension, nil
+}
+
+// Strip any directories from the uploaded file name, which is only used to name downloads
+func cleanFilename(filename string) string {
+	filename = strings.TrimSpace(filepath.Base(strings.ReplaceAll(filename, "\\", "/")))
+	if filename == "." || filename == "/" || filename == "" {
+		return "file"
+	}
+	if len(filename) > 255 {
+		filename = filename[:255]
+	}
+	return filename
+}
+
+// Scale the image down to fit within the thumbnail size and encode it as a JPEG
+// Transparent areas are drawn over white, since JPEG has no transparency
+func makeThumbnail(img image.Image) ([]byte, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > thumbnailSize || height > thumbnailSize {
+		if width > height {
+			width, height = thumbnailSize, max(1, height*thumbnailSize/width)
+		} else {
+			width, height = max(1, width*thumbnailSize/height), thumbnailSize
+		}
+	}
+
+	thumbnail := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(thumbnail, thumbnail.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	xdraw.CatmullRom.Scale(thumbnail, thumbnail.Bounds(), img, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Attach an uploaded file to a post. Only the post's author may attach files to it.
+func (service *AttachmentService) AttachToPost(ctx context.Context, postID uint, filename string, content io.Reader, currentUserID uint) (*models.Attachment, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "AttachmentService.AttachToPost")
+	defer span.End()
+
+	post, err := service.postRepo.GetByID(ctx, postID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.Wrap(errs.ErrNotFound, "Post not found", err)
+		}
+		return nil, err
+	}
+	if post.AuthorID != currentUserID {
+		return nil, errs.New(errs.ErrForbidden, "Forbidden")
+	}
+
+	return service.upload(ctx, &models.Attachment{PostID: &postID, UploaderID: currentUserID, Filename: cleanFilename(filename)}, content)
+}
+
+// Attach an uploaded file to a comment. Only the comment's author may attach files to it.
+func (service *AttachmentService) AttachToComment(ctx context.Context, commentID uint, filename string, content io.Reader, currentUserID uint) (*models.Attachment, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "AttachmentService.AttachToComment")
+	defer span.End()
+
+	comment, err := service.commentRepo.GetByID(ctx, commentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.Wrap(errs.ErrNotFound, "Comment not found", err)
+		}
+		return nil, err
+	}
+	if comment.AuthorID != currentUserID {
+		return nil, errs.New(errs.ErrForbidden, "Forbidden")
+	}
+
+	return service.upload(ctx, &models.Attachment{CommentID: &commentID, UploaderID: currentUserID, Filename: cleanFilename(filename)}, content)
+}
+
+// Check the file's size and type, store it with a thumbnail if it is an image, and record it
+func (service *AttachmentService) upload(ctx context.Context, attachment *models.Attachment, content io.Reader) (*models.Attachment, error) {
+	// Read 1 byte past the limit to tell whether the file is too large
+	data, err := io.ReadAll(io.LimitReader(content, service.limits.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > service.limits.MaxSize {
+		return nil, errs.New(errs.ErrTooLarge, fmt.Sprintf("File must be at most %d bytes", service.limits.MaxSize))
+	}
+	if len(data) == 0 {
+		return nil, errs.New(errs.ErrInvalid, "File is empty")
+	}
+
+	detected := mimetype.Detect(data)
+	for contentType := range attachmentTypes {
+		if detected.Is(contentType) {
+			attachment.ContentType = contentType
+		}
+	}
+	if attachment.ContentType == "" {
+		return nil, errs.New(errs.ErrUnsupportedMediaType, fmt.Sprintf("Files of type %s cannot be attached", detected.String()))
+	}
+	attachment.Size = int64(len(data))
+
+	attachment.BlobKey, err = newBlobKey(attachmentTypes[attachment.ContentType])
+	if err != nil {
+		return nil, err
+	}
+
+	var thumbnail []byte
+	if strings.HasPrefix(attachment.ContentType, "image/") {
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, errs.Wrap(errs.ErrInvalid, "Invalid image", err)
+		}
+		if config.Width*config.Height > maxImagePixels {
+			return nil, errs.New(errs.ErrTooLarge, "Image dimensions are too large")
+		}
+		attachment.Width, attachment.Height = config.Width, config.Height
+
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, errs.Wrap(errs.ErrInvalid, "Invalid image", err)
+		}
+		if thumbnail, err = makeThumbnail(img); err != nil {
+			return nil, err
+		}
+		attachment.HasThumbnail = true
+	}
+
+	// Store the files before the record, so that a record never refers to missing files
+	// If recording fails, the files are removed here or, failing that, by CleanupOrphans
+	if err := service.blobs.Put(ctx, attachment.BlobKey, bytes.NewReader(data), attachment.Size, attachment.ContentType); err != nil {
+		return nil, err
+	}
+	if thumbnail != nil {
+		if err := service.blobs.Put(ctx, thumbnailKey(attachment.BlobKey), bytes.NewReader(thumbnail), int64(len(thumbnail)), "image/jpeg"); err != nil {
+			service.deleteFiles(ctx, []models.Attachment{*attachment})
+			return nil, err
+		}
+	}
+	if err := service.attachmentRepo.Create(ctx, attachment); err != nil {
+		service.deleteFiles(ctx, []models.Attachment{*attachment})
+		if errors.Is(err, gorm.ErrForeignKeyViolated) {
+			return nil, errs.Wrap(errs.ErrNotFound, "Post or comment not found", err)
+		}
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+// Get an attachment and open its file, or its thumbnail. The caller must close the file.
+// Attachments of drafts and scheduled posts are only served to the post's author.
+// Also returns whether the attachment is public, since only public attachments may be stored by shared caches.
+func (service *AttachmentService) Open(ctx context.Context, attachmentID uint, thumbnail bool, currentUserID uint) (*models.Attachment, io.ReadCloser, bool, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "AttachmentService.Open")
+	defer span.End()
+
+	attachment, err := service.attachmentRepo.GetByID(ctx, attachmentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, false, errs.Wrap(errs.ErrNotFound, "Attachment not found", err)
+		}
+		return nil, nil, false, err
+	}
+
+	post, err := service.parentPost(ctx, attachment)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	public := post == nil || post.Status == models.PostStatusPublished
+	if !public && post.AuthorID != currentUserID {
+		return nil, nil, false, errs.New(errs.ErrNotFound, "Attachment not found")
+	}
+
+	key := attachment.BlobKey
+	if thumbnail {
+		if !attachment.HasThumbnail {
+			return nil, nil, false, errs.New(errs.ErrNotFound, "Attachment has no thumbnail")
+		}
+		key = thumbnailKey(key)
+	}
+
+	file, err := service.blobs.Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, false, errs.Wrap(errs.ErrNotFound, "Attachment file not found", err)
+		}
+		return nil, nil, false, err
+	}
+	return attachment, file, public, nil
+}
+
+// Get the post an attachment belongs to, directly or through its comment, or nil if the post has since been deleted
+func (service *AttachmentService) parentPost(ctx context.Context, attachment *models.Attachment) (*models.Post, error) {
+	var postID uint
+	switch {
+	case attachment.PostID != nil:
+		postID = *attachment.PostID
+	case attachment.CommentID != nil:
+		comment, err := service.commentRepo.GetByID(ctx, *attachment.CommentID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errs.Wrap(errs.ErrNotFound, "Attachment not found", err)
+			}
+			return nil, err
+		}
+		postID = comment.PostID
+	default:
+		return nil, nil
+	}
+
+	post, err := service.postRepo.GetByID(ctx, postID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return post, err
+}
+
+// Delete an attachment and its files. Only the uploader may delete it.
+func (service *AttachmentService) Delete(ctx context.Context, attachmentID uint, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "AttachmentService.Delete")
+	defer span.End()
+
+	attachment, err := service.attachmentRepo.GetByID(ctx, attachmentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.Wrap(errs.ErrNotFound, "Attachment not found", err)
+		}
+		return err
+	}
+	if attachment.UploaderID != currentUserID {
+		return errs.New(errs.ErrForbidden, "Forbidden")
+	}
+
+	if err := service.attachmentRepo.Delete(ctx, attachmentID); err != nil {
+		return err
+	}
+	service.deleteFiles(ctx, []models.Attachment{*attachment})
+	return nil
+}
+
+// Delete a post or comment with deleteRecord, which cascades to its attachment records, then delete the attachments' files
+func (service *AttachmentService) deleteWith(ctx context.Context, getAttachments func(context.Context, uint) ([]models.Attachment, error), id uint, deleteRecord func(context.Context, uint) error) error {
+	// Get the attachments first, since their records are deleted together with the post or comment
+	attachments, err := getAttachments(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := deleteRecord(ctx, id); err != nil {
+		return err
+	}
+	service.deleteFiles(ctx, attachments)
+	return nil
+}
+
+// Delete a post with deleteRecord, and then its attachments' files
+func (service *AttachmentService) deletePost(ctx context.Context, postID uint, deleteRecord func(context.Context, uint) error) error {
+	return service.deleteWith(ctx, service.attachmentRepo.GetByPostID, postID, deleteRecord)
+}
+
+// Delete a comment with its attachments
+func (service *AttachmentService) deleteComment(ctx context.Context, commentID uint) error {
+	return service.deleteWith(ctx, service.attachmentRepo.GetByCommentID, commentID, service.commentRepo.Delete)
+}
+
+// Delete the files of attachments whose records are gone
+// Failures are only logged, since CleanupOrphans removes any files left behind
+func (service *AttachmentService) deleteFiles(ctx context.Context, attachments []models.Attachment) {
+	for _, attachment := range attachments {
+		keys := []string{attachment.BlobKey}
+		if attachment.HasThumbnail {
+			keys = append(keys, thumbnailKey(attachment.BlobKey))
+		}
+		for _, key := range keys {
+			if err := service.blobs.Delete(ctx, key); err != nil {
+				log.Printf("Failed to delete attachment file %s: %v", key, err)
+			}
+		}
+	}
+}
+
+// Delete stored files that no attachment refers to, such as files left behind when deleting them failed
+// Files modified within the grace period are kept, since they may belong to an upload in progress
+// Returns the number of files deleted
+func (service *AttachmentService) CleanupOrphans(ctx context.Context, gracePeriod time.Duration) (int, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "AttachmentService.CleanupOrphans")
+	defer span.End()
+
+	// Map each old enough file to the blob key of the attachment it would belong to
+	owners := make(map[string]string)
+	cutoff := time.Now().Add(-gracePeriod)
+	err := service.blobs.List(ctx, func(key string, modifiedAt time.Time) error {
+		if modifiedAt.Before(cutoff) {
+			owners[key] = key
+			if strings.HasPrefix(key, thumbnailPrefix) {
+				owners[key] = strings.TrimSuffix(strings.TrimPrefix(key, thumbnailPrefix), thumbnailSuffix)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	ownerKeys := make([]string, 0, len(owners))
+	for _, ownerKey := range owners {
+		ownerKeys = append(ownerKeys, ownerKey)
+	}
+	referenced := make(map[string]bool)
+	for start := 0; start < len(ownerKeys); start += 500 {
+		attachments, err := service.attachmentRepo.GetByBlobKeys(ctx, ownerKeys[start:min(start+500, len(ownerKeys))])
+		if err != nil {
+			return 0, err
+		}
+		for _, attachment := range attachments {
+			referenced[attachment.BlobKey] = true
+			if attachment.HasThumbnail {
+				referenced[thumbnailKey(attachment.BlobKey)] = true
+			}
+		}
+	}
+
+	deleted := 0
+	for key := range owners {
+		if referenced[key] {
+			continue
+		}
+		if err := service.blobs.Delete(ctx, key); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
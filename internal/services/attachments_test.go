@@ -0,0 +1,287 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Encode a solid PNG image of the given size
+func pngImage(t *testing.T, width int, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// Keys of all files in the blob store
+func (f *fixture) blobKeys(t *testing.T) []string {
+	t.Helper()
+
+	var keys []string
+	if err := f.blobs.List(context.Background(), func(key string, modifiedAt time.Time) error {
+		keys = append(keys, key)
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to list blobs: %v", err)
+	}
+	return keys
+}
+
+func TestAttachmentUpload(t *testing.T) {
+	f := newFixture(t)
+	service := NewAttachmentService(f.attachments, f.posts, f.comments, f.blobs, AttachmentLimits{MaxSize: 1 << 20})
+	ctx := context.Background()
+	post := f.createPost(t, f.alice.ID, "post")
+	comment := f.createComment(t, f.bob.ID, post.ID)
+
+	tests := []struct {
+		name        string
+		attach      func(content io.Reader) (*models.Attachment, error)
+		content     []byte
+		wantCode    int
+		wantType    string
+		wantWidth   int
+		wantHeight  int
+		wantThumbed bool
+	}{
+		{"image on post", func(content io.Reader) (*models.Attachment, error) {
+			return service.AttachToPost(ctx, post.ID, "photo.png", content, f.alice.ID)
+		}, pngImage(t, 640, 480), noError, "image/png", 640, 480, true},
+		// The type is sniffed from the content rather than taken from the file name
+		{"text on comment", func(content io.Reader) (*models.Attachment, error) {
+			return service.AttachToComment(ctx, comment.ID, "notes.png", content, f.bob.ID)
+		}, []byte("just some notes"), noError, "text/plain", 0, 0, false},
+		{"unsupported type", func(content io.Reader) (*models.Attachment, error) {
+			return service.AttachToPost(ctx, post.ID, "archive.zip", content, f.alice.ID)
+		}, []byte("PK\x03\x04\x14\x00\x00\x00\x08\x00"), errs.ErrUnsupportedMediaType, "", 0, 0, false},
+		{"too large", func(content io.Reader) (*models.Attachment, error) {
+			return service.AttachToPost(ctx, post.ID, "big.txt", content, f.alice.ID)
+		}, []byte(strings.Repeat("a", 1<<20+1)), errs.ErrTooLarge, "", 0, 0, false},
+		{"empty", func(content io.Reader) (*models.Attachment, error) {
+			return service.AttachToPost(ctx, post.ID, "empty.txt", content, f.alice.ID)
+		}, nil, errs.ErrInvalid, "", 0, 0, false},
+		{"corrupt image", func(content io.Reader) (*models.Attachment, error) {
+			return service.AttachToPost(ctx, post.ID, "broken.png", content, f.alice.ID)
+		}, pngImage(t, 10, 10)[:40], errs.ErrInvalid, "", 0, 0, false},
+		{"not the post's author", func(content io.Reader) (*models.Attachment, error) {
+			return service.AttachToPost(ctx, post.ID, "notes.txt", content, f.bob.ID)
+		}, []byte("notes"), errs.ErrForbidden, "", 0, 0, false},
+		{"not the comment's author", func(content io.Reader) (*models.Attachment, error) {
+			return service.AttachToComment(ctx, comment.ID, "notes.txt", content, f.alice.ID)
+		}, []byte("notes"), errs.ErrForbidden, "", 0, 0, false},
+		{"missing post", func(content io.Reader) (*models.Attachment, error) {
+			return service.AttachToPost(ctx, 9999, "notes.txt", content, f.alice.ID)
+		}, []byte("notes"), errs.ErrNotFound, "", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attachment, err := tt.attach(bytes.NewReader(tt.content))
+			assertCode(t, err, tt.wantCode)
+			if tt.wantCode != noError {
+				return
+			}
+			if attachment.ContentType != tt.wantType || attachment.Width != tt.wantWidth || attachment.Height != tt.wantHeight || attachment.HasThumbnail != tt.wantThumbed {
+				t.Fatalf("unexpected attachment: %+v", attachment)
+			}
+
+			_, file, _, err := service.Open(ctx, attachment.ID, false, 0)
+			if err != nil {
+				t.Fatalf("failed to open attachment: %v", err)
+			}
+			defer file.Close()
+			if content, _ := io.ReadAll(file); !bytes.Equal(content, tt.content) {
+				t.Error("stored file differs from the upload")
+			}
+		})
+	}
+
+	// Rejected uploads leave no files behind: only the image, its thumbnail and the text file are stored
+	if keys := f.blobKeys(t); len(keys) != 3 {
+		t.Errorf("expected 3 stored files, got %v", keys)
+	}
+}
+
+func TestAttachmentThumbnail(t *testing.T) {
+	f := newFixture(t)
+	service := f.attachmentService()
+	ctx := context.Background()
+	post := f.createPost(t, f.alice.ID, "post")
+
+	attachment, err := service.AttachToPost(ctx, post.ID, "wide.png", bytes.NewReader(pngImage(t, 1000, 250)), f.alice.ID)
+	if err != nil {
+		t.Fatalf("failed to attach image: %v", err)
+	}
+
+	_, file, _, err := service.Open(ctx, attachment.ID, true, 0)
+	if err != nil {
+		t.Fatalf("failed to open thumbnail: %v", err)
+	}
+	defer file.Close()
+	config, format, err := image.DecodeConfig(file)
+	if err != nil {
+		t.Fatalf("failed to decode thumbnail: %v", err)
+	}
+	// The thumbnail keeps the aspect ratio within the thumbnail size
+	if format != "jpeg" || config.Width != 320 || config.Height != 80 {
+		t.Errorf("expected a 320x80 jpeg thumbnail, got a %dx%d %s", config.Width, config.Height, format)
+	}
+
+	// Files other than images have no thumbnail
+	text, err := service.AttachToPost(ctx, post.ID, "notes.txt", strings.NewReader("notes"), f.alice.ID)
+	if err != nil {
+		t.Fatalf("failed to attach text: %v", err)
+	}
+	_, _, _, err = service.Open(ctx, text.ID, true, 0)
+	assertCode(t, err, errs.ErrNotFound)
+}
+
+func TestAttachmentFilesAreDeleted(t *testing.T) {
+	f := newFixture(t)
+	service := f.attachmentService()
+	postService := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), service, DefaultContentLimits, nil)
+	commentService := NewCommentService(f.comments, f.posts, f.users, f.blocks, f.outbox, f.transactor, service, DefaultContentLimits)
+	ctx := context.Background()
+
+	attach := func(attach func() (*models.Attachment, error)) *models.Attachment {
+		t.Helper()
+		attachment, err := attach()
+		if err != nil {
+			t.Fatalf("failed to attach file: %v", err)
+		}
+		return attachment
+	}
+
+	post := f.createPost(t, f.alice.ID, "post")
+	comment := f.createComment(t, f.bob.ID, post.ID)
+	photo := attach(func() (*models.Attachment, error) {
+		return service.AttachToPost(ctx, post.ID, "photo.png", bytes.NewReader(pngImage(t, 20, 20)), f.alice.ID)
+	})
+	attach(func() (*models.Attachment, error) {
+		return service.AttachToComment(ctx, comment.ID, "notes.txt", strings.NewReader("notes"), f.bob.ID)
+	})
+	text := attach(func() (*models.Attachment, error) {
+		return service.AttachToPost(ctx, post.ID, "more.txt", strings.NewReader("more notes"), f.alice.ID)
+	})
+
+	// Only the uploader may delete an attachment
+	assertCode(t, service.Delete(ctx, text.ID, f.bob.ID), errs.ErrForbidden)
+	assertCode(t, service.Delete(ctx, text.ID, f.alice.ID), noError)
+	assertCode(t, service.Delete(ctx, text.ID, f.alice.ID), errs.ErrNotFound)
+	if keys := f.blobKeys(t); len(keys) != 3 {
+		t.Fatalf("expected the image, its thumbnail and the comment's file to remain, got %v", keys)
+	}
+
+	// Deleting a post deletes its attachments' files
+	assertCode(t, postService.Delete(ctx, post.ID, f.alice.ID), noError)
+	_, _, _, err := service.Open(ctx, photo.ID, false, 0)
+	assertCode(t, err, errs.ErrNotFound)
+	if keys := f.blobKeys(t); len(keys) != 1 {
+		t.Fatalf("expected only the comment's file to remain, got %v", keys)
+	}
+
+	// Deleting a comment deletes its attachments' files
+	assertCode(t, commentService.Delete(ctx, comment.ID, f.bob.ID), noError)
+	if keys := f.blobKeys(t); len(keys) != 0 {
+		t.Fatalf("expected no files to remain, got %v", keys)
+	}
+}
+
+func TestCleanupOrphans(t *testing.T) {
+	f := newFixture(t)
+	service := f.attachmentService()
+	ctx := context.Background()
+	post := f.createPost(t, f.alice.ID, "post")
+
+	kept, err := service.AttachToPost(ctx, post.ID, "photo.png", bytes.NewReader(pngImage(t, 20, 20)), f.alice.ID)
+	if err != nil {
+		t.Fatalf("failed to attach image: %v", err)
+	}
+	// Files left behind by an upload whose record was never created
+	for _, key := range []string{"orphan.png", thumbnailKey("orphan.png")} {
+		if err := f.blobs.Put(ctx, key, strings.NewReader("orphan"), 6, "image/png"); err != nil {
+			t.Fatalf("failed to store orphan: %v", err)
+		}
+	}
+
+	// Files within the grace period are kept
+	deleted, err := service.CleanupOrphans(ctx, time.Hour)
+	if err != nil || deleted != 0 {
+		t.Fatalf("expected no files to be deleted within the grace period, got %d, %v", deleted, err)
+	}
+
+	// A negative grace period treats every file as old enough
+	deleted, err = service.CleanupOrphans(ctx, -time.Hour)
+	if err != nil || deleted != 2 {
+		t.Fatalf("expected the 2 orphaned files to be deleted, got %d, %v", deleted, err)
+	}
+	keys := f.blobKeys(t)
+	if len(keys) != 2 {
+		t.Fatalf("expected the attachment and its thumbnail to remain, got %v", keys)
+	}
+	for _, key := range keys {
+		if key != kept.BlobKey && key != thumbnailKey(kept.BlobKey) {
+			t.Errorf("unexpected remaining file %s", key)
+		}
+	}
+}
+
+// Attachments of unpublished posts are only served to the post's author, and are not public
+func TestAttachmentsOfDrafts(t *testing.T) {
+	f := newFixture(t)
+	service := f.attachmentService()
+	postService := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), service, DefaultContentLimits, nil)
+	ctx := context.Background()
+
+	draft, err := postService.Create(ctx, &models.Post{Title: "draft", Content: "some content", AuthorID: f.alice.ID, Status: models.PostStatusDraft}, nil)
+	if err != nil {
+		t.Fatalf("failed to create draft: %v", err)
+	}
+	attachment, err := service.AttachToPost(ctx, draft.ID, "photo.png", bytes.NewReader(pngImage(t, 20, 20)), f.alice.ID)
+	if err != nil {
+		t.Fatalf("failed to attach image: %v", err)
+	}
+
+	for _, thumbnail := range []bool{false, true} {
+		_, _, _, err := service.Open(ctx, attachment.ID, thumbnail, f.bob.ID)
+		assertCode(t, err, errs.ErrNotFound)
+		_, _, _, err = service.Open(ctx, attachment.ID, thumbnail, 0)
+		assertCode(t, err, errs.ErrNotFound)
+
+		_, file, public, err := service.Open(ctx, attachment.ID, thumbnail, f.alice.ID)
+		assertCode(t, err, noError)
+		file.Close()
+		if public {
+			t.Errorf("expected the draft's attachment not to be public")
+		}
+	}
+
+	// Published posts' attachments are served to everyone
+	if _, err := postService.UpdateStatus(ctx, draft.ID, models.PostStatusPublished, nil, f.alice.ID); err != nil {
+		t.Fatalf("failed to publish draft: %v", err)
+	}
+	_, file, public, err := service.Open(ctx, attachment.ID, false, 0)
+	assertCode(t, err, noError)
+	file.Close()
+	if !public {
+		t.Errorf("expected the published post's attachment to be public")
+	}
+}
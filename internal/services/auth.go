@@ -1,9 +1,13 @@
 package services
 
 import (
+	"context"
 	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/metrics"
 	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/ratelimit"
 	"cvwo-backend/internal/repos"
+	"cvwo-backend/internal/tracing"
 
 	"errors"
 	"fmt"
@@ -18,10 +22,11 @@ import (
 
 type AuthService struct {
 	userRepo repos.UserRepo
+	lockout  *ratelimit.Lockout
 }
 
-func NewAuthService(userRepo repos.UserRepo) *AuthService {
-	return &AuthService{userRepo}
+func NewAuthService(userRepo repos.UserRepo, lockout *ratelimit.Lockout) *AuthService {
+	return &AuthService{userRepo, lockout}
 }
 
 func HashPassword(password string) (string, error) {
@@ -33,17 +38,35 @@ func HashPassword(password string) (string, error) {
 }
 
 // Given a username and password, check if the password matches and if so, generate a jwt
-func (service *AuthService) Authenticate(authInput *models.AuthInput) (*models.User, string, error) {
+// Failed attempts lock out the username for the client's IP address only, so that others cannot lock a user out of their account.
+func (service *AuthService) Authenticate(ctx context.Context, authInput *models.AuthInput, clientIP string) (*models.User, string, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "AuthService.Authenticate")
+	defer span.End()
+
+	// IP addresses never contain a slash, so keys of different usernames and IP addresses are distinct
+	lockoutKey := clientIP + "/" + authInput.Username
+
+	// Reject attempts for usernames that are locked out after too many failed attempts
+	if lockedFor := service.lockout.LockedFor(lockoutKey); lockedFor > 0 {
+		metrics.LoginFailures.WithLabelValues("locked_out").Inc()
+		return nil, "", errs.NewTooManyRequests(fmt.Sprintf("Too many failed login attempts. Try again in %v", lockedFor.Round(time.Second)), lockedFor)
+	}
+
 	// Check if there is any user with that username
-	user, err := service.userRepo.GetByUsername(authInput.Username)
+	user, err := service.userRepo.GetByUsername(ctx, authInput.Username)
 	if err != nil {
+		service.lockout.Fail(lockoutKey)
+		metrics.LoginFailures.WithLabelValues("username_not_found").Inc()
 		return nil, "", errs.New(errs.ErrUnauthorized, "Username not found")
 	}
 
 	// Check if password matches
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(authInput.Password)); err != nil {
+		service.lockout.Fail(lockoutKey)
+		metrics.LoginFailures.WithLabelValues("incorrect_password").Inc()
 		return nil, "", errs.New(errs.ErrUnauthorized, "Incorrect password")
 	}
+	service.lockout.Reset(lockoutKey)
 
 	// Set jwt claims including userID and expiration time
 	claims := &jwt.RegisteredClaims{
@@ -61,7 +84,10 @@ func (service *AuthService) Authenticate(authInput *models.AuthInput) (*models.U
 	return user, signedToken, err
 }
 
-func (service *AuthService) ValidateToken(tokenString string) (*models.User, error) {
+func (service *AuthService) ValidateToken(ctx context.Context, tokenString string) (*models.User, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "AuthService.ValidateToken")
+	defer span.End()
+
 	// Parse jwt while checking for correct claims type and signing method	
 	claims := &jwt.RegisteredClaims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
@@ -88,10 +114,10 @@ func (service *AuthService) ValidateToken(tokenString string) (*models.User, err
 	}
 
 	// Retrieve the user whose ID corresponds to the token ID
-	user, err := service.userRepo.GetByID(uint(userId))
+	user, err := service.userRepo.GetByID(ctx, uint(userId))
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errs.New(errs.ErrUnauthorized, "User not found")
+			return nil, errs.Wrap(errs.ErrUnauthorized, "User not found", err)
 		}
 		return nil, err
 	}
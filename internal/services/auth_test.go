@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/ratelimit"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAuthServiceAuthenticate(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	f := newFixture(t)
+	service := NewAuthService(f.users, ratelimit.NewLockout(5, time.Minute, time.Hour))
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		wantCode int
+	}{
+		{"correct password", "alice", "password", noError},
+		{"incorrect password", "alice", "wrong-password", errs.ErrUnauthorized},
+		{"unknown username", "carol", "password", errs.ErrUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, token, err := service.Authenticate(context.Background(), &models.AuthInput{Username: tt.username, Password: tt.password}, "192.0.2.1")
+			assertCode(t, err, tt.wantCode)
+			if tt.wantCode != noError {
+				return
+			}
+
+			if user.ID != f.alice.ID {
+				t.Errorf("expected user %d, got %d", f.alice.ID, user.ID)
+			}
+
+			// The issued token should authenticate the same user
+			validated, err := service.ValidateToken(context.Background(), token)
+			assertCode(t, err, noError)
+			if validated.ID != f.alice.ID {
+				t.Errorf("expected token for user %d, got %d", f.alice.ID, validated.ID)
+			}
+		})
+	}
+}
+
+func TestAuthServiceLockout(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	f := newFixture(t)
+	service := NewAuthService(f.users, ratelimit.NewLockout(3, time.Minute, time.Hour))
+
+	wrong := &models.AuthInput{Username: "alice", Password: "wrong-password"}
+	for i := 0; i < 3; i++ {
+		_, _, err := service.Authenticate(context.Background(), wrong, "192.0.2.1")
+		assertCode(t, err, errs.ErrUnauthorized)
+	}
+
+	// Even the correct password is rejected while locked out, with the time left until the lockout ends
+	_, _, err := service.Authenticate(context.Background(), &models.AuthInput{Username: "alice", Password: "password"}, "192.0.2.1")
+	assertCode(t, err, errs.ErrTooManyRequests)
+	var e *errs.Error
+	if errors.As(err, &e) && (e.RetryAfter <= 0 || e.RetryAfter > time.Minute) {
+		t.Errorf("expected to retry within the lockout, got %v", e.RetryAfter)
+	}
+
+	// Other accounts are unaffected
+	_, _, err = service.Authenticate(context.Background(), &models.AuthInput{Username: "bob", Password: "password"}, "192.0.2.1")
+	assertCode(t, err, noError)
+
+	// The account is only locked out for the client that failed to log in
+	_, _, err = service.Authenticate(context.Background(), &models.AuthInput{Username: "alice", Password: "password"}, "198.51.100.1")
+	assertCode(t, err, noError)
+}
+
+func TestAuthServiceValidateToken(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	f := newFixture(t)
+	service := NewAuthService(f.users, ratelimit.NewLockout(5, time.Minute, time.Hour))
+
+	tests := []struct {
+		name     string
+		token    string
+		wantCode int
+	}{
+		{"malformed token", "not-a-jwt", errs.ErrUnauthorized},
+		{"empty token", "", errs.ErrUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.ValidateToken(context.Background(), tt.token)
+			assertCode(t, err, tt.wantCode)
+		})
+	}
+
+	t.Run("token signed with another secret", func(t *testing.T) {
+		_, token, err := service.Authenticate(context.Background(), &models.AuthInput{Username: "alice", Password: "password"}, "192.0.2.1")
+		assertCode(t, err, noError)
+
+		t.Setenv("JWT_SECRET", "another-secret")
+		_, err = service.ValidateToken(context.Background(), token)
+		assertCode(t, err, errs.ErrUnauthorized)
+	})
+}
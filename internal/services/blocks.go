@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"cvwo-backend/internal/tracing"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type BlockService struct {
+	blockRepo repos.BlockRepo
+	userRepo  repos.UserRepo
+}
+
+func NewBlockService(blockRepo repos.BlockRepo, userRepo repos.UserRepo) *BlockService {
+	return &BlockService{blockRepo, userRepo}
+}
+
+// Check that the block type param is valid. An empty type matches both blocks and mutes.
+func validBlockType(blockType string) error {
+	if blockType != "" && blockType != models.BlockTypeBlock && blockType != models.BlockTypeMute {
+		return errs.New(errs.ErrInvalid, "Invalid block type")
+	}
+	return nil
+}
+
+// Block or mute a user as the current user, replacing any existing block or mute of that user
+func (service *BlockService) Block(ctx context.Context, userID uint, blockType string, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "BlockService.Block")
+	defer span.End()
+
+	if blockType != models.BlockTypeBlock && blockType != models.BlockTypeMute {
+		return errs.New(errs.ErrInvalid, "Invalid block type")
+	}
+	if userID == currentUserID {
+		return errs.New(errs.ErrInvalid, "Cannot block yourself")
+	}
+	if _, err := service.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.Wrap(errs.ErrNotFound, "User not found", err)
+		}
+		return err
+	}
+
+	err := service.blockRepo.Block(ctx, &models.Block{UserID: currentUserID, BlockedUserID: userID, Type: blockType})
+	if errors.Is(err, gorm.ErrForeignKeyViolated) {
+		return errs.Wrap(errs.ErrNotFound, "User not found", err)
+	}
+	return err
+}
+
+// Remove the current user's block or mute of a user
+func (service *BlockService) Unblock(ctx context.Context, userID uint, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "BlockService.Unblock")
+	defer span.End()
+
+	return service.blockRepo.Unblock(ctx, currentUserID, userID)
+}
+
+// Get the users the current user has blocked or muted
+// blockType may be "block" or "mute" to filter by type, or empty for both
+func (service *BlockService) GetBlocks(ctx context.Context, blockType string, limit, offset int, currentUserID uint) ([]models.Block, int64, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "BlockService.GetBlocks")
+	defer span.End()
+
+	if err := validBlockType(blockType); err != nil {
+		return nil, 0, err
+	}
+	return service.blockRepo.GetByUserID(ctx, currentUserID, blockType, limit, offset)
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"testing"
+)
+
+func TestBlockServiceBlock(t *testing.T) {
+	f := newFixture(t)
+	service := NewBlockService(f.blocks, f.users)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		userID    uint
+		blockType string
+		wantCode  int
+	}{
+		{"block", f.bob.ID, models.BlockTypeBlock, noError},
+		{"change to mute", f.bob.ID, models.BlockTypeMute, noError},
+		{"invalid type", f.bob.ID, "ignore", errs.ErrInvalid},
+		{"block yourself", f.alice.ID, models.BlockTypeBlock, errs.ErrInvalid},
+		{"unknown user", 999, models.BlockTypeBlock, errs.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.Block(ctx, tt.userID, tt.blockType, f.alice.ID)
+			assertCode(t, err, tt.wantCode)
+		})
+	}
+
+	// Blocking again replaced the block rather than adding another
+	blocks, total, err := service.GetBlocks(ctx, "", 10, 0, f.alice.ID)
+	assertCode(t, err, noError)
+	if total != 1 || blocks[0].Type != models.BlockTypeMute || blocks[0].BlockedUser == nil || blocks[0].BlockedUser.ID != f.bob.ID {
+		t.Errorf("expected a single mute of bob, got %+v", blocks)
+	}
+	_, total, err = service.GetBlocks(ctx, models.BlockTypeBlock, 10, 0, f.alice.ID)
+	assertCode(t, err, noError)
+	if total != 0 {
+		t.Errorf("expected no blocks, got %d", total)
+	}
+	_, _, err = service.GetBlocks(ctx, "ignore", 10, 0, f.alice.ID)
+	assertCode(t, err, errs.ErrInvalid)
+
+	assertCode(t, service.Unblock(ctx, f.bob.ID, f.alice.ID), noError)
+	_, total, _ = service.GetBlocks(ctx, "", 10, 0, f.alice.ID)
+	if total != 0 {
+		t.Errorf("expected no blocks after unblocking, got %d", total)
+	}
+}
+
+func TestBlocksAreEnforced(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		blockType     string
+		wantReplyCode int // Bob replying to alice's post
+		wantMention   int // Bob mentioning alice on another post
+		wantVisible   bool
+	}{
+		{"no block", "", noError, noError, true},
+		{"mute hides content only", models.BlockTypeMute, noError, noError, false},
+		{"block hides content and prevents replies and mentions", models.BlockTypeBlock, errs.ErrForbidden, errs.ErrForbidden, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			postService := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, nil)
+			commentService := NewCommentService(f.comments, f.posts, f.users, f.blocks, f.outbox, f.transactor, f.attachmentService(), DefaultContentLimits)
+			carol := f.createUser(t, "carol", "password")
+			alicesPost := f.createPost(t, f.alice.ID, "alice's post")
+			bobsPost := f.createPost(t, f.bob.ID, "bob's post")
+			carolsPost := f.createPost(t, carol.ID, "carol's post")
+			f.createComment(t, f.bob.ID, carolsPost.ID)
+			if tt.blockType != "" {
+				if err := f.blocks.Block(ctx, &models.Block{UserID: f.alice.ID, BlockedUserID: f.bob.ID, Type: tt.blockType}); err != nil {
+					t.Fatalf("failed to block: %v", err)
+				}
+			}
+
+			_, err := commentService.Create(ctx, &models.Comment{Content: "A reply", PostID: alicesPost.ID, AuthorID: f.bob.ID})
+			assertCode(t, err, tt.wantReplyCode)
+			_, err = commentService.Create(ctx, &models.Comment{Content: "What do you think, @alice?", PostID: carolsPost.ID, AuthorID: f.bob.ID})
+			assertCode(t, err, tt.wantMention)
+
+			// Alice's listings hide bob's posts and comments, but others' listings do not
+			wantCount := int64(3)
+			if !tt.wantVisible {
+				wantCount = 2
+			}
+			_, count, err := postService.GetList(ctx, 10, 0, "new", f.alice.ID)
+			assertCode(t, err, noError)
+			if count != wantCount {
+				t.Errorf("expected alice to see %d posts, got %d", wantCount, count)
+			}
+			_, count, _ = postService.GetList(ctx, 10, 0, "new", carol.ID)
+			if count != 3 {
+				t.Errorf("expected carol to see 3 posts, got %d", count)
+			}
+			comments, _, err := commentService.GetByPostID(ctx, carolsPost.ID, 10, 0, "new", f.alice.ID)
+			assertCode(t, err, noError)
+			for _, comment := range comments {
+				if comment.AuthorID == f.bob.ID && !tt.wantVisible {
+					t.Errorf("expected bob's comment %d to be hidden from alice", comment.ID)
+				}
+			}
+			if _, err := postService.GetByIDWithAuth(ctx, bobsPost.ID, f.alice.ID); err != nil {
+				t.Errorf("expected bob's post to still be reachable directly, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"cvwo-backend/internal/cache"
+	"cvwo-backend/internal/metrics"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Namespaces of cached listings, each invalidated as a whole
+const (
+	postsNamespace  = "posts"
+	topicsNamespace = "topics"
+)
+
+// Caches the listings seen by anonymous users, which are the same for all of them
+// Listings are cached under the current generation of their namespace, so invalidating a namespace only
+// increments its generation and leaves the old listings to expire or be evicted. A listing computed while its
+// namespace is invalidated is stored under the old generation, and so is never served.
+type ListingCache struct {
+	cache cache.Cache
+	ttl   time.Duration
+}
+
+// Returns nil, which disables caching, if c is nil
+func NewListingCache(c cache.Cache, ttl time.Duration) *ListingCache {
+	if c == nil {
+		return nil
+	}
+	return &ListingCache{cache: c, ttl: ttl}
+}
+
+// Drop the cached post listings, after posts are created, edited, deleted, voted on, tagged or pinned
+func (listings *ListingCache) InvalidatePosts(ctx context.Context) error {
+	return listings.invalidate(ctx, postsNamespace)
+}
+
+// Drop the cached list of topics, after topics are seeded or imported
+func (listings *ListingCache) InvalidateTopics(ctx context.Context) error {
+	return listings.invalidate(ctx, topicsNamespace)
+}
+
+func (listings *ListingCache) invalidate(ctx context.Context, namespace string) error {
+	if listings == nil {
+		return nil
+	}
+	_, err := listings.cache.Incr(ctx, namespace+":generation")
+	return err
+}
+
+// Invalidate a namespace after a change that has already been made, which must not fail because of the cache
+// The listings are served stale until they expire if this fails.
+func (listings *ListingCache) invalidateOrLog(ctx context.Context, namespace string) {
+	if err := listings.invalidate(ctx, namespace); err != nil {
+		log.Printf("Failed to invalidate cached %s: %v", namespace, err)
+	}
+}
+
+// Get the listing cached under key in namespace, or load and cache it
+// Errors of the cache are logged and treated as misses, so that listings are still served when the cache is down.
+func cachedListing[T any](ctx context.Context, listings *ListingCache, namespace, key string, load func(ctx context.Context) (T, error)) (T, error) {
+	if listings == nil {
+		return load(ctx)
+	}
+
+	generation, ok, err := listings.cache.Get(ctx, namespace+":generation")
+	if err != nil {
+		log.Printf("Failed to get the generation of cached %s: %v", namespace, err)
+		metrics.CacheRequests.WithLabelValues(namespace, "error").Inc()
+		return load(ctx)
+	}
+	// Namespaces start at generation 0
+	if !ok {
+		generation = []byte("0")
+	}
+	key = fmt.Sprintf("%s:%s:%s", namespace, generation, key)
+
+	value, ok, err := listings.cache.Get(ctx, key)
+	if err != nil {
+		log.Printf("Failed to get cached %s: %v", key, err)
+		metrics.CacheRequests.WithLabelValues(namespace, "error").Inc()
+		return load(ctx)
+	}
+	if ok {
+		var listing T
+		if err := json.Unmarshal(value, &listing); err == nil {
+			metrics.CacheRequests.WithLabelValues(namespace, "hit").Inc()
+			return listing, nil
+		}
+	}
+
+	metrics.CacheRequests.WithLabelValues(namespace, "miss").Inc()
+	listing, err := load(ctx)
+	if err != nil {
+		return listing, err
+	}
+	if value, err = json.Marshal(listing); err == nil {
+		err = listings.cache.Set(ctx, key, value, listings.ttl)
+	}
+	if err != nil {
+		log.Printf("Failed to cache %s: %v", key, err)
+	}
+	return listing, nil
+}
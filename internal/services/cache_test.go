@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"cvwo-backend/internal/cache"
+	"cvwo-backend/internal/metrics"
+	"cvwo-backend/internal/models"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPostServiceCachesAnonymousListings(t *testing.T) {
+	f := newFixture(t)
+	listings := NewListingCache(cache.NewLRU(100), time.Minute)
+	service := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, listings)
+	moderationService := NewModerationService(f.posts, f.users, f.topics, listings)
+	ctx := context.Background()
+	mod := f.createModerator(t, "mod")
+	philosophy := f.store.AddTopic("Philosophy")
+	literature := f.store.AddTopic("Literature")
+
+	first := f.createPost(t, f.alice.ID, "first")
+	if err := f.posts.AssociatePostWithTopics(ctx, first, []models.Topic{philosophy}); err != nil {
+		t.Fatalf("failed to tag post: %v", err)
+	}
+
+	getList := func(userID uint) int64 {
+		t.Helper()
+		_, total, err := service.GetList(ctx, 10, 0, "new", userID)
+		if err != nil {
+			t.Fatalf("failed to get posts: %v", err)
+		}
+		return total
+	}
+	getByTags := func(topicIDs ...uint) int64 {
+		t.Helper()
+		_, total, err := service.GetByTags(ctx, topicIDs, 10, 0, "new", 0)
+		if err != nil {
+			t.Fatalf("failed to get posts: %v", err)
+		}
+		return total
+	}
+
+	hits := testutil.ToFloat64(metrics.CacheRequests.WithLabelValues("posts", "hit"))
+	if getList(0) != 1 || getByTags(philosophy.ID, literature.ID) != 1 {
+		t.Fatalf("expected the first post to be listed")
+	}
+
+	// Posts created behind the service's back are not seen until the listings are invalidated
+	second := f.createPost(t, f.bob.ID, "second")
+	if err := f.posts.AssociatePostWithTopics(ctx, second, []models.Topic{philosophy}); err != nil {
+		t.Fatalf("failed to tag post: %v", err)
+	}
+	if got := getList(0); got != 1 {
+		t.Errorf("expected the cached listing of 1 post, got %d", got)
+	}
+	// The order and repetition of the topics do not matter
+	if got := getByTags(literature.ID, philosophy.ID, philosophy.ID); got != 1 {
+		t.Errorf("expected the cached listing of 1 post, got %d", got)
+	}
+	if got := testutil.ToFloat64(metrics.CacheRequests.WithLabelValues("posts", "hit")) - hits; got != 2 {
+		t.Errorf("expected 2 cache hits, got %v", got)
+	}
+	// Listings of authenticated users include their votes, so they are never cached
+	if got := getList(f.alice.ID); got != 2 {
+		t.Errorf("expected an uncached listing of 2 posts, got %d", got)
+	}
+
+	if err := listings.InvalidatePosts(ctx); err != nil {
+		t.Fatalf("failed to invalidate posts: %v", err)
+	}
+	if getList(0) != 2 || getByTags(philosophy.ID) != 2 {
+		t.Errorf("expected 2 posts after invalidating the listings")
+	}
+
+	// Pinning invalidates the listings itself
+	if err := moderationService.Pin(ctx, first.ID, 0, mod.ID); err != nil {
+		t.Fatalf("failed to pin post: %v", err)
+	}
+	posts, _, err := service.GetList(ctx, 10, 0, "new", 0)
+	if err != nil {
+		t.Fatalf("failed to get posts: %v", err)
+	}
+	if len(posts) != 2 || posts[0].ID != first.ID {
+		t.Errorf("expected the pinned post first, got %+v", posts)
+	}
+}
+
+// Changes made through the services drop the cached listings as soon as they commit
+func TestChangesInvalidateListings(t *testing.T) {
+	f := newFixture(t)
+	listings := NewListingCache(cache.NewLRU(100), time.Minute)
+	postService := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, listings)
+	votingService := NewVotingService(f.postVotes, f.commentVotes, f.posts, f.comments, f.outbox, f.transactor, listings)
+	taggingService := NewTaggingService(f.posts, f.topics, f.outbox, f.transactor, listings)
+	ctx := context.Background()
+	philosophy := f.store.AddTopic("Philosophy")
+
+	getList := func() []models.Post {
+		t.Helper()
+		posts, _, err := postService.GetList(ctx, 10, 0, "votes", 0)
+		if err != nil {
+			t.Fatalf("failed to get posts: %v", err)
+		}
+		return posts
+	}
+	getByTags := func() []models.Post {
+		t.Helper()
+		posts, _, err := postService.GetByTags(ctx, []uint{philosophy.ID}, 10, 0, "votes", 0)
+		if err != nil {
+			t.Fatalf("failed to get posts: %v", err)
+		}
+		return posts
+	}
+
+	first, err := postService.Create(ctx, &models.Post{Title: "first", Content: "some content", AuthorID: f.alice.ID}, nil)
+	assertCode(t, err, noError)
+	if posts := getList(); len(posts) != 1 || len(getByTags()) != 0 {
+		t.Fatalf("expected the first post, got %+v", posts)
+	}
+
+	second, err := postService.Create(ctx, &models.Post{Title: "second", Content: "some content", AuthorID: f.bob.ID}, nil)
+	assertCode(t, err, noError)
+	if posts := getList(); len(posts) != 2 {
+		t.Errorf("expected the created post to be listed, got %+v", posts)
+	}
+
+	assertCode(t, votingService.VotePost(ctx, second.ID, f.alice.ID, 1, f.alice.ID), noError)
+	if posts := getList(); len(posts) != 2 || posts[0].ID != second.ID || posts[0].NetVotes != 1 {
+		t.Errorf("expected the voted post first with its vote, got %+v", posts)
+	}
+
+	assertCode(t, taggingService.TagPostWithTopics(ctx, first.ID, []uint{philosophy.ID}, f.alice.ID), noError)
+	if posts := getByTags(); len(posts) != 1 || posts[0].ID != first.ID {
+		t.Errorf("expected the tagged post in its topic, got %+v", posts)
+	}
+
+	_, err = postService.Update(ctx, first.ID, 0, "edited", "some content", f.alice.ID)
+	assertCode(t, err, noError)
+	if posts := getByTags(); len(posts) != 1 || posts[0].Title != "edited" {
+		t.Errorf("expected the edited title, got %+v", posts)
+	}
+
+	assertCode(t, postService.Delete(ctx, second.ID, f.bob.ID), noError)
+	if posts := getList(); len(posts) != 1 || posts[0].ID != first.ID {
+		t.Errorf("expected the deleted post to be gone, got %+v", posts)
+	}
+}
+
+func TestTopicServiceCachesTopics(t *testing.T) {
+	f := newFixture(t)
+	listings := NewListingCache(cache.NewLRU(100), time.Minute)
+	service := NewTopicService(f.topics, listings)
+	ctx := context.Background()
+	f.store.AddTopic("Philosophy")
+
+	for range 2 {
+		topics, err := service.GetAll(ctx)
+		if err != nil {
+			t.Fatalf("failed to get topics: %v", err)
+		}
+		if len(topics) != 1 || topics[0].Name != "Philosophy" {
+			t.Errorf("expected the seeded topic, got %+v", topics)
+		}
+		f.store.AddTopic("Literature")
+	}
+
+	if err := listings.InvalidateTopics(ctx); err != nil {
+		t.Fatalf("failed to invalidate topics: %v", err)
+	}
+	if topics, err := service.GetAll(ctx); err != nil || len(topics) != 3 {
+		t.Errorf("expected every topic after invalidating the list, got %+v, %v", topics, err)
+	}
+}
+
+// Cache whose every operation fails, as when its server is down
+type failingCache struct{}
+
+func (failingCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+
+func (failingCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func (failingCache) Incr(ctx context.Context, key string) (int64, error) {
+	return 0, errors.New("connection refused")
+}
+
+// Listings are still served when the cache is down
+func TestListingCacheErrors(t *testing.T) {
+	f := newFixture(t)
+	listings := NewListingCache(failingCache{}, time.Minute)
+	service := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, listings)
+	ctx := context.Background()
+	f.createPost(t, f.alice.ID, "first")
+
+	cacheErrors := testutil.ToFloat64(metrics.CacheRequests.WithLabelValues("posts", "error"))
+	posts, total, err := service.GetList(ctx, 10, 0, "new", 0)
+	if err != nil || total != 1 || len(posts) != 1 {
+		t.Errorf("expected the post to be listed, got %+v, %d, %v", posts, total, err)
+	}
+	if got := testutil.ToFloat64(metrics.CacheRequests.WithLabelValues("posts", "error")) - cacheErrors; got != 1 {
+		t.Errorf("expected 1 cache error, got %v", got)
+	}
+	// Invalidation failures are reported, so that event handlers are retried
+	if err := listings.InvalidatePosts(ctx); err == nil {
+		t.Errorf("expected the invalidation to fail")
+	}
+}
@@ -1,10 +1,16 @@
 package services
 
 import (
+	"context"
 	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/events"
+	"cvwo-backend/internal/metrics"
 	"cvwo-backend/internal/models"
 	"cvwo-backend/internal/repos"
+	"cvwo-backend/internal/tracing"
 	"errors"
+	"fmt"
+	"regexp"
 
 	"gorm.io/gorm"
 )
@@ -13,10 +19,43 @@ type CommentService struct {
 	commentRepo repos.CommentRepo
 	postRepo    repos.PostRepo
 	userRepo    repos.UserRepo
+	blockRepo   repos.BlockRepo
+	// Records events in the same transaction as the change they describe
+	outboxRepo repos.OutboxRepo
+	transactor repos.Transactor
+	// Deletes the files attached to deleted comments
+	attachmentService *AttachmentService
+	limits            ContentLimits
 }
 
-func NewCommentService(commentRepo repos.CommentRepo, postRepo repos.PostRepo, userRepo repos.UserRepo) *CommentService {
-	return &CommentService{commentRepo, postRepo, userRepo}
+func NewCommentService(commentRepo repos.CommentRepo, postRepo repos.PostRepo, userRepo repos.UserRepo, blockRepo repos.BlockRepo, outboxRepo repos.OutboxRepo, transactor repos.Transactor, attachmentService *AttachmentService, limits ContentLimits) *CommentService {
+	return &CommentService{commentRepo, postRepo, userRepo, blockRepo, outboxRepo, transactor, attachmentService, limits}
+}
+
+// Matches @username mentions in comment content
+var mentionPattern = regexp.MustCompile(`(?:^|\s)@(\w+)`)
+
+// Check that none of the users mentioned in the content have blocked the author
+func (service *CommentService) checkMentions(ctx context.Context, content string, authorID uint) error {
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		mentioned, err := service.userRepo.GetByUsername(ctx, match[1])
+		if err != nil {
+			// Mentions of users that do not exist are left as plain text
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return err
+		}
+
+		blocked, err := service.blockRepo.IsBlocked(ctx, mentioned.ID, authorID)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return errs.New(errs.ErrForbidden, fmt.Sprintf("You cannot mention @%s", mentioned.Username))
+		}
+	}
+	return nil
 }
 
 // Maps valid sort params to the corresponding SQL orderBy clause
@@ -36,17 +75,20 @@ func validCommentSortField(sortBy string) (string, error) {
 }
 
 // Get all comments associated with a specified post
-func (service *CommentService) GetByPostID(postId uint, limit int, offset int, sortBy string, currentUserID uint) ([]models.Comment, int64, error) {
+func (service *CommentService) GetByPostID(ctx context.Context, postId uint, limit int, offset int, sortBy string, currentUserID uint) ([]models.Comment, int64, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "CommentService.GetByPostID")
+	defer span.End()
+
 	// Validate sortBy param
 	sortField, err := validCommentSortField(sortBy)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	comments, count, err := service.commentRepo.GetByPostID(postId, limit, offset, sortField, currentUserID)
+	comments, count, err := service.commentRepo.GetByPostID(ctx, postId, limit, offset, sortField, currentUserID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, 0, errs.New(errs.ErrNotFound, "Post not found")
+			return nil, 0, errs.Wrap(errs.ErrNotFound, "Post not found", err)
 		}
 		return nil, 0, err
 	}
@@ -54,11 +96,14 @@ func (service *CommentService) GetByPostID(postId uint, limit int, offset int, s
 }
 
 // Get an individual comment by ID
-func (service *CommentService) GetByID(id uint) (*models.Comment, error) {
-	comment, err := service.commentRepo.GetByID(id)
+func (service *CommentService) GetByID(ctx context.Context, id uint) (*models.Comment, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "CommentService.GetByID")
+	defer span.End()
+
+	comment, err := service.commentRepo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errs.New(errs.ErrNotFound, "Comment not found")
+			return nil, errs.Wrap(errs.ErrNotFound, "Comment not found", err)
 		}
 		return nil, err
 	}
@@ -66,33 +111,95 @@ func (service *CommentService) GetByID(id uint) (*models.Comment, error) {
 }
 
 // Create a new comment associated with a specific post and user
-func (service *CommentService) Create(commentData *models.Comment) (*models.Comment, error) {
-	comment, err := service.commentRepo.Create(commentData)
+func (service *CommentService) Create(ctx context.Context, commentData *models.Comment) (*models.Comment, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "CommentService.Create")
+	defer span.End()
+
+	contentHTML, err := renderContent(commentData.Content, service.limits.Comment)
+	if err != nil {
+		return nil, err
+	}
+	commentData.ContentHTML = contentHTML
+
+	// Check that the post exists, since comments are not constrained by a foreign key to posts
+	post, err := service.postRepo.GetByID(ctx, commentData.PostID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.Wrap(errs.ErrNotFound, "Post not found", err)
+		}
+		return nil, err
+	}
+
+	// Unpublished posts cannot be commented on, since they are only visible to their author
+	if post.Status != models.PostStatusPublished {
+		return nil, errs.New(errs.ErrNotFound, "Post not found")
+	}
+	// Locked posts cannot be commented on
+	if post.Locked {
+		return nil, errs.New(errs.ErrForbidden, "Post is locked")
+	}
+
+	// Users blocked by the post's author cannot reply to it
+	blocked, err := service.blockRepo.IsBlocked(ctx, post.AuthorID, commentData.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, errs.New(errs.ErrForbidden, "You cannot reply to this post")
+	}
+	if err := service.checkMentions(ctx, commentData.Content, commentData.AuthorID); err != nil {
+		return nil, err
+	}
+
+	var comment *models.Comment
+	err = service.transactor.InTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if comment, err = service.commentRepo.Create(ctx, commentData); err != nil {
+			return err
+		}
+		return emit(ctx, service.outboxRepo, events.CommentCreated{CommentID: comment.ID, PostID: comment.PostID, AuthorID: comment.AuthorID})
+	})
 	if err != nil {
 		if errors.Is(err, gorm.ErrForeignKeyViolated) {
-			return nil, errs.New(errs.ErrNotFound, "Post or author not found")
+			return nil, errs.Wrap(errs.ErrNotFound, "Post or author not found", err)
 		}
 		return nil, err
 	}
+	metrics.CommentsCreated.Inc()
 	return comment, nil
 }
 
 // Update the content of the given comment
-func (service *CommentService) Update(commentID uint, content string, currentUserID uint) (*models.Comment, error) {
-	post, err := service.GetByID(commentID)
+// version is the version of the comment the edit is based on, or 0 to overwrite any concurrent edits
+func (service *CommentService) Update(ctx context.Context, commentID uint, version uint, content string, currentUserID uint) (*models.Comment, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "CommentService.Update")
+	defer span.End()
+
+	post, err := service.GetByID(ctx, commentID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Check authorization
 	if currentUserID != post.AuthorID {
-		return nil, errs.New(errs.ErrUnauthorized, "Unauthorized")
+		return nil, errs.New(errs.ErrForbidden, "Forbidden")
+	}
+	if err := service.checkMentions(ctx, content, currentUserID); err != nil {
+		return nil, err
+	}
+
+	contentHTML, err := renderContent(content, service.limits.Comment)
+	if err != nil {
+		return nil, err
 	}
 
-	comment, err := service.commentRepo.Update(commentID, content)
+	comment, err := service.commentRepo.Update(ctx, commentID, version, content, contentHTML)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errs.New(errs.ErrNotFound, "Comment not found")
+			return nil, errs.Wrap(errs.ErrNotFound, "Comment not found", err)
+		}
+		if errors.Is(err, repos.ErrVersionConflict) {
+			return nil, errs.Wrap(errs.ErrPreconditionFailed, "Comment has been edited since it was retrieved", err)
 		}
 		return nil, err
 	}
@@ -100,15 +207,18 @@ func (service *CommentService) Update(commentID uint, content string, currentUse
 }
 
 // Delete an individual comment
-func (service *CommentService) Delete(commentID uint, currentUserID uint) error {
+func (service *CommentService) Delete(ctx context.Context, commentID uint, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "CommentService.Delete")
+	defer span.End()
+
 	// Check authorization
-	comment, err := service.GetByID(commentID)
+	comment, err := service.GetByID(ctx, commentID)
 	if err != nil {
 		return err
 	}
 	if currentUserID != comment.AuthorID {
-		return errs.New(errs.ErrUnauthorized, "Unauthorized")
+		return errs.New(errs.ErrForbidden, "Forbidden")
 	}
 
-	return service.commentRepo.Delete(commentID); 
+	return service.attachmentService.deleteComment(ctx, commentID)
 }
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"testing"
+)
+
+func TestCommentServiceCreate(t *testing.T) {
+	f := newFixture(t)
+	service := NewCommentService(f.comments, f.posts, f.users, f.blocks, f.outbox, f.transactor, f.attachmentService(), DefaultContentLimits)
+	post := f.createPost(t, f.alice.ID, "post")
+
+	tests := []struct {
+		name     string
+		postID   uint
+		authorID uint
+		wantCode int
+	}{
+		{"existing post", post.ID, f.bob.ID, noError},
+		{"unknown post", 999, f.bob.ID, errs.ErrNotFound},
+		{"unknown author", post.ID, 999, errs.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Create(context.Background(), &models.Comment{Content: "comment", PostID: tt.postID, AuthorID: tt.authorID})
+			assertCode(t, err, tt.wantCode)
+		})
+	}
+}
+
+// The post is checked before creating the comment, since the database does not constrain comments to existing posts
+func TestCommentServiceCreateChecksPost(t *testing.T) {
+	f := newFixture(t)
+	service := NewCommentService(f.comments, f.posts, f.users, f.blocks, f.outbox, f.transactor, f.attachmentService(), DefaultContentLimits)
+
+	_, err := service.Create(context.Background(), &models.Comment{Content: "comment", PostID: 999, AuthorID: f.bob.ID})
+	assertCode(t, err, errs.ErrNotFound)
+	if err.Error() != "Post not found" {
+		t.Errorf("expected the post to be reported missing before creating the comment, got %q", err)
+	}
+	if comments, total, err := f.comments.GetByPostID(context.Background(), 999, 10, 0, "created_at DESC", 0); err != nil || total != 0 {
+		t.Errorf("expected no comment to be created, got %+v, %v", comments, err)
+	}
+}
+
+func TestCommentServiceGetByPostID(t *testing.T) {
+	f := newFixture(t)
+	service := NewCommentService(f.comments, f.posts, f.users, f.blocks, f.outbox, f.transactor, f.attachmentService(), DefaultContentLimits)
+	post := f.createPost(t, f.alice.ID, "post")
+	older := f.createComment(t, f.alice.ID, post.ID)
+	newer := f.createComment(t, f.bob.ID, post.ID)
+
+	tests := []struct {
+		name      string
+		sortBy    string
+		wantCode  int
+		wantFirst uint
+	}{
+		{"newest first", "new", noError, newer.ID},
+		{"oldest first", "old", noError, older.ID},
+		{"invalid sort", "random", errs.ErrInvalid, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comments, count, err := service.GetByPostID(context.Background(), post.ID, 10, 0, tt.sortBy, 0)
+			assertCode(t, err, tt.wantCode)
+			if tt.wantCode != noError {
+				return
+			}
+			if count != 2 || len(comments) != 2 {
+				t.Fatalf("expected 2 comments, got %d (total %d)", len(comments), count)
+			}
+			if comments[0].ID != tt.wantFirst {
+				t.Errorf("expected comment %d first, got %d", tt.wantFirst, comments[0].ID)
+			}
+		})
+	}
+}
+
+func TestCommentServiceUpdate(t *testing.T) {
+	f := newFixture(t)
+	service := NewCommentService(f.comments, f.posts, f.users, f.blocks, f.outbox, f.transactor, f.attachmentService(), DefaultContentLimits)
+	post := f.createPost(t, f.alice.ID, "post")
+	comment := f.createComment(t, f.bob.ID, post.ID)
+
+	tests := []struct {
+		name        string
+		commentID   uint
+		version     uint
+		userID      uint
+		wantCode    int
+		wantVersion uint
+	}{
+		{"author can update", comment.ID, 1, f.bob.ID, noError, 2},
+		{"edit based on an old version", comment.ID, 1, f.bob.ID, errs.ErrPreconditionFailed, 0},
+		{"edit based on any version", comment.ID, 0, f.bob.ID, noError, 3},
+		{"post author is forbidden", comment.ID, 0, f.alice.ID, errs.ErrForbidden, 0},
+		{"comment not found", 999, 0, f.bob.ID, errs.ErrNotFound, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, err := service.Update(context.Background(), tt.commentID, tt.version, "edited", tt.userID)
+			assertCode(t, err, tt.wantCode)
+			if tt.wantCode == noError && (updated.Content != "edited" || updated.Version != tt.wantVersion) {
+				t.Errorf("expected content to be updated to version %d, got %q version %d", tt.wantVersion, updated.Content, updated.Version)
+			}
+		})
+	}
+}
+
+func TestCommentServiceDelete(t *testing.T) {
+	tests := []struct {
+		name     string
+		byAuthor bool
+		wantCode int
+	}{
+		{"author can delete", true, noError},
+		{"other user is forbidden", false, errs.ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			service := NewCommentService(f.comments, f.posts, f.users, f.blocks, f.outbox, f.transactor, f.attachmentService(), DefaultContentLimits)
+			post := f.createPost(t, f.alice.ID, "post")
+			comment := f.createComment(t, f.bob.ID, post.ID)
+
+			userID := f.alice.ID
+			if tt.byAuthor {
+				userID = f.bob.ID
+			}
+			assertCode(t, service.Delete(context.Background(), comment.ID, userID), tt.wantCode)
+		})
+	}
+
+	t.Run("comment not found", func(t *testing.T) {
+		f := newFixture(t)
+		service := NewCommentService(f.comments, f.posts, f.users, f.blocks, f.outbox, f.transactor, f.attachmentService(), DefaultContentLimits)
+		assertCode(t, service.Delete(context.Background(), 999, f.alice.ID), errs.ErrNotFound)
+	})
+}
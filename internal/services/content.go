@@ -0,0 +1,26 @@
+package services
+
+import (
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/markdown"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Maximum lengths of Markdown content, in characters
+type ContentLimits struct {
+	Post    int
+	Comment int
+}
+
+var DefaultContentLimits = ContentLimits{Post: 10000, Comment: 2000}
+
+// Check that the Markdown content is within the length limit, then render it to sanitized HTML
+func renderContent(content string, maxLength int) (string, error) {
+	if utf8.RuneCountInString(content) > maxLength {
+		return "", errs.NewValidation("Invalid request body", []errs.FieldError{
+			{Field: "content", Message: fmt.Sprintf("must be at most %d characters", maxLength)},
+		})
+	}
+	return markdown.Render(content)
+}
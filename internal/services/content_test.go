@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestContentIsRenderedAndLimited(t *testing.T) {
+	f := newFixture(t)
+	limits := ContentLimits{Post: 20, Comment: 10}
+	postService := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), f.attachmentService(), limits, nil)
+	commentService := NewCommentService(f.comments, f.posts, f.users, f.blocks, f.outbox, f.transactor, f.attachmentService(), limits)
+	ctx := context.Background()
+	post := f.createPost(t, f.alice.ID, "post")
+
+	tests := []struct {
+		name     string
+		write    func(content string) (string, error) // Returns the rendered content
+		content  string
+		wantCode int
+		wantHTML string
+	}{
+		{"create post", func(content string) (string, error) {
+			post, err := postService.Create(ctx, &models.Post{Title: "title", Content: content, AuthorID: f.alice.ID}, nil)
+			if err != nil {
+				return "", err
+			}
+			return post.ContentHTML, nil
+		}, "**hello**", noError, "<p><strong>hello</strong></p>"},
+		{"update post", func(content string) (string, error) {
+			post, err := postService.Update(ctx, post.ID, 0, "title", content, f.alice.ID)
+			if err != nil {
+				return "", err
+			}
+			return post.ContentHTML, nil
+		}, "# heading", noError, "<h1>heading</h1>"},
+		{"post too long", func(content string) (string, error) {
+			_, err := postService.Create(ctx, &models.Post{Title: "title", Content: content, AuthorID: f.alice.ID}, nil)
+			return "", err
+		}, strings.Repeat("a", 21), errs.ErrInvalid, ""},
+		// Limits count characters rather than bytes
+		{"post at limit with multibyte characters", func(content string) (string, error) {
+			_, err := postService.Create(ctx, &models.Post{Title: "title", Content: content, AuthorID: f.alice.ID}, nil)
+			return "", err
+		}, strings.Repeat("é", 20), noError, ""},
+		{"create comment", func(content string) (string, error) {
+			comment, err := commentService.Create(ctx, &models.Comment{Content: content, PostID: post.ID, AuthorID: f.bob.ID})
+			if err != nil {
+				return "", err
+			}
+			return comment.ContentHTML, nil
+		}, "||secret||", noError, `<p><span class="spoiler">secret</span></p>`},
+		{"comment too long", func(content string) (string, error) {
+			_, err := commentService.Create(ctx, &models.Comment{Content: content, PostID: post.ID, AuthorID: f.bob.ID})
+			return "", err
+		}, strings.Repeat("a", 11), errs.ErrInvalid, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := tt.write(tt.content)
+			assertCode(t, err, tt.wantCode)
+			if tt.wantHTML != "" && strings.TrimSpace(html) != tt.wantHTML {
+				t.Errorf("expected %q, got %q", tt.wantHTML, html)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/metrics"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"cvwo-backend/internal/tracing"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ConversationService struct {
+	conversationRepo repos.ConversationRepo
+	userRepo         repos.UserRepo
+	blockRepo        repos.BlockRepo
+}
+
+func NewConversationService(conversationRepo repos.ConversationRepo, userRepo repos.UserRepo, blockRepo repos.BlockRepo) *ConversationService {
+	return &ConversationService{conversationRepo, userRepo, blockRepo}
+}
+
+// Encode a cursor as an opaque string for clients to send back
+func encodeConversationCursor(cursor models.ConversationCursor) string {
+	raw := fmt.Sprintf("%s|%d", cursor.UpdatedAt.Format(time.RFC3339Nano), cursor.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Decode a cursor sent by a client
+func decodeConversationCursor(encoded string) (*models.ConversationCursor, error) {
+	invalid := errs.New(errs.ErrInvalid, "Invalid cursor")
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, invalid
+	}
+	updatedAt, id, found := strings.Cut(string(raw), "|")
+	if !found {
+		return nil, invalid
+	}
+	cursor := models.ConversationCursor{}
+	if cursor.UpdatedAt, err = time.Parse(time.RFC3339Nano, updatedAt); err != nil {
+		return nil, invalid
+	}
+	parsedID, err := strconv.ParseUint(id, 10, 0)
+	if err != nil {
+		return nil, invalid
+	}
+	cursor.ID = uint(parsedID)
+	return &cursor, nil
+}
+
+// Messages are paginated by ID, so their cursor is the encoded ID of the last message on the page
+func decodeMessageCursor(encoded string) (uint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, errs.New(errs.ErrInvalid, "Invalid cursor")
+	}
+	id, err := strconv.ParseUint(string(raw), 10, 0)
+	if err != nil || id == 0 {
+		return 0, errs.New(errs.ErrInvalid, "Invalid cursor")
+	}
+	return uint(id), nil
+}
+
+func encodeMessageCursor(id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(id), 10)))
+}
+
+// Get a conversation, checking that the current user takes part in it
+func (service *ConversationService) getParticipating(ctx context.Context, conversationID uint, currentUserID uint) (*models.Conversation, error) {
+	conversation, err := service.conversationRepo.GetByID(ctx, conversationID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.Wrap(errs.ErrNotFound, "Conversation not found", err)
+		}
+		return nil, err
+	}
+
+	for _, participant := range conversation.Participants {
+		if participant.UserID == currentUserID {
+			return conversation, nil
+		}
+	}
+	return nil, errs.New(errs.ErrForbidden, "Forbidden")
+}
+
+// Check that the sender may message each of the recipients, i.e. that neither has blocked the other
+func (service *ConversationService) checkBlocks(ctx context.Context, senderID uint, recipients []models.User) error {
+	for _, recipient := range recipients {
+		blocked, err := service.blockRepo.IsBlocked(ctx, recipient.ID, senderID)
+		if err != nil {
+			return err
+		}
+		blocking, err := service.blockRepo.IsBlocked(ctx, senderID, recipient.ID)
+		if err != nil {
+			return err
+		}
+		if blocked || blocking {
+			return errs.New(errs.ErrForbidden, fmt.Sprintf("You cannot message @%s", recipient.Username))
+		}
+	}
+	return nil
+}
+
+// Get the participants of a conversation other than the given user
+func otherParticipants(conversation *models.Conversation, userID uint) []models.User {
+	users := []models.User{}
+	for _, participant := range conversation.Participants {
+		if participant.UserID != userID && participant.User != nil {
+			users = append(users, *participant.User)
+		}
+	}
+	return users
+}
+
+// Start a conversation between the current user and the given users with a first message
+// Messaging a single user reuses the existing conversation between the 2 users, if any
+func (service *ConversationService) Create(ctx context.Context, input *models.NewConversation, currentUserID uint) (*models.Conversation, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "ConversationService.Create")
+	defer span.End()
+
+	// Remove duplicate participants
+	recipients := []models.User{}
+	seen := map[uint]bool{}
+	for _, userID := range input.ParticipantIDs {
+		if userID == currentUserID {
+			return nil, errs.New(errs.ErrInvalid, "Cannot message yourself")
+		}
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		user, err := service.userRepo.GetByID(ctx, userID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errs.Wrap(errs.ErrNotFound, "User not found", err)
+			}
+			return nil, err
+		}
+		recipients = append(recipients, *user)
+	}
+
+	if err := service.checkBlocks(ctx, currentUserID, recipients); err != nil {
+		return nil, err
+	}
+
+	message := &models.Message{SenderID: currentUserID, Content: input.Content}
+
+	if len(recipients) == 1 {
+		conversation, err := service.conversationRepo.FindDirect(ctx, currentUserID, recipients[0].ID)
+		if err == nil {
+			message.ConversationID = conversation.ID
+			if err := service.conversationRepo.CreateMessage(ctx, message); err != nil {
+				return nil, err
+			}
+			metrics.MessagesSent.Inc()
+			conversation.UpdatedAt = message.CreatedAt
+			conversation.LastMessage = message
+			return conversation, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	conversation := &models.Conversation{
+		Participants: []models.ConversationParticipant{{UserID: currentUserID}},
+	}
+	for _, recipient := range recipients {
+		conversation.Participants = append(conversation.Participants, models.ConversationParticipant{UserID: recipient.ID})
+	}
+	if err := service.conversationRepo.Create(ctx, conversation, message); err != nil {
+		if errors.Is(err, gorm.ErrForeignKeyViolated) {
+			return nil, errs.Wrap(errs.ErrNotFound, "User not found", err)
+		}
+		return nil, err
+	}
+	metrics.MessagesSent.Inc()
+
+	// Return the conversation with the participants' details
+	created, err := service.conversationRepo.GetByID(ctx, conversation.ID)
+	if err != nil {
+		return nil, err
+	}
+	created.LastMessage = message
+	return created, nil
+}
+
+// Get a page of the current user's conversations, most recently active first
+// cursor is empty for the first page, or the cursor returned with the previous page. The returned cursor is empty on the last page.
+func (service *ConversationService) GetConversations(ctx context.Context, cursor string, limit int, currentUserID uint) ([]models.Conversation, string, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "ConversationService.GetConversations")
+	defer span.End()
+
+	var after *models.ConversationCursor
+	if cursor != "" {
+		var err error
+		if after, err = decodeConversationCursor(cursor); err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Fetch 1 more conversation than needed to tell whether there is a next page
+	conversations, err := service.conversationRepo.GetByUserID(ctx, currentUserID, after, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(conversations) <= limit {
+		return conversations, "", nil
+	}
+	conversations = conversations[:limit]
+	last := conversations[limit-1]
+	return conversations, encodeConversationCursor(models.ConversationCursor{UpdatedAt: last.UpdatedAt, ID: last.ID}), nil
+}
+
+// Get a page of the messages in a conversation, newest first, with the participants who have read each message
+func (service *ConversationService) GetMessages(ctx context.Context, conversationID uint, cursor string, limit int, currentUserID uint) ([]models.Message, string, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "ConversationService.GetMessages")
+	defer span.End()
+
+	var beforeID uint
+	if cursor != "" {
+		var err error
+		if beforeID, err = decodeMessageCursor(cursor); err != nil {
+			return nil, "", err
+		}
+	}
+
+	conversation, err := service.getParticipating(ctx, conversationID, currentUserID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Fetch 1 more message than needed to tell whether there is a next page
+	messages, err := service.conversationRepo.GetMessages(ctx, conversationID, beforeID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// A participant has read every message up to their last read message
+	for i := range messages {
+		messages[i].ReadBy = []uint{}
+		for _, participant := range conversation.Participants {
+			if participant.UserID != messages[i].SenderID && participant.LastReadMessageID >= messages[i].ID {
+				messages[i].ReadBy = append(messages[i].ReadBy, participant.UserID)
+			}
+		}
+	}
+
+	if len(messages) <= limit {
+		return messages, "", nil
+	}
+	messages = messages[:limit]
+	return messages, encodeMessageCursor(messages[limit-1].ID), nil
+}
+
+// Send a message in a conversation as the current user
+func (service *ConversationService) Send(ctx context.Context, conversationID uint, input *models.NewMessage, currentUserID uint) (*models.Message, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "ConversationService.Send")
+	defer span.End()
+
+	conversation, err := service.getParticipating(ctx, conversationID, currentUserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.checkBlocks(ctx, currentUserID, otherParticipants(conversation, currentUserID)); err != nil {
+		return nil, err
+	}
+
+	message := &models.Message{ConversationID: conversationID, SenderID: currentUserID, Content: input.Content, ReadBy: []uint{}}
+	if err := service.conversationRepo.CreateMessage(ctx, message); err != nil {
+		if errors.Is(err, gorm.ErrForeignKeyViolated) {
+			return nil, errs.Wrap(errs.ErrNotFound, "Conversation not found", err)
+		}
+		return nil, err
+	}
+	metrics.MessagesSent.Inc()
+
+	return message, nil
+}
+
+// Mark all messages in a conversation as read by the current user
+func (service *ConversationService) MarkRead(ctx context.Context, conversationID uint, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "ConversationService.MarkRead")
+	defer span.End()
+
+	if _, err := service.getParticipating(ctx, conversationID, currentUserID); err != nil {
+		return err
+	}
+
+	latest, err := service.conversationRepo.GetMessages(ctx, conversationID, 0, 1)
+	if err != nil {
+		return err
+	}
+	if len(latest) == 0 {
+		return nil
+	}
+	return service.conversationRepo.MarkRead(ctx, conversationID, currentUserID, latest[0].ID)
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"testing"
+)
+
+func TestConversationServiceCreate(t *testing.T) {
+	f := newFixture(t)
+	service := NewConversationService(f.conversations, f.users, f.blocks)
+	ctx := context.Background()
+	carol := f.createUser(t, "carol", "password")
+	dave := f.createUser(t, "dave", "password")
+	if err := f.blocks.Block(ctx, &models.Block{UserID: dave.ID, BlockedUserID: f.alice.ID, Type: models.BlockTypeBlock}); err != nil {
+		t.Fatalf("failed to block: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		participantIDs   []uint
+		wantCode         int
+		wantParticipants int
+	}{
+		{"direct", []uint{f.bob.ID}, noError, 2},
+		{"group with duplicates", []uint{f.bob.ID, carol.ID, carol.ID}, noError, 3},
+		{"yourself", []uint{f.alice.ID}, errs.ErrInvalid, 0},
+		{"unknown user", []uint{999}, errs.ErrNotFound, 0},
+		{"blocked by recipient", []uint{dave.ID}, errs.ErrForbidden, 0},
+		{"group including a user who blocked the sender", []uint{f.bob.ID, dave.ID}, errs.ErrForbidden, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversation, err := service.Create(ctx, &models.NewConversation{ParticipantIDs: tt.participantIDs, Content: "Hello"}, f.alice.ID)
+			assertCode(t, err, tt.wantCode)
+			if err != nil {
+				return
+			}
+			if len(conversation.Participants) != tt.wantParticipants {
+				t.Errorf("expected %d participants, got %d", tt.wantParticipants, len(conversation.Participants))
+			}
+			if conversation.LastMessage == nil || conversation.LastMessage.Content != "Hello" {
+				t.Errorf("expected the first message to be returned, got %+v", conversation.LastMessage)
+			}
+		})
+	}
+
+	// Messaging bob again continues the direct conversation
+	first, err := service.Create(ctx, &models.NewConversation{ParticipantIDs: []uint{f.bob.ID}, Content: "Again"}, f.alice.ID)
+	assertCode(t, err, noError)
+	second, err := service.Create(ctx, &models.NewConversation{ParticipantIDs: []uint{f.alice.ID}, Content: "Reply"}, f.bob.ID)
+	assertCode(t, err, noError)
+	if first.ID != second.ID {
+		t.Errorf("expected direct messages to reuse conversation %d, got %d", first.ID, second.ID)
+	}
+	conversations, _, err := service.GetConversations(ctx, "", 10, f.alice.ID)
+	assertCode(t, err, noError)
+	if len(conversations) != 2 {
+		t.Errorf("expected alice to have 2 conversations, got %d", len(conversations))
+	}
+}
+
+func TestConversationServiceAuthorization(t *testing.T) {
+	f := newFixture(t)
+	service := NewConversationService(f.conversations, f.users, f.blocks)
+	ctx := context.Background()
+	carol := f.createUser(t, "carol", "password")
+
+	conversation, err := service.Create(ctx, &models.NewConversation{ParticipantIDs: []uint{f.bob.ID}, Content: "Hello"}, f.alice.ID)
+	assertCode(t, err, noError)
+
+	tests := []struct {
+		name           string
+		conversationID uint
+		userID         uint
+		wantCode       int
+	}{
+		{"participant", conversation.ID, f.bob.ID, noError},
+		{"not a participant", conversation.ID, carol.ID, errs.ErrForbidden},
+		{"unknown conversation", 999, f.bob.ID, errs.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := service.GetMessages(ctx, tt.conversationID, "", 10, tt.userID)
+			assertCode(t, err, tt.wantCode)
+			_, err = service.Send(ctx, tt.conversationID, &models.NewMessage{Content: "Hi"}, tt.userID)
+			assertCode(t, err, tt.wantCode)
+			assertCode(t, service.MarkRead(ctx, tt.conversationID, tt.userID), tt.wantCode)
+		})
+	}
+
+	// Blocking stops further messages in an existing conversation
+	if err := f.blocks.Block(ctx, &models.Block{UserID: f.bob.ID, BlockedUserID: f.alice.ID, Type: models.BlockTypeBlock}); err != nil {
+		t.Fatalf("failed to block: %v", err)
+	}
+	_, err = service.Send(ctx, conversation.ID, &models.NewMessage{Content: "Hi"}, f.alice.ID)
+	assertCode(t, err, errs.ErrForbidden)
+}
+
+func TestConversationServiceReadReceipts(t *testing.T) {
+	f := newFixture(t)
+	service := NewConversationService(f.conversations, f.users, f.blocks)
+	ctx := context.Background()
+
+	conversation, err := service.Create(ctx, &models.NewConversation{ParticipantIDs: []uint{f.bob.ID}, Content: "1"}, f.alice.ID)
+	assertCode(t, err, noError)
+	for _, content := range []string{"2", "3"} {
+		_, err := service.Send(ctx, conversation.ID, &models.NewMessage{Content: content}, f.alice.ID)
+		assertCode(t, err, noError)
+	}
+
+	unreadCount := func(userID uint) int64 {
+		t.Helper()
+		conversations, _, err := service.GetConversations(ctx, "", 10, userID)
+		assertCode(t, err, noError)
+		if len(conversations) != 1 {
+			t.Fatalf("expected 1 conversation, got %d", len(conversations))
+		}
+		return conversations[0].UnreadCount
+	}
+	if alice, bob := unreadCount(f.alice.ID), unreadCount(f.bob.ID); alice != 0 || bob != 3 {
+		t.Errorf("expected alice to have 0 and bob 3 unread messages, got %d and %d", alice, bob)
+	}
+
+	messages, _, err := service.GetMessages(ctx, conversation.ID, "", 10, f.alice.ID)
+	assertCode(t, err, noError)
+	if len(messages[0].ReadBy) != 0 {
+		t.Errorf("expected no read receipts before bob reads, got %v", messages[0].ReadBy)
+	}
+
+	assertCode(t, service.MarkRead(ctx, conversation.ID, f.bob.ID), noError)
+	if bob := unreadCount(f.bob.ID); bob != 0 {
+		t.Errorf("expected bob to have no unread messages after reading, got %d", bob)
+	}
+	messages, _, err = service.GetMessages(ctx, conversation.ID, "", 10, f.alice.ID)
+	assertCode(t, err, noError)
+	for _, message := range messages {
+		if len(message.ReadBy) != 1 || message.ReadBy[0] != f.bob.ID {
+			t.Errorf("expected message %q to be read by bob, got %v", message.Content, message.ReadBy)
+		}
+	}
+}
+
+func TestConversationServicePagination(t *testing.T) {
+	f := newFixture(t)
+	service := NewConversationService(f.conversations, f.users, f.blocks)
+	ctx := context.Background()
+
+	conversation, err := service.Create(ctx, &models.NewConversation{ParticipantIDs: []uint{f.bob.ID}, Content: "1"}, f.alice.ID)
+	assertCode(t, err, noError)
+	for _, content := range []string{"2", "3", "4", "5"} {
+		_, err := service.Send(ctx, conversation.ID, &models.NewMessage{Content: content}, f.bob.ID)
+		assertCode(t, err, noError)
+	}
+
+	// Walk the messages 2 at a time, newest first
+	var contents []string
+	cursor := ""
+	for page := 0; page < 5; page++ {
+		messages, next, err := service.GetMessages(ctx, conversation.ID, cursor, 2, f.alice.ID)
+		assertCode(t, err, noError)
+		for _, message := range messages {
+			contents = append(contents, message.Content)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if got := len(contents); got != 5 || contents[0] != "5" || contents[4] != "1" {
+		t.Errorf("expected messages 5 to 1, got %v", contents)
+	}
+
+	_, _, err = service.GetMessages(ctx, conversation.ID, "not a cursor", 2, f.alice.ID)
+	assertCode(t, err, errs.ErrInvalid)
+	_, _, err = service.GetConversations(ctx, "not a cursor", 2, f.alice.ID)
+	assertCode(t, err, errs.ErrInvalid)
+
+	// The most recently active conversation comes first
+	carol := f.createUser(t, "carol", "password")
+	_, err = service.Create(ctx, &models.NewConversation{ParticipantIDs: []uint{carol.ID}, Content: "Hi"}, f.alice.ID)
+	assertCode(t, err, noError)
+	first, next, err := service.GetConversations(ctx, "", 1, f.alice.ID)
+	assertCode(t, err, noError)
+	second, last, err := service.GetConversations(ctx, next, 1, f.alice.ID)
+	assertCode(t, err, noError)
+	if len(first) != 1 || len(second) != 1 || second[0].ID != conversation.ID || last != "" {
+		t.Errorf("expected the conversation with carol then bob, got %+v then %+v", first, second)
+	}
+}
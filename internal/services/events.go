@@ -0,0 +1,17 @@
+package services
+
+import (
+	"context"
+	"cvwo-backend/internal/events"
+	"cvwo-backend/internal/repos"
+)
+
+// Record domain events in the outbox
+// Called inside the transaction of the change the events describe, so that they are dispatched if and only if it commits
+func emit(ctx context.Context, outboxRepo repos.OutboxRepo, evts ...events.Event) error {
+	records, err := events.Encode(evts...)
+	if err != nil {
+		return err
+	}
+	return outboxRepo.Add(ctx, records)
+}
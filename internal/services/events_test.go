@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/events"
+	"cvwo-backend/internal/metrics"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// Check that the outbox holds exactly the given events, in order
+func (f *fixture) assertEvents(t *testing.T, want ...events.Event) {
+	t.Helper()
+
+	wantRecords, err := events.Encode(want...)
+	if err != nil {
+		t.Fatalf("failed to encode events: %v", err)
+	}
+	got := f.store.OutboxEvents()
+	if len(got) != len(wantRecords) {
+		t.Fatalf("expected %d events, got %+v", len(wantRecords), got)
+	}
+	for i, record := range got {
+		if record.Type != wantRecords[i].Type || record.Payload != wantRecords[i].Payload {
+			t.Errorf("expected %s event %s, got %s event %s", wantRecords[i].Type, wantRecords[i].Payload, record.Type, record.Payload)
+		}
+	}
+}
+
+func TestServicesEmitEvents(t *testing.T) {
+	f := newFixture(t)
+	postService := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, nil)
+	commentService := NewCommentService(f.comments, f.posts, f.users, f.blocks, f.outbox, f.transactor, f.attachmentService(), DefaultContentLimits)
+	votingService := NewVotingService(f.postVotes, f.commentVotes, f.posts, f.comments, f.outbox, f.transactor, nil)
+	taggingService := NewTaggingService(f.posts, f.topics, f.outbox, f.transactor, nil)
+	ctx := context.Background()
+	topic := f.store.AddTopic("news")
+
+	post, err := postService.Create(ctx, &models.Post{Title: "Hello", Content: "World", AuthorID: f.alice.ID}, nil)
+	assertCode(t, err, noError)
+	// Drafts are announced when they are published rather than when they are created
+	draft, err := postService.Create(ctx, &models.Post{Title: "Draft", Content: "Later", AuthorID: f.bob.ID, Status: models.PostStatusDraft}, nil)
+	assertCode(t, err, noError)
+	assertCode(t, taggingService.TagPostWithTopics(ctx, post.ID, []uint{topic.ID}, f.alice.ID), noError)
+	_, err = postService.Update(ctx, post.ID, post.Version, "Hello again", "World", f.alice.ID)
+	assertCode(t, err, noError)
+	comment, err := commentService.Create(ctx, &models.Comment{Content: "Nice", PostID: post.ID, AuthorID: f.bob.ID})
+	assertCode(t, err, noError)
+	assertCode(t, votingService.VotePost(ctx, post.ID, f.bob.ID, 1, f.bob.ID), noError)
+	assertCode(t, votingService.VotePost(ctx, post.ID, f.bob.ID, 0, f.bob.ID), noError)
+	assertCode(t, votingService.VoteComment(ctx, comment.ID, f.alice.ID, -1, f.alice.ID), noError)
+	_, err = postService.UpdateStatus(ctx, draft.ID, models.PostStatusPublished, nil, f.bob.ID)
+	assertCode(t, err, noError)
+	assertCode(t, postService.Delete(ctx, post.ID, f.alice.ID), noError)
+
+	// Failed changes emit nothing
+	assertCode(t, votingService.VotePost(ctx, 9999, f.bob.ID, 1, f.bob.ID), errs.ErrNotFound)
+	assertCode(t, taggingService.TagPostWithTopics(ctx, draft.ID, []uint{topic.ID}, f.alice.ID), errs.ErrForbidden)
+	_, err = postService.Update(ctx, draft.ID, 0, "Edited", "By someone else", f.alice.ID)
+	assertCode(t, err, errs.ErrForbidden)
+
+	f.assertEvents(t,
+		events.PostCreated{PostID: post.ID, AuthorID: f.alice.ID},
+		events.PostTagged{PostID: post.ID, TopicIDs: []uint{topic.ID}},
+		events.PostEdited{PostID: post.ID, Version: post.Version + 1},
+		events.CommentCreated{CommentID: comment.ID, PostID: post.ID, AuthorID: f.bob.ID},
+		events.PostVoted{PostID: post.ID, UserID: f.bob.ID, Value: 1},
+		events.PostVoted{PostID: post.ID, UserID: f.bob.ID, Value: 0},
+		events.CommentVoted{CommentID: comment.ID, UserID: f.alice.ID, Value: -1},
+		events.PostCreated{PostID: draft.ID, AuthorID: f.bob.ID},
+		events.PostDeleted{PostID: post.ID, AuthorID: f.alice.ID},
+	)
+}
+
+// Removing a vote that was never cast is not announced
+func TestRemovingMissingVotesEmitsNothing(t *testing.T) {
+	f := newFixture(t)
+	votingService := NewVotingService(f.postVotes, f.commentVotes, f.posts, f.comments, f.outbox, f.transactor, nil)
+	ctx := context.Background()
+	post := f.createPost(t, f.alice.ID, "post")
+	comment := f.createComment(t, f.alice.ID, post.ID)
+
+	assertCode(t, votingService.VotePost(ctx, post.ID, f.bob.ID, 0, f.bob.ID), noError)
+	assertCode(t, votingService.VoteComment(ctx, comment.ID, f.bob.ID, 0, f.bob.ID), noError)
+	f.assertEvents(t)
+
+	// Removing a vote that was cast is
+	assertCode(t, votingService.VotePost(ctx, post.ID, f.bob.ID, 1, f.bob.ID), noError)
+	assertCode(t, votingService.VotePost(ctx, post.ID, f.bob.ID, 0, f.bob.ID), noError)
+	assertCode(t, votingService.VotePost(ctx, post.ID, f.bob.ID, 0, f.bob.ID), noError)
+	f.assertEvents(t,
+		events.PostVoted{PostID: post.ID, UserID: f.bob.ID, Value: 1},
+		events.PostVoted{PostID: post.ID, UserID: f.bob.ID, Value: 0},
+	)
+}
+
+// Outbox that fails to record events
+type failingOutbox struct{}
+
+func (failingOutbox) Add(ctx context.Context, events []models.OutboxEvent) error {
+	return errors.New("outbox unavailable")
+}
+
+// Changes are rolled back when their events cannot be recorded, so that no change goes unannounced
+func TestEventsAreAtomicWithChanges(t *testing.T) {
+	f := newFixture(t)
+	postService := NewPostService(f.posts, f.users, f.topics, failingOutbox{}, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, nil)
+	votingService := NewVotingService(f.postVotes, f.commentVotes, f.posts, f.comments, failingOutbox{}, f.transactor, nil)
+	ctx := context.Background()
+
+	if _, err := postService.Create(ctx, &models.Post{Title: "Hello", Content: "World", AuthorID: f.alice.ID}, nil); err == nil {
+		t.Fatalf("expected creating the post to fail")
+	}
+	posts, total, err := f.posts.GetList(ctx, 10, 0, "new", 0)
+	if err != nil || total != 0 {
+		t.Fatalf("expected the post to be rolled back, got %+v, %v", posts, err)
+	}
+
+	post := f.createPost(t, f.alice.ID, "post")
+	if err := votingService.VotePost(ctx, post.ID, f.bob.ID, 1, f.bob.ID); err == nil {
+		t.Fatalf("expected voting to fail")
+	}
+	got, err := f.posts.GetByIDWithAuth(ctx, post.ID, f.bob.ID)
+	if err != nil || got.NetVotes != 0 || got.UserVote != 0 {
+		t.Errorf("expected the vote to be rolled back, got %+v, %v", got, err)
+	}
+}
+
+// Outbox that fails to record events of one type, after the events before them in the transaction were recorded
+type rejectingOutbox struct {
+	repos.OutboxRepo
+	eventType string
+}
+
+func (outbox rejectingOutbox) Add(ctx context.Context, records []models.OutboxEvent) error {
+	for _, record := range records {
+		if record.Type == outbox.eventType {
+			return errors.New("outbox unavailable")
+		}
+	}
+	return outbox.OutboxRepo.Add(ctx, records)
+}
+
+// Posts are only counted as created once their transaction commits
+func TestPostsCreatedMetric(t *testing.T) {
+	f := newFixture(t)
+	topic := f.store.AddTopic("Philosophy")
+	outbox := rejectingOutbox{f.outbox, events.PostTagged{}.EventType()}
+	postService := NewPostService(f.posts, f.users, f.topics, outbox, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, nil)
+	ctx := context.Background()
+	created := testutil.ToFloat64(metrics.PostsCreated)
+
+	// Rolled back after the post was published within the transaction
+	if _, err := postService.Create(ctx, &models.Post{Title: "Hello", Content: "World", AuthorID: f.alice.ID}, []uint{topic.ID}); err == nil {
+		t.Fatalf("expected creating the post to fail")
+	}
+	if got := testutil.ToFloat64(metrics.PostsCreated) - created; got != 0 {
+		t.Errorf("expected the rolled back post not to be counted, got %v", got)
+	}
+
+	if _, err := postService.Create(ctx, &models.Post{Title: "Hello", Content: "World", AuthorID: f.alice.ID}, nil); err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+	draft, err := postService.Create(ctx, &models.Post{Title: "Draft", Content: "Not yet", AuthorID: f.alice.ID, Status: models.PostStatusDraft}, nil)
+	if err != nil {
+		t.Fatalf("failed to create draft: %v", err)
+	}
+	if got := testutil.ToFloat64(metrics.PostsCreated) - created; got != 1 {
+		t.Errorf("expected drafts not to be counted until published, got %v", got)
+	}
+	if _, err := postService.UpdateStatus(ctx, draft.ID, models.PostStatusPublished, nil, f.alice.ID); err != nil {
+		t.Fatalf("failed to publish draft: %v", err)
+	}
+	if got := testutil.ToFloat64(metrics.PostsCreated) - created; got != 2 {
+		t.Errorf("expected the published draft to be counted, got %v", got)
+	}
+}
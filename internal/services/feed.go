@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"cvwo-backend/internal/tracing"
+)
+
+type FeedService struct {
+	postRepo         repos.PostRepo
+	subscriptionRepo repos.SubscriptionRepo
+	followRepo       repos.FollowRepo
+}
+
+func NewFeedService(postRepo repos.PostRepo, subscriptionRepo repos.SubscriptionRepo, followRepo repos.FollowRepo) *FeedService {
+	return &FeedService{postRepo, subscriptionRepo, followRepo}
+}
+
+// Get the current user's home feed of posts from the topics they subscribe to and the users they follow
+// Anonymous users and users without subscriptions or follows get the global listing instead
+func (service *FeedService) GetFeed(ctx context.Context, limit, offset int, sortBy string, currentUserID uint) ([]models.Post, int64, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "FeedService.GetFeed")
+	defer span.End()
+
+	// Validate sortBy param
+	sortField, err := validPostSortField(sortBy)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if currentUserID == 0 {
+		return service.postRepo.GetList(ctx, limit, offset, sortField, currentUserID)
+	}
+
+	topics, err := service.subscriptionRepo.GetTopicsByUserID(ctx, currentUserID)
+	if err != nil {
+		return nil, 0, err
+	}
+	_, following, err := service.followRepo.GetCounts(ctx, currentUserID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(topics) == 0 && following == 0 {
+		return service.postRepo.GetList(ctx, limit, offset, sortField, currentUserID)
+	}
+
+	return service.postRepo.GetFeed(ctx, currentUserID, limit, offset, sortField)
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"slices"
+	"testing"
+)
+
+func TestSubscriptionService(t *testing.T) {
+	f := newFixture(t)
+	service := NewSubscriptionService(f.subscriptions, f.topics)
+	philosophy := f.store.AddTopic("Philosophy")
+	literature := f.store.AddTopic("Literature")
+
+	// Cases run in order, so each builds on the previous subscriptions
+	tests := []struct {
+		name       string
+		subscribe  bool
+		topicID    uint
+		wantCode   int
+		wantTopics []string
+	}{
+		{"subscribe", true, philosophy.ID, noError, []string{"Philosophy"}},
+		{"subscribe again is idempotent", true, philosophy.ID, noError, []string{"Philosophy"}},
+		{"subscribe to another topic", true, literature.ID, noError, []string{"Literature", "Philosophy"}},
+		{"unsubscribe", false, philosophy.ID, noError, []string{"Literature"}},
+		{"unsubscribe again is idempotent", false, philosophy.ID, noError, []string{"Literature"}},
+		{"unknown topic", true, 999, errs.ErrNotFound, []string{"Literature"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.subscribe {
+				err = service.Subscribe(context.Background(), tt.topicID, f.bob.ID)
+			} else {
+				err = service.Unsubscribe(context.Background(), tt.topicID, f.bob.ID)
+			}
+			assertCode(t, err, tt.wantCode)
+
+			topics, err := service.GetTopics(context.Background(), f.bob.ID)
+			assertCode(t, err, noError)
+			names := []string{}
+			for _, topic := range topics {
+				names = append(names, topic.Name)
+			}
+			if !slices.Equal(names, tt.wantTopics) {
+				t.Errorf("expected topics %v, got %v", tt.wantTopics, names)
+			}
+		})
+	}
+}
+
+func TestFeedServiceGetFeed(t *testing.T) {
+	f := newFixture(t)
+	service := NewFeedService(f.posts, f.subscriptions, f.follows)
+	philosophy := f.store.AddTopic("Philosophy")
+	literature := f.store.AddTopic("Literature")
+
+	ctx := context.Background()
+	tagged := func(title string, topics ...models.Topic) *models.Post {
+		post := f.createPost(t, f.alice.ID, title)
+		if err := f.posts.AssociatePostWithTopics(ctx, post, topics); err != nil {
+			t.Fatalf("failed to tag post: %v", err)
+		}
+		return post
+	}
+	plato := tagged("plato", philosophy)
+	untagged := f.createPost(t, f.alice.ID, "untagged")
+	homer := tagged("homer", literature)
+	both := tagged("both", philosophy, literature)
+
+	bobs := f.createPost(t, f.bob.ID, "bob's post")
+
+	// Bob subscribes to philosophy; carol follows bob and subscribes to literature; alice has no subscriptions or follows
+	carol := f.createUser(t, "carol", "password")
+	if err := f.subscriptions.Subscribe(ctx, &models.TopicSubscription{UserID: f.bob.ID, TopicID: philosophy.ID}); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if err := f.subscriptions.Subscribe(ctx, &models.TopicSubscription{UserID: carol.ID, TopicID: literature.ID}); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if err := f.follows.Follow(ctx, &models.Follow{FollowerID: carol.ID, FolloweeID: f.bob.ID}); err != nil {
+		t.Fatalf("failed to follow: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		sortBy      string
+		limit       int
+		offset      int
+		currentUser uint
+		wantCode    int
+		wantIDs     []uint
+		wantTotal   int64
+	}{
+		{"subscribed topics only", "new", 10, 0, f.bob.ID, noError, []uint{both.ID, plato.ID}, 2},
+		{"sorted oldest first", "old", 10, 0, f.bob.ID, noError, []uint{plato.ID, both.ID}, 2},
+		{"paginated", "new", 1, 1, f.bob.ID, noError, []uint{plato.ID}, 2},
+		{"subscribed topics and followed users", "new", 10, 0, carol.ID, noError, []uint{bobs.ID, both.ID, homer.ID}, 3},
+		{"anonymous user gets global listing", "new", 10, 0, 0, noError, []uint{bobs.ID, both.ID, homer.ID, untagged.ID, plato.ID}, 5},
+		{"no subscriptions gets global listing", "new", 10, 0, f.alice.ID, noError, []uint{bobs.ID, both.ID, homer.ID, untagged.ID, plato.ID}, 5},
+		{"invalid sort", "random", 10, 0, f.bob.ID, errs.ErrInvalid, []uint{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posts, total, err := service.GetFeed(ctx, tt.limit, tt.offset, tt.sortBy, tt.currentUser)
+			assertCode(t, err, tt.wantCode)
+			ids := []uint{}
+			for _, post := range posts {
+				ids = append(ids, post.ID)
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("expected posts %v, got %v", tt.wantIDs, ids)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("expected total %d, got %d", tt.wantTotal, total)
+			}
+		})
+	}
+}
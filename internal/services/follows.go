@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"cvwo-backend/internal/tracing"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type FollowService struct {
+	followRepo repos.FollowRepo
+	userRepo   repos.UserRepo
+}
+
+func NewFollowService(followRepo repos.FollowRepo, userRepo repos.UserRepo) *FollowService {
+	return &FollowService{followRepo, userRepo}
+}
+
+// Check that the user exists
+func (service *FollowService) checkUser(ctx context.Context, userID uint) (*models.User, error) {
+	user, err := service.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.Wrap(errs.ErrNotFound, "User not found", err)
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// Get a user's profile, including their follower counts and whether the current user follows them
+func (service *FollowService) GetProfile(ctx context.Context, userID uint, currentUserID uint) (*models.UserProfile, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "FollowService.GetProfile")
+	defer span.End()
+
+	user, err := service.checkUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &models.UserProfile{User: *user}
+	if profile.FollowerCount, profile.FollowingCount, err = service.followRepo.GetCounts(ctx, userID); err != nil {
+		return nil, err
+	}
+	if currentUserID != 0 {
+		if profile.Followed, err = service.followRepo.IsFollowing(ctx, currentUserID, userID); err != nil {
+			return nil, err
+		}
+	}
+	return profile, nil
+}
+
+// Follow a user as the current user
+func (service *FollowService) Follow(ctx context.Context, userID uint, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "FollowService.Follow")
+	defer span.End()
+
+	if userID == currentUserID {
+		return errs.New(errs.ErrInvalid, "Cannot follow yourself")
+	}
+	if _, err := service.checkUser(ctx, userID); err != nil {
+		return err
+	}
+
+	err := service.followRepo.Follow(ctx, &models.Follow{FollowerID: currentUserID, FolloweeID: userID})
+	if errors.Is(err, gorm.ErrForeignKeyViolated) {
+		return errs.Wrap(errs.ErrNotFound, "User not found", err)
+	}
+	return err
+}
+
+// Stop following a user as the current user
+func (service *FollowService) Unfollow(ctx context.Context, userID uint, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "FollowService.Unfollow")
+	defer span.End()
+
+	return service.followRepo.Unfollow(ctx, currentUserID, userID)
+}
+
+// Get the users who follow the given user
+func (service *FollowService) GetFollowers(ctx context.Context, userID uint, limit, offset int) ([]models.User, int64, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "FollowService.GetFollowers")
+	defer span.End()
+
+	if _, err := service.checkUser(ctx, userID); err != nil {
+		return nil, 0, err
+	}
+	return service.followRepo.GetFollowers(ctx, userID, limit, offset)
+}
+
+// Get the users the given user follows
+func (service *FollowService) GetFollowing(ctx context.Context, userID uint, limit, offset int) ([]models.User, int64, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "FollowService.GetFollowing")
+	defer span.End()
+
+	if _, err := service.checkUser(ctx, userID); err != nil {
+		return nil, 0, err
+	}
+	return service.followRepo.GetFollowing(ctx, userID, limit, offset)
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"testing"
+)
+
+func TestFollowService(t *testing.T) {
+	f := newFixture(t)
+	service := NewFollowService(f.follows, f.users)
+	carol := f.createUser(t, "carol", "password")
+	ctx := context.Background()
+
+	// Cases run in order, so each builds on the previous follows
+	tests := []struct {
+		name          string
+		follow        bool
+		followerID    uint
+		followeeID    uint
+		wantCode      int
+		wantFollowers int64 // Of alice
+		wantFollowed  bool  // Whether bob follows alice
+	}{
+		{"follow", true, f.bob.ID, f.alice.ID, noError, 1, true},
+		{"follow again is idempotent", true, f.bob.ID, f.alice.ID, noError, 1, true},
+		{"another follower", true, carol.ID, f.alice.ID, noError, 2, true},
+		{"follow yourself", true, f.alice.ID, f.alice.ID, errs.ErrInvalid, 2, true},
+		{"unknown user", true, f.bob.ID, 999, errs.ErrNotFound, 2, true},
+		{"unfollow", false, f.bob.ID, f.alice.ID, noError, 1, false},
+		{"unfollow again is idempotent", false, f.bob.ID, f.alice.ID, noError, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.follow {
+				err = service.Follow(ctx, tt.followeeID, tt.followerID)
+			} else {
+				err = service.Unfollow(ctx, tt.followeeID, tt.followerID)
+			}
+			assertCode(t, err, tt.wantCode)
+
+			profile, err := service.GetProfile(ctx, f.alice.ID, f.bob.ID)
+			assertCode(t, err, noError)
+			if profile.FollowerCount != tt.wantFollowers {
+				t.Errorf("expected %d followers, got %d", tt.wantFollowers, profile.FollowerCount)
+			}
+			if profile.Followed != tt.wantFollowed {
+				t.Errorf("expected followed %v, got %v", tt.wantFollowed, profile.Followed)
+			}
+		})
+	}
+
+	following, total, err := service.GetFollowing(ctx, carol.ID, 10, 0)
+	assertCode(t, err, noError)
+	if total != 1 || len(following) != 1 || following[0].ID != f.alice.ID {
+		t.Errorf("expected carol to follow only alice, got %+v (total %d)", following, total)
+	}
+	_, _, err = service.GetFollowers(ctx, 999, 10, 0)
+	assertCode(t, err, errs.ErrNotFound)
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"cvwo-backend/internal/tracing"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type ModerationService struct {
+	postRepo  repos.PostRepo
+	userRepo  repos.UserRepo
+	topicRepo repos.TopicRepo
+	// Pins and locks change the cached post listings. Nil if caching is disabled.
+	listings *ListingCache
+}
+
+func NewModerationService(postRepo repos.PostRepo, userRepo repos.UserRepo, topicRepo repos.TopicRepo, listings *ListingCache) *ModerationService {
+	return &ModerationService{postRepo, userRepo, topicRepo, listings}
+}
+
+// Check that the current user is a moderator
+func (service *ModerationService) authorize(ctx context.Context, currentUserID uint) error {
+	return authorizeModerator(ctx, service.userRepo, currentUserID)
+}
+
+// Check that the current user is a moderator, treating deleted users as any other user
+func authorizeModerator(ctx context.Context, userRepo repos.UserRepo, currentUserID uint) error {
+	user, err := userRepo.GetByID(ctx, currentUserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.New(errs.ErrForbidden, "Forbidden")
+		}
+		return err
+	}
+	if !user.Moderator {
+		return errs.New(errs.ErrForbidden, "Forbidden")
+	}
+	return nil
+}
+
+// Pin a published post to the top of GET /posts at the given position, or move it there if it is already pinned
+func (service *ModerationService) Pin(ctx context.Context, postID uint, position int, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "ModerationService.Pin")
+	defer span.End()
+
+	if err := service.authorize(ctx, currentUserID); err != nil {
+		return err
+	}
+	post, err := service.postRepo.GetByID(ctx, postID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.Wrap(errs.ErrNotFound, "Post not found", err)
+		}
+		return err
+	}
+	// Drafts and scheduled posts are hidden from listings, so they would take a pin position without showing up
+	if post.Status != models.PostStatusPublished {
+		return errs.New(errs.ErrConflict, "Only published posts can be pinned")
+	}
+	if err := service.postRepo.SetPinPosition(ctx, postID, &position); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.Wrap(errs.ErrNotFound, "Post not found", err)
+		}
+		return err
+	}
+	service.listings.invalidateOrLog(ctx, postsNamespace)
+	return nil
+}
+
+// Unpin a post from the top of GET /posts
+func (service *ModerationService) Unpin(ctx context.Context, postID uint, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "ModerationService.Unpin")
+	defer span.End()
+
+	if err := service.authorize(ctx, currentUserID); err != nil {
+		return err
+	}
+	if err := service.postRepo.SetPinPosition(ctx, postID, nil); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.Wrap(errs.ErrNotFound, "Post not found", err)
+		}
+		return err
+	}
+	service.listings.invalidateOrLog(ctx, postsNamespace)
+	return nil
+}
+
+// Pin a post to the top of a topic's listing at the given position, or move it there if it is already pinned
+// The post must be published and tagged with the topic
+func (service *ModerationService) PinToTopic(ctx context.Context, topicID, postID uint, position int, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "ModerationService.PinToTopic")
+	defer span.End()
+
+	if err := service.authorize(ctx, currentUserID); err != nil {
+		return err
+	}
+	if _, err := service.topicRepo.GetByID(ctx, topicID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.Wrap(errs.ErrNotFound, "Topic not found", err)
+		}
+		return err
+	}
+	post, err := service.postRepo.GetByIDWithAuth(ctx, postID, currentUserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.Wrap(errs.ErrNotFound, "Post not found", err)
+		}
+		return err
+	}
+	if post.Status != models.PostStatusPublished {
+		return errs.New(errs.ErrConflict, "Only published posts can be pinned")
+	}
+	tagged := false
+	for _, topic := range post.Topics {
+		tagged = tagged || topic.ID == topicID
+	}
+	if !tagged {
+		return errs.New(errs.ErrInvalid, "Post is not tagged with the topic")
+	}
+
+	err = service.postRepo.PinToTopic(ctx, &models.TopicPin{TopicID: topicID, PostID: postID, Position: position})
+	if errors.Is(err, gorm.ErrForeignKeyViolated) {
+		return errs.Wrap(errs.ErrNotFound, "Post or topic not found", err)
+	}
+	if err != nil {
+		return err
+	}
+	service.listings.invalidateOrLog(ctx, postsNamespace)
+	return nil
+}
+
+// Unpin a post from the top of a topic's listing
+func (service *ModerationService) UnpinFromTopic(ctx context.Context, topicID, postID uint, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "ModerationService.UnpinFromTopic")
+	defer span.End()
+
+	if err := service.authorize(ctx, currentUserID); err != nil {
+		return err
+	}
+	if err := service.postRepo.UnpinFromTopic(ctx, topicID, postID); err != nil {
+		return err
+	}
+	service.listings.invalidateOrLog(ctx, postsNamespace)
+	return nil
+}
+
+// Lock or unlock a post. Locked posts cannot be commented on or voted on.
+func (service *ModerationService) SetLocked(ctx context.Context, postID uint, locked bool, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "ModerationService.SetLocked")
+	defer span.End()
+
+	if err := service.authorize(ctx, currentUserID); err != nil {
+		return err
+	}
+	if err := service.postRepo.SetLocked(ctx, postID, locked); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.Wrap(errs.ErrNotFound, "Post not found", err)
+		}
+		return err
+	}
+	service.listings.invalidateOrLog(ctx, postsNamespace)
+	return nil
+}
+
+// Check that a post is published and not locked before it is commented on or voted on
+// A missing post is left for the caller to report
+func checkOpen(ctx context.Context, postRepo repos.PostRepo, postID uint) error {
+	post, err := postRepo.GetByID(ctx, postID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	// Unpublished posts are only visible to their author, and cannot be commented on or voted on even by them
+	if post.Status != models.PostStatusPublished {
+		return errs.New(errs.ErrNotFound, "Post not found")
+	}
+	if post.Locked {
+		return errs.New(errs.ErrForbidden, "Post is locked")
+	}
+	return nil
+}
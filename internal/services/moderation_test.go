@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"slices"
+	"testing"
+)
+
+// Register a moderator
+func (f *fixture) createModerator(t *testing.T, username string) *models.User {
+	t.Helper()
+
+	user, err := f.users.Create(context.Background(), &models.User{Username: username, Password: "hash", Moderator: true})
+	if err != nil {
+		t.Fatalf("failed to create moderator: %v", err)
+	}
+	return user
+}
+
+func TestModerationRequiresModerator(t *testing.T) {
+	f := newFixture(t)
+	service := NewModerationService(f.posts, f.users, f.topics, nil)
+	ctx := context.Background()
+	mod := f.createModerator(t, "mod")
+	topic := f.store.AddTopic("news")
+	post := f.createPost(t, f.alice.ID, "post")
+	if err := f.posts.AssociatePostWithTopics(ctx, post, []models.Topic{topic}); err != nil {
+		t.Fatalf("failed to tag post: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		moderate func(userID uint) error
+	}{
+		{"pin", func(userID uint) error { return service.Pin(ctx, post.ID, 0, userID) }},
+		{"unpin", func(userID uint) error { return service.Unpin(ctx, post.ID, userID) }},
+		{"pin to topic", func(userID uint) error { return service.PinToTopic(ctx, topic.ID, post.ID, 0, userID) }},
+		{"unpin from topic", func(userID uint) error { return service.UnpinFromTopic(ctx, topic.ID, post.ID, userID) }},
+		{"lock", func(userID uint) error { return service.SetLocked(ctx, post.ID, true, userID) }},
+		{"unlock", func(userID uint) error { return service.SetLocked(ctx, post.ID, false, userID) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Not even the post's author may moderate it
+			assertCode(t, tt.moderate(f.alice.ID), errs.ErrForbidden)
+			assertCode(t, tt.moderate(0), errs.ErrForbidden)
+			assertCode(t, tt.moderate(mod.ID), noError)
+		})
+	}
+
+	// Posts must exist, and be tagged with the topic they are pinned to
+	other := f.store.AddTopic("other")
+	assertCode(t, service.Pin(ctx, 9999, 0, mod.ID), errs.ErrNotFound)
+	assertCode(t, service.SetLocked(ctx, 9999, true, mod.ID), errs.ErrNotFound)
+	assertCode(t, service.PinToTopic(ctx, 9999, post.ID, 0, mod.ID), errs.ErrNotFound)
+	assertCode(t, service.PinToTopic(ctx, other.ID, 9999, 0, mod.ID), errs.ErrNotFound)
+	assertCode(t, service.PinToTopic(ctx, other.ID, post.ID, 0, mod.ID), errs.ErrInvalid)
+}
+
+func TestOnlyPublishedPostsCanBePinned(t *testing.T) {
+	f := newFixture(t)
+	service := NewModerationService(f.posts, f.users, f.topics, nil)
+	postService := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, nil)
+	ctx := context.Background()
+	mod := f.createModerator(t, "mod")
+	topic := f.store.AddTopic("news")
+
+	// Drafts are rejected even when the moderator wrote them
+	draft, err := postService.Create(ctx, &models.Post{Title: "draft", Content: "some content", AuthorID: mod.ID, Status: models.PostStatusDraft}, nil)
+	if err != nil {
+		t.Fatalf("failed to create draft: %v", err)
+	}
+	if err := f.posts.AssociatePostWithTopics(ctx, draft, []models.Topic{topic}); err != nil {
+		t.Fatalf("failed to tag post: %v", err)
+	}
+	assertCode(t, service.Pin(ctx, draft.ID, 0, mod.ID), errs.ErrConflict)
+	assertCode(t, service.PinToTopic(ctx, topic.ID, draft.ID, 0, mod.ID), errs.ErrConflict)
+
+	got, err := f.posts.GetByID(ctx, draft.ID)
+	if err != nil {
+		t.Fatalf("failed to get post: %v", err)
+	}
+	if got.PinPosition != nil {
+		t.Errorf("expected draft to stay unpinned, got position %d", *got.PinPosition)
+	}
+	posts, _, err := f.posts.GetByTopics(ctx, []uint{topic.ID}, 10, 0, "new", 0)
+	if err != nil {
+		t.Fatalf("failed to list posts: %v", err)
+	}
+	if len(posts) != 0 {
+		t.Errorf("expected no posts in topic, got %d", len(posts))
+	}
+}
+
+func TestRetaggingDropsTopicPins(t *testing.T) {
+	f := newFixture(t)
+	service := NewModerationService(f.posts, f.users, f.topics, nil)
+	tagging := NewTaggingService(f.posts, f.topics, f.outbox, f.transactor, nil)
+	ctx := context.Background()
+	mod := f.createModerator(t, "mod")
+	news, sports := f.store.AddTopic("news"), f.store.AddTopic("sports")
+	post := f.createPost(t, f.alice.ID, "post")
+
+	assertCode(t, tagging.TagPostWithTopics(ctx, post.ID, []uint{news.ID, sports.ID}, f.alice.ID), noError)
+	assertCode(t, service.PinToTopic(ctx, news.ID, post.ID, 0, mod.ID), noError)
+	assertCode(t, service.PinToTopic(ctx, sports.ID, post.ID, 0, mod.ID), noError)
+
+	pinnedIn := func(topicID uint) bool {
+		t.Helper()
+		posts, _, err := f.posts.GetByTopics(ctx, []uint{topicID}, 10, 0, "new", 0)
+		if err != nil {
+			t.Fatalf("failed to list posts: %v", err)
+		}
+		return len(posts) == 1 && posts[0].Pinned
+	}
+
+	// Dropping news unpins the post there, but keeps it pinned to sports
+	assertCode(t, tagging.TagPostWithTopics(ctx, post.ID, []uint{sports.ID}, f.alice.ID), noError)
+	assertCode(t, tagging.TagPostWithTopics(ctx, post.ID, []uint{news.ID, sports.ID}, f.alice.ID), noError)
+	if pinnedIn(news.ID) {
+		t.Error("expected post to be unpinned from news after being untagged")
+	}
+	if !pinnedIn(sports.ID) {
+		t.Error("expected post to stay pinned to sports")
+	}
+
+	// Clearing the tags unpins it everywhere
+	assertCode(t, tagging.TagPostWithTopics(ctx, post.ID, []uint{}, f.alice.ID), noError)
+	assertCode(t, tagging.TagPostWithTopics(ctx, post.ID, []uint{sports.ID}, f.alice.ID), noError)
+	if pinnedIn(sports.ID) {
+		t.Error("expected post to be unpinned from sports after its tags were cleared")
+	}
+}
+
+func TestPinnedPostsComeFirst(t *testing.T) {
+	f := newFixture(t)
+	service := NewModerationService(f.posts, f.users, f.topics, nil)
+	postService := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, nil)
+	ctx := context.Background()
+	mod := f.createModerator(t, "mod")
+	news, sports := f.store.AddTopic("news"), f.store.AddTopic("sports")
+
+	first := f.createPost(t, f.alice.ID, "first")
+	second := f.createPost(t, f.alice.ID, "second")
+	third := f.createPost(t, f.bob.ID, "third")
+	fourth := f.createPost(t, f.bob.ID, "fourth")
+	for _, post := range []*models.Post{first, second, third, fourth} {
+		if err := f.posts.AssociatePostWithTopics(ctx, post, []models.Topic{news, sports}); err != nil {
+			t.Fatalf("failed to tag post: %v", err)
+		}
+	}
+	if err := f.postVotes.Upsert(ctx, &models.PostVote{PostID: fourth.ID, UserID: f.alice.ID, Value: 1}); err != nil {
+		t.Fatalf("failed to vote: %v", err)
+	}
+
+	// Pinned globally: second, then first. Pinned to topics: first in sports, third in news, and second further down in news.
+	for _, pin := range []error{
+		service.Pin(ctx, first.ID, 2, mod.ID),
+		service.Pin(ctx, second.ID, 1, mod.ID),
+		service.PinToTopic(ctx, sports.ID, first.ID, 0, mod.ID),
+		service.PinToTopic(ctx, news.ID, third.ID, 0, mod.ID),
+		service.PinToTopic(ctx, news.ID, second.ID, 5, mod.ID),
+	} {
+		assertCode(t, pin, noError)
+	}
+
+	tests := []struct {
+		name       string
+		topicIDs   []uint
+		sortBy     string
+		wantIDs    []uint
+		wantPinned int
+	}{
+		{"all posts, newest first", nil, "new", []uint{second.ID, first.ID, fourth.ID, third.ID}, 2},
+		{"all posts, oldest first", nil, "old", []uint{second.ID, first.ID, third.ID, fourth.ID}, 2},
+		{"all posts, most votes first", nil, "votes", []uint{second.ID, first.ID, fourth.ID, third.ID}, 2},
+		{"one topic", []uint{news.ID}, "old", []uint{third.ID, second.ID, first.ID, fourth.ID}, 2},
+		// A post pinned to several of the topics is ordered by its lowest position
+		{"several topics", []uint{news.ID, sports.ID}, "new", []uint{third.ID, first.ID, second.ID, fourth.ID}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var posts []models.Post
+			var err error
+			if tt.topicIDs == nil {
+				posts, _, err = postService.GetList(ctx, 10, 0, tt.sortBy, f.bob.ID)
+			} else {
+				posts, _, err = postService.GetByTags(ctx, tt.topicIDs, 10, 0, tt.sortBy, f.bob.ID)
+			}
+			if err != nil {
+				t.Fatalf("failed to list posts: %v", err)
+			}
+
+			ids := []uint{}
+			pinned := 0
+			for i, post := range posts {
+				ids = append(ids, post.ID)
+				if post.Pinned {
+					pinned++
+					if i >= tt.wantPinned {
+						t.Errorf("unexpected pinned post %d at index %d", post.ID, i)
+					}
+				}
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("expected posts %v, got %v", tt.wantIDs, ids)
+			}
+			if pinned != tt.wantPinned {
+				t.Errorf("expected %d pinned posts, got %d", tt.wantPinned, pinned)
+			}
+		})
+	}
+
+	// Unpinned posts return to their place in the sort order
+	assertCode(t, service.Unpin(ctx, second.ID, mod.ID), noError)
+	assertCode(t, service.UnpinFromTopic(ctx, news.ID, third.ID, mod.ID), noError)
+	posts, _, err := postService.GetByTags(ctx, []uint{news.ID}, 10, 0, "old", f.bob.ID)
+	if err != nil {
+		t.Fatalf("failed to list posts: %v", err)
+	}
+	ids := []uint{}
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	if !slices.Equal(ids, []uint{second.ID, first.ID, third.ID, fourth.ID}) {
+		t.Errorf("expected only second to remain pinned to news, got %v", ids)
+	}
+}
+
+func TestLockedPosts(t *testing.T) {
+	f := newFixture(t)
+	service := NewModerationService(f.posts, f.users, f.topics, nil)
+	pollService := f.pollService()
+	postService := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, pollService, f.attachmentService(), DefaultContentLimits, nil)
+	commentService := NewCommentService(f.comments, f.posts, f.users, f.blocks, f.outbox, f.transactor, f.attachmentService(), DefaultContentLimits)
+	votingService := NewVotingService(f.postVotes, f.commentVotes, f.posts, f.comments, f.outbox, f.transactor, nil)
+	ctx := context.Background()
+	mod := f.createModerator(t, "mod")
+
+	post := f.createPoll(t, postService, false, nil, "Yes", "No")
+	comment := f.createComment(t, f.alice.ID, post.ID)
+	assertCode(t, votingService.VotePost(ctx, post.ID, f.bob.ID, 1, f.bob.ID), noError)
+	assertCode(t, service.SetLocked(ctx, post.ID, true, mod.ID), noError)
+
+	got, err := postService.GetByIDWithAuth(ctx, post.ID, f.bob.ID)
+	if err != nil || !got.Locked {
+		t.Fatalf("expected the post to be locked, got %+v, %v", got, err)
+	}
+
+	tests := []struct {
+		name string
+		act  func() error
+	}{
+		{"comment", func() error {
+			_, err := commentService.Create(ctx, &models.Comment{Content: "Too late", PostID: post.ID, AuthorID: f.bob.ID})
+			return err
+		}},
+		{"vote on post", func() error { return votingService.VotePost(ctx, post.ID, f.alice.ID, 1, f.alice.ID) }},
+		{"remove vote on post", func() error { return votingService.VotePost(ctx, post.ID, f.bob.ID, 0, f.bob.ID) }},
+		{"vote on comment", func() error { return votingService.VoteComment(ctx, comment.ID, f.bob.ID, -1, f.bob.ID) }},
+		{"vote in poll", func() error {
+			_, err := pollService.Vote(ctx, post.ID, []uint{post.Poll.Options[0].ID}, f.bob.ID)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertCode(t, tt.act(), errs.ErrForbidden)
+			assertCode(t, service.SetLocked(ctx, post.ID, false, mod.ID), noError)
+			assertCode(t, tt.act(), noError)
+			assertCode(t, service.SetLocked(ctx, post.ID, true, mod.ID), noError)
+		})
+	}
+
+	// The post's author can still edit a locked post
+	_, err = postService.Update(ctx, post.ID, 0, "Edited", "Edited content", f.alice.ID)
+	assertCode(t, err, noError)
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/metrics"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"cvwo-backend/internal/tracing"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Limits on the options of a poll, matching the binding constraints of models.NewPoll
+const (
+	minPollOptions      = 2
+	maxPollOptions      = 10
+	maxPollOptionLength = 100
+)
+
+type PollService struct {
+	pollRepo repos.PollRepo
+	postRepo repos.PostRepo
+	// Current time, replaceable in tests
+	now func() time.Time
+}
+
+func NewPollService(pollRepo repos.PollRepo, postRepo repos.PostRepo) *PollService {
+	return &PollService{pollRepo: pollRepo, postRepo: postRepo, now: time.Now}
+}
+
+// Check the options and close time of the poll of a new poll post, and number its options in order
+func (service *PollService) preparePoll(poll *models.Poll) error {
+	if len(poll.Options) < minPollOptions || len(poll.Options) > maxPollOptions {
+		return errs.NewValidation("Invalid request body", []errs.FieldError{
+			{Field: "poll.options", Message: fmt.Sprintf("must have between %d and %d options", minPollOptions, maxPollOptions)},
+		})
+	}
+
+	seen := make(map[string]bool)
+	for i := range poll.Options {
+		text := strings.TrimSpace(poll.Options[i].Text)
+		field := fmt.Sprintf("poll.options[%d]", i)
+		switch {
+		case text == "":
+			return errs.NewValidation("Invalid request body", []errs.FieldError{{Field: field, Message: "is required"}})
+		case len([]rune(text)) > maxPollOptionLength:
+			return errs.NewValidation("Invalid request body", []errs.FieldError{{Field: field, Message: fmt.Sprintf("must be at most %d characters", maxPollOptionLength)}})
+		case seen[strings.ToLower(text)]:
+			return errs.NewValidation("Invalid request body", []errs.FieldError{{Field: field, Message: "must be different from the other options"}})
+		}
+		seen[strings.ToLower(text)] = true
+		poll.Options[i].Text = text
+		poll.Options[i].Position = i
+	}
+
+	if poll.ClosesAt != nil && !poll.ClosesAt.After(service.now()) {
+		return errs.NewValidation("Invalid request body", []errs.FieldError{{Field: "poll.closes_at", Message: "must be in the future"}})
+	}
+
+	return nil
+}
+
+// Get the poll carried by a post
+// The vote counts are hidden until the current user votes or the poll closes, so that early results do not sway voters
+func (service *PollService) GetByPostID(ctx context.Context, postID uint, currentUserID uint) (*models.Poll, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "PollService.GetByPostID")
+	defer span.End()
+
+	poll, err := service.pollRepo.GetByPostID(ctx, postID, currentUserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.Wrap(errs.ErrNotFound, "Poll not found", err)
+		}
+		return nil, err
+	}
+
+	poll.Closed = poll.ClosesAt != nil && !poll.ClosesAt.After(service.now())
+	if !poll.Voted && !poll.Closed {
+		poll.TotalVoters = nil
+		for i := range poll.Options {
+			poll.Options[i].Votes = nil
+		}
+	}
+	return poll, nil
+}
+
+// Vote for the given options in the poll carried by a post, returning the poll with its results
+// Each user votes once, for 1 option in a single choice poll or at least 1 option in a multiple choice poll
+func (service *PollService) Vote(ctx context.Context, postID uint, optionIDs []uint, currentUserID uint) (*models.Poll, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "PollService.Vote")
+	defer span.End()
+
+	poll, err := service.GetByPostID(ctx, postID, currentUserID)
+	if err != nil {
+		return nil, err
+	}
+	if poll.Closed {
+		return nil, errs.New(errs.ErrConflict, "Poll is closed")
+	}
+	if poll.Voted {
+		return nil, errs.New(errs.ErrConflict, "You have already voted in this poll")
+	}
+	if err := checkOpen(ctx, service.postRepo, postID); err != nil {
+		return nil, err
+	}
+
+	if len(optionIDs) == 0 {
+		return nil, errs.New(errs.ErrInvalid, "At least 1 option must be chosen")
+	}
+	if !poll.MultipleChoice && len(optionIDs) > 1 {
+		return nil, errs.New(errs.ErrInvalid, "Only 1 option may be chosen in this poll")
+	}
+
+	vote := &models.PollVote{PostID: postID, UserID: currentUserID}
+	for _, optionID := range optionIDs {
+		if !slices.ContainsFunc(poll.Options, func(option models.PollOption) bool { return option.ID == optionID }) {
+			return nil, errs.New(errs.ErrInvalid, fmt.Sprintf("Invalid option ID: %d", optionID))
+		}
+		if slices.ContainsFunc(vote.Choices, func(choice models.PollChoice) bool { return choice.OptionID == optionID }) {
+			return nil, errs.New(errs.ErrInvalid, fmt.Sprintf("Option %d is chosen more than once", optionID))
+		}
+		vote.Choices = append(vote.Choices, models.PollChoice{PostID: postID, UserID: currentUserID, OptionID: optionID})
+	}
+
+	if err := service.pollRepo.Vote(ctx, vote); err != nil {
+		switch {
+		// Another request from the same user voted first
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			return nil, errs.Wrap(errs.ErrConflict, "You have already voted in this poll", err)
+		case errors.Is(err, gorm.ErrForeignKeyViolated):
+			return nil, errs.Wrap(errs.ErrNotFound, "Poll or user not found", err)
+		}
+		return nil, err
+	}
+	metrics.PollVotesCast.Inc()
+
+	return service.GetByPostID(ctx, postID, currentUserID)
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Create a poll post by alice with the given options
+func (f *fixture) createPoll(t *testing.T, postService *PostService, multipleChoice bool, closesAt *time.Time, options ...string) *models.Post {
+	t.Helper()
+
+	poll := &models.Poll{MultipleChoice: multipleChoice, ClosesAt: closesAt}
+	for _, text := range options {
+		poll.Options = append(poll.Options, models.PollOption{Text: text})
+	}
+	post, err := postService.Create(context.Background(), &models.Post{Title: "poll", Content: "Which one?", AuthorID: f.alice.ID, Poll: poll}, nil)
+	if err != nil {
+		t.Fatalf("failed to create poll: %v", err)
+	}
+	return post
+}
+
+func TestCreatePoll(t *testing.T) {
+	f := newFixture(t)
+	pollService := f.pollService()
+	postService := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, pollService, f.attachmentService(), DefaultContentLimits, nil)
+	ctx := context.Background()
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		options   []string
+		closesAt  *time.Time
+		wantCode  int
+		wantField string
+	}{
+		{"valid", []string{"  Tea ", "Coffee"}, &future, noError, ""},
+		{"too few options", []string{"Tea"}, nil, errs.ErrInvalid, "poll.options"},
+		{"too many options", []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11"}, nil, errs.ErrInvalid, "poll.options"},
+		{"blank option", []string{"Tea", "  "}, nil, errs.ErrInvalid, "poll.options[1]"},
+		{"duplicate options", []string{"Tea", "tea"}, nil, errs.ErrInvalid, "poll.options[1]"},
+		{"closes in the past", []string{"Tea", "Coffee"}, &past, errs.ErrInvalid, "poll.closes_at"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poll := &models.Poll{ClosesAt: tt.closesAt}
+			for _, text := range tt.options {
+				poll.Options = append(poll.Options, models.PollOption{Text: text})
+			}
+			post, err := postService.Create(ctx, &models.Post{Title: "poll", Content: "Which one?", AuthorID: f.alice.ID, Poll: poll}, nil)
+			assertCode(t, err, tt.wantCode)
+			if tt.wantCode != noError {
+				var appErr *errs.Error
+				if !errors.As(err, &appErr) || len(appErr.Fields) != 1 || appErr.Fields[0].Field != tt.wantField {
+					t.Errorf("expected a field error for %s, got %v", tt.wantField, err)
+				}
+				return
+			}
+
+			if post.Type != models.PostTypePoll {
+				t.Errorf("expected a poll post, got %q", post.Type)
+			}
+			got, err := postService.GetByIDWithAuth(ctx, post.ID, f.bob.ID)
+			if err != nil {
+				t.Fatalf("failed to get poll post: %v", err)
+			}
+			if got.Poll == nil || len(got.Poll.Options) != 2 || got.Poll.Options[0].Text != "Tea" || got.Poll.Options[1].Text != "Coffee" {
+				t.Fatalf("unexpected poll: %+v", got.Poll)
+			}
+		})
+	}
+
+	// Posts without a poll are text posts without a poll
+	post, err := postService.Create(ctx, &models.Post{Title: "text", Content: "Just text", AuthorID: f.alice.ID}, nil)
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+	got, err := postService.GetByIDWithAuth(ctx, post.ID, f.bob.ID)
+	if err != nil || got.Type != models.PostTypeText || got.Poll != nil {
+		t.Fatalf("expected a text post without a poll, got %+v, %v", got, err)
+	}
+}
+
+func TestPollVoting(t *testing.T) {
+	f := newFixture(t)
+	pollService := f.pollService()
+	postService := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, pollService, f.attachmentService(), DefaultContentLimits, nil)
+	ctx := context.Background()
+	carol := f.createUser(t, "carol", "password")
+
+	single := f.createPoll(t, postService, false, nil, "Tea", "Coffee", "Juice")
+	multiple := f.createPoll(t, postService, true, nil, "Mon", "Tue", "Wed")
+	text := f.createPost(t, f.alice.ID, "text")
+	optionIDs := func(post *models.Post, indexes ...int) []uint {
+		var ids []uint
+		for _, i := range indexes {
+			ids = append(ids, post.Poll.Options[i].ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name      string
+		postID    uint
+		optionIDs []uint
+		userID    uint
+		wantCode  int
+	}{
+		{"single choice", single.ID, optionIDs(single, 1), f.bob.ID, noError},
+		{"vote again", single.ID, optionIDs(single, 0), f.bob.ID, errs.ErrConflict},
+		{"several options in a single choice poll", single.ID, optionIDs(single, 0, 1), carol.ID, errs.ErrInvalid},
+		{"option of another poll", single.ID, optionIDs(multiple, 0), carol.ID, errs.ErrInvalid},
+		{"no options", single.ID, nil, carol.ID, errs.ErrInvalid},
+		{"multiple choice", multiple.ID, optionIDs(multiple, 0, 2), f.bob.ID, noError},
+		{"same option twice", multiple.ID, optionIDs(multiple, 1, 1), carol.ID, errs.ErrInvalid},
+		{"post without a poll", text.ID, []uint{1}, f.bob.ID, errs.ErrNotFound},
+		{"missing post", 9999, []uint{1}, f.bob.ID, errs.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poll, err := pollService.Vote(ctx, tt.postID, tt.optionIDs, tt.userID)
+			assertCode(t, err, tt.wantCode)
+			if tt.wantCode != noError {
+				return
+			}
+			// The voter sees the results, with their choices selected
+			if !poll.Voted || poll.TotalVoters == nil || *poll.TotalVoters != 1 {
+				t.Fatalf("unexpected poll after voting: %+v", poll)
+			}
+			for _, option := range poll.Options {
+				chosen := false
+				for _, id := range tt.optionIDs {
+					chosen = chosen || id == option.ID
+				}
+				if option.Votes == nil || (*option.Votes == 1) != chosen || option.Selected != chosen {
+					t.Errorf("unexpected result for option %q: %+v", option.Text, option)
+				}
+			}
+		})
+	}
+}
+
+func TestPollResultsAreHiddenUntilVotingOrClose(t *testing.T) {
+	f := newFixture(t)
+	pollService := f.pollService()
+	postService := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, pollService, f.attachmentService(), DefaultContentLimits, nil)
+	ctx := context.Background()
+	carol := f.createUser(t, "carol", "password")
+
+	closesAt := time.Now().Add(time.Hour)
+	post := f.createPoll(t, postService, false, &closesAt, "Yes", "No")
+	if _, err := pollService.Vote(ctx, post.ID, []uint{post.Poll.Options[0].ID}, f.bob.ID); err != nil {
+		t.Fatalf("failed to vote: %v", err)
+	}
+
+	// Users who have not voted, including anonymous users, do not see the results
+	for _, userID := range []uint{carol.ID, 0} {
+		poll, err := pollService.GetByPostID(ctx, post.ID, userID)
+		if err != nil {
+			t.Fatalf("failed to get poll: %v", err)
+		}
+		if poll.Voted || poll.Closed || poll.TotalVoters != nil || poll.Options[0].Votes != nil {
+			t.Errorf("expected hidden results for user %d, got %+v", userID, poll)
+		}
+	}
+
+	// Once the poll closes, everyone sees the results and no more votes are accepted
+	pollService.now = func() time.Time { return closesAt.Add(time.Second) }
+	poll, err := pollService.GetByPostID(ctx, post.ID, carol.ID)
+	if err != nil {
+		t.Fatalf("failed to get poll: %v", err)
+	}
+	if !poll.Closed || poll.TotalVoters == nil || *poll.TotalVoters != 1 || *poll.Options[0].Votes != 1 || *poll.Options[1].Votes != 0 {
+		t.Errorf("expected visible results after closing, got %+v", poll)
+	}
+	_, err = pollService.Vote(ctx, post.ID, []uint{post.Poll.Options[1].ID}, carol.ID)
+	assertCode(t, err, errs.ErrConflict)
+
+	// Deleting the post deletes the poll
+	if err := postService.Delete(ctx, post.ID, f.alice.ID); err != nil {
+		t.Fatalf("failed to delete post: %v", err)
+	}
+	_, err = pollService.GetByPostID(ctx, post.ID, carol.ID)
+	assertCode(t, err, errs.ErrNotFound)
+}
@@ -1,10 +1,18 @@
 package services
 
 import (
+	"context"
 	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/events"
+	"cvwo-backend/internal/metrics"
 	"cvwo-backend/internal/models"
 	"cvwo-backend/internal/repos"
+	"cvwo-backend/internal/tracing"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -13,10 +21,33 @@ type PostService struct {
 	postRepo  repos.PostRepo
 	userRepo  repos.UserRepo
 	topicRepo repos.TopicRepo
+	// Records events in the same transaction as the change they describe
+	outboxRepo repos.OutboxRepo
+	transactor repos.Transactor
+	// Checks the polls of new poll posts and gets the polls of individual posts
+	pollService *PollService
+	// Deletes the files attached to deleted posts
+	attachmentService *AttachmentService
+	limits            ContentLimits
+	// Caches the listings seen by anonymous users. Nil if caching is disabled.
+	listings *ListingCache
+	// Current time, replaceable in tests
+	now func() time.Time
 }
 
-func NewPostService(postRepo repos.PostRepo, userRepo repos.UserRepo, topicRepo repos.TopicRepo) *PostService {
-	return &PostService{postRepo, userRepo, topicRepo}
+func NewPostService(postRepo repos.PostRepo, userRepo repos.UserRepo, topicRepo repos.TopicRepo, outboxRepo repos.OutboxRepo, transactor repos.Transactor, pollService *PollService, attachmentService *AttachmentService, limits ContentLimits, listings *ListingCache) *PostService {
+	return &PostService{
+		postRepo:          postRepo,
+		userRepo:          userRepo,
+		topicRepo:         topicRepo,
+		outboxRepo:        outboxRepo,
+		transactor:        transactor,
+		pollService:       pollService,
+		attachmentService: attachmentService,
+		limits:            limits,
+		listings:          listings,
+		now:               time.Now,
+	}
 }
 
 // Maps valid sort params to the corresponding SQL orderBy clause
@@ -36,32 +67,81 @@ func validPostSortField(sortBy string) (string, error) {
 }
 
 // Get a list of posts
-func (service *PostService) GetList(limit, offset int, sortBy string, currentUserID uint) ([]models.Post, int64, error) {
+func (service *PostService) GetList(ctx context.Context, limit, offset int, sortBy string, currentUserID uint) ([]models.Post, int64, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "PostService.GetList")
+	defer span.End()
+
 	// Validate sortBy param
 	sortField, err := validPostSortField(sortBy)
 	if err != nil {
 		return nil, 0, err
 	}
-	return service.postRepo.GetList(limit, offset, sortField, currentUserID)
+
+	// Listings with the current user's votes cannot be shared, so only anonymous listings are cached
+	if currentUserID != 0 {
+		return service.postRepo.GetList(ctx, limit, offset, sortField, currentUserID)
+	}
+	key := fmt.Sprintf("list:%s:%d:%d", sortBy, limit, offset)
+	page, err := cachedListing(ctx, service.listings, postsNamespace, key, func(ctx context.Context) (postPage, error) {
+		posts, total, err := service.postRepo.GetList(ctx, limit, offset, sortField, 0)
+		return postPage{posts, total}, err
+	})
+	return page.Posts, page.Total, err
+}
+
+// Page of a post listing, as cached
+type postPage struct {
+	Posts []models.Post `json:"posts"`
+	Total int64         `json:"total"`
 }
 
 // Get all posts tagged with at least 1 of the given topics
-func (service *PostService) GetByTags(topicIDs []uint, limit, offset int, sortBy string, currentUserID uint) ([]models.Post, int64, error) {
+func (service *PostService) GetByTags(ctx context.Context, topicIDs []uint, limit, offset int, sortBy string, currentUserID uint) ([]models.Post, int64, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "PostService.GetByTags")
+	defer span.End()
+
 	// Validate sortBy param
 	sortField, err := validPostSortField(sortBy)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	return service.postRepo.GetByTopics(topicIDs, limit, offset, sortField, currentUserID)
+	if currentUserID != 0 {
+		return service.postRepo.GetByTopics(ctx, topicIDs, limit, offset, sortField, currentUserID)
+	}
+	// The order and repetition of the topics do not change the listing
+	sortedIDs := slices.Clone(topicIDs)
+	slices.Sort(sortedIDs)
+	sortedIDs = slices.Compact(sortedIDs)
+	key := fmt.Sprintf("tags:%s:%s:%d:%d", strings.Trim(fmt.Sprint(sortedIDs), "[]"), sortBy, limit, offset)
+	page, err := cachedListing(ctx, service.listings, postsNamespace, key, func(ctx context.Context) (postPage, error) {
+		posts, total, err := service.postRepo.GetByTopics(ctx, topicIDs, limit, offset, sortField, 0)
+		return postPage{posts, total}, err
+	})
+	return page.Posts, page.Total, err
+}
+
+// Get the published posts by the given user, or none if there is no such user
+func (service *PostService) GetByAuthor(ctx context.Context, authorID uint, limit, offset int, sortBy string, currentUserID uint) ([]models.Post, int64, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "PostService.GetByAuthor")
+	defer span.End()
+
+	sortField, err := validPostSortField(sortBy)
+	if err != nil {
+		return nil, 0, err
+	}
+	return service.postRepo.GetByAuthor(ctx, authorID, limit, offset, sortField, currentUserID)
 }
 
 // Get an individual post by ID
-func (service *PostService) GetByID(postID uint) (*models.Post, error) {
-	post, err := service.postRepo.GetByID(postID)
+func (service *PostService) GetByID(ctx context.Context, postID uint) (*models.Post, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "PostService.GetByID")
+	defer span.End()
+
+	post, err := service.postRepo.GetByID(ctx, postID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errs.New(errs.ErrNotFound, "Post not found")
+			return nil, errs.Wrap(errs.ErrNotFound, "Post not found", err)
 		}
 		return nil, err
 	}
@@ -69,61 +149,277 @@ func (service *PostService) GetByID(postID uint) (*models.Post, error) {
 }
 
 // Get an individual post by ID, including additional fields associated with the authenticated user
-func (service *PostService) GetByIDWithAuth(postID uint, currentUserID uint) (*models.Post, error) {
-	post, err := service.postRepo.GetByIDWithAuth(postID, currentUserID)
+// Poll posts include their poll, with the results if the authenticated user may see them
+func (service *PostService) GetByIDWithAuth(ctx context.Context, postID uint, currentUserID uint) (*models.Post, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "PostService.GetByIDWithAuth")
+	defer span.End()
+
+	post, err := service.postRepo.GetByIDWithAuth(ctx, postID, currentUserID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errs.New(errs.ErrNotFound, "Post not found")
+			return nil, errs.Wrap(errs.ErrNotFound, "Post not found", err)
 		}
 		return nil, err
 	}
+
+	if post.Type == models.PostTypePoll {
+		if post.Poll, err = service.pollService.GetByPostID(ctx, postID, currentUserID); err != nil {
+			return nil, err
+		}
+	}
 	return post, nil
 }
 
-// Create a new post
-func (service *PostService) Create(postData *models.Post) (*models.Post, error) {
-	post, err := service.postRepo.Create(postData)
+// Create a new post tagged with the given topics
+func (service *PostService) Create(ctx context.Context, postData *models.Post, topicIDs []uint) (*models.Post, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "PostService.Create")
+	defer span.End()
+
+	contentHTML, err := renderContent(postData.Content, service.limits.Post)
+	if err != nil {
+		return nil, err
+	}
+	postData.ContentHTML = contentHTML
+
+	// Posts are published immediately unless they are drafts or scheduled
+	if postData.Status == "" {
+		postData.Status = models.PostStatusPublished
+	}
+	if err := service.checkSchedule(postData.Status, postData.PublishAt); err != nil {
+		return nil, err
+	}
+
+	// A post with a poll is a poll post, whose poll is created together with it
+	postData.Type = models.PostTypeText
+	if postData.Poll != nil {
+		if err := service.pollService.preparePoll(postData.Poll); err != nil {
+			return nil, err
+		}
+		postData.Type = models.PostTypePoll
+	}
+
+	// The post is created together with its tags, so that a post is never left without the topics it was meant to have
+	var post *models.Post
+	err = service.transactor.InTransaction(ctx, func(ctx context.Context) error {
+		topics, err := findTopics(ctx, service.topicRepo, topicIDs)
+		if err != nil {
+			return err
+		}
+		if post, err = service.postRepo.Create(ctx, postData); err != nil {
+			return err
+		}
+		if post.Status == models.PostStatusPublished {
+			if err := service.published(ctx, post); err != nil {
+				return err
+			}
+		}
+		if len(topics) == 0 {
+			return nil
+		}
+		if err := service.postRepo.AssociatePostWithTopics(ctx, post, topics); err != nil {
+			return err
+		}
+		return emit(ctx, service.outboxRepo, events.PostTagged{PostID: post.ID, TopicIDs: topicIDsOf(topics)})
+	})
 	if err != nil {
 		if errors.Is(err, gorm.ErrForeignKeyViolated) {
-			return nil, errs.New(errs.ErrNotFound, "Author not found")
+			return nil, errs.Wrap(errs.ErrNotFound, "Author not found", err)
 		}
 		return nil, err
 	}
+	if post.Status == models.PostStatusPublished {
+		metrics.PostsCreated.Inc()
+		service.listings.invalidateOrLog(ctx, postsNamespace)
+	}
 	return post, nil
 }
 
+// Check that only scheduled posts have a publish time, and that it is in the future
+func (service *PostService) checkSchedule(status string, publishAt *time.Time) error {
+	switch {
+	case status == models.PostStatusScheduled && publishAt == nil:
+		return errs.NewValidation("Invalid request body", []errs.FieldError{{Field: "publish_at", Message: "is required for scheduled posts"}})
+	case status == models.PostStatusScheduled && !publishAt.After(service.now()):
+		return errs.NewValidation("Invalid request body", []errs.FieldError{{Field: "publish_at", Message: "must be in the future"}})
+	case status != models.PostStatusScheduled && publishAt != nil:
+		return errs.NewValidation("Invalid request body", []errs.FieldError{{Field: "publish_at", Message: "must only be set for scheduled posts"}})
+	}
+	return nil
+}
+
+// Side effects of a post becoming visible, whether it was published when created, by its author or by the scheduler
+// Must be called in the transaction that publishes the post, while the post is only counted once the transaction commits
+func (service *PostService) published(ctx context.Context, post *models.Post) error {
+	return emit(ctx, service.outboxRepo, events.PostCreated{PostID: post.ID, AuthorID: post.AuthorID})
+}
+
+// Publish an unpublished post now, as if it had just been created
+// Returns whether the post was published by this call, so that the side effects are fired once when several callers race to publish it
+func (service *PostService) publish(ctx context.Context, post *models.Post) (bool, error) {
+	publishedAt := service.now()
+	var ok bool
+	err := service.transactor.InTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if ok, err = service.postRepo.Publish(ctx, post.ID, publishedAt); err != nil || !ok {
+			return err
+		}
+		return service.published(ctx, post)
+	})
+	if err != nil || !ok {
+		return false, err
+	}
+	metrics.PostsCreated.Inc()
+	service.listings.invalidateOrLog(ctx, postsNamespace)
+	post.Status = models.PostStatusPublished
+	post.CreatedAt = publishedAt
+	post.UpdatedAt = publishedAt
+	return true, nil
+}
+
+// Get the current user's draft and scheduled posts, most recently updated first
+func (service *PostService) GetDrafts(ctx context.Context, limit, offset int, currentUserID uint) ([]models.Post, int64, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "PostService.GetDrafts")
+	defer span.End()
+
+	return service.postRepo.GetUnpublished(ctx, currentUserID, limit, offset)
+}
+
+// Publish, schedule or unschedule a draft or scheduled post
+// Published posts cannot be unpublished
+func (service *PostService) UpdateStatus(ctx context.Context, postID uint, status string, publishAt *time.Time, currentUserID uint) (*models.Post, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "PostService.UpdateStatus")
+	defer span.End()
+
+	post, err := service.GetByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check authorization
+	if currentUserID != post.AuthorID {
+		return nil, errs.New(errs.ErrForbidden, "Forbidden")
+	}
+	if post.Status == models.PostStatusPublished {
+		return nil, errs.New(errs.ErrConflict, "Post is already published")
+	}
+	if err := service.checkSchedule(status, publishAt); err != nil {
+		return nil, err
+	}
+
+	if status == models.PostStatusPublished {
+		ok, err := service.publish(ctx, post)
+		if err != nil {
+			return nil, err
+		}
+		// The scheduler published the post first
+		if !ok {
+			return nil, errs.New(errs.ErrConflict, "Post is already published")
+		}
+	} else if err := service.postRepo.SetStatus(ctx, postID, status, publishAt); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.Wrap(errs.ErrConflict, "Post is already published", err)
+		}
+		return nil, err
+	}
+
+	return service.GetByIDWithAuth(ctx, postID, currentUserID)
+}
+
+// Publish the scheduled posts that are due, returning the number of posts published
+// Called periodically by the scheduler; posts published concurrently by another instance or their author are skipped
+func (service *PostService) PublishDue(ctx context.Context) (int, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "PostService.PublishDue")
+	defer span.End()
+
+	posts, err := service.postRepo.GetDue(ctx, service.now())
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for i := range posts {
+		ok, err := service.publish(ctx, &posts[i])
+		if err != nil {
+			return published, err
+		}
+		if ok {
+			published++
+		}
+	}
+	return published, nil
+}
+
 // Update the title and content of the given post
-func (service *PostService) Update(postID uint, title string, content string, currentUserID uint) (*models.Post, error) {
-	post, err := service.GetByID(postID)
+// version is the version of the post the edit is based on, or 0 to overwrite any concurrent edits
+func (service *PostService) Update(ctx context.Context, postID uint, version uint, title string, content string, currentUserID uint) (*models.Post, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "PostService.Update")
+	defer span.End()
+
+	post, err := service.GetByID(ctx, postID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Check authorization
 	if currentUserID != post.AuthorID {
-		return nil, errs.New(errs.ErrUnauthorized, "Unauthorized")
+		return nil, errs.New(errs.ErrForbidden, "Forbidden")
 	}
 
-	post, err = service.postRepo.Update(postID, title, content)
+	contentHTML, err := renderContent(content, service.limits.Post)
+	if err != nil {
+		return nil, err
+	}
+
+	published := post.Status == models.PostStatusPublished
+	err = service.transactor.InTransaction(ctx, func(ctx context.Context) error {
+		if post, err = service.postRepo.Update(ctx, postID, version, title, content, contentHTML); err != nil {
+			return err
+		}
+		return emit(ctx, service.outboxRepo, events.PostEdited{PostID: postID, Version: post.Version})
+	})
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errs.New(errs.ErrNotFound, "Post not found")
+			return nil, errs.Wrap(errs.ErrNotFound, "Post not found", err)
+		}
+		if errors.Is(err, repos.ErrVersionConflict) {
+			return nil, errs.Wrap(errs.ErrPreconditionFailed, "Post has been edited since it was retrieved", err)
 		}
 		return nil, err
 	}
+	if published {
+		service.listings.invalidateOrLog(ctx, postsNamespace)
+	}
 	return post, nil
 }
 
 // Delete an individual post
-func (service *PostService) Delete(postID uint, currentUserID uint) error {
+func (service *PostService) Delete(ctx context.Context, postID uint, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "PostService.Delete")
+	defer span.End()
+
 	// Check authorization
-	post, err := service.GetByID(postID)
+	post, err := service.GetByID(ctx, postID)
 	if err != nil {
 		return err
 	}
 	if currentUserID != post.AuthorID {
-		return errs.New(errs.ErrUnauthorized, "Unauthorized")
+		return errs.New(errs.ErrForbidden, "Forbidden")
 	}
 
-	return service.postRepo.Delete(postID)
+	// Delete the post and record the event together, before the attachments' files are deleted
+	err = service.attachmentService.deletePost(ctx, postID, func(ctx context.Context, postID uint) error {
+		return service.transactor.InTransaction(ctx, func(ctx context.Context) error {
+			if err := service.postRepo.Delete(ctx, postID); err != nil {
+				return err
+			}
+			return emit(ctx, service.outboxRepo, events.PostDeleted{PostID: postID, AuthorID: post.AuthorID})
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if post.Status == models.PostStatusPublished {
+		service.listings.invalidateOrLog(ctx, postsNamespace)
+	}
+	return nil
 }
@@ -0,0 +1,400 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPostServiceGetList(t *testing.T) {
+	f := newFixture(t)
+	service := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, nil)
+
+	first := f.createPost(t, f.alice.ID, "first")
+	second := f.createPost(t, f.alice.ID, "second")
+	third := f.createPost(t, f.bob.ID, "third")
+
+	// second: +2, first: +1, third: -1
+	votes := []models.PostVote{
+		{PostID: second.ID, UserID: f.alice.ID, Value: 1},
+		{PostID: second.ID, UserID: f.bob.ID, Value: 1},
+		{PostID: first.ID, UserID: f.bob.ID, Value: 1},
+		{PostID: third.ID, UserID: f.alice.ID, Value: -1},
+	}
+	for _, vote := range votes {
+		if err := f.postVotes.Upsert(context.Background(), &vote); err != nil {
+			t.Fatalf("failed to vote: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		sortBy   string
+		limit    int
+		offset   int
+		wantCode int
+		wantIDs  []uint
+	}{
+		{"newest first", "new", 10, 0, noError, []uint{third.ID, second.ID, first.ID}},
+		{"oldest first", "old", 10, 0, noError, []uint{first.ID, second.ID, third.ID}},
+		{"most votes first", "votes", 10, 0, noError, []uint{second.ID, first.ID, third.ID}},
+		{"second page", "old", 2, 2, noError, []uint{third.ID}},
+		{"invalid sort", "random", 10, 0, errs.ErrInvalid, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posts, count, err := service.GetList(context.Background(), tt.limit, tt.offset, tt.sortBy, f.bob.ID)
+			assertCode(t, err, tt.wantCode)
+			if tt.wantCode != noError {
+				return
+			}
+
+			if count != 3 {
+				t.Errorf("expected total count 3, got %d", count)
+			}
+			ids := []uint{}
+			for _, post := range posts {
+				ids = append(ids, post.ID)
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("expected posts %v, got %v", tt.wantIDs, ids)
+			}
+		})
+	}
+}
+
+func TestPostServiceGetByIDWithAuth(t *testing.T) {
+	f := newFixture(t)
+	service := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, nil)
+
+	post := f.createPost(t, f.alice.ID, "post")
+	if err := f.postVotes.Upsert(context.Background(), &models.PostVote{PostID: post.ID, UserID: f.bob.ID, Value: -1}); err != nil {
+		t.Fatalf("failed to vote: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		postID       uint
+		userID       uint
+		wantCode     int
+		wantUserVote int
+	}{
+		{"voter sees their vote", post.ID, f.bob.ID, noError, -1},
+		{"other user sees no vote", post.ID, f.alice.ID, noError, 0},
+		{"anonymous user sees no vote", post.ID, 0, noError, 0},
+		{"post not found", 999, f.bob.ID, errs.ErrNotFound, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.GetByIDWithAuth(context.Background(), tt.postID, tt.userID)
+			assertCode(t, err, tt.wantCode)
+			if tt.wantCode != noError {
+				return
+			}
+
+			if got.NetVotes != -1 {
+				t.Errorf("expected net votes -1, got %d", got.NetVotes)
+			}
+			if got.UserVote != tt.wantUserVote {
+				t.Errorf("expected user vote %d, got %d", tt.wantUserVote, got.UserVote)
+			}
+			if got.Author == nil || got.Author.ID != f.alice.ID {
+				t.Errorf("expected author to be preloaded")
+			}
+		})
+	}
+}
+
+func TestPostServiceCreate(t *testing.T) {
+	f := newFixture(t)
+	service := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, nil)
+
+	tests := []struct {
+		name     string
+		authorID uint
+		wantCode int
+	}{
+		{"existing author", f.alice.ID, noError},
+		{"unknown author", 999, errs.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post, err := service.Create(context.Background(), &models.Post{Title: "title", Content: "some content", AuthorID: tt.authorID}, nil)
+			assertCode(t, err, tt.wantCode)
+			if tt.wantCode == noError && post.ID == 0 {
+				t.Error("expected created post to have an ID")
+			}
+		})
+	}
+}
+
+// Posts are created together with their tags, or not at all
+func TestPostServiceCreateWithTopics(t *testing.T) {
+	f := newFixture(t)
+	service := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, nil)
+	ctx := context.Background()
+	philosophy := f.store.AddTopic("Philosophy")
+	literature := f.store.AddTopic("Literature")
+
+	_, err := service.Create(ctx, &models.Post{Title: "title", Content: "some content", AuthorID: f.alice.ID}, []uint{philosophy.ID, 999})
+	assertCode(t, err, errs.ErrInvalid)
+	if posts, total, err := f.posts.GetList(ctx, 10, 0, "new", 0); err != nil || total != 0 {
+		t.Fatalf("expected no post to be created, got %+v, %v", posts, err)
+	}
+
+	post, err := service.Create(ctx, &models.Post{Title: "title", Content: "some content", AuthorID: f.alice.ID}, []uint{philosophy.ID, literature.ID, philosophy.ID})
+	assertCode(t, err, noError)
+	got, err := f.posts.GetByIDWithAuth(ctx, post.ID, 0)
+	if err != nil {
+		t.Fatalf("failed to get post: %v", err)
+	}
+	if len(got.Topics) != 2 {
+		t.Errorf("expected 2 topics, got %+v", got.Topics)
+	}
+}
+
+func TestPostServiceUpdate(t *testing.T) {
+	f := newFixture(t)
+	service := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, nil)
+	post := f.createPost(t, f.alice.ID, "original")
+
+	tests := []struct {
+		name        string
+		postID      uint
+		version     uint
+		userID      uint
+		wantCode    int
+		wantVersion uint
+	}{
+		{"author can update", post.ID, 1, f.alice.ID, noError, 2},
+		{"edit based on an old version", post.ID, 1, f.alice.ID, errs.ErrPreconditionFailed, 0},
+		{"edit based on any version", post.ID, 0, f.alice.ID, noError, 3},
+		{"other user is forbidden", post.ID, 0, f.bob.ID, errs.ErrForbidden, 0},
+		{"post not found", 999, 0, f.alice.ID, errs.ErrNotFound, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, err := service.Update(context.Background(), tt.postID, tt.version, "updated", "updated content", tt.userID)
+			assertCode(t, err, tt.wantCode)
+			if tt.wantCode == noError && (updated.Title != "updated" || updated.Version != tt.wantVersion) {
+				t.Errorf("expected title to be updated to version %d, got %q version %d", tt.wantVersion, updated.Title, updated.Version)
+			}
+		})
+	}
+}
+
+func TestPostServiceDelete(t *testing.T) {
+	tests := []struct {
+		name     string
+		postID   func(post *models.Post) uint
+		userID   func(f *fixture) uint
+		wantCode int
+	}{
+		{"author can delete", func(post *models.Post) uint { return post.ID }, func(f *fixture) uint { return f.alice.ID }, noError},
+		{"other user is forbidden", func(post *models.Post) uint { return post.ID }, func(f *fixture) uint { return f.bob.ID }, errs.ErrForbidden},
+		{"post not found", func(*models.Post) uint { return 999 }, func(f *fixture) uint { return f.alice.ID }, errs.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			service := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, nil)
+			post := f.createPost(t, f.alice.ID, "post")
+
+			err := service.Delete(context.Background(), tt.postID(post), tt.userID(f))
+			assertCode(t, err, tt.wantCode)
+
+			_, getErr := service.GetByID(context.Background(), post.ID)
+			if tt.wantCode == noError {
+				assertCode(t, getErr, errs.ErrNotFound)
+			} else {
+				assertCode(t, getErr, noError)
+			}
+		})
+	}
+}
+
+func TestPostServiceCreateDraftsAndScheduledPosts(t *testing.T) {
+	f := newFixture(t)
+	service := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, nil)
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		status     string
+		publishAt  *time.Time
+		wantCode   int
+		wantStatus string
+	}{
+		{"published by default", "", nil, noError, models.PostStatusPublished},
+		{"draft", models.PostStatusDraft, nil, noError, models.PostStatusDraft},
+		{"scheduled", models.PostStatusScheduled, &future, noError, models.PostStatusScheduled},
+		{"scheduled without a publish time", models.PostStatusScheduled, nil, errs.ErrInvalid, ""},
+		{"scheduled in the past", models.PostStatusScheduled, &past, errs.ErrInvalid, ""},
+		{"draft with a publish time", models.PostStatusDraft, &future, errs.ErrInvalid, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post, err := service.Create(context.Background(), &models.Post{Title: "title", Content: "some content", AuthorID: f.alice.ID, Status: tt.status, PublishAt: tt.publishAt}, nil)
+			assertCode(t, err, tt.wantCode)
+			if tt.wantCode == noError && post.Status != tt.wantStatus {
+				t.Errorf("expected status %q, got %q", tt.wantStatus, post.Status)
+			}
+		})
+	}
+}
+
+func TestUnpublishedPostsAreOnlyVisibleToTheirAuthor(t *testing.T) {
+	f := newFixture(t)
+	service := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, nil)
+	commentService := NewCommentService(f.comments, f.posts, f.users, f.blocks, f.outbox, f.transactor, f.attachmentService(), DefaultContentLimits)
+	votingService := NewVotingService(f.postVotes, f.commentVotes, f.posts, f.comments, f.outbox, f.transactor, nil)
+	savingService := NewSavingService(f.savedItems, f.posts, f.comments)
+	ctx := context.Background()
+	future := time.Now().Add(time.Hour)
+
+	published := f.createPost(t, f.alice.ID, "published")
+	draft, err := service.Create(ctx, &models.Post{Title: "draft", Content: "some content", AuthorID: f.alice.ID, Status: models.PostStatusDraft}, nil)
+	if err != nil {
+		t.Fatalf("failed to create draft: %v", err)
+	}
+	scheduled, err := service.Create(ctx, &models.Post{Title: "scheduled", Content: "some content", AuthorID: f.alice.ID, Status: models.PostStatusScheduled, PublishAt: &future}, nil)
+	if err != nil {
+		t.Fatalf("failed to create scheduled post: %v", err)
+	}
+
+	// Listings only include published posts, even for the author
+	posts, count, err := service.GetList(ctx, 10, 0, "new", f.alice.ID)
+	if err != nil || count != 1 || len(posts) != 1 || posts[0].ID != published.ID {
+		t.Fatalf("expected only the published post, got %v (%d), %v", posts, count, err)
+	}
+
+	// The author sees their unpublished posts, most recently updated first
+	drafts, count, err := service.GetDrafts(ctx, 10, 0, f.alice.ID)
+	if err != nil || count != 2 || len(drafts) != 2 || drafts[0].ID != scheduled.ID || drafts[1].ID != draft.ID {
+		t.Fatalf("expected the scheduled post and the draft, got %v (%d), %v", drafts, count, err)
+	}
+	if _, count, _ := service.GetDrafts(ctx, 10, 0, f.bob.ID); count != 0 {
+		t.Errorf("expected bob to have no drafts, got %d", count)
+	}
+
+	for _, post := range []*models.Post{draft, scheduled} {
+		_, err := service.GetByIDWithAuth(ctx, post.ID, f.alice.ID)
+		assertCode(t, err, noError)
+		_, err = service.GetByIDWithAuth(ctx, post.ID, f.bob.ID)
+		assertCode(t, err, errs.ErrNotFound)
+		_, err = service.GetByIDWithAuth(ctx, post.ID, 0)
+		assertCode(t, err, errs.ErrNotFound)
+
+		// Unpublished posts cannot be commented on, voted on or saved, even by their author
+		_, err = commentService.Create(ctx, &models.Comment{Content: "First!", PostID: post.ID, AuthorID: f.alice.ID})
+		assertCode(t, err, errs.ErrNotFound)
+		assertCode(t, votingService.VotePost(ctx, post.ID, f.bob.ID, 1, f.bob.ID), errs.ErrNotFound)
+		assertCode(t, savingService.SavePost(ctx, post.ID, f.bob.ID), errs.ErrNotFound)
+	}
+}
+
+func TestPostServiceUpdateStatus(t *testing.T) {
+	f := newFixture(t)
+	service := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, nil)
+	ctx := context.Background()
+	future := time.Now().Add(time.Hour)
+
+	draft, err := service.Create(ctx, &models.Post{Title: "draft", Content: "some content", AuthorID: f.alice.ID, Status: models.PostStatusDraft}, nil)
+	if err != nil {
+		t.Fatalf("failed to create draft: %v", err)
+	}
+	published := f.createPost(t, f.alice.ID, "published")
+
+	tests := []struct {
+		name       string
+		postID     uint
+		status     string
+		publishAt  *time.Time
+		userID     uint
+		wantCode   int
+		wantStatus string
+	}{
+		{"other user is forbidden", draft.ID, models.PostStatusPublished, nil, f.bob.ID, errs.ErrForbidden, ""},
+		{"schedule without a publish time", draft.ID, models.PostStatusScheduled, nil, f.alice.ID, errs.ErrInvalid, ""},
+		{"schedule", draft.ID, models.PostStatusScheduled, &future, f.alice.ID, noError, models.PostStatusScheduled},
+		{"unschedule", draft.ID, models.PostStatusDraft, nil, f.alice.ID, noError, models.PostStatusDraft},
+		{"publish", draft.ID, models.PostStatusPublished, nil, f.alice.ID, noError, models.PostStatusPublished},
+		{"unpublish", draft.ID, models.PostStatusDraft, nil, f.alice.ID, errs.ErrConflict, ""},
+		{"already published", published.ID, models.PostStatusPublished, nil, f.alice.ID, errs.ErrConflict, ""},
+		{"post not found", 999, models.PostStatusPublished, nil, f.alice.ID, errs.ErrNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post, err := service.UpdateStatus(ctx, tt.postID, tt.status, tt.publishAt, tt.userID)
+			assertCode(t, err, tt.wantCode)
+			if tt.wantCode != noError {
+				return
+			}
+			if post.Status != tt.wantStatus || (post.PublishAt != nil) != (tt.publishAt != nil) {
+				t.Errorf("expected status %q with publish time %v, got %q with %v", tt.wantStatus, tt.publishAt, post.Status, post.PublishAt)
+			}
+		})
+	}
+
+	// A post published later is listed as new
+	posts, _, err := service.GetList(ctx, 10, 0, "new", f.bob.ID)
+	if err != nil || len(posts) != 2 || posts[0].ID != draft.ID {
+		t.Errorf("expected the newly published post first, got %v, %v", posts, err)
+	}
+}
+
+func TestPublishDue(t *testing.T) {
+	f := newFixture(t)
+	service := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, nil)
+	ctx := context.Background()
+	now := time.Now()
+	soon, later := now.Add(time.Minute), now.Add(time.Hour)
+
+	for _, publishAt := range []*time.Time{&soon, &later} {
+		if _, err := service.Create(ctx, &models.Post{Title: "scheduled", Content: "some content", AuthorID: f.alice.ID, Status: models.PostStatusScheduled, PublishAt: publishAt}, nil); err != nil {
+			t.Fatalf("failed to create scheduled post: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		now           time.Time
+		wantPublished int
+		wantListed    int64
+	}{
+		{"nothing due", now, 0, 0},
+		{"first post due", soon, 1, 1},
+		{"already published", soon.Add(time.Second), 0, 1},
+		{"second post due", later.Add(time.Second), 1, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service.now = func() time.Time { return tt.now }
+			published, err := service.PublishDue(ctx)
+			if err != nil || published != tt.wantPublished {
+				t.Fatalf("expected %d posts to be published, got %d, %v", tt.wantPublished, published, err)
+			}
+			posts, count, err := service.GetList(ctx, 10, 0, "new", f.bob.ID)
+			if err != nil || count != tt.wantListed {
+				t.Fatalf("expected %d listed posts, got %d, %v", tt.wantListed, count, err)
+			}
+			// Published posts are dated when they were published
+			for _, post := range posts {
+				if post.CreatedAt.Before(*post.PublishAt) {
+					t.Errorf("expected post %d to be dated at or after %v, got %v", post.ID, post.PublishAt, post.CreatedAt)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"cvwo-backend/internal/tracing"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type SavingService struct {
+	savedItemRepo repos.SavedItemRepo
+	postRepo      repos.PostRepo
+	commentRepo   repos.CommentRepo
+}
+
+func NewSavingService(savedItemRepo repos.SavedItemRepo, postRepo repos.PostRepo, commentRepo repos.CommentRepo) *SavingService {
+	return &SavingService{savedItemRepo, postRepo, commentRepo}
+}
+
+// Save a post for the current user
+func (service *SavingService) SavePost(ctx context.Context, postID uint, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "SavingService.SavePost")
+	defer span.End()
+
+	post, err := service.postRepo.GetByID(ctx, postID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.Wrap(errs.ErrNotFound, "Post not found", err)
+		}
+		return err
+	}
+	// Unpublished posts cannot be saved, since they are not visible to other users
+	if post.Status != models.PostStatusPublished {
+		return errs.New(errs.ErrNotFound, "Post not found")
+	}
+
+	return service.savedItemRepo.Save(ctx, &models.SavedItem{UserID: currentUserID, ItemType: models.SavedItemPost, ItemID: postID})
+}
+
+// Remove a post from the current user's saved items
+func (service *SavingService) UnsavePost(ctx context.Context, postID uint, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "SavingService.UnsavePost")
+	defer span.End()
+
+	return service.savedItemRepo.Unsave(ctx, currentUserID, models.SavedItemPost, postID)
+}
+
+// Save a comment for the current user
+func (service *SavingService) SaveComment(ctx context.Context, commentID uint, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "SavingService.SaveComment")
+	defer span.End()
+
+	if _, err := service.commentRepo.GetByID(ctx, commentID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.Wrap(errs.ErrNotFound, "Comment not found", err)
+		}
+		return err
+	}
+
+	return service.savedItemRepo.Save(ctx, &models.SavedItem{UserID: currentUserID, ItemType: models.SavedItemComment, ItemID: commentID})
+}
+
+// Remove a comment from the current user's saved items
+func (service *SavingService) UnsaveComment(ctx context.Context, commentID uint, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "SavingService.UnsaveComment")
+	defer span.End()
+
+	return service.savedItemRepo.Unsave(ctx, currentUserID, models.SavedItemComment, commentID)
+}
+
+// Get the current user's saved items together with the saved posts and comments
+// itemType may be "post" or "comment" to filter by type, or empty for all items
+func (service *SavingService) GetSaved(ctx context.Context, itemType string, limit, offset int, currentUserID uint) ([]models.SavedItem, int64, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "SavingService.GetSaved")
+	defer span.End()
+
+	// Validate itemType param
+	if itemType != "" && itemType != models.SavedItemPost && itemType != models.SavedItemComment {
+		return nil, 0, errs.New(errs.ErrInvalid, "Invalid item type")
+	}
+
+	items, count, err := service.savedItemRepo.GetByUserID(ctx, currentUserID, itemType, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Include the saved post or comment, with computed fields for the current user
+	for i := range items {
+		switch items[i].ItemType {
+		case models.SavedItemPost:
+			if items[i].Post, err = service.postRepo.GetByIDWithAuth(ctx, items[i].ItemID, currentUserID); err != nil {
+				return nil, 0, err
+			}
+		case models.SavedItemComment:
+			if items[i].Comment, err = service.commentRepo.GetByIDWithAuth(ctx, items[i].ItemID, currentUserID); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+
+	return items, count, nil
+}
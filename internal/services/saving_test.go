@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"testing"
+)
+
+func TestSavingServiceSave(t *testing.T) {
+	f := newFixture(t)
+	service := NewSavingService(f.savedItems, f.posts, f.comments)
+	postService := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, nil)
+	post := f.createPost(t, f.alice.ID, "post")
+
+	// Cases run in order against the same post, so each builds on the previous state
+	tests := []struct {
+		name      string
+		save      bool
+		postID    uint
+		wantCode  int
+		wantSaved bool
+	}{
+		{"save", true, post.ID, noError, true},
+		{"save again is idempotent", true, post.ID, noError, true},
+		{"unsave", false, post.ID, noError, false},
+		{"unsave again is idempotent", false, post.ID, noError, false},
+		{"unknown post", true, 999, errs.ErrNotFound, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.save {
+				err = service.SavePost(context.Background(), tt.postID, f.bob.ID)
+			} else {
+				err = service.UnsavePost(context.Background(), tt.postID, f.bob.ID)
+			}
+			assertCode(t, err, tt.wantCode)
+
+			got, err := postService.GetByIDWithAuth(context.Background(), post.ID, f.bob.ID)
+			assertCode(t, err, noError)
+			if got.Saved != tt.wantSaved {
+				t.Errorf("expected saved %v, got %v", tt.wantSaved, got.Saved)
+			}
+		})
+	}
+
+	// Saved state is per user
+	if err := service.SavePost(context.Background(), post.ID, f.bob.ID); err != nil {
+		t.Fatalf("failed to save post: %v", err)
+	}
+	got, err := postService.GetByIDWithAuth(context.Background(), post.ID, f.alice.ID)
+	assertCode(t, err, noError)
+	if got.Saved {
+		t.Error("expected post not to be saved for another user")
+	}
+}
+
+func TestSavingServiceGetSaved(t *testing.T) {
+	f := newFixture(t)
+	service := NewSavingService(f.savedItems, f.posts, f.comments)
+	first := f.createPost(t, f.alice.ID, "first")
+	second := f.createPost(t, f.alice.ID, "second")
+	comment := f.createComment(t, f.alice.ID, first.ID)
+
+	ctx := context.Background()
+	for _, save := range []func() error{
+		func() error { return service.SavePost(ctx, first.ID, f.bob.ID) },
+		func() error { return service.SaveComment(ctx, comment.ID, f.bob.ID) },
+		func() error { return service.SavePost(ctx, second.ID, f.bob.ID) },
+		func() error { return service.SavePost(ctx, second.ID, f.alice.ID) },
+	} {
+		if err := save(); err != nil {
+			t.Fatalf("failed to save item: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		itemType  string
+		limit     int
+		offset    int
+		wantCode  int
+		wantItems []uint
+		wantTotal int64
+	}{
+		{"all types, most recent first", "", 10, 0, noError, []uint{second.ID, comment.ID, first.ID}, 3},
+		{"posts only", models.SavedItemPost, 10, 0, noError, []uint{second.ID, first.ID}, 2},
+		{"comments only", models.SavedItemComment, 10, 0, noError, []uint{comment.ID}, 1},
+		{"second page", "", 2, 2, noError, []uint{first.ID}, 3},
+		{"invalid type", "user", 10, 0, errs.ErrInvalid, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, total, err := service.GetSaved(ctx, tt.itemType, tt.limit, tt.offset, f.bob.ID)
+			assertCode(t, err, tt.wantCode)
+			if total != tt.wantTotal {
+				t.Errorf("expected total %d, got %d", tt.wantTotal, total)
+			}
+			if len(items) != len(tt.wantItems) {
+				t.Fatalf("expected %d items, got %d", len(tt.wantItems), len(items))
+			}
+			for i, item := range items {
+				if item.ItemID != tt.wantItems[i] {
+					t.Errorf("item %d: expected ID %d, got %d", i, tt.wantItems[i], item.ItemID)
+				}
+				// The saved post or comment is included and marked as saved
+				switch item.ItemType {
+				case models.SavedItemPost:
+					if item.Post == nil || !item.Post.Saved {
+						t.Errorf("item %d: expected saved post to be included", i)
+					}
+				case models.SavedItemComment:
+					if item.Comment == nil || !item.Comment.Saved {
+						t.Errorf("item %d: expected saved comment to be included", i)
+					}
+				}
+			}
+		})
+	}
+}
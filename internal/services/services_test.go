@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"cvwo-backend/internal/repos/memory"
+	"cvwo-backend/internal/storage"
+	"errors"
+	"testing"
+)
+
+// Expected error code for cases that should succeed
+const noError = -1
+
+// Repositories backed by a shared in-memory store, with two users already registered
+type fixture struct {
+	store         *memory.Store
+	users         repos.UserRepo
+	posts         repos.PostRepo
+	comments      repos.CommentRepo
+	topics        repos.TopicRepo
+	postVotes     repos.PostVoteRepo
+	commentVotes  repos.CommentVoteRepo
+	savedItems    repos.SavedItemRepo
+	subscriptions repos.SubscriptionRepo
+	follows       repos.FollowRepo
+	activity      repos.ActivityRepo
+	blocks        repos.BlockRepo
+	conversations repos.ConversationRepo
+	attachments   repos.AttachmentRepo
+	blobs         *storage.MemoryStore
+	polls         repos.PollRepo
+	outbox        repos.OutboxRepo
+	transactor    repos.Transactor
+
+	alice *models.User
+	bob   *models.User
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	store := memory.NewStore()
+	f := &fixture{
+		store:         store,
+		users:         memory.NewUserRepo(store),
+		posts:         memory.NewPostRepo(store),
+		comments:      memory.NewCommentRepo(store),
+		topics:        memory.NewTopicRepo(store),
+		postVotes:     memory.NewPostVoteRepo(store),
+		commentVotes:  memory.NewCommentVoteRepo(store),
+		savedItems:    memory.NewSavedItemRepo(store),
+		subscriptions: memory.NewSubscriptionRepo(store),
+		follows:       memory.NewFollowRepo(store),
+		activity:      memory.NewActivityRepo(store),
+		blocks:        memory.NewBlockRepo(store),
+		conversations: memory.NewConversationRepo(store),
+		attachments:   memory.NewAttachmentRepo(store),
+		blobs:         storage.NewMemoryStore(),
+		polls:         memory.NewPollRepo(store),
+		outbox:        memory.NewOutboxRepo(store),
+		transactor:    memory.NewTransactor(store),
+	}
+	f.alice = f.createUser(t, "alice", "password")
+	f.bob = f.createUser(t, "bob", "password")
+	return f
+}
+
+func (f *fixture) attachmentService() *AttachmentService {
+	return NewAttachmentService(f.attachments, f.posts, f.comments, f.blobs, DefaultAttachmentLimits)
+}
+
+func (f *fixture) pollService() *PollService {
+	return NewPollService(f.polls, f.posts)
+}
+
+func (f *fixture) createUser(t *testing.T, username string, password string) *models.User {
+	t.Helper()
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	user, err := f.users.Create(context.Background(), &models.User{Username: username, Password: hash})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+func (f *fixture) createPost(t *testing.T, authorID uint, title string) *models.Post {
+	t.Helper()
+
+	post, err := f.posts.Create(context.Background(), &models.Post{Title: title, Content: "Some content for " + title, AuthorID: authorID})
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+	return post
+}
+
+func (f *fixture) createComment(t *testing.T, authorID uint, postID uint) *models.Comment {
+	t.Helper()
+
+	comment, err := f.comments.Create(context.Background(), &models.Comment{Content: "A comment", PostID: postID, AuthorID: authorID})
+	if err != nil {
+		t.Fatalf("failed to create comment: %v", err)
+	}
+	return comment
+}
+
+// Check that err is an *errs.Error with the expected code, or nil if noError is expected
+func assertCode(t *testing.T, err error, want int) {
+	t.Helper()
+
+	if want == noError {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+
+	var e *errs.Error
+	if !errors.As(err, &e) {
+		t.Fatalf("expected error with code %d, got %v", want, err)
+	}
+	if int(e.Code) != want {
+		t.Fatalf("expected error code %d, got %d (%v)", want, e.Code, err)
+	}
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/models"
+	"cvwo-backend/internal/repos"
+	"cvwo-backend/internal/tracing"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type SubscriptionService struct {
+	subscriptionRepo repos.SubscriptionRepo
+	topicRepo        repos.TopicRepo
+}
+
+func NewSubscriptionService(subscriptionRepo repos.SubscriptionRepo, topicRepo repos.TopicRepo) *SubscriptionService {
+	return &SubscriptionService{subscriptionRepo, topicRepo}
+}
+
+// Subscribe the current user to a topic
+func (service *SubscriptionService) Subscribe(ctx context.Context, topicID uint, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "SubscriptionService.Subscribe")
+	defer span.End()
+
+	if _, err := service.topicRepo.GetByID(ctx, topicID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.Wrap(errs.ErrNotFound, "Topic not found", err)
+		}
+		return err
+	}
+
+	err := service.subscriptionRepo.Subscribe(ctx, &models.TopicSubscription{UserID: currentUserID, TopicID: topicID})
+	if errors.Is(err, gorm.ErrForeignKeyViolated) {
+		return errs.Wrap(errs.ErrNotFound, "Topic or user not found", err)
+	}
+	return err
+}
+
+// Unsubscribe the current user from a topic
+func (service *SubscriptionService) Unsubscribe(ctx context.Context, topicID uint, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "SubscriptionService.Unsubscribe")
+	defer span.End()
+
+	return service.subscriptionRepo.Unsubscribe(ctx, currentUserID, topicID)
+}
+
+// Get the topics the current user subscribes to
+func (service *SubscriptionService) GetTopics(ctx context.Context, currentUserID uint) ([]models.Topic, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "SubscriptionService.GetTopics")
+	defer span.End()
+
+	return service.subscriptionRepo.GetTopicsByUserID(ctx, currentUserID)
+}
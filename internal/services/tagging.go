@@ -1,34 +1,102 @@
 package services
 
 import (
+	"context"
 	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/events"
+	"cvwo-backend/internal/models"
 	"cvwo-backend/internal/repos"
+	"cvwo-backend/internal/tracing"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
 )
 
 type TaggingService struct {
 	postRepo  repos.PostRepo
 	topicRepo repos.TopicRepo
+	// Records events in the same transaction as the change they describe
+	outboxRepo repos.OutboxRepo
+	transactor repos.Transactor
+	// Tags change which of the cached post listings of topics a post is in. Nil if caching is disabled.
+	listings *ListingCache
 }
 
-func NewTaggingService(postRepo repos.PostRepo, topicRepo repos.TopicRepo) *TaggingService {
-	return &TaggingService{postRepo, topicRepo}
+func NewTaggingService(postRepo repos.PostRepo, topicRepo repos.TopicRepo, outboxRepo repos.OutboxRepo, transactor repos.Transactor, listings *ListingCache) *TaggingService {
+	return &TaggingService{postRepo, topicRepo, outboxRepo, transactor, listings}
 }
 
-func (service *TaggingService) TagPostWithTopics(postId uint, topicIDs []uint, currentUserID uint) error {
-	post, err := service.postRepo.GetByID(postId)
+func (service *TaggingService) TagPostWithTopics(ctx context.Context, postId uint, topicIDs []uint, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "TaggingService.TagPostWithTopics")
+	defer span.End()
+
+	post, err := service.postRepo.GetByID(ctx, postId)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.Wrap(errs.ErrNotFound, "Post not found", err)
+		}
 		return err
 	}
 
-	topics, err := service.topicRepo.GetByIDs(topicIDs)
+	// Check authorization
+	if currentUserID != post.AuthorID {
+		return errs.New(errs.ErrForbidden, "Forbidden")
+	}
+
+	topics, err := findTopics(ctx, service.topicRepo, topicIDs)
 	if err != nil {
 		return err
 	}
 
-	// Check authorization
-	if currentUserID != post.AuthorID {
-		return errs.New(errs.ErrUnauthorized, "Unauthorized")
+	err = service.transactor.InTransaction(ctx, func(ctx context.Context) error {
+		if err := service.postRepo.AssociatePostWithTopics(ctx, post, topics); err != nil {
+			return err
+		}
+		// Pins only apply to the topics a post is tagged with
+		if err := service.postRepo.UnpinFromOtherTopics(ctx, post.ID, topicIDsOf(topics)); err != nil {
+			return err
+		}
+		return emit(ctx, service.outboxRepo, events.PostTagged{PostID: post.ID, TopicIDs: topicIDsOf(topics)})
+	})
+	if err != nil {
+		return err
+	}
+	service.listings.invalidateOrLog(ctx, postsNamespace)
+	return nil
+}
+
+// Get the topics with the given IDs, rejecting any ID that does not belong to a topic
+func findTopics(ctx context.Context, topicRepo repos.TopicRepo, topicIDs []uint) ([]models.Topic, error) {
+	found, err := topicRepo.GetByIDs(ctx, topicIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[uint]models.Topic{}
+	for _, topic := range found {
+		byID[topic.ID] = topic
 	}
 
-	return service.postRepo.AssociatePostWithTopics(post, topics)
+	topics := []models.Topic{}
+	seen := map[uint]bool{}
+	for _, id := range topicIDs {
+		topic, exists := byID[id]
+		if !exists {
+			return nil, errs.NewValidation("Invalid request body", []errs.FieldError{{Field: "topic_ids", Message: fmt.Sprintf("contains unknown topic %d", id)}})
+		}
+		// Ignore duplicate IDs
+		if !seen[id] {
+			seen[id] = true
+			topics = append(topics, topic)
+		}
+	}
+	return topics, nil
+}
+
+func topicIDsOf(topics []models.Topic) []uint {
+	ids := []uint{}
+	for _, topic := range topics {
+		ids = append(ids, topic.ID)
+	}
+	return ids
 }
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"testing"
+)
+
+func TestTaggingServiceTagPostWithTopics(t *testing.T) {
+	f := newFixture(t)
+	service := NewTaggingService(f.posts, f.topics, f.outbox, f.transactor, nil)
+	post := f.createPost(t, f.alice.ID, "post")
+	philosophy := f.store.AddTopic("Philosophy")
+	literature := f.store.AddTopic("Literature")
+
+	tests := []struct {
+		name       string
+		postID     uint
+		topicIDs   []uint
+		userID     uint
+		wantCode   int
+		wantTopics int
+	}{
+		{"author tags post", post.ID, []uint{philosophy.ID, literature.ID}, f.alice.ID, noError, 2},
+		{"author replaces tags", post.ID, []uint{literature.ID}, f.alice.ID, noError, 1},
+		{"other user is forbidden", post.ID, []uint{philosophy.ID}, f.bob.ID, errs.ErrForbidden, 1},
+		{"post not found", 999, []uint{philosophy.ID}, f.alice.ID, errs.ErrNotFound, 1},
+		{"unknown topic", post.ID, []uint{philosophy.ID, 999}, f.alice.ID, errs.ErrInvalid, 1},
+		{"author clears tags", post.ID, []uint{}, f.alice.ID, noError, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.TagPostWithTopics(context.Background(), tt.postID, tt.topicIDs, tt.userID)
+			assertCode(t, err, tt.wantCode)
+
+			got, err := f.posts.GetByIDWithAuth(context.Background(), post.ID, 0)
+			if err != nil {
+				t.Fatalf("failed to get post: %v", err)
+			}
+			if len(got.Topics) != tt.wantTopics {
+				t.Errorf("expected %d topics, got %d", tt.wantTopics, len(got.Topics))
+			}
+		})
+	}
+}
@@ -1,22 +1,51 @@
 package services
 
 import (
+	"context"
+	errs "cvwo-backend/internal/errors"
 	"cvwo-backend/internal/models"
 	"cvwo-backend/internal/repos"
+	"cvwo-backend/internal/tracing"
+	"errors"
+
+	"gorm.io/gorm"
 )
 
 type TopicService struct {
 	repo repos.TopicRepo
+	// Caches the list of all topics. Nil if caching is disabled.
+	listings *ListingCache
+}
+
+func NewTopicService(repo repos.TopicRepo, listings *ListingCache) *TopicService {
+	return &TopicService{repo, listings}
 }
 
-func NewTopicService(repo repos.TopicRepo) *TopicService {
-	return &TopicService{repo}
+func (service *TopicService) GetAll(ctx context.Context) ([]models.Topic, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "TopicService.GetAll")
+	defer span.End()
+
+	// Topics are only added by seeding or importing the database, after which the server drops the cached list when it starts
+	return cachedListing(ctx, service.listings, topicsNamespace, "all", service.repo.GetAll)
 }
 
-func (service *TopicService) GetAll() ([]models.Topic, error) {
-	return service.repo.GetAll()
+func (service *TopicService) GetByIDs(ctx context.Context, ids []uint) ([]models.Topic, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "TopicService.GetByIDs")
+	defer span.End()
+
+	return service.repo.GetByIDs(ctx, ids)
 }
 
-func (service *TopicService) GetByIDs(ids []uint) ([]models.Topic, error) {
-	return service.repo.GetByIDs(ids)
+func (service *TopicService) GetByID(ctx context.Context, id uint) (*models.Topic, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "TopicService.GetByID")
+	defer span.End()
+
+	topic, err := service.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.Wrap(errs.ErrNotFound, "Topic not found", err)
+		}
+		return nil, err
+	}
+	return topic, nil
 }
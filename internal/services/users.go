@@ -1,9 +1,11 @@
 package services
 
 import (
+	"context"
 	errs "cvwo-backend/internal/errors"
 	"cvwo-backend/internal/models"
 	"cvwo-backend/internal/repos"
+	"cvwo-backend/internal/tracing"
 	"errors"
 
 	"gorm.io/gorm"
@@ -17,28 +19,38 @@ func NewUserService(repo repos.UserRepo) *UserService {
 	return &UserService{repo}
 }
 
-func (service *UserService) GetAll() ([]models.User, error) {
-	return service.repo.GetAll()
+func (service *UserService) GetAll(ctx context.Context) ([]models.User, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.GetAll")
+	defer span.End()
+
+	return service.repo.GetAll(ctx)
 }
 
-func (service *UserService) GetByID(id uint) (*models.User, error) {
-	user, err := service.repo.GetByID(id)
+func (service *UserService) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.GetByID")
+	defer span.End()
+
+	user, err := service.repo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errs.New(errs.ErrNotFound, "User not found")
+			return nil, errs.Wrap(errs.ErrNotFound, "User not found", err)
 		}
+		return nil, err
 	}
 	return user, nil
 }
 
-func (service *UserService) Create(userData *models.AuthInput) (*models.User, error) {
+func (service *UserService) Create(ctx context.Context, userData *models.AuthInput) (*models.User, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.Create")
+	defer span.End()
+
 	// Store hashed password instead of actual password
 	passwordHash, err := HashPassword(userData.Password)
 	if err != nil {
 		return nil, err
 	}
 
-	user, err := service.repo.Create(&models.User{
+	user, err := service.repo.Create(ctx, &models.User{
 		Username: userData.Username,
 		Password: passwordHash, // Create user using hashed password
 	})
@@ -46,17 +58,35 @@ func (service *UserService) Create(userData *models.AuthInput) (*models.User, er
 	// Check if username is already in use
 	if err != nil {
 		if errors.Is(err, gorm.ErrDuplicatedKey) {
-			return nil, errs.New(errs.ErrConflict, "Username already in use")
+			return nil, errs.Wrap(errs.ErrConflict, "Username already in use", err)
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// Update the current user's settings
+func (service *UserService) UpdateSettings(ctx context.Context, settings *models.UserSettings, currentUserID uint) (*models.User, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.UpdateSettings")
+	defer span.End()
+
+	user, err := service.repo.UpdateSettings(ctx, currentUserID, *settings.PublicVotes)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.Wrap(errs.ErrNotFound, "User not found", err)
 		}
 		return nil, err
 	}
 	return user, nil
 }
 
-func (service *UserService) Delete(id uint) error {
-	if err:= service.repo.Delete(id); err != nil {
+func (service *UserService) Delete(ctx context.Context, id uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "UserService.Delete")
+	defer span.End()
+
+	if err:= service.repo.Delete(ctx, id); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errs.New(errs.ErrNotFound, "User not found")
+			return errs.Wrap(errs.ErrNotFound, "User not found", err)
 		}
 		return err
 	}
@@ -1,56 +1,131 @@
 package services
 
 import (
+	"context"
 	errs "cvwo-backend/internal/errors"
+	"cvwo-backend/internal/events"
+	"cvwo-backend/internal/metrics"
 	"cvwo-backend/internal/models"
 	"cvwo-backend/internal/repos"
+	"cvwo-backend/internal/tracing"
+	"errors"
+
+	"gorm.io/gorm"
 )
 
 type VotingService struct {
 	postVoteRepo    repos.PostVoteRepo
 	commentVoteRepo repos.CommentVoteRepo
+	postRepo        repos.PostRepo
+	commentRepo     repos.CommentRepo
+	// Records events in the same transaction as the change they describe
+	outboxRepo repos.OutboxRepo
+	transactor repos.Transactor
+	// Post votes change the scores and order of the cached post listings. Nil if caching is disabled.
+	listings *ListingCache
 }
 
-func NewVotingService(postRepo repos.PostVoteRepo, commentRepo repos.CommentVoteRepo) *VotingService {
-	return &VotingService{postRepo, commentRepo}
+func NewVotingService(postVoteRepo repos.PostVoteRepo, commentVoteRepo repos.CommentVoteRepo, postRepo repos.PostRepo, commentRepo repos.CommentRepo, outboxRepo repos.OutboxRepo, transactor repos.Transactor, listings *ListingCache) *VotingService {
+	return &VotingService{postVoteRepo, commentVoteRepo, postRepo, commentRepo, outboxRepo, transactor, listings}
 }
 
 // Update a user's vote for a post
-func (service *VotingService) VotePost(postID, userID uint, value int, currentUserID uint) error {
+func (service *VotingService) VotePost(ctx context.Context, postID, userID uint, value int, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "VotingService.VotePost")
+	defer span.End()
+
 	// Check authorization
 	if currentUserID != userID {
-		return errs.New(errs.ErrUnauthorized, "Unauthorized")
+		return errs.New(errs.ErrForbidden, "Forbidden")
 	}
 
-	// If vote value is 0, delete the vote record
-	if value == 0 {
-		return service.postVoteRepo.Delete(postID, userID)
+	// Votes on locked posts cannot be cast, changed or removed
+	if err := checkOpen(ctx, service.postRepo, postID); err != nil {
+		return err
 	}
 
-	// Only allow value of 1 (upvote) or -1 (downvote)
-	if value != 1 && value != -1 {
+	// Only allow value of 1 (upvote) or -1 (downvote), or 0 to remove the vote
+	if value != 1 && value != -1 && value != 0 {
 		return errs.New(errs.ErrInvalid, "Invalid vote value")
 	}
 
-	return service.postVoteRepo.Upsert(&models.PostVote{PostID: postID, UserID: userID, Value: value})
+	// Removing a vote that was never cast changes nothing, so nothing is announced
+	changed := true
+	err := service.transactor.InTransaction(ctx, func(ctx context.Context) error {
+		// If vote value is 0, delete the vote record
+		var err error
+		if value == 0 {
+			if changed, err = service.postVoteRepo.Delete(ctx, postID, userID); err != nil || !changed {
+				return err
+			}
+		} else if err := service.postVoteRepo.Upsert(ctx, &models.PostVote{PostID: postID, UserID: userID, Value: value}); err != nil {
+			return err
+		}
+		return emit(ctx, service.outboxRepo, events.PostVoted{PostID: postID, UserID: userID, Value: value})
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrForeignKeyViolated) {
+			return errs.Wrap(errs.ErrNotFound, "Post or user not found", err)
+		}
+		return err
+	}
+	if !changed {
+		return nil
+	}
+	metrics.VotesCast.WithLabelValues("post", metrics.VoteLabel(value)).Inc()
+	service.listings.invalidateOrLog(ctx, postsNamespace)
+	return nil
 }
 
 // Update a user's vote for a comment
-func (service *VotingService) VoteComment(commentID, userID uint, value int, currentUserID uint) error {
+func (service *VotingService) VoteComment(ctx context.Context, commentID, userID uint, value int, currentUserID uint) error {
+	ctx, span := tracing.Tracer.Start(ctx, "VotingService.VoteComment")
+	defer span.End()
+
 	// Check authorization
 	if currentUserID != userID {
-		return errs.New(errs.ErrUnauthorized, "Unauthorized")
+		return errs.New(errs.ErrForbidden, "Forbidden")
 	}
 
-	// If vote value is 0, delete the vote record
-	if value == 0 {
-		return service.commentVoteRepo.Delete(commentID, userID)
+	// Votes on comments of locked posts cannot be cast, changed or removed
+	comment, err := service.commentRepo.GetByID(ctx, commentID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if comment != nil {
+		if err := checkOpen(ctx, service.postRepo, comment.PostID); err != nil {
+			return err
+		}
 	}
 
-	// Only allow value of 1 (upvote) or -1 (downvote)
-	if value != 1 && value != -1 {
+	// Only allow value of 1 (upvote) or -1 (downvote), or 0 to remove the vote
+	if value != 1 && value != -1 && value != 0 {
 		return errs.New(errs.ErrInvalid, "Invalid vote value")
 	}
 
-	return service.commentVoteRepo.Upsert(&models.CommentVote{CommentID: commentID, UserID: userID, Value: value})
+	// Removing a vote that was never cast changes nothing, so nothing is announced
+	changed := true
+	err = service.transactor.InTransaction(ctx, func(ctx context.Context) error {
+		// If vote value is 0, delete the vote record
+		var err error
+		if value == 0 {
+			if changed, err = service.commentVoteRepo.Delete(ctx, commentID, userID); err != nil || !changed {
+				return err
+			}
+		} else if err := service.commentVoteRepo.Upsert(ctx, &models.CommentVote{CommentID: commentID, UserID: userID, Value: value}); err != nil {
+			return err
+		}
+		return emit(ctx, service.outboxRepo, events.CommentVoted{CommentID: commentID, UserID: userID, Value: value})
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrForeignKeyViolated) {
+			return errs.Wrap(errs.ErrNotFound, "Comment or user not found", err)
+		}
+		return err
+	}
+	if !changed {
+		return nil
+	}
+	metrics.VotesCast.WithLabelValues("comment", metrics.VoteLabel(value)).Inc()
+	return nil
 }
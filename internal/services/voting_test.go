@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	errs "cvwo-backend/internal/errors"
+	"testing"
+)
+
+func TestVotingServiceVotePost(t *testing.T) {
+	f := newFixture(t)
+	service := NewVotingService(f.postVotes, f.commentVotes, f.posts, f.comments, f.outbox, f.transactor, nil)
+	postService := NewPostService(f.posts, f.users, f.topics, f.outbox, f.transactor, f.pollService(), f.attachmentService(), DefaultContentLimits, nil)
+	post := f.createPost(t, f.alice.ID, "post")
+
+	// Cases run in order against the same post, so each builds on the previous vote
+	tests := []struct {
+		name         string
+		postID       uint
+		userID       uint
+		value        int
+		currentUser  uint
+		wantCode     int
+		wantNetVotes int
+	}{
+		{"upvote", post.ID, f.bob.ID, 1, f.bob.ID, noError, 1},
+		{"upvote again is idempotent", post.ID, f.bob.ID, 1, f.bob.ID, noError, 1},
+		{"change to downvote", post.ID, f.bob.ID, -1, f.bob.ID, noError, -1},
+		{"invalid value", post.ID, f.bob.ID, 2, f.bob.ID, errs.ErrInvalid, -1},
+		{"vote on behalf of another user", post.ID, f.bob.ID, 1, f.alice.ID, errs.ErrForbidden, -1},
+		{"remove vote", post.ID, f.bob.ID, 0, f.bob.ID, noError, 0},
+		{"unknown post", 999, f.bob.ID, 1, f.bob.ID, errs.ErrNotFound, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.VotePost(context.Background(), tt.postID, tt.userID, tt.value, tt.currentUser)
+			assertCode(t, err, tt.wantCode)
+
+			got, err := postService.GetByIDWithAuth(context.Background(), post.ID, f.bob.ID)
+			assertCode(t, err, noError)
+			if got.NetVotes != tt.wantNetVotes {
+				t.Errorf("expected net votes %d, got %d", tt.wantNetVotes, got.NetVotes)
+			}
+		})
+	}
+}
+
+func TestVotingServiceVoteComment(t *testing.T) {
+	f := newFixture(t)
+	service := NewVotingService(f.postVotes, f.commentVotes, f.posts, f.comments, f.outbox, f.transactor, nil)
+	post := f.createPost(t, f.alice.ID, "post")
+	comment := f.createComment(t, f.alice.ID, post.ID)
+
+	tests := []struct {
+		name        string
+		commentID   uint
+		value       int
+		currentUser uint
+		wantCode    int
+	}{
+		{"upvote", comment.ID, 1, f.bob.ID, noError},
+		{"downvote", comment.ID, -1, f.bob.ID, noError},
+		{"remove vote", comment.ID, 0, f.bob.ID, noError},
+		{"invalid value", comment.ID, -2, f.bob.ID, errs.ErrInvalid},
+		{"vote on behalf of another user", comment.ID, 1, f.alice.ID, errs.ErrForbidden},
+		{"unknown comment", 999, 1, f.bob.ID, errs.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.VoteComment(context.Background(), tt.commentID, f.bob.ID, tt.value, tt.currentUser)
+			assertCode(t, err, tt.wantCode)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Prefix of files being written, which are renamed to their key once complete
+const tempPrefix = ".tmp-"
+
+// Blob store backed by a directory on the local filesystem
+type LocalStore struct {
+	root string
+}
+
+// Create a store that keeps blobs in the given directory, creating it if needed
+func NewLocalStore(root string) (*LocalStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStore{root}, nil
+}
+
+// Path of the file holding the blob with the given key
+func (store *LocalStore) path(key string) (string, error) {
+	if !filepath.IsLocal(key) || strings.HasPrefix(filepath.Base(key), tempPrefix) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(store.root, filepath.FromSlash(key)), nil
+}
+
+// Write to a temporary file first so that readers never see a partially written blob
+func (store *LocalStore) Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), tempPrefix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name()) // No-op once renamed
+
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func (store *LocalStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := store.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (store *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (store *LocalStore) List(ctx context.Context, fn func(key string, modifiedAt time.Time) error) error {
+	return filepath.WalkDir(store.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), tempPrefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(store.root, path)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(relative), info.ModTime())
+	})
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+type memoryBlob struct {
+	content    []byte
+	modifiedAt time.Time
+}
+
+// Blob store that keeps blobs in memory, for tests
+type MemoryStore struct {
+	mu    sync.Mutex
+	blobs map[string]memoryBlob
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{blobs: make(map[string]memoryBlob)}
+}
+
+func (store *MemoryStore) Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.blobs[key] = memoryBlob{data, time.Now()}
+	return nil
+}
+
+func (store *MemoryStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	blob, exists := store.blobs[key]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(blob.content)), nil
+}
+
+func (store *MemoryStore) Delete(ctx context.Context, key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.blobs, key)
+	return nil
+}
+
+func (store *MemoryStore) List(ctx context.Context, fn func(key string, modifiedAt time.Time) error) error {
+	// Copy the blobs so that fn may call other methods of the store
+	store.mu.Lock()
+	blobs := make(map[string]memoryBlob, len(store.blobs))
+	for key, blob := range store.blobs {
+		blobs[key] = blob
+	}
+	store.mu.Unlock()
+
+	for key, blob := range blobs {
+		if err := fn(key, blob.modifiedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Connection settings for an S3-compatible object store such as AWS S3 or MinIO
+type S3Config struct {
+	// Host and optional port, without the scheme, e.g. "s3.amazonaws.com" or "localhost:9000"
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+}
+
+// Blob store backed by a bucket in an S3-compatible object store
+type S3Store struct {
+	client *minio.Client
+	bucket string
+}
+
+// Connect to the object store. The bucket must already exist.
+func NewS3Store(config S3Config) (*S3Store, error) {
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(config.AccessKey, config.SecretKey, ""),
+		Secure: config.UseSSL,
+		Region: config.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &S3Store{client: client, bucket: config.Bucket}, nil
+}
+
+func (store *S3Store) Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	_, err := store.client.PutObject(ctx, store.bucket, key, content, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (store *S3Store) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	// GetObject is lazy, so check that the object exists before returning it
+	object, err := store.client.GetObject(ctx, store.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return object, nil
+}
+
+func (store *S3Store) Delete(ctx context.Context, key string) error {
+	// S3 does not report an error when deleting an object that does not exist
+	return store.client.RemoveObject(ctx, store.bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (store *S3Store) List(ctx context.Context, fn func(key string, modifiedAt time.Time) error) error {
+	// Cancel the listing if fn returns early
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for object := range store.client.ListObjects(ctx, store.bucket, minio.ListObjectsOptions{Recursive: true}) {
+		if object.Err != nil {
+			return object.Err
+		}
+		if err := fn(object.Key, object.LastModified); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+// Package storage stores uploaded files (blobs) outside the database.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// Returned when opening a blob that does not exist
+var ErrNotFound = errors.New("blob not found")
+
+// Store of blobs addressed by keys such as "3f2a9c.png"
+// Keys are generated by the application, never taken from user input
+type BlobStore interface {
+	// Store a blob, replacing any existing blob with the same key
+	Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error
+	// Open a blob for reading. The caller must close it.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete a blob. Deleting a blob that does not exist is not an error.
+	Delete(ctx context.Context, key string) error
+	// Call fn with the key and last modification time of every blob, stopping at the first error
+	List(ctx context.Context, fn func(key string, modifiedAt time.Time) error) error
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+)
+
+// Start an in-process S3-compatible server as a stand-in for a real object store
+func newTestS3Store(t *testing.T) *S3Store {
+	t.Helper()
+
+	backend := s3mem.New()
+	if err := backend.CreateBucket("attachments"); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+	faker := gofakes3.New(backend).Server()
+	// Unlike S3, gofakes3 treats an empty delimiter param as a delimiter and lists no objects, so drop it
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Has("delimiter") && query.Get("delimiter") == "" {
+			query.Del("delimiter")
+			r.URL.RawQuery = query.Encode()
+		}
+		faker.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	endpoint, _ := url.Parse(server.URL)
+	store, err := NewS3Store(S3Config{Endpoint: endpoint.Host, Region: "us-east-1", Bucket: "attachments", AccessKey: "key", SecretKey: "secret"})
+	if err != nil {
+		t.Fatalf("failed to create S3 store: %v", err)
+	}
+	return store
+}
+
+// Every implementation must behave the same way
+func TestBlobStores(t *testing.T) {
+	stores := []struct {
+		name  string
+		store func(t *testing.T) BlobStore
+	}{
+		{"local", func(t *testing.T) BlobStore {
+			store, err := NewLocalStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("failed to create local store: %v", err)
+			}
+			return store
+		}},
+		{"memory", func(t *testing.T) BlobStore { return NewMemoryStore() }},
+		{"s3", func(t *testing.T) BlobStore { return newTestS3Store(t) }},
+	}
+
+	for _, tt := range stores {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.store(t)
+			ctx := context.Background()
+
+			put := func(key string, content string) {
+				t.Helper()
+				if err := store.Put(ctx, key, strings.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+					t.Fatalf("failed to put %s: %v", key, err)
+				}
+			}
+			read := func(key string) string {
+				t.Helper()
+				blob, err := store.Open(ctx, key)
+				if err != nil {
+					t.Fatalf("failed to open %s: %v", key, err)
+				}
+				defer blob.Close()
+				content, err := io.ReadAll(blob)
+				if err != nil {
+					t.Fatalf("failed to read %s: %v", key, err)
+				}
+				return string(content)
+			}
+
+			put("a.txt", "first")
+			put("b.txt", "second")
+			put("a.txt", "replaced")
+			if content := read("a.txt"); content != "replaced" {
+				t.Errorf("expected replaced content, got %q", content)
+			}
+
+			var keys []string
+			err := store.List(ctx, func(key string, modifiedAt time.Time) error {
+				if modifiedAt.IsZero() {
+					t.Errorf("expected modification time for %s", key)
+				}
+				keys = append(keys, key)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("failed to list: %v", err)
+			}
+			slices.Sort(keys)
+			if !slices.Equal(keys, []string{"a.txt", "b.txt"}) {
+				t.Errorf("expected keys a.txt and b.txt, got %v", keys)
+			}
+
+			if err := store.Delete(ctx, "a.txt"); err != nil {
+				t.Fatalf("failed to delete: %v", err)
+			}
+			if err := store.Delete(ctx, "a.txt"); err != nil {
+				t.Errorf("expected deleting a missing blob to succeed, got %v", err)
+			}
+			if _, err := store.Open(ctx, "a.txt"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected ErrNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLocalStoreRejectsKeysOutsideRoot(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create local store: %v", err)
+	}
+	for _, key := range []string{"../escape.txt", "/etc/passwd", ".tmp-partial"} {
+		if err := store.Put(context.Background(), key, strings.NewReader("x"), 1, "text/plain"); err == nil {
+			t.Errorf("expected key %q to be rejected", key)
+		}
+	}
+}
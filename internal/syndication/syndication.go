@@ -0,0 +1,238 @@
+// Package syndication renders posts as RSS 2.0 and Atom feeds for feed readers.
+package syndication
+
+import (
+	"bytes"
+	"cvwo-backend/internal/models"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Feed of posts, rendered as RSS or Atom
+type Feed struct {
+	// Permanent, unique ID of the feed
+	ID          string
+	Title       string
+	Description string
+	// Page of the forum the feed follows
+	Link string
+	// Latest update of any of the entries
+	Updated time.Time
+	Entries []Entry
+}
+
+// A post in a feed
+type Entry struct {
+	// Permanent, unique ID of the post, which does not change when the post is edited
+	ID         string
+	Title      string
+	Link       string
+	Author     string
+	Categories []string
+	Published  time.Time
+	Updated    time.Time
+	// Sanitized HTML
+	Content string
+}
+
+// Builds feeds linking to the forum's pages under a base URL, such as https://forum.example.com
+type Builder struct {
+	baseURL string
+	// Authority of the tag URIs of entries, which is the host of the base URL
+	authority string
+}
+
+// baseURL must be an absolute http or https URL
+func NewBuilder(baseURL string) (*Builder, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid feed base URL %q: must be an absolute http or https URL", baseURL)
+	}
+	return &Builder{baseURL: strings.TrimSuffix(baseURL, "/"), authority: parsed.Hostname()}, nil
+}
+
+// Absolute URL of a page of the forum, such as /posts/1
+func (builder *Builder) Link(path string) string {
+	return builder.baseURL + path
+}
+
+// Build a feed of posts, identified by and linking to the page at path
+// The feed's update time is that of its most recently updated post, or the Unix epoch if it has none, so that it only changes with its posts.
+func (builder *Builder) Feed(path, title, description string, posts []models.Post) Feed {
+	feed := Feed{
+		ID:          builder.Link(path),
+		Title:       title,
+		Description: description,
+		Link:        builder.Link(path),
+		Updated:     time.Unix(0, 0),
+		Entries:     make([]Entry, 0, len(posts)),
+	}
+	for _, post := range posts {
+		entry := builder.entry(post)
+		if entry.Updated.After(feed.Updated) {
+			feed.Updated = entry.Updated
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
+
+func (builder *Builder) entry(post models.Post) Entry {
+	// Users deleted since are no longer known
+	author := "[deleted]"
+	if post.Author != nil {
+		author = post.Author.Username
+	}
+	categories := make([]string, 0, len(post.Topics))
+	for _, topic := range post.Topics {
+		categories = append(categories, topic.Name)
+	}
+
+	return Entry{
+		// Tag URI (RFC 4151) minted on the day the post was published, which stays the same when the post is edited
+		ID:         fmt.Sprintf("tag:%s,%s:posts/%d", builder.authority, post.CreatedAt.UTC().Format(time.DateOnly), post.ID),
+		Title:      post.Title,
+		Link:       builder.Link(fmt.Sprintf("/posts/%d", post.ID)),
+		Author:     author,
+		Categories: categories,
+		Published:  post.CreatedAt,
+		Updated:    post.UpdatedAt,
+		Content:    post.ContentHTML,
+	}
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	// RSS authors must be email addresses, so the author's name is given with Dublin Core instead
+	Creator    string   `xml:"dc:creator"`
+	Categories []string `xml:"category"`
+	GUID       rssGUID  `xml:"guid"`
+	PubDate    string   `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// Render a feed as RSS 2.0
+func RSS(feed Feed) ([]byte, error) {
+	channel := rssChannel{
+		Title:         feed.Title,
+		Link:          feed.Link,
+		Description:   feed.Description,
+		LastBuildDate: feed.Updated.UTC().Format(time.RFC1123Z),
+		Items:         make([]rssItem, 0, len(feed.Entries)),
+	}
+	for _, entry := range feed.Entries {
+		channel.Items = append(channel.Items, rssItem{
+			Title:       entry.Title,
+			Link:        entry.Link,
+			Description: entry.Content,
+			Creator:     entry.Author,
+			Categories:  entry.Categories,
+			GUID:        rssGUID{IsPermaLink: false, Value: entry.ID},
+			PubDate:     entry.Published.UTC().Format(time.RFC1123Z),
+		})
+	}
+	return encode(rss{Version: "2.0", DC: "http://purl.org/dc/elements/1.1/", Channel: channel})
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle"`
+	Updated  string      `xml:"updated"`
+	Link     atomLink    `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Link       atomLink       `xml:"link"`
+	Author     atomAuthor     `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Render a feed as Atom (RFC 4287)
+func Atom(feed Feed) ([]byte, error) {
+	atom := atomFeed{
+		ID:       feed.ID,
+		Title:    feed.Title,
+		Subtitle: feed.Description,
+		Updated:  feed.Updated.UTC().Format(time.RFC3339),
+		Link:     atomLink{Rel: "alternate", Href: feed.Link},
+		Entries:  make([]atomEntry, 0, len(feed.Entries)),
+	}
+	for _, entry := range feed.Entries {
+		categories := make([]atomCategory, 0, len(entry.Categories))
+		for _, category := range entry.Categories {
+			categories = append(categories, atomCategory{Term: category})
+		}
+		atom.Entries = append(atom.Entries, atomEntry{
+			ID:         entry.ID,
+			Title:      entry.Title,
+			Link:       atomLink{Rel: "alternate", Href: entry.Link},
+			Author:     atomAuthor{Name: entry.Author},
+			Categories: categories,
+			Published:  entry.Published.UTC().Format(time.RFC3339),
+			Updated:    entry.Updated.UTC().Format(time.RFC3339),
+			Content:    atomContent{Type: "html", Value: entry.Content},
+		})
+	}
+	return encode(atom)
+}
+
+func encode(document any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
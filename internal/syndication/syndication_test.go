@@ -0,0 +1,77 @@
+package syndication
+
+import (
+	"cvwo-backend/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewBuilder(t *testing.T) {
+	tests := []struct {
+		baseURL  string
+		wantLink string
+	}{
+		{"https://forum.example.com", "https://forum.example.com/posts/1"},
+		{"http://localhost:5173/", "http://localhost:5173/posts/1"},
+		{"https://example.com/forum", "https://example.com/forum/posts/1"},
+		{"", ""},
+		{"forum.example.com", ""},
+		{"ftp://forum.example.com", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.baseURL, func(t *testing.T) {
+			builder, err := NewBuilder(tt.baseURL)
+			if tt.wantLink == "" {
+				if err == nil {
+					t.Errorf("expected %q to be rejected", tt.baseURL)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := builder.Link("/posts/1"); got != tt.wantLink {
+				t.Errorf("expected link %q, got %q", tt.wantLink, got)
+			}
+		})
+	}
+}
+
+func TestFeed(t *testing.T) {
+	builder, err := NewBuilder("http://localhost:5173")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	published := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	posts := []models.Post{
+		{ID: 2, Title: "Orphaned", ContentHTML: "<p>By a deleted user</p>", CreatedAt: published, UpdatedAt: published.Add(time.Hour)},
+		{ID: 1, Title: "Q&A", ContentHTML: "<p>Hello</p>", CreatedAt: published, UpdatedAt: published, Author: &models.User{Username: "alice"}},
+	}
+
+	feed := builder.Feed("/", "Latest posts", "The newest posts", posts)
+	if !feed.Updated.Equal(published.Add(time.Hour)) {
+		t.Errorf("expected the feed to be updated with its latest post, got %v", feed.Updated)
+	}
+	if feed.Entries[0].Author != "[deleted]" || feed.Entries[1].Author != "alice" {
+		t.Errorf("expected the authors' names, got %+v", feed.Entries)
+	}
+	if feed.Entries[1].ID != "tag:localhost,2026-03-01:posts/1" {
+		t.Errorf("expected a tag URI, got %q", feed.Entries[1].ID)
+	}
+	if empty := builder.Feed("/", "Latest posts", "The newest posts", nil); !empty.Updated.Equal(time.Unix(0, 0)) {
+		t.Errorf("expected an empty feed to never be updated, got %v", empty.Updated)
+	}
+
+	// Titles and HTML content are escaped
+	for name, render := range map[string]func(Feed) ([]byte, error){"rss": RSS, "atom": Atom} {
+		encoded, err := render(feed)
+		if err != nil {
+			t.Fatalf("failed to render %s: %v", name, err)
+		}
+		if !strings.Contains(string(encoded), "Q&amp;A") || !strings.Contains(string(encoded), "&lt;p&gt;Hello&lt;/p&gt;") {
+			t.Errorf("expected escaped content in %s, got %s", name, encoded)
+		}
+	}
+}